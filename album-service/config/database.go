@@ -5,6 +5,7 @@ import (
 	"os"
 	"time"
 
+	"github.com/Zackly23/queue-app/migrations"
 	"github.com/Zackly23/queue-app/models"
 	"github.com/Zackly23/queue-app/seeders"
 	"gorm.io/driver/postgres"
@@ -66,9 +67,23 @@ func (d *Database) ConnectDatabase() (*gorm.DB, error) {
 	// 	return nil, err	
 	// }
 
-	//seed sucbsctiption
-	if err := seeders.SeedSubscriptions(db); err != nil {
-		fmt.Println("Gagal Melakukan Seeding")
+	// kode dan versi plan untuk subscription lama, paling awal karena migrasi lain
+	// ikut meng-AutoMigrate tabel subscriptions lewat relasi (media -> album -> user)
+	if err := migrations.MigrateSubscriptionPlans(db); err != nil {
+		fmt.Println("Failed to migrate subscription plans:", err)
+		return nil, err
+	}
+
+	// gabungkan album_images & album_videos ke tabel media
+	if err := migrations.MigrateLegacyMedia(db); err != nil {
+		fmt.Println("Failed to migrate legacy media:", err)
+		return nil, err
+	}
+
+	// album_likes & media_likes menjadi tabel reaksi
+	if err := migrations.MigrateLikesToReactions(db); err != nil {
+		fmt.Println("Failed to migrate likes to reactions:", err)
+		return nil, err
 	}
 
 	// Auto migrate models
@@ -77,6 +92,37 @@ func (d *Database) ConnectDatabase() (*gorm.DB, error) {
 		return nil, err
 	}
 
+	// kolom tsvector dan index GIN untuk global search
+	if err := migrations.MigrateSearchVectors(db); err != nil {
+		fmt.Println("Failed to migrate search vectors:", err)
+		return nil, err
+	}
+
+	//seed sucbsctiption, setelah AutoMigrate agar kolom plan sudah ada
+	if err := seeders.SeedSubscriptions(db); err != nil {
+		fmt.Println("Gagal Melakukan Seeding:", err)
+	}
+
+	// path album lama sebelum ada sub-album
+	if err := seeders.SeedAlbumPaths(db); err != nil {
+		fmt.Println("Gagal Melakukan Seeding Path Album:", err)
+	}
+
+	// posisi awal media lama untuk urutan manual
+	if err := seeders.SeedMediaPositions(db); err != nil {
+		fmt.Println("Gagal Melakukan Seeding Posisi Media:", err)
+	}
+
+	// jumlah reaksi per jenis dari like lama
+	if err := seeders.SeedReactionCounts(db); err != nil {
+		fmt.Println("Gagal Melakukan Seeding Jumlah Reaksi:", err)
+	}
+
+	// undangan untuk target email lama
+	if err := seeders.SeedAlbumInvitations(db); err != nil {
+		fmt.Println("Gagal Melakukan Seeding Undangan Album:", err)
+	}
+
 	return db, nil
 }
 
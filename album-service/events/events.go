@@ -0,0 +1,51 @@
+package events
+
+import (
+	"log"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// Event adalah domain event sosial (follow, reaksi, komentar, mention, undangan) yang
+// dikirim handler. Pengiriman ke penerima (in-app, email) dilakukan oleh subscriber.
+type Event struct {
+	Type        string
+	RecipientID uuid.UUID
+	ActorID     uuid.UUID
+	AlbumID     *uuid.UUID
+	MediaID     *uuid.UUID
+	CommentID   *uuid.UUID
+	Data        map[string]string // data tambahan, mis. jenis reaksi atau potongan komentar
+}
+
+type Handler func(Event)
+
+var (
+	mu       sync.RWMutex
+	handlers []Handler
+)
+
+// Subscribe mendaftarkan handler yang dipanggil untuk setiap event.
+func Subscribe(handler Handler) {
+	mu.Lock()
+	defer mu.Unlock()
+	handlers = append(handlers, handler)
+}
+
+// Publish mengirim event ke semua subscriber di background agar request tidak tertahan.
+func Publish(event Event) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	for _, handler := range handlers {
+		go func(handler Handler) {
+			defer func() {
+				if r := recover(); r != nil {
+					log.Printf("Subscriber event %s panic: %v", event.Type, r)
+				}
+			}()
+			handler(event)
+		}(handler)
+	}
+}
@@ -36,6 +36,7 @@ type UserSignUpRequest struct {
 	Password        string `json:"password" validate:"required,min=6"`
 	PasswordConfirm string `json:"passwordConfirm" validate:"required"`
 	AgreeTermService bool `json:"agreeTermService" validate:"required"`
+	InvitationToken string `json:"invitationToken,omitempty"` // opsional, dari link undangan album
 }
 
 type UserLoginRequest struct {
@@ -65,6 +66,8 @@ type UserLoginResponse struct {
 	DeactivateUntil	time.Time `json:"deactivate_until,omitempty"`
 	IsTwoFactorEnabled bool `json:"is_two_factor_enabled,omitempty"`
 	AreYouFollowingUser	bool `json:"are_you_following_user"`
+	FollowStatus	string `json:"follow_status,omitempty"` // pending bila permintaan follow belum disetujui
+	IsPrivate	bool `json:"is_private"`
 	Address	  string `json:"address,omitempty"`
 	Country       string          `json:"country,omitempty"`
 	City           string          `json:"city,omitempty"`
@@ -137,6 +140,21 @@ func Login(ctx *fiber.Ctx, db *gorm.DB) error {
 		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"message": "Akun Telah Dihapus Sebelumnya",
 		})
+	case "suspended":
+		// Suspend sementara berakhir otomatis saat login setelah SuspendedUntil
+		if user.SuspendedUntil == nil || time.Now().Before(*user.SuspendedUntil) {
+			return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"message":         "Akun sedang ditangguhkan oleh moderator",
+				"suspended_until": user.SuspendedUntil,
+			})
+		}
+		user.Status = "active"
+		user.SuspendedUntil = nil
+		if err := db.Save(&user).Error; err != nil {
+			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Gagal mengupdate status user",
+			})
+		}
 	case "deactivated":
 		user.Status = "active"
 		user.DeactivateUntil = time.Time{}
@@ -258,9 +276,9 @@ func SignUp(ctx *fiber.Ctx, db *gorm.DB, client notif.NotificationServiceClient)
 		})
 	}
 
-	// Get Subscription ID where SubscriptionType = type-1
+	// User baru mendapat versi terbaru plan default
 	var subscription models.Subscription
-	if err := db.First(&subscription, "subscription_type = ?", "Basic").Error; err != nil {
+	if err := db.Where("code = ? AND is_active = ?", models.DefaultPlanCode, true).Order("version DESC").First(&subscription).Error; err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Gagal Mengambil Data Subscription",
 		})
@@ -300,8 +318,10 @@ func SignUp(ctx *fiber.Ctx, db *gorm.DB, client notif.NotificationServiceClient)
 		StartDate: time.Now(),
 		EndDate: time.Now().AddDate(0,0,30),
 		PaymentMethod: "Free Method",
-		Status: "Free Tier",
+		Status: models.UserSubscriptionFreeTier,
 		Amount: 0.00,
+		Currency: subscription.Currency,
+		ChangeType: models.SubscriptionChangeNew,
 	}
 
 	if err := db.Create(&newUserSubscription).Error; err != nil {
@@ -324,6 +344,21 @@ func SignUp(ctx *fiber.Ctx, db *gorm.DB, client notif.NotificationServiceClient)
 		})
 	}
 
+	// Signup dari link undangan: ikat undangan ke akun yang baru dibuat
+	var invitedAlbumID *uuid.UUID
+	if req.InvitationToken != "" {
+		invitation, errInv := loadInvitationFromToken(db, req.InvitationToken)
+		if errInv == nil {
+			errInv = acceptAlbumInvitation(db, &invitation, user.ID)
+		}
+
+		if errInv != nil {
+			log.Printf("Gagal menerima undangan saat signup %s: %v", user.Email, errInv)
+		} else {
+			invitedAlbumID = &invitation.AlbumID
+		}
+	}
+
 	// 🔄 Kirim notifikasi lewat gRPC di background
 	go func(user models.User, client notif.NotificationServiceClient) {
 		ctxNotif, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -372,6 +407,7 @@ func SignUp(ctx *fiber.Ctx, db *gorm.DB, client notif.NotificationServiceClient)
 			"last_name":   user.LastName,
 		},
 		"user_data" : user,
+		"invited_album_id": invitedAlbumID,
 	})
 	
 }
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/Zackly23/queue-app/models"
+	"github.com/Zackly23/queue-app/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// BlockUser memblokir :userId. Follow dan permintaan follow di kedua arah ikut dihapus.
+func BlockUser(ctx *fiber.Ctx, db *gorm.DB) error {
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	targetID, err := uuid.Parse(ctx.Params("userId"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "User ID tidak valid"})
+	}
+	if targetID == userID {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Tidak bisa memblokir diri sendiri"})
+	}
+
+	var target models.User
+	if err := db.Select("id").First(&target, "id = ?", targetID).Error; err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	errTx := db.Transaction(func(tx *gorm.DB) error {
+		block := models.UserBlock{BlockerID: userID, BlockedID: targetID}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&block).Error; err != nil {
+			return err
+		}
+
+		return tx.Unscoped().
+			Where("(user_id = ? AND following_id = ?) OR (user_id = ? AND following_id = ?)", userID, targetID, targetID, userID).
+			Delete(&models.Following{}).Error
+	})
+	if errTx != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memblokir user"})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"message": "User berhasil diblokir",
+		"user_id": targetID,
+	})
+}
+
+func UnblockUser(ctx *fiber.Ctx, db *gorm.DB) error {
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	targetID, err := uuid.Parse(ctx.Params("userId"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "User ID tidak valid"})
+	}
+
+	result := db.Where("blocker_id = ? AND blocked_id = ?", userID, targetID).Delete(&models.UserBlock{})
+	if result.Error != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuka blokir user"})
+	}
+	if result.RowsAffected == 0 {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User tidak ada di daftar blokir"})
+	}
+
+	return ctx.JSON(fiber.Map{"message": "Blokir user berhasil dibuka"})
+}
+
+// GetBlockedUsers mengambil daftar user yang diblokir (terbaru dulu, cursor pagination).
+func GetBlockedUsers(ctx *fiber.Ctx, db *gorm.DB) error {
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	limit := utils.ParseLimit(ctx.Query("limit"), 20, 100)
+	query := db.Preload("Blocked").Where("blocker_id = ?", userID)
+
+	if cursor := ctx.Query("cursor"); cursor != "" {
+		createdAt, id, err := utils.DecodeCursor(cursor)
+		if err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		query = query.Where("(created_at, blocked_id) < (?, ?)", createdAt, id)
+	}
+
+	var blocks []models.UserBlock
+	if err := query.Order("created_at DESC, blocked_id DESC").Limit(limit + 1).Find(&blocks).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil daftar blokir"})
+	}
+
+	nextCursor := ""
+	if len(blocks) > limit {
+		blocks = blocks[:limit]
+		last := blocks[len(blocks)-1]
+		nextCursor = utils.EncodeCursor(last.CreatedAt, last.BlockedID)
+	}
+
+	type BlockedUserResponse struct {
+		UserID     uuid.UUID `json:"user_id"`
+		User       string    `json:"user"`
+		UserAvatar string    `json:"user_avatar"`
+		BlockedAt  time.Time `json:"blocked_at"`
+	}
+
+	users := []BlockedUserResponse{}
+	for _, block := range blocks {
+		avatar, err := avatarURL(block.Blocked)
+		if err != nil {
+			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal generate presigned URL"})
+		}
+		users = append(users, BlockedUserResponse{
+			UserID:     block.BlockedID,
+			User:       displayName(block.Blocked),
+			UserAvatar: avatar,
+			BlockedAt:  block.CreatedAt,
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"users":       users,
+		"next_cursor": nextCursor,
+		"has_more":    nextCursor != "",
+	})
+}
@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"github.com/Zackly23/queue-app/models"
+	"github.com/Zackly23/queue-app/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type CollectionRequest struct {
+	Name        string `json:"name" validate:"required,min=1,max=100"`
+	Description string `json:"description,omitempty" validate:"max=255"`
+}
+
+type CollectionSummary struct {
+	ID          uuid.UUID `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	AlbumCount  int64     `json:"album_count"`
+	MediaCount  int64     `json:"media_count"`
+	ImageCount  int64     `json:"image_count"`
+	VideoCount  int64     `json:"video_count"`
+	LikesCount  int64     `json:"likes_count"`
+	ViewCount   int64     `json:"view_count"`
+}
+
+// collectionAggregates menghitung jumlah album, media dan like untuk sekumpulan album.
+func collectionAggregates(db *gorm.DB, collection models.Collection, albumIDs []uuid.UUID) (CollectionSummary, error) {
+	summary := CollectionSummary{
+		ID:          collection.ID,
+		Name:        collection.Name,
+		Description: collection.Description,
+		AlbumCount:  int64(len(albumIDs)),
+	}
+
+	if len(albumIDs) == 0 {
+		return summary, nil
+	}
+
+	type albumTotals struct {
+		LikesCount int64
+		ViewCount  int64
+	}
+	var totals albumTotals
+	if err := db.Model(&models.Album{}).
+		Select("COALESCE(SUM(likes_count), 0) AS likes_count, COALESCE(SUM(view_count), 0) AS view_count").
+		Where("id IN ?", albumIDs).
+		Scan(&totals).Error; err != nil {
+		return summary, err
+	}
+
+	if err := db.Model(&models.Media{}).Where("album_id IN ? AND kind = ?", albumIDs, models.MediaKindImage).Count(&summary.ImageCount).Error; err != nil {
+		return summary, err
+	}
+	if err := db.Model(&models.Media{}).Where("album_id IN ?", albumIDs).Count(&summary.MediaCount).Error; err != nil {
+		return summary, err
+	}
+	if err := db.Model(&models.Media{}).Where("album_id IN ? AND kind = ?", albumIDs, models.MediaKindVideo).Count(&summary.VideoCount).Error; err != nil {
+		return summary, err
+	}
+
+	summary.LikesCount = totals.LikesCount
+	summary.ViewCount = totals.ViewCount
+
+	return summary, nil
+}
+
+func findOwnedCollection(ctx *fiber.Ctx, db *gorm.DB) (models.Collection, uuid.UUID, error) {
+	var collection models.Collection
+
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return collection, userID, fiber.NewError(fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	collectionID, err := uuid.Parse(ctx.Params("collectionId"))
+	if err != nil {
+		return collection, userID, fiber.NewError(fiber.StatusBadRequest, "Collection ID tidak valid")
+	}
+
+	if err := db.Where("id = ? AND user_id = ?", collectionID, userID).First(&collection).Error; err != nil {
+		return collection, userID, fiber.NewError(fiber.StatusNotFound, "Collection tidak ditemukan")
+	}
+
+	return collection, userID, nil
+}
+
+func GetCollections(ctx *fiber.Ctx, db *gorm.DB) error {
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var collections []models.Collection
+	if err := db.Where("user_id = ?", userID).Order("name ASC").Find(&collections).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil collection"})
+	}
+
+	response := []CollectionSummary{}
+	for _, collection := range collections {
+		var albumIDs []uuid.UUID
+		if err := db.Table("album_collections").
+			Joins("JOIN albums ON albums.id = album_collections.album_id AND albums.deleted_at IS NULL").
+			Where("album_collections.collection_id = ?", collection.ID).
+			Pluck("album_collections.album_id", &albumIDs).Error; err != nil {
+			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil album collection"})
+		}
+
+		summary, err := collectionAggregates(db, collection, albumIDs)
+		if err != nil {
+			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menghitung statistik collection"})
+		}
+		response = append(response, summary)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":     "Collection berhasil diambil",
+		"collections": response,
+	})
+}
+
+func StoreCollection(ctx *fiber.Ctx, db *gorm.DB) error {
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var req CollectionRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Format permintaan tidak valid"})
+	}
+
+	if err := validate.Struct(req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	collection := models.Collection{
+		UserID:      userID,
+		Name:        req.Name,
+		Description: req.Description,
+	}
+
+	if err := db.Create(&collection).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyimpan collection"})
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message":    "Collection berhasil dibuat",
+		"collection": collection,
+	})
+}
+
+func UpdateCollection(ctx *fiber.Ctx, db *gorm.DB) error {
+	collection, _, err := findOwnedCollection(ctx, db)
+	if err != nil {
+		return fiberErrorResponse(ctx, err)
+	}
+
+	var req CollectionRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Format permintaan tidak valid"})
+	}
+
+	if err := validate.Struct(req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	collection.Name = req.Name
+	collection.Description = req.Description
+
+	if err := db.Save(&collection).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal update collection"})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":    "Collection berhasil diupdate",
+		"collection": collection,
+	})
+}
+
+func DeleteCollection(ctx *fiber.Ctx, db *gorm.DB) error {
+	collection, _, err := findOwnedCollection(ctx, db)
+	if err != nil {
+		return fiberErrorResponse(ctx, err)
+	}
+
+	// Album di dalamnya tidak ikut terhapus, hanya relasinya
+	if err := db.Model(&collection).Association("Albums").Clear(); err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menghapus relasi album"})
+	}
+
+	if err := db.Delete(&collection).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menghapus collection"})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Collection berhasil dihapus",
+	})
+}
+
+func AddAlbumsToCollection(ctx *fiber.Ctx, db *gorm.DB) error {
+	collection, userID, err := findOwnedCollection(ctx, db)
+	if err != nil {
+		return fiberErrorResponse(ctx, err)
+	}
+
+	type addAlbumsRequest struct {
+		AlbumIDs []string `json:"album_ids"`
+	}
+
+	var req addAlbumsRequest
+	if err := ctx.BodyParser(&req); err != nil || len(req.AlbumIDs) == 0 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "album_ids wajib diisi"})
+	}
+
+	// Hanya album milik pemilik collection yang bisa ditambahkan
+	var albums []models.Album
+	if err := db.Where("id IN ? AND user_id = ?", req.AlbumIDs, userID).Find(&albums).Error; err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Album ID tidak valid"})
+	}
+
+	if len(albums) != len(req.AlbumIDs) {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Sebagian album tidak ditemukan atau bukan milik Anda"})
+	}
+
+	if err := db.Model(&collection).Association("Albums").Append(&albums); err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menambahkan album ke collection"})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Album berhasil ditambahkan ke collection",
+	})
+}
+
+func RemoveAlbumFromCollection(ctx *fiber.Ctx, db *gorm.DB) error {
+	collection, _, err := findOwnedCollection(ctx, db)
+	if err != nil {
+		return fiberErrorResponse(ctx, err)
+	}
+
+	albumID, err := uuid.Parse(ctx.Params("albumId"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Album ID tidak valid"})
+	}
+
+	if err := db.Model(&collection).Association("Albums").Delete(&models.Album{ID: albumID}); err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menghapus album dari collection"})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Album berhasil dihapus dari collection",
+	})
+}
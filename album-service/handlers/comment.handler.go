@@ -0,0 +1,534 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Zackly23/queue-app/config"
+	"github.com/Zackly23/queue-app/events"
+	"github.com/Zackly23/queue-app/models"
+	"github.com/Zackly23/queue-app/realtime"
+	"github.com/Zackly23/queue-app/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const maxCommentLength = 2000
+
+type AlbumCommentResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	AlbumID    uuid.UUID  `json:"album_id"`
+	MediaID    *uuid.UUID `json:"media_id,omitempty"`
+	ParentID   *uuid.UUID `json:"parent_id,omitempty"`
+	UserID     uuid.UUID  `json:"user_id"`
+	User       string     `json:"user"` // bisa nama user atau struct kecil
+	UserAvatar string     `json:"user_avatar"`
+	Comment    string     `json:"comment"`
+	Status     string     `json:"status"`
+	ReplyCount int64      `json:"reply_count"`
+	Edited     bool       `json:"edited"`
+	EditedAt   *time.Time `json:"edited_at,omitempty"`
+	CreatedAt  string     `json:"created_at"` // dalam format human-readable
+}
+
+// visibleCommentCondition: komentar hidden hanya terlihat oleh penulisnya dan pemilik album.
+func visibleCommentCondition(db *gorm.DB, album models.Album, userID uuid.UUID) *gorm.DB {
+	if album.UserID == userID {
+		return db
+	}
+	return db.Where("status = ? OR user_id = ?", models.CommentStatusVisible, userID)
+}
+
+// avatarURL membuat presigned URL foto profil user, kosong bila user belum punya foto.
+func avatarURL(user models.User) (string, error) {
+	if user.ProfilePicture == "" {
+		return "", nil
+	}
+	return utils.GeneratePresignedURL(config.S3Bucket.BucketName, utils.S3KeyFromURL(user.ProfilePicture))
+}
+
+func commentResponse(comment models.AlbumComment, replyCount int64) (AlbumCommentResponse, error) {
+	avatarSignedURL, err := avatarURL(comment.User)
+	if err != nil {
+		return AlbumCommentResponse{}, err
+	}
+
+	return AlbumCommentResponse{
+		ID:         comment.ID,
+		AlbumID:    comment.AlbumID,
+		MediaID:    comment.MediaID,
+		ParentID:   comment.ParentID,
+		UserID:     comment.UserID,
+		User:       fmt.Sprintf("%s %s", comment.User.FirstName, comment.User.LastName),
+		UserAvatar: avatarSignedURL,
+		Comment:    comment.Comment,
+		Status:     comment.Status,
+		ReplyCount: replyCount,
+		Edited:     comment.EditedAt != nil,
+		EditedAt:   comment.EditedAt,
+		CreatedAt:  comment.CreatedAt.Format("02 Jan 2006 15:04"),
+	}, nil
+}
+
+// findViewableComment mengambil komentar beserta albumnya dan memastikan user boleh melihatnya.
+func findViewableComment(ctx *fiber.Ctx, db *gorm.DB, userID uuid.UUID) (models.AlbumComment, error) {
+	var comment models.AlbumComment
+	if err := db.Preload("Album").First(&comment, "id = ?", ctx.Params("commentId")).Error; err != nil {
+		return comment, fiber.NewError(fiber.StatusNotFound, "Komentar tidak ditemukan")
+	}
+
+	allowed, err := canViewAlbum(db, comment.Album, userID)
+	if err != nil {
+		return comment, fiber.NewError(fiber.StatusInternalServerError, "Gagal memeriksa akses album")
+	}
+	if !allowed || (comment.Status == models.CommentStatusHidden && comment.UserID != userID && comment.Album.UserID != userID) {
+		return comment, fiber.NewError(fiber.StatusNotFound, "Komentar tidak ditemukan")
+	}
+
+	return comment, nil
+}
+
+func validateCommentText(comment string) (string, error) {
+	comment = strings.TrimSpace(comment)
+	if comment == "" {
+		return "", errors.New("Komentar tidak boleh kosong")
+	}
+	if utf8.RuneCountInString(comment) > maxCommentLength {
+		return "", fmt.Errorf("Komentar maksimal %d karakter", maxCommentLength)
+	}
+	return comment, nil
+}
+
+// GetAlbumComments mengambil komentar album (atau media bila media_id diisi) dengan cursor pagination.
+// Tanpa parent_id yang diambil komentar teratas (terbaru dulu), dengan parent_id yang diambil balasannya
+// (terlama dulu).
+func GetAlbumComments(ctx *fiber.Ctx, db *gorm.DB) error {
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	albumIDParam := ctx.Query("album_id")
+	if albumIDParam == "" {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Album ID harus disertakan"})
+	}
+
+	albumID, err := uuid.Parse(albumIDParam)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Album ID tidak valid"})
+	}
+
+	var album models.Album
+	if err := db.First(&album, "id = ?", albumID).Error; err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Album tidak ditemukan"})
+	}
+
+	allowed, err := canViewAlbum(db, album, userID)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memeriksa akses album"})
+	}
+	if !allowed {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "User tidak memiliki akses ke album ini"})
+	}
+
+	limit := utils.ParseLimit(ctx.Query("limit"), 20, 100)
+	query := visibleCommentCondition(db.Preload("User").Where("album_id = ?", albumID), album, userID).
+		Where(notBlockedByCondition("album_comments.user_id"), userID)
+
+	descending := true
+	if parentID := ctx.Query("parent_id"); parentID != "" {
+		if _, err := uuid.Parse(parentID); err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Parent ID tidak valid"})
+		}
+		query = query.Where("parent_id = ?", parentID)
+		descending = false
+	} else {
+		query = query.Where("parent_id IS NULL")
+		if mediaID := ctx.Query("media_id"); mediaID != "" {
+			if _, err := uuid.Parse(mediaID); err != nil {
+				return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Media ID tidak valid"})
+			}
+			query = query.Where("media_id = ?", mediaID)
+		} else {
+			query = query.Where("media_id IS NULL")
+		}
+	}
+
+	if cursor := ctx.Query("cursor"); cursor != "" {
+		createdAt, id, err := utils.DecodeCursor(cursor)
+		if err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		if descending {
+			query = query.Where("(created_at, id) < (?, ?)", createdAt, id)
+		} else {
+			query = query.Where("(created_at, id) > (?, ?)", createdAt, id)
+		}
+	}
+
+	if descending {
+		query = query.Order("created_at DESC, id DESC")
+	} else {
+		query = query.Order("created_at ASC, id ASC")
+	}
+
+	var comments []models.AlbumComment
+	if err := query.Limit(limit + 1).Find(&comments).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil komentar"})
+	}
+
+	nextCursor := ""
+	if len(comments) > limit {
+		comments = comments[:limit]
+		last := comments[len(comments)-1]
+		nextCursor = utils.EncodeCursor(last.CreatedAt, last.ID)
+	}
+
+	commentIDs := make([]uuid.UUID, 0, len(comments))
+	for _, comment := range comments {
+		commentIDs = append(commentIDs, comment.ID)
+	}
+
+	type replyCountRow struct {
+		ParentID uuid.UUID
+		Total    int64
+	}
+	var replyCounts []replyCountRow
+	if len(commentIDs) > 0 {
+		if err := visibleCommentCondition(db.Model(&models.AlbumComment{}), album, userID).
+			Select("parent_id, COUNT(*) AS total").
+			Where("parent_id IN ?", commentIDs).
+			Group("parent_id").
+			Scan(&replyCounts).Error; err != nil {
+			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menghitung balasan komentar"})
+		}
+	}
+
+	replies := map[uuid.UUID]int64{}
+	for _, row := range replyCounts {
+		replies[row.ParentID] = row.Total
+	}
+
+	response := []AlbumCommentResponse{}
+	for _, comment := range comments {
+		item, err := commentResponse(comment, replies[comment.ID])
+		if err != nil {
+			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal generate presigned URL"})
+		}
+		response = append(response, item)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"message":     "Komen Berhasil Diambil",
+		"album_id":    albumID,
+		"comments":    response,
+		"next_cursor": nextCursor,
+		"has_more":    nextCursor != "",
+	})
+}
+
+// PostAlbumComment menambah komentar atau balasan. Penulis selalu diambil dari token.
+func PostAlbumComment(ctx *fiber.Ctx, db *gorm.DB) error {
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	type requestBody struct {
+		AlbumID  string `json:"album_id"`
+		MediaID  string `json:"media_id"`
+		ParentID string `json:"parent_id"`
+		Comment  string `json:"comment"`
+	}
+
+	var req requestBody
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Format permintaan tidak valid"})
+	}
+
+	albumID, err := uuid.Parse(req.AlbumID)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Album ID tidak valid"})
+	}
+
+	text, err := validateCommentText(req.Comment)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	// Cek apakah album ada dan bisa dilihat user
+	var album models.Album
+	if err := db.First(&album, "id = ?", albumID).Error; err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Album tidak ditemukan"})
+	}
+
+	allowed, err := canViewAlbum(db, album, userID)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memeriksa akses album"})
+	}
+	if !allowed {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "User tidak memiliki akses ke album ini"})
+	}
+
+	comment := models.AlbumComment{
+		AlbumID: albumID,
+		UserID:  userID,
+		Comment: text,
+		Status:  models.CommentStatusVisible,
+	}
+
+	if req.MediaID != "" {
+		var media models.Media
+		if err := db.Where("id = ? AND album_id = ?", req.MediaID, albumID).First(&media).Error; err != nil {
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Media tidak ditemukan di album ini"})
+		}
+		comment.MediaID = &media.ID
+	}
+
+	// Balasan selalu mengikuti album dan media komentar induknya
+	if req.ParentID != "" {
+		var parent models.AlbumComment
+		if err := db.Where("id = ? AND album_id = ?", req.ParentID, albumID).First(&parent).Error; err != nil {
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Komentar induk tidak ditemukan"})
+		}
+		if parent.Status == models.CommentStatusHidden && parent.UserID != userID && album.UserID != userID {
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Komentar induk tidak ditemukan"})
+		}
+		comment.ParentID = &parent.ID
+		comment.MediaID = parent.MediaID
+	}
+
+	if err := db.Create(&comment).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyimpan komentar"})
+	}
+
+	var author models.User
+	if err := db.First(&author, "id = ?", userID).Error; err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User tidak ditemukan"})
+	}
+	if err := processCommentText(db, album, comment, author); err != nil {
+		log.Printf("Gagal menyimpan mention/hashtag komentar %s: %v", comment.ID, err)
+	}
+
+	publishCommentEvents(db, album, comment)
+
+	comment.User = author
+	if data, err := commentResponse(comment, 0); err == nil {
+		realtime.Broadcast(realtime.AlbumChannel(album.ID), realtime.MessageCommentCreated, data)
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Komentar berhasil ditambahkan",
+		"data":    comment,
+	})
+}
+
+// publishCommentEvents memberi tahu pemilik album dan penulis komentar induk (untuk balasan).
+func publishCommentEvents(db *gorm.DB, album models.Album, comment models.AlbumComment) {
+	event := events.Event{
+		ActorID:   comment.UserID,
+		AlbumID:   &album.ID,
+		MediaID:   comment.MediaID,
+		CommentID: &comment.ID,
+		Data:      map[string]string{"comment": comment.Comment},
+	}
+
+	var parentAuthorID uuid.UUID
+	if comment.ParentID != nil {
+		var parent models.AlbumComment
+		if err := db.Select("user_id").First(&parent, "id = ?", comment.ParentID).Error; err == nil {
+			parentAuthorID = parent.UserID
+			reply := event
+			reply.Type = models.NotificationEventCommentReply
+			reply.RecipientID = parent.UserID
+			events.Publish(reply)
+		}
+	}
+
+	// Pemilik album yang juga penulis komentar induk cukup menerima notifikasi balasan
+	if album.UserID != parentAuthorID {
+		event.Type = models.NotificationEventComment
+		event.RecipientID = album.UserID
+		events.Publish(event)
+	}
+}
+
+// UpdateAlbumComment mengubah isi komentar milik sendiri, isi sebelumnya disimpan sebagai riwayat.
+func UpdateAlbumComment(ctx *fiber.Ctx, db *gorm.DB) error {
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var req struct {
+		Comment string `json:"comment"`
+	}
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Format permintaan tidak valid"})
+	}
+
+	text, err := validateCommentText(req.Comment)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var comment models.AlbumComment
+	if err := db.First(&comment, "id = ?", ctx.Params("commentId")).Error; err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Komentar tidak ditemukan"})
+	}
+	if comment.UserID != userID {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Hanya penulis yang bisa mengedit komentar"})
+	}
+	if comment.Comment == text {
+		return ctx.JSON(fiber.Map{"message": "Komentar tidak berubah", "data": comment})
+	}
+
+	editedAt := time.Now()
+	errTx := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&models.AlbumCommentEdit{CommentID: comment.ID, Comment: comment.Comment}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&comment).Updates(map[string]interface{}{
+			"comment":   text,
+			"edited_at": editedAt,
+		}).Error
+	})
+	if errTx != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengedit komentar"})
+	}
+
+	var album models.Album
+	var author models.User
+	if err := db.First(&album, "id = ?", comment.AlbumID).Error; err == nil && db.First(&author, "id = ?", userID).Error == nil {
+		if err := processCommentText(db, album, comment, author); err != nil {
+			log.Printf("Gagal menyimpan mention/hashtag komentar %s: %v", comment.ID, err)
+		}
+	}
+
+	realtime.Broadcast(realtime.AlbumChannel(comment.AlbumID), realtime.MessageCommentUpdated, fiber.Map{
+		"id":        comment.ID,
+		"comment":   comment.Comment,
+		"edited_at": comment.EditedAt,
+	})
+
+	return ctx.JSON(fiber.Map{
+		"message": "Komentar berhasil diedit",
+		"data":    comment,
+	})
+}
+
+// GetAlbumCommentHistory mengambil riwayat edit komentar (terbaru dulu).
+func GetAlbumCommentHistory(ctx *fiber.Ctx, db *gorm.DB) error {
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	comment, err := findViewableComment(ctx, db, userID)
+	if err != nil {
+		return fiberErrorResponse(ctx, err)
+	}
+
+	var edits []models.AlbumCommentEdit
+	if err := db.Where("comment_id = ?", comment.ID).Order("created_at DESC").Find(&edits).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil riwayat komentar"})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"comment_id": comment.ID,
+		"current":    comment.Comment,
+		"edited_at":  comment.EditedAt,
+		"history":    edits,
+	})
+}
+
+// deleteCommentThread menghapus (soft delete) komentar beserta seluruh balasannya.
+func deleteCommentThread(db *gorm.DB, commentID uuid.UUID, deletedByID uuid.UUID) (int64, error) {
+	result := db.Exec(`
+		WITH RECURSIVE thread AS (
+			SELECT id FROM album_comments WHERE id = ?
+			UNION ALL
+			SELECT c.id FROM album_comments c JOIN thread t ON c.parent_id = t.id
+		)
+		UPDATE album_comments SET deleted_at = ?, deleted_by_id = ?
+		WHERE id IN (SELECT id FROM thread) AND deleted_at IS NULL`,
+		commentID, time.Now(), deletedByID)
+	return result.RowsAffected, result.Error
+}
+
+// DeleteAlbumComment menghapus komentar beserta seluruh balasannya.
+// Bisa dilakukan oleh penulis komentar atau pemilik album.
+func DeleteAlbumComment(ctx *fiber.Ctx, db *gorm.DB) error {
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var comment models.AlbumComment
+	if err := db.Preload("Album").First(&comment, "id = ?", ctx.Params("commentId")).Error; err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Komentar tidak ditemukan"})
+	}
+	if comment.UserID != userID && comment.Album.UserID != userID {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "User tidak diperbolehkan menghapus komentar ini"})
+	}
+
+	deletedCount, err := deleteCommentThread(db, comment.ID, userID)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menghapus komentar"})
+	}
+
+	realtime.Broadcast(realtime.AlbumChannel(comment.AlbumID), realtime.MessageCommentDeleted, fiber.Map{
+		"id":            comment.ID,
+		"deleted_count": deletedCount,
+	})
+
+	return ctx.JSON(fiber.Map{
+		"message":       "Komentar berhasil dihapus",
+		"deleted_count": deletedCount,
+	})
+}
+
+// ModerateAlbumComment menyembunyikan atau menampilkan kembali komentar di album milik user.
+func ModerateAlbumComment(ctx *fiber.Ctx, db *gorm.DB) error {
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var req struct {
+		Status string `json:"status"` // "visible" atau "hidden"
+	}
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Format permintaan tidak valid"})
+	}
+	if req.Status != models.CommentStatusVisible && req.Status != models.CommentStatusHidden {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Status harus visible atau hidden"})
+	}
+
+	var comment models.AlbumComment
+	if err := db.Preload("Album").First(&comment, "id = ?", ctx.Params("commentId")).Error; err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Komentar tidak ditemukan"})
+	}
+	if comment.Album.UserID != userID {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Hanya pemilik album yang bisa memoderasi komentar"})
+	}
+
+	if err := db.Model(&comment).Update("status", req.Status).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memoderasi komentar"})
+	}
+
+	realtime.Broadcast(realtime.AlbumChannel(comment.AlbumID), realtime.MessageCommentModerated, fiber.Map{
+		"id":     comment.ID,
+		"status": comment.Status,
+	})
+
+	return ctx.JSON(fiber.Map{
+		"message": "Status komentar berhasil diubah",
+		"data":    comment,
+	})
+}
@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Zackly23/queue-app/config"
+	"github.com/Zackly23/queue-app/jobs"
+	"github.com/Zackly23/queue-app/models"
+	notif "github.com/Zackly23/queue-app/proto/notificationpb"
+	"github.com/Zackly23/queue-app/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+var unsafeFileNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// canDownloadAlbum mengecek izin download ZIP: pemilik selalu boleh, viewer harus bisa melihat album,
+// album mengizinkan download, dan undangan user (bila ada) tidak mematikan izin download.
+func canDownloadAlbum(db *gorm.DB, album models.Album, userID uuid.UUID) (bool, error) {
+	if album.UserID == userID {
+		return true, nil
+	}
+
+	allowed, err := canViewAlbum(db, album, userID)
+	if err != nil || !allowed {
+		return false, err
+	}
+
+	if !album.AllowDownload {
+		return false, nil
+	}
+
+	var blocked int64
+	if err := db.Model(&models.AlbumInvitation{}).
+		Where("album_id = ? AND user_id = ? AND status = ? AND allow_download = ?", album.ID, userID, models.InvitationStatusAccepted, false).
+		Count(&blocked).Error; err != nil {
+		return false, err
+	}
+
+	return blocked == 0, nil
+}
+
+// downloadableMedia mengambil media yang ikut diunduh (semua atau mediaIDs saja). Isi smart album
+// dihitung dengan filter dan hak akses yang sama seperti saat album dibuka.
+func downloadableMedia(db *gorm.DB, album models.Album, userID uuid.UUID, mediaIDs []string) ([]models.Media, error) {
+	if album.AlbumType == models.AlbumTypeSmart {
+		medias, err := loadSmartAlbumMedia(db, album, userID)
+		if err != nil || len(mediaIDs) == 0 {
+			return medias, err
+		}
+
+		requested := map[string]bool{}
+		for _, id := range mediaIDs {
+			requested[id] = true
+		}
+		selected := []models.Media{}
+		for _, media := range medias {
+			if requested[media.ID.String()] {
+				selected = append(selected, media)
+			}
+		}
+		return selected, nil
+	}
+
+	query := db.Where("album_id = ?", album.ID)
+	if len(mediaIDs) > 0 {
+		query = query.Where("id IN ?", mediaIDs)
+	}
+	if album.UserID != userID {
+		query = query.Where("moderation_status = ?", models.ModerationStatusVisible)
+	}
+
+	var medias []models.Media
+	err := query.Order("position ASC, created_at ASC").Find(&medias).Error
+	return medias, err
+}
+
+func DownloadAlbum(ctx *fiber.Ctx, db *gorm.DB, client notif.NotificationServiceClient) error {
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	albumID, err := uuid.Parse(ctx.Params("albumId"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Album ID tidak valid"})
+	}
+
+	var album models.Album
+	if err := db.First(&album, "id = ?", albumID).Error; err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Album tidak ditemukan"})
+	}
+
+	allowed, err := canDownloadAlbum(db, album, userID)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memeriksa akses album"})
+	}
+	if !allowed {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "User tidak diperbolehkan mengunduh album"})
+	}
+
+	// media_ids=a,b,c untuk mengunduh sebagian media saja
+	var mediaIDs []string
+	for _, id := range strings.Split(ctx.Query("media_ids"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			parsed, err := uuid.Parse(id)
+			if err != nil {
+				return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Media ID tidak valid"})
+			}
+			mediaIDs = append(mediaIDs, parsed.String())
+		}
+	}
+
+	medias, err := downloadableMedia(db, album, userID, mediaIDs)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil media album"})
+	}
+
+	if len(mediaIDs) > 0 && len(medias) != len(mediaIDs) {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Sebagian media tidak ditemukan di album"})
+	}
+	if len(medias) == 0 {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Album tidak memiliki media"})
+	}
+
+	var totalSize float32
+	for _, media := range medias {
+		totalSize += media.Size
+	}
+
+	// Album besar diproses di background, link arsip dikirim lewat notifikasi
+	if totalSize > utils.ArchiveStreamLimitMB() {
+		// Isi smart album bisa berubah, export memakai daftar media saat diminta
+		if album.AlbumType == models.AlbumTypeSmart {
+			mediaIDs = mediaIDs[:0]
+			for _, media := range medias {
+				mediaIDs = append(mediaIDs, media.ID.String())
+			}
+		}
+
+		export := models.AlbumExport{
+			AlbumID:    album.ID,
+			UserID:     userID,
+			MediaIDs:   pq.StringArray(mediaIDs),
+			Status:     models.ExportStatusPending,
+			MediaCount: len(medias),
+			TotalSize:  totalSize,
+		}
+
+		if err := db.Create(&export).Error; err != nil {
+			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuat export album"})
+		}
+
+		go jobs.ProcessAlbumExport(db, client, export.ID.String())
+
+		return ctx.Status(fiber.StatusAccepted).JSON(fiber.Map{
+			"message": "Album terlalu besar untuk diunduh langsung, link arsip akan dikirim lewat email",
+			"export":  export,
+		})
+	}
+
+	fileName := unsafeFileNameChars.ReplaceAllString(album.Title, "_")
+	if fileName == "" || fileName == "_" {
+		fileName = album.ID.String()
+	}
+
+	ctx.Set(fiber.HeaderContentType, "application/zip")
+	ctx.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s.zip"`, fileName))
+
+	// Setiap file langsung di-copy dari S3 ke response, header sudah terkirim sehingga error hanya bisa dicatat
+	ctx.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if err := utils.WriteMediaZip(context.Background(), w, medias, w.Flush); err != nil {
+			log.Printf("Gagal streaming zip album %s: %v", album.ID, err)
+		}
+		w.Flush()
+	})
+
+	return nil
+}
+
+func GetAlbumExport(ctx *fiber.Ctx, db *gorm.DB) error {
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var export models.AlbumExport
+	if err := db.Where("id = ? AND user_id = ?", ctx.Params("exportId"), userID).First(&export).Error; err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Export album tidak ditemukan"})
+	}
+
+	downloadURL := ""
+	if export.Status == models.ExportStatusCompleted && export.ExpiresAt != nil && export.ExpiresAt.After(time.Now()) {
+		url, err := utils.GeneratePresignedURLWithExpiry(config.S3Bucket.BucketName, export.ObjectKey, time.Until(*export.ExpiresAt))
+		if err != nil {
+			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal generate presigned URL"})
+		}
+		downloadURL = url
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":      "Export album berhasil diambil",
+		"export":       export,
+		"download_url": downloadURL,
+	})
+}
+
+func UpdateInvitationDownload(ctx *fiber.Ctx, db *gorm.DB) error {
+	album, _, err := findOwnedAlbum(ctx, db, "albumId")
+	if err != nil {
+		return fiberErrorResponse(ctx, err)
+	}
+
+	type downloadRequest struct {
+		AllowDownload bool `json:"allow_download"`
+	}
+
+	var req downloadRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Format permintaan tidak valid"})
+	}
+
+	result := db.Model(&models.AlbumInvitation{}).
+		Where("id = ? AND album_id = ?", ctx.Params("invitationId"), album.ID).
+		Update("allow_download", req.AllowDownload)
+	if result.Error != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal update izin download"})
+	}
+	if result.RowsAffected == 0 {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Undangan tidak ditemukan"})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":        "Izin download undangan berhasil diupdate",
+		"allow_download": req.AllowDownload,
+	})
+}
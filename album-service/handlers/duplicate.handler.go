@@ -0,0 +1,381 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+
+	"github.com/Zackly23/queue-app/config"
+	"github.com/Zackly23/queue-app/models"
+	"github.com/Zackly23/queue-app/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	DuplicateModeReference = "reference" // media baru memakai file S3 yang sama
+	DuplicateModeCopy      = "copy"      // file S3 disalin, ukurannya dihitung ke kuota pemilik baru
+)
+
+type DuplicateAlbumRequest struct {
+	Title           string  `json:"title"`
+	Description     *string `json:"description"`
+	AlbumPrivacy    string  `json:"album_privacy"`
+	ParentID        string  `json:"parent_id"`
+	Mode            string  `json:"mode"`             // "reference" (default) atau "copy"
+	IncludeComments bool    `json:"include_comments"` // hanya untuk album milik sendiri
+	IncludeLikes    bool    `json:"include_likes"`    // hanya untuk album milik sendiri
+}
+
+// DuplicateAlbum membuat salinan album (metadata, tags, media) untuk user yang login.
+// Pemilik bisa menduplikasi albumnya sendiri, user lain hanya bila diizinkan mengunduh album.
+func DuplicateAlbum(ctx *fiber.Ctx, db *gorm.DB) error {
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var source models.Album
+	if err := db.Preload("Tags").Preload("Media", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("position ASC, created_at ASC")
+	}).First(&source, "id = ?", ctx.Params("albumId")).Error; err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Album tidak ditemukan"})
+	}
+
+	allowed, err := canDownloadAlbum(db, source, userID)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memeriksa akses album"})
+	}
+	if !allowed {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "User tidak diperbolehkan menduplikasi album"})
+	}
+
+	// Media yang disembunyikan atau dihapus moderator hanya ikut bila pemilik menduplikasi albumnya sendiri
+	if source.UserID != userID {
+		visible := source.Media[:0]
+		for _, media := range source.Media {
+			if media.ModerationStatus == models.ModerationStatusVisible {
+				visible = append(visible, media)
+			}
+		}
+		source.Media = visible
+	}
+
+	var req DuplicateAlbumRequest
+	if len(ctx.Body()) > 0 {
+		if err := ctx.BodyParser(&req); err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Format permintaan tidak valid"})
+		}
+	}
+
+	// Komentar dan reaksi milik user lain tidak boleh ikut terbawa ke album hasil fork
+	if source.UserID != userID && (req.IncludeComments || req.IncludeLikes) {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Komentar dan like hanya bisa disalin dari album milik sendiri"})
+	}
+
+	if req.Mode == "" {
+		req.Mode = DuplicateModeReference
+	}
+	if req.Mode != DuplicateModeReference && req.Mode != DuplicateModeCopy {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Mode harus reference atau copy"})
+	}
+	if req.AlbumPrivacy == "" {
+		req.AlbumPrivacy = source.AlbumPrivacy
+	}
+	if _, ok := privacyRank[req.AlbumPrivacy]; !ok {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Album privacy tidak valid"})
+	}
+	if req.Title == "" {
+		req.Title = source.Title + " (Copy)"
+	}
+	description := source.Description
+	if req.Description != nil {
+		description = *req.Description
+	}
+
+	// Parent default: tetap di parent yang sama bila album milik sendiri, root bila fork album orang lain
+	var parent *models.Album
+	parentIDStr := req.ParentID
+	if parentIDStr == "" && source.UserID == userID && source.ParentID != nil {
+		parentIDStr = source.ParentID.String()
+	}
+	if parentIDStr != "" && parentIDStr != "root" {
+		if _, err := uuid.Parse(parentIDStr); err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Parent ID tidak valid"})
+		}
+		var parentAlbum models.Album
+		if err := db.Where("id = ? AND user_id = ?", parentIDStr, userID).First(&parentAlbum).Error; err != nil {
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Parent album tidak ditemukan"})
+		}
+		parent = &parentAlbum
+	}
+
+	// Salinan fisik membebani kuota pemilik baru
+	if req.Mode == DuplicateModeCopy {
+		var user models.User
+		if err := db.Preload("Subscription").First(&user, "id = ?", userID).Error; err != nil {
+			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil data subscription user"})
+		}
+
+		storageUsed, err := utils.CalculateStorageUsed(db, userID)
+		if err != nil {
+			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menghitung penggunaan storage"})
+		}
+
+		var copySize float64
+		for _, media := range source.Media {
+			copySize += float64(media.Size)
+		}
+
+		storageCapacity := user.Subscription.StorageCapacity * 1024 // GB ke MB
+		if storageCapacity > 0 && storageUsed+copySize > storageCapacity {
+			return ctx.Status(fiber.StatusUnavailableForLegalReasons).JSON(fiber.Map{
+				"error": fmt.Sprintf("Kapasitas penyimpanan tidak cukup. Maksimum %.2f GB", user.Subscription.StorageCapacity),
+			})
+		}
+	}
+
+	album := models.Album{
+		ID:            uuid.New(),
+		UserID:        userID,
+		ParentID:      nil,
+		SourceAlbumID: &source.ID,
+		Title:         req.Title,
+		Description:   description,
+		AlbumPrivacy:  req.AlbumPrivacy,
+		AlbumType:     source.AlbumType,
+		SmartFilter:   source.SmartFilter,
+		AllowDownload: source.AllowDownload,
+	}
+	if parent != nil {
+		album.ParentID = &parent.ID
+	}
+	album.Path = albumPath(parent, album.ID)
+
+	medias, copiedURLs, err := duplicateMedia(source, album.ID, req.Mode)
+	if err != nil {
+		log.Printf("Gagal menyalin media album %s: %v", source.ID, err)
+		cleanupCopiedObjects(copiedURLs)
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyalin media album"})
+	}
+
+	errTx := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&album).Error; err != nil {
+			return err
+		}
+		// default:true pada AllowDownload membuat nilai false diabaikan saat create
+		if !album.AllowDownload {
+			if err := tx.Model(&album).Update("allow_download", false).Error; err != nil {
+				return err
+			}
+		}
+
+		if len(source.Tags) > 0 {
+			if err := tx.Model(&album).Association("Tags").Append(source.Tags); err != nil {
+				return err
+			}
+		}
+
+		mediaIDs := map[uuid.UUID]uuid.UUID{} // id media asal -> id media baru
+		for i := range medias {
+			if err := tx.Create(&medias[i]).Error; err != nil {
+				return err
+			}
+			mediaIDs[*medias[i].SourceMediaID] = medias[i].ID
+		}
+
+		// Cover pilihan manual ikut dipetakan ke media baru
+		if source.CoverMediaID != nil {
+			if coverID, ok := mediaIDs[*source.CoverMediaID]; ok {
+				if err := tx.Model(&album).Update("cover_media_id", coverID).Error; err != nil {
+					return err
+				}
+			}
+		}
+		if err := refreshAlbumCover(tx, album.ID); err != nil {
+			return err
+		}
+
+		if req.IncludeComments {
+			if err := duplicateAlbumComments(tx, source.ID, album.ID, mediaIDs); err != nil {
+				return err
+			}
+		}
+
+		if req.IncludeLikes {
+			if err := duplicateLikes(tx, source, album.ID, mediaIDs); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if errTx != nil {
+		log.Printf("Gagal menduplikasi album %s: %v", source.ID, errTx)
+		cleanupCopiedObjects(copiedURLs)
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menduplikasi album"})
+	}
+
+	if err := db.Preload("Tags").Preload("Media", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("position ASC")
+	}).First(&album, "id = ?", album.ID).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil album hasil duplikasi"})
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Album berhasil diduplikasi",
+		"mode":    req.Mode,
+		"album":   album,
+	})
+}
+
+// duplicateMedia menyiapkan record media untuk album baru. Pada mode copy file S3 disalin
+// lebih dulu; URL hasil salinan dikembalikan agar bisa dibersihkan bila terjadi error.
+func duplicateMedia(source models.Album, albumID uuid.UUID, mode string) ([]models.Media, []string, error) {
+	medias := make([]models.Media, 0, len(source.Media))
+	copiedURLs := []string{}
+
+	for _, media := range source.Media {
+		sourceID := media.ID
+		duplicate := models.Media{
+			AlbumID:        albumID,
+			Kind:           media.Kind,
+			URL:            media.URL,
+			ThumbnailURL:   media.ThumbnailURL,
+			Title:          media.Title,
+			Description:    media.Description,
+			Size:           media.Size,
+			Type:           media.Type,
+			Position:       media.Position,
+			Metadata:       media.Metadata,
+			PerceptualHash: media.PerceptualHash,
+			CapturedAt:     media.CapturedAt,
+			Latitude:       media.Latitude,
+			Longitude:      media.Longitude,
+			ExifScanned:    media.ExifScanned,
+			SourceMediaID:  &sourceID,
+			IsReference:    mode == DuplicateModeReference,
+		}
+
+		if mode == DuplicateModeCopy {
+			folder := "media"
+			if kind, ok := models.LookupMediaKind(media.Kind); ok {
+				folder = kind.StorageFolder
+			}
+
+			key := folder + "/albums/album_" + albumID.String() + "/" + path.Base(utils.S3KeyFromURL(media.URL))
+			url, err := utils.CopyS3Object(context.Background(), utils.S3KeyFromURL(media.URL), key)
+			if err != nil {
+				return nil, copiedURLs, err
+			}
+			copiedURLs = append(copiedURLs, url)
+
+			if duplicate.ThumbnailURL == media.URL {
+				duplicate.ThumbnailURL = url
+			}
+			duplicate.URL = url
+		}
+
+		medias = append(medias, duplicate)
+	}
+
+	return medias, copiedURLs, nil
+}
+
+func cleanupCopiedObjects(urls []string) {
+	for _, url := range urls {
+		if err := utils.DeleteFromS3(url, config.S3Bucket.BucketName); err != nil {
+			log.Printf("Gagal hapus file salinan %s: %v", url, err)
+		}
+	}
+}
+
+// duplicateAlbumComments menyalin komentar beserta struktur balasan dan media tujuannya.
+// Komentar pada media yang tidak ikut tersalin dilewati bersama balasannya.
+func duplicateAlbumComments(tx *gorm.DB, sourceID, albumID uuid.UUID, mediaIDs map[uuid.UUID]uuid.UUID) error {
+	var comments []models.AlbumComment
+	if err := tx.Where("album_id = ?", sourceID).Order("created_at ASC").Find(&comments).Error; err != nil {
+		return err
+	}
+
+	commentIDs := map[uuid.UUID]uuid.UUID{} // id komentar asal -> id komentar baru
+	for _, comment := range comments {
+		duplicate := models.AlbumComment{
+			AlbumID:   albumID,
+			UserID:    comment.UserID,
+			Comment:   comment.Comment,
+			Status:    comment.Status,
+			EditedAt:  comment.EditedAt,
+			CreatedAt: comment.CreatedAt,
+		}
+
+		if comment.MediaID != nil {
+			mediaID, ok := mediaIDs[*comment.MediaID]
+			if !ok {
+				continue
+			}
+			duplicate.MediaID = &mediaID
+		}
+
+		if comment.ParentID != nil {
+			parentID, ok := commentIDs[*comment.ParentID]
+			if !ok {
+				continue
+			}
+			duplicate.ParentID = &parentID
+		}
+
+		if err := tx.Create(&duplicate).Error; err != nil {
+			return err
+		}
+		commentIDs[comment.ID] = duplicate.ID
+	}
+
+	return nil
+}
+
+// duplicateLikes menyalin reaksi album dan media beserta counter-nya.
+func duplicateLikes(tx *gorm.DB, source models.Album, albumID uuid.UUID, mediaIDs map[uuid.UUID]uuid.UUID) error {
+	var albumReactions []models.AlbumReaction
+	if err := tx.Where("album_id = ?", source.ID).Find(&albumReactions).Error; err != nil {
+		return err
+	}
+	for _, reaction := range albumReactions {
+		if err := tx.Create(&models.AlbumReaction{AlbumID: albumID, UserID: reaction.UserID, Type: reaction.Type}).Error; err != nil {
+			return err
+		}
+	}
+	if err := tx.Model(&models.Album{}).Where("id = ?", albumID).Updates(map[string]interface{}{
+		"likes_count":     source.LikesCount,
+		"reaction_counts": source.ReactionCounts,
+	}).Error; err != nil {
+		return err
+	}
+
+	for _, media := range source.Media {
+		newID, ok := mediaIDs[media.ID]
+		if !ok {
+			continue
+		}
+
+		var mediaReactions []models.MediaReaction
+		if err := tx.Where("media_id = ?", media.ID).Find(&mediaReactions).Error; err != nil {
+			return err
+		}
+		for _, reaction := range mediaReactions {
+			if err := tx.Create(&models.MediaReaction{MediaID: newID, UserID: reaction.UserID, Type: reaction.Type}).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&models.Media{}).Where("id = ?", newID).Updates(map[string]interface{}{
+			"likes_count":     media.LikesCount,
+			"reaction_counts": media.ReactionCounts,
+		}).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
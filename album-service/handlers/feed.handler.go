@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Zackly23/queue-app/config"
+	"github.com/Zackly23/queue-app/models"
+	"github.com/Zackly23/queue-app/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+// Bobot skor feed: score = recency * (1 + engagement) * (1 + affinity)
+const (
+	feedHalfLifeHours        = 48.0                // skor recency turun setengah tiap 48 jam
+	feedRecommendationWindow = 30 * 24 * time.Hour // album non-follow & tanpa tag cocok hanya yang baru
+	feedFollowAffinity       = 2.0
+	feedTagAffinity          = 0.5 // per tag yang cocok dengan TagPreference, maksimal 3 tag
+	feedViewWeight           = 0.2
+	feedCommentWeight        = 2.0
+	feedInteractionWindow    = 90 * 24 * time.Hour // reaksi/komentar viewer ke pemilik album
+)
+
+const (
+	FeedReasonFollowing = "following"
+	FeedReasonTags      = "tags"
+	FeedReasonPopular   = "popular"
+)
+
+type FeedAlbumResponse struct {
+	AlbumID      uuid.UUID  `json:"album_id"`
+	Title        string     `json:"title"`
+	Description  string     `json:"description"`
+	MediaCount   int        `json:"media_count"`
+	ImageCount   int        `json:"image_count"`
+	VideoCount   int        `json:"video_count"`
+	ThumbnailURL string     `json:"thumbnail_url"`
+	LikesCount   uint       `json:"likes_count"`
+	ViewCount    uint       `json:"view_count"`
+	LastUpdate   string     `json:"last_update"`
+	Reason       string     `json:"reason"` // following, tags atau popular
+	Score        float64    `json:"score"`
+	UserDetail   UserDetail `json:"user_detail,omitempty"`
+}
+
+// lastUpdateLabel mengubah waktu menjadi format seperti "3 days ago".
+func lastUpdateLabel(t time.Time, now time.Time) string {
+	diff := now.Sub(t)
+	switch {
+	case diff < time.Hour:
+		return "just now"
+	case diff < 24*time.Hour:
+		return fmt.Sprintf("%d hours ago", int(diff.Hours()))
+	case diff < 7*24*time.Hour:
+		return fmt.Sprintf("%d days ago", int(diff.Hours()/24))
+	case diff < 30*24*time.Hour:
+		return fmt.Sprintf("%d weeks ago", int(diff.Hours()/(24*7)))
+	default:
+		return fmt.Sprintf("%d months ago", int(diff.Hours()/(24*30)))
+	}
+}
+
+// feedCandidates memilih album yang boleh dilihat viewer beserta sinyal untuk skor.
+// Album yang dibuat setelah asOf tidak ikut agar urutan antar halaman tetap stabil.
+func feedCandidates(db *gorm.DB, viewerID uuid.UUID, tags pq.StringArray, asOf time.Time) *gorm.DB {
+	interactionSince := asOf.Add(-feedInteractionWindow)
+
+	return db.Table("albums").
+		Select(`albums.id, albums.created_at, albums.likes_count, albums.view_count,
+			EXISTS (SELECT 1 FROM followings f WHERE f.user_id = ? AND f.following_id = albums.user_id AND f.status = ? AND f.deleted_at IS NULL) AS is_following,
+			(SELECT COUNT(*) FROM album_album_tags aat JOIN album_tags t ON t.id = aat.album_tag_id
+				WHERE aat.album_id = albums.id AND t.deleted_at IS NULL
+				AND (t.tag_name = ANY(?) OR EXISTS (SELECT 1 FROM tag_aliases ta WHERE ta.tag_id = t.id AND ta.alias = ANY(?)))) AS tag_matches,
+			(SELECT COUNT(*) FROM album_comments ac WHERE ac.album_id = albums.id AND ac.status = ? AND ac.deleted_at IS NULL) AS comment_count,
+			(SELECT COUNT(*) FROM album_reactions ar JOIN albums ra ON ra.id = ar.album_id
+				WHERE ar.user_id = ? AND ra.user_id = albums.user_id AND ar.created_at >= ?) +
+			(SELECT COUNT(*) FROM album_comments vc JOIN albums ca ON ca.id = vc.album_id
+				WHERE vc.user_id = ? AND ca.user_id = albums.user_id AND vc.deleted_at IS NULL AND vc.created_at >= ?) AS interactions`,
+			viewerID, models.FollowStatusAccepted, tags, tags, models.CommentStatusVisible,
+			viewerID, interactionSince, viewerID, interactionSince).
+		Where("albums.deleted_at IS NULL AND albums.user_id <> ? AND albums.created_at <= ?", viewerID, asOf).
+		Where("albums.moderation_status = ?", models.ModerationStatusVisible).
+		Where("albums.album_privacy = ? OR (albums.album_privacy = ? AND "+acceptedInvitationCondition+")", "public", "restricted", viewerID).
+		Where(visibleAncestorsCondition, viewerID).
+		Where(notBlockedByCondition("albums.user_id"), viewerID).
+		Where(followerOnlyCondition("albums.user_id"), viewerID).
+		Where("NOT EXISTS (SELECT 1 FROM user_blocks vb WHERE vb.blocker_id = ? AND vb.blocked_id = albums.user_id)", viewerID)
+}
+
+// feedScored menghitung skor tiap kandidat relatif terhadap asOf. Eksponen recency dibatasi
+// agar POWER tidak underflow untuk album yang sangat lama.
+func feedScored(db *gorm.DB, candidates *gorm.DB, asOf time.Time) *gorm.DB {
+	return db.Table("(?) AS c", candidates).
+		Select(`c.id, c.is_following, c.tag_matches,
+			CAST(
+				POWER(0.5, LEAST(EXTRACT(EPOCH FROM (CAST(? AS timestamptz) - c.created_at)) / 3600.0 / ?, 500))
+				* (1 + LN(1 + c.likes_count + ? * c.view_count + ? * c.comment_count))
+				* (1 + CASE WHEN c.is_following THEN ? ELSE 0 END + LEAST(c.tag_matches, 3) * ? + LN(1 + c.interactions))
+			AS double precision) AS score`,
+			asOf, feedHalfLifeHours, feedViewWeight, feedCommentWeight, feedFollowAffinity, feedTagAffinity).
+		Where("c.is_following OR c.tag_matches > 0 OR c.created_at >= ?", asOf.Add(-feedRecommendationWindow))
+}
+
+// GetFeed mengambil feed home yang menggabungkan album dari user yang di-follow,
+// rekomendasi berdasarkan TagPreference dan album publik populer, diurutkan berdasarkan skor.
+func GetFeed(ctx *fiber.Ctx, db *gorm.DB) error {
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var userLogin models.User
+	if err := db.Select("id", "tag_preference").First(&userLogin, "id = ?", userID).Error; err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	tags := pq.StringArray(utils.NormalizeTags(userLogin.TagPreference))
+
+	limit := utils.ParseLimit(ctx.Query("limit"), 20, 50)
+	asOf := time.Now()
+
+	var cursorScore float64
+	var cursorID uuid.UUID
+	cursor := ctx.Query("cursor")
+	if cursor != "" {
+		if asOf, cursorScore, cursorID, err = utils.DecodeScoreCursor(cursor); err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
+
+	query := db.Table("(?) AS feed", feedScored(db, feedCandidates(db, userID, tags, asOf), asOf))
+	if cursor != "" {
+		query = query.Where("(feed.score, feed.id) < (CAST(? AS double precision), CAST(? AS uuid))", cursorScore, cursorID)
+	}
+
+	var rows []struct {
+		ID          uuid.UUID
+		IsFollowing bool
+		TagMatches  int64
+		Score       float64
+	}
+	if err := query.Order("feed.score DESC, feed.id DESC").Limit(limit + 1).Scan(&rows).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil feed"})
+	}
+
+	nextCursor := ""
+	if len(rows) > limit {
+		rows = rows[:limit]
+		last := rows[len(rows)-1]
+		nextCursor = utils.EncodeScoreCursor(asOf, last.Score, last.ID)
+	}
+
+	albumIDs := make([]uuid.UUID, len(rows))
+	for i, row := range rows {
+		albumIDs[i] = row.ID
+	}
+
+	var albums []models.Album
+	if len(albumIDs) > 0 {
+		if err := db.Preload("Media").Preload("User").Where("id IN ?", albumIDs).Find(&albums).Error; err != nil {
+			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to retrieve albums"})
+		}
+	}
+	albumsByID := map[uuid.UUID]models.Album{}
+	for _, album := range albums {
+		albumsByID[album.ID] = album
+	}
+
+	now := time.Now()
+	feed := []FeedAlbumResponse{}
+	for _, row := range rows {
+		album, ok := albumsByID[row.ID]
+		if !ok {
+			continue
+		}
+
+		thumbnailURL := ""
+		if album.CoverImage != "" {
+			if thumbnailURL, err = utils.GeneratePresignedURL(config.S3Bucket.BucketName, utils.S3KeyFromURL(album.CoverImage)); err != nil {
+				return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal generate presigned URL"})
+			}
+		}
+
+		reason := FeedReasonPopular
+		if row.IsFollowing {
+			reason = FeedReasonFollowing
+		} else if row.TagMatches > 0 {
+			reason = FeedReasonTags
+		}
+
+		imageCount, videoCount := countMediaKinds(album.Media)
+		feed = append(feed, FeedAlbumResponse{
+			AlbumID:      album.ID,
+			Title:        album.Title,
+			Description:  album.Description,
+			MediaCount:   len(album.Media),
+			ImageCount:   imageCount,
+			VideoCount:   videoCount,
+			ThumbnailURL: thumbnailURL,
+			LikesCount:   album.LikesCount,
+			ViewCount:    album.ViewCount,
+			LastUpdate:   lastUpdateLabel(album.UpdatedAt, now),
+			Reason:       reason,
+			Score:        row.Score,
+			UserDetail: UserDetail{
+				UserID:    album.User.ID,
+				FirstName: album.User.FirstName,
+				LastName:  album.User.LastName,
+				FullName:  album.User.FirstName + " " + album.User.LastName,
+			},
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"albums":      feed,
+		"next_cursor": nextCursor,
+		"has_more":    nextCursor != "",
+	})
+}
@@ -0,0 +1,349 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/Zackly23/queue-app/events"
+	"github.com/Zackly23/queue-app/models"
+	"github.com/Zackly23/queue-app/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type FollowUserResponse struct {
+	UserID     uuid.UUID `json:"user_id"`
+	User       string    `json:"user"`
+	UserName   string    `json:"user_name,omitempty"`
+	UserAvatar string    `json:"user_avatar"`
+	Status     string    `json:"status,omitempty"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+// notBlockedByCondition menyembunyikan baris milik user yang memblokir viewer,
+// column adalah kolom user pemilik baris (mis. albums.user_id).
+func notBlockedByCondition(column string) string {
+	return "NOT EXISTS (SELECT 1 FROM user_blocks ub WHERE ub.blocker_id = " + column + " AND ub.blocked_id = ?)"
+}
+
+// isBlockedBy mengecek apakah blockerID memblokir userID.
+func isBlockedBy(db *gorm.DB, blockerID uuid.UUID, userID uuid.UUID) (bool, error) {
+	if blockerID == userID {
+		return false, nil
+	}
+
+	var count int64
+	err := db.Model(&models.UserBlock{}).
+		Where("blocker_id = ? AND blocked_id = ?", blockerID, userID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// hasBlockBetween mengecek blokir dari salah satu arah.
+func hasBlockBetween(db *gorm.DB, userA uuid.UUID, userB uuid.UUID) (bool, error) {
+	var count int64
+	err := db.Model(&models.UserBlock{}).
+		Where("(blocker_id = ? AND blocked_id = ?) OR (blocker_id = ? AND blocked_id = ?)", userA, userB, userB, userA).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func isPrivateAccount(db *gorm.DB, userID uuid.UUID) (bool, error) {
+	var private []bool
+	if err := db.Model(&models.AccountConfig{}).Where("user_id = ?", userID).Pluck("is_private", &private).Error; err != nil {
+		return false, err
+	}
+	return len(private) > 0 && private[0], nil
+}
+
+// followerOnlyCondition menyembunyikan baris milik akun privat dari viewer yang bukan follower
+// yang sudah diterima, column adalah kolom user pemilik baris (mis. albums.user_id).
+func followerOnlyCondition(column string) string {
+	return "(NOT EXISTS (SELECT 1 FROM account_configs pac WHERE pac.user_id = " + column + " AND pac.is_private AND pac.deleted_at IS NULL)" +
+		" OR EXISTS (SELECT 1 FROM followings pf WHERE pf.user_id = ? AND pf.following_id = " + column +
+		" AND pf.status = '" + models.FollowStatusAccepted + "' AND pf.deleted_at IS NULL))"
+}
+
+// canSeePrivateContent: konten akun privat hanya untuk pemilik dan follower yang sudah diterima.
+func canSeePrivateContent(db *gorm.DB, ownerID uuid.UUID, viewerID uuid.UUID) (bool, error) {
+	if ownerID == viewerID {
+		return true, nil
+	}
+
+	private, err := isPrivateAccount(db, ownerID)
+	if err != nil || !private {
+		return !private, err
+	}
+
+	status, err := followStatus(db, viewerID, ownerID)
+	return status == models.FollowStatusAccepted, err
+}
+
+// followStatus mengembalikan status follow followerID ke followingID, kosong bila belum follow.
+func followStatus(db *gorm.DB, followerID uuid.UUID, followingID uuid.UUID) (string, error) {
+	var statuses []string
+	err := db.Model(&models.Following{}).
+		Where("user_id = ? AND following_id = ?", followerID, followingID).
+		Pluck("status", &statuses).Error
+	if err != nil || len(statuses) == 0 {
+		return "", err
+	}
+	return statuses[0], nil
+}
+
+// canSeeConnections: daftar followers/following akun privat hanya untuk pemilik dan follower-nya.
+func canSeeConnections(db *gorm.DB, ownerID uuid.UUID, viewerID uuid.UUID) error {
+	if ownerID == viewerID {
+		return nil
+	}
+
+	blocked, err := isBlockedBy(db, ownerID, viewerID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Gagal memeriksa status blokir")
+	}
+	if blocked {
+		return fiber.NewError(fiber.StatusNotFound, "User not found")
+	}
+
+	private, err := isPrivateAccount(db, ownerID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Gagal memeriksa privasi akun")
+	}
+	if !private {
+		return nil
+	}
+
+	status, err := followStatus(db, viewerID, ownerID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Gagal memeriksa status follow")
+	}
+	if status != models.FollowStatusAccepted {
+		return fiber.NewError(fiber.StatusForbidden, "Akun ini privat")
+	}
+	return nil
+}
+
+// listFollowUsers mengambil user di sisi lain relasi follow (terbaru dulu, cursor pagination).
+// matchColumn adalah kolom user pemilik daftar, userColumn kolom user yang ditampilkan.
+func listFollowUsers(ctx *fiber.Ctx, db *gorm.DB, matchColumn string, userColumn string, ownerID uuid.UUID, status string, viewerID uuid.UUID) error {
+	limit := utils.ParseLimit(ctx.Query("limit"), 20, 100)
+	query := db.Table("followings f").
+		Select("f.status, f.created_at, u.id, u.first_name, u.last_name, u.user_name, u.profile_picture").
+		Joins("JOIN users u ON u.id = f."+userColumn+" AND u.deleted_at IS NULL").
+		Where("f."+matchColumn+" = ? AND f.status = ? AND f.deleted_at IS NULL", ownerID, status).
+		Where(notBlockedByCondition("u.id"), viewerID)
+
+	if cursor := ctx.Query("cursor"); cursor != "" {
+		createdAt, id, err := utils.DecodeCursor(cursor)
+		if err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		query = query.Where("(f.created_at, u.id) < (?, ?)", createdAt, id)
+	}
+
+	var rows []struct {
+		ID             uuid.UUID
+		Status         string
+		CreatedAt      time.Time
+		FirstName      string
+		LastName       string
+		UserName       string
+		ProfilePicture string
+	}
+	if err := query.Order("f.created_at DESC, u.id DESC").Limit(limit + 1).Scan(&rows).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil daftar user"})
+	}
+
+	nextCursor := ""
+	if len(rows) > limit {
+		rows = rows[:limit]
+		nextCursor = utils.EncodeCursor(rows[len(rows)-1].CreatedAt, rows[len(rows)-1].ID)
+	}
+
+	users := []FollowUserResponse{}
+	for _, row := range rows {
+		user := models.User{FirstName: row.FirstName, LastName: row.LastName, UserName: row.UserName, ProfilePicture: row.ProfilePicture}
+		avatar, err := avatarURL(user)
+		if err != nil {
+			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal generate presigned URL"})
+		}
+
+		users = append(users, FollowUserResponse{
+			UserID:     row.ID,
+			User:       displayName(user),
+			UserName:   row.UserName,
+			UserAvatar: avatar,
+			Status:     row.Status,
+			FollowedAt: row.CreatedAt,
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"users":       users,
+		"next_cursor": nextCursor,
+		"has_more":    nextCursor != "",
+	})
+}
+
+func GetFollowers(ctx *fiber.Ctx, db *gorm.DB) error {
+	viewerID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	ownerID, err := uuid.Parse(ctx.Params("userId"))
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+	if err := canSeeConnections(db, ownerID, viewerID); err != nil {
+		return fiberErrorResponse(ctx, err)
+	}
+
+	return listFollowUsers(ctx, db, "following_id", "user_id", ownerID, models.FollowStatusAccepted, viewerID)
+}
+
+func GetFollowing(ctx *fiber.Ctx, db *gorm.DB) error {
+	viewerID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	ownerID, err := uuid.Parse(ctx.Params("userId"))
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+	if err := canSeeConnections(db, ownerID, viewerID); err != nil {
+		return fiberErrorResponse(ctx, err)
+	}
+
+	return listFollowUsers(ctx, db, "user_id", "following_id", ownerID, models.FollowStatusAccepted, viewerID)
+}
+
+// GetFollowRequests mengambil permintaan follow yang masuk, atau yang dikirim user
+// bila ?direction=outgoing.
+func GetFollowRequests(ctx *fiber.Ctx, db *gorm.DB) error {
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	if ctx.Query("direction") == "outgoing" {
+		return listFollowUsers(ctx, db, "user_id", "following_id", userID, models.FollowStatusPending, userID)
+	}
+	return listFollowUsers(ctx, db, "following_id", "user_id", userID, models.FollowStatusPending, userID)
+}
+
+// findFollowRequest mengambil permintaan follow pending dari :userId ke user yang login.
+func findFollowRequest(ctx *fiber.Ctx, db *gorm.DB, userID uuid.UUID) (models.Following, error) {
+	var request models.Following
+	err := db.Where("user_id = ? AND following_id = ? AND status = ?", ctx.Params("userId"), userID, models.FollowStatusPending).
+		First(&request).Error
+	if err != nil {
+		return request, fiber.NewError(fiber.StatusNotFound, "Permintaan follow tidak ditemukan")
+	}
+	return request, nil
+}
+
+func ApproveFollowRequest(ctx *fiber.Ctx, db *gorm.DB) error {
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	request, err := findFollowRequest(ctx, db, userID)
+	if err != nil {
+		return fiberErrorResponse(ctx, err)
+	}
+
+	if err := db.Model(&models.Following{}).
+		Where("user_id = ? AND following_id = ?", request.UserID, request.FollowingID).
+		Update("status", models.FollowStatusAccepted).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyetujui permintaan follow"})
+	}
+
+	events.Publish(events.Event{
+		Type:        models.NotificationEventFollowAccepted,
+		RecipientID: request.UserID,
+		ActorID:     userID,
+	})
+
+	return ctx.JSON(fiber.Map{
+		"message": "Permintaan follow disetujui",
+		"user_id": request.UserID,
+	})
+}
+
+func DeclineFollowRequest(ctx *fiber.Ctx, db *gorm.DB) error {
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	request, err := findFollowRequest(ctx, db, userID)
+	if err != nil {
+		return fiberErrorResponse(ctx, err)
+	}
+
+	if err := db.Unscoped().
+		Where("user_id = ? AND following_id = ?", request.UserID, request.FollowingID).
+		Delete(&models.Following{}).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menolak permintaan follow"})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"message": "Permintaan follow ditolak",
+		"user_id": request.UserID,
+	})
+}
+
+// UpdateAccountPrivacy mengubah akun menjadi privat/publik. Saat kembali publik,
+// semua permintaan follow yang masih pending otomatis disetujui.
+func UpdateAccountPrivacy(ctx *fiber.Ctx, db *gorm.DB) error {
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var req struct {
+		IsPrivate *bool `json:"is_private"`
+	}
+	if err := ctx.BodyParser(&req); err != nil || req.IsPrivate == nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "is_private harus diisi"})
+	}
+
+	var approved int64
+	errTx := db.Transaction(func(tx *gorm.DB) error {
+		var accountConfig models.AccountConfig
+		if err := tx.Where("user_id = ?", userID).First(&accountConfig).Error; err != nil {
+			if err != gorm.ErrRecordNotFound {
+				return err
+			}
+			accountConfig = models.AccountConfig{UserID: userID}
+		}
+
+		accountConfig.IsPrivate = *req.IsPrivate
+		if err := tx.Save(&accountConfig).Error; err != nil {
+			return err
+		}
+
+		if !accountConfig.IsPrivate {
+			result := tx.Model(&models.Following{}).
+				Where("following_id = ? AND status = ?", userID, models.FollowStatusPending).
+				Update("status", models.FollowStatusAccepted)
+			if result.Error != nil {
+				return result.Error
+			}
+			approved = result.RowsAffected
+		}
+		return nil
+	})
+	if errTx != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengubah privasi akun"})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"message":           "Privasi akun berhasil diubah",
+		"is_private":        *req.IsPrivate,
+		"approved_requests": approved,
+	})
+}
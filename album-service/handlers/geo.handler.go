@@ -0,0 +1,398 @@
+package handlers
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Zackly23/queue-app/models"
+	"github.com/Zackly23/queue-app/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// takenAtSQL adalah waktu foto diambil (EXIF), atau waktu upload bila media tidak punya EXIF.
+const takenAtSQL = "COALESCE(media.captured_at, media.created_at)"
+
+const (
+	defaultMapZoom = 3
+	maxMapZoom     = 20
+	maxMapClusters = 500
+)
+
+// timelineLayouts adalah format label periode untuk setiap granularity timeline.
+var timelineLayouts = map[string]string{
+	"day":   "2006-01-02",
+	"month": "2006-01",
+}
+
+type TimelineBucket struct {
+	Period       string    `json:"period"` // "2024-05-17" (day) atau "2024-05" (month)
+	Start        time.Time `json:"start"`
+	Count        int64     `json:"count"`
+	CoverMediaID uuid.UUID `json:"cover_media_id"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+}
+
+type TimelineMedia struct {
+	MediaID      uuid.UUID  `json:"media_id"`
+	AlbumID      uuid.UUID  `json:"album_id"`
+	AlbumTitle   string     `json:"album_title"`
+	MediaKind    string     `json:"media_kind"`
+	Description  string     `json:"description"`
+	ThumbnailURL string     `json:"thumbnail_url"`
+	CapturedAt   *time.Time `json:"captured_at,omitempty"`
+	TakenAt      time.Time  `json:"taken_at"`
+	Latitude     *float64   `json:"latitude,omitempty"`
+	Longitude    *float64   `json:"longitude,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+type MapCluster struct {
+	Latitude     float64   `json:"latitude"` // titik tengah (rata-rata) media di cluster
+	Longitude    float64   `json:"longitude"`
+	Count        int64     `json:"count"`
+	MediaID      uuid.UUID `json:"media_id"` // media terbaru di cluster, dipakai sebagai preview
+	ThumbnailURL string    `json:"thumbnail_url"`
+}
+
+type boundingBox struct {
+	MinLng, MinLat, MaxLng, MaxLat float64
+}
+
+// browsableMedia adalah media yang boleh dilihat viewer. Bisa dibatasi ke satu album (?album_id=)
+// atau album milik user tertentu (?user_id=), default hanya album milik viewer sendiri.
+func browsableMedia(ctx *fiber.Ctx, db *gorm.DB, viewerID uuid.UUID) (*gorm.DB, error) {
+	query := db.Table("media").
+		Joins("JOIN albums ON albums.id = media.album_id").
+		Where("media.deleted_at IS NULL").
+		Where("media.moderation_status = ? OR albums.user_id = ?", models.ModerationStatusVisible, viewerID)
+	query = searchableAlbums(query, searchFilter{ViewerID: viewerID, AsOf: time.Now()})
+
+	switch {
+	case ctx.Query("album_id") != "":
+		albumID, err := uuid.Parse(ctx.Query("album_id"))
+		if err != nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "album_id tidak valid")
+		}
+		query = query.Where("media.album_id = ?", albumID)
+	case ctx.Query("user_id") != "":
+		ownerID, err := uuid.Parse(ctx.Query("user_id"))
+		if err != nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "user_id tidak valid")
+		}
+		query = query.Where("albums.user_id = ?", ownerID)
+	default:
+		query = query.Where("albums.user_id = ?", viewerID)
+	}
+
+	return query, nil
+}
+
+// mediaThumbnails mengambil presigned URL thumbnail untuk daftar media.
+func mediaThumbnails(db *gorm.DB, mediaIDs []uuid.UUID) (map[uuid.UUID]string, error) {
+	thumbnails := map[uuid.UUID]string{}
+	if len(mediaIDs) == 0 {
+		return thumbnails, nil
+	}
+
+	var medias []models.Media
+	if err := db.Select("id, kind, url, thumbnail_url").Where("id IN ?", mediaIDs).Find(&medias).Error; err != nil {
+		return nil, err
+	}
+	for _, media := range medias {
+		thumbnailURL, err := presignedOrEmpty(media.CoverURL())
+		if err != nil {
+			return nil, err
+		}
+		thumbnails[media.ID] = thumbnailURL
+	}
+	return thumbnails, nil
+}
+
+// GetMediaTimeline mengelompokkan media per hari atau bulan pengambilan (?granularity=day|month),
+// diurutkan dari yang terbaru. Cursor berisi label periode terakhir pada halaman sebelumnya.
+func GetMediaTimeline(ctx *fiber.Ctx, db *gorm.DB) error {
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	granularity := ctx.Query("granularity", "day")
+	layout, ok := timelineLayouts[granularity]
+	if !ok {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "granularity harus day atau month"})
+	}
+
+	query, err := browsableMedia(ctx, db, userID)
+	if err != nil {
+		return fiberErrorResponse(ctx, err)
+	}
+
+	if cursor := ctx.Query("cursor"); cursor != "" {
+		before, err := time.Parse(layout, cursor)
+		if err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "cursor tidak valid"})
+		}
+		query = query.Where(takenAtSQL+" < ?", before)
+	}
+
+	limit := utils.ParseLimit(ctx.Query("limit"), 30, 100)
+
+	// captured_at disimpan sebagai jam kamera dalam UTC, jadi periode dihitung di UTC
+	var rows []struct {
+		Period       time.Time
+		Count        int64
+		CoverMediaID uuid.UUID
+	}
+	if err := query.
+		Select(`date_trunc('` + granularity + `', ` + takenAtSQL + ` AT TIME ZONE 'UTC') AS period, COUNT(*) AS count,
+			(array_agg(media.id ORDER BY ` + takenAtSQL + ` DESC, media.id DESC))[1] AS cover_media_id`).
+		Group("period").
+		Order("period DESC").
+		Limit(limit + 1).
+		Scan(&rows).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil timeline"})
+	}
+
+	nextCursor := ""
+	if len(rows) > limit {
+		rows = rows[:limit]
+		nextCursor = rows[len(rows)-1].Period.Format(layout)
+	}
+
+	coverIDs := make([]uuid.UUID, 0, len(rows))
+	for _, row := range rows {
+		coverIDs = append(coverIDs, row.CoverMediaID)
+	}
+	thumbnails, err := mediaThumbnails(db, coverIDs)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal generate presigned URL"})
+	}
+
+	buckets := []TimelineBucket{}
+	for _, row := range rows {
+		buckets = append(buckets, TimelineBucket{
+			Period:       row.Period.Format(layout),
+			Start:        row.Period,
+			Count:        row.Count,
+			CoverMediaID: row.CoverMediaID,
+			ThumbnailURL: thumbnails[row.CoverMediaID],
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"granularity": granularity,
+		"buckets":     buckets,
+		"next_cursor": nextCursor,
+	})
+}
+
+// GetMediaTimelinePeriod menampilkan media pada satu periode timeline (:period "2024-05-17" atau "2024-05"),
+// diurutkan dari waktu pengambilan terbaru dengan cursor pagination.
+func GetMediaTimelinePeriod(ctx *fiber.Ctx, db *gorm.DB) error {
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	period := ctx.Params("period")
+	var start, end time.Time
+	if day, err := time.Parse(timelineLayouts["day"], period); err == nil {
+		start, end = day, day.AddDate(0, 0, 1)
+	} else if month, err := time.Parse(timelineLayouts["month"], period); err == nil {
+		start, end = month, month.AddDate(0, 1, 0)
+	} else {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Periode harus berformat YYYY-MM-DD atau YYYY-MM"})
+	}
+
+	query, err := browsableMedia(ctx, db, userID)
+	if err != nil {
+		return fiberErrorResponse(ctx, err)
+	}
+	query = query.Where(takenAtSQL+" >= ? AND "+takenAtSQL+" < ?", start, end)
+
+	if cursor := ctx.Query("cursor"); cursor != "" {
+		takenAt, mediaID, err := utils.DecodeCursor(cursor)
+		if err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		query = query.Where("("+takenAtSQL+", media.id) < (?, ?)", takenAt, mediaID)
+	}
+
+	limit := utils.ParseLimit(ctx.Query("limit"), 50, 200)
+
+	var rows []struct {
+		ID           uuid.UUID
+		AlbumID      uuid.UUID
+		AlbumTitle   string
+		Kind         string
+		Description  string
+		URL          string
+		ThumbnailURL string
+		CapturedAt   *time.Time
+		TakenAt      time.Time
+		Latitude     *float64
+		Longitude    *float64
+		CreatedAt    time.Time
+	}
+	if err := query.
+		Select(`media.id, media.album_id, albums.title AS album_title, media.kind, media.description, media.url,
+			media.thumbnail_url, media.captured_at, ` + takenAtSQL + ` AS taken_at, media.latitude, media.longitude, media.created_at`).
+		Order("taken_at DESC, media.id DESC").
+		Limit(limit + 1).
+		Scan(&rows).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil media"})
+	}
+
+	nextCursor := ""
+	if len(rows) > limit {
+		rows = rows[:limit]
+		last := rows[len(rows)-1]
+		nextCursor = utils.EncodeCursor(last.TakenAt, last.ID)
+	}
+
+	items := []TimelineMedia{}
+	for _, row := range rows {
+		thumbnailURL, err := presignedOrEmpty(models.Media{Kind: row.Kind, URL: row.URL, ThumbnailURL: row.ThumbnailURL}.CoverURL())
+		if err != nil {
+			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal generate presigned URL"})
+		}
+
+		items = append(items, TimelineMedia{
+			MediaID:      row.ID,
+			AlbumID:      row.AlbumID,
+			AlbumTitle:   row.AlbumTitle,
+			MediaKind:    row.Kind,
+			Description:  row.Description,
+			ThumbnailURL: thumbnailURL,
+			CapturedAt:   row.CapturedAt,
+			TakenAt:      row.TakenAt,
+			Latitude:     row.Latitude,
+			Longitude:    row.Longitude,
+			CreatedAt:    row.CreatedAt,
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"period":      period,
+		"media":       items,
+		"next_cursor": nextCursor,
+	})
+}
+
+// parseBoundingBox membaca ?bbox=minLng,minLat,maxLng,maxLat. minLng boleh lebih besar dari maxLng
+// untuk area yang melewati garis 180° (antimeridian).
+func parseBoundingBox(value string) (boundingBox, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 4 {
+		return boundingBox{}, fiber.NewError(fiber.StatusBadRequest, "bbox harus berformat minLng,minLat,maxLng,maxLat")
+	}
+
+	var values [4]float64
+	for i, part := range parts {
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || math.IsNaN(parsed) {
+			return boundingBox{}, fiber.NewError(fiber.StatusBadRequest, "bbox harus berisi angka")
+		}
+		values[i] = parsed
+	}
+
+	box := boundingBox{MinLng: values[0], MinLat: values[1], MaxLng: values[2], MaxLat: values[3]}
+	if box.MinLng < -180 || box.MaxLng > 180 || box.MaxLng < -180 || box.MinLng > 180 {
+		return boundingBox{}, fiber.NewError(fiber.StatusBadRequest, "Longitude harus di antara -180 dan 180")
+	}
+	if box.MinLat < -90 || box.MaxLat > 90 || box.MinLat > box.MaxLat {
+		return boundingBox{}, fiber.NewError(fiber.StatusBadRequest, "Latitude harus di antara -90 dan 90 dan minLat <= maxLat")
+	}
+	return box, nil
+}
+
+// GetMediaMap mengembalikan titik media di dalam bounding box (?bbox=) yang dikelompokkan ke grid.
+// Ukuran sel grid mengikuti ?zoom= (0-20), semakin besar zoom semakin kecil sel.
+func GetMediaMap(ctx *fiber.Ctx, db *gorm.DB) error {
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	box, err := parseBoundingBox(ctx.Query("bbox"))
+	if err != nil {
+		return fiberErrorResponse(ctx, err)
+	}
+
+	zoom := defaultMapZoom
+	if value := ctx.Query("zoom"); value != "" {
+		zoom, err = strconv.Atoi(value)
+		if err != nil || zoom < 0 || zoom > maxMapZoom {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "zoom harus di antara 0 dan 20"})
+		}
+	}
+	// Zoom 0 dibagi 4x2 sel, setiap level zoom membagi sel menjadi 4
+	cellSize := 90 / math.Pow(2, float64(zoom))
+
+	query, err := browsableMedia(ctx, db, userID)
+	if err != nil {
+		return fiberErrorResponse(ctx, err)
+	}
+	query = query.Where("media.latitude BETWEEN ? AND ?", box.MinLat, box.MaxLat)
+	if box.MinLng <= box.MaxLng {
+		query = query.Where("media.longitude BETWEEN ? AND ?", box.MinLng, box.MaxLng)
+	} else {
+		query = query.Where("media.longitude >= ? OR media.longitude <= ?", box.MinLng, box.MaxLng)
+	}
+
+	var rows []struct {
+		Latitude  float64
+		Longitude float64
+		Count     int64
+		MediaID   uuid.UUID
+	}
+	if err := query.
+		Select(`floor(media.longitude / ?) AS cell_x, floor(media.latitude / ?) AS cell_y, COUNT(*) AS count,
+			AVG(media.latitude) AS latitude, AVG(media.longitude) AS longitude,
+			(array_agg(media.id ORDER BY `+takenAtSQL+` DESC, media.id DESC))[1] AS media_id`, cellSize, cellSize).
+		Group("cell_x, cell_y").
+		Order("count DESC").
+		Limit(maxMapClusters + 1).
+		Scan(&rows).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil lokasi media"})
+	}
+
+	truncated := len(rows) > maxMapClusters
+	if truncated {
+		rows = rows[:maxMapClusters]
+	}
+
+	mediaIDs := make([]uuid.UUID, 0, len(rows))
+	for _, row := range rows {
+		mediaIDs = append(mediaIDs, row.MediaID)
+	}
+	thumbnails, err := mediaThumbnails(db, mediaIDs)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal generate presigned URL"})
+	}
+
+	clusters := []MapCluster{}
+	var total int64
+	for _, row := range rows {
+		total += row.Count
+		clusters = append(clusters, MapCluster{
+			Latitude:     row.Latitude,
+			Longitude:    row.Longitude,
+			Count:        row.Count,
+			MediaID:      row.MediaID,
+			ThumbnailURL: thumbnails[row.MediaID],
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"zoom":        zoom,
+		"cell_size":   cellSize,
+		"clusters":    clusters,
+		"total_media": total,
+		"truncated":   truncated, // cluster terlalu banyak, perbesar zoom atau perkecil bbox
+	})
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Zackly23/queue-app/jobs"
+	"github.com/Zackly23/queue-app/models"
+	"github.com/Zackly23/queue-app/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ImportAlbum menerima ZIP (field "archive") atau banyak file (field "files"),
+// opsional manifest (field "manifest"), lalu memproses import di background.
+func ImportAlbum(ctx *fiber.Ctx, db *gorm.DB) error {
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	form, err := ctx.MultipartForm()
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Form multipart tidak valid"})
+	}
+
+	archives := form.File["archive"]
+	files := form.File["files"]
+	if len(archives) == 0 && len(files) == 0 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Upload file ZIP (archive) atau files"})
+	}
+	if len(archives) > 1 || (len(archives) == 1 && len(files) > 0) {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Gunakan satu file ZIP atau upload files, tidak keduanya"})
+	}
+
+	sourceType := "files"
+	if len(archives) == 1 {
+		if !strings.EqualFold(filepath.Ext(archives[0].Filename), ".zip") {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Archive harus berformat .zip"})
+		}
+		sourceType = "zip"
+	}
+
+	manifests := form.File["manifest"]
+	if len(manifests) > 0 {
+		if ext := strings.ToLower(filepath.Ext(manifests[0].Filename)); ext != ".json" && ext != ".csv" {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Manifest harus berformat .json atau .csv"})
+		}
+	}
+
+	albumPrivacy := ctx.FormValue("album_privacy")
+	if _, ok := privacyRank[albumPrivacy]; albumPrivacy != "" && !ok {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Album privacy tidak valid"})
+	}
+
+	var parentID *uuid.UUID
+	if parentIDStr := ctx.FormValue("parent_id"); parentIDStr != "" {
+		var parent models.Album
+		if _, err := uuid.Parse(parentIDStr); err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Parent album ID tidak valid"})
+		}
+		if err := db.Where("id = ? AND user_id = ?", parentIDStr, userID).First(&parent).Error; err != nil {
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Parent album tidak ditemukan"})
+		}
+		parentID = &parent.ID
+	}
+
+	sourcePath, err := os.MkdirTemp("", "album-import-*")
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyiapkan direktori import"})
+	}
+
+	if err := saveImportUploads(sourcePath, sourceType, archives, files, manifests); err != nil {
+		os.RemoveAll(sourcePath)
+		log.Printf("Gagal menyimpan file import: %v", err)
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyimpan file import"})
+	}
+
+	albumImport := models.AlbumImport{
+		UserID:       userID,
+		ParentID:     parentID,
+		Title:        ctx.FormValue("title"),
+		Description:  ctx.FormValue("description"),
+		AlbumPrivacy: albumPrivacy,
+		Tags:         form.Value["tags"],
+		SourceType:   sourceType,
+		SourcePath:   sourcePath,
+		Status:       models.ImportStatusPending,
+	}
+
+	if err := db.Create(&albumImport).Error; err != nil {
+		os.RemoveAll(sourcePath)
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuat job import"})
+	}
+
+	go jobs.ProcessAlbumImport(db, albumImport.ID.String())
+
+	return ctx.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message": "Import album sedang diproses",
+		"import":  albumImport,
+	})
+}
+
+// saveImportUploads menyimpan upload ke direktori sementara. Nama file yang sama diberi suffix agar tidak saling menimpa.
+func saveImportUploads(sourcePath, sourceType string, archives, files, manifests []*multipart.FileHeader) error {
+	if sourceType == "zip" {
+		if err := utils.SaveMultipartFile(archives[0], filepath.Join(sourcePath, "archive.zip")); err != nil {
+			return err
+		}
+	} else {
+		dir := filepath.Join(sourcePath, "files")
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+
+		used := map[string]int{}
+		for _, file := range files {
+			name := filepath.Base(file.Filename)
+			if count := used[strings.ToLower(name)]; count > 0 {
+				ext := filepath.Ext(name)
+				name = fmt.Sprintf("%s_%d%s", strings.TrimSuffix(name, ext), count, ext)
+			}
+			used[strings.ToLower(filepath.Base(file.Filename))]++
+
+			if err := utils.SaveMultipartFile(file, filepath.Join(dir, name)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(manifests) > 0 {
+		name := "manifest.json"
+		if strings.HasSuffix(strings.ToLower(manifests[0].Filename), ".csv") {
+			name = "manifest.csv"
+		}
+		if err := utils.SaveMultipartFile(manifests[0], filepath.Join(sourcePath, name)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func GetAlbumImport(ctx *fiber.Ctx, db *gorm.DB) error {
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var albumImport models.AlbumImport
+	if err := db.Preload("Items", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("created_at ASC")
+	}).Where("id = ? AND user_id = ?", ctx.Params("importId"), userID).First(&albumImport).Error; err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Import tidak ditemukan"})
+	}
+
+	progress := 0.0
+	if albumImport.TotalItems > 0 {
+		progress = float64(albumImport.ProcessedItems) / float64(albumImport.TotalItems) * 100
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"import":   albumImport,
+		"progress": progress,
+	})
+}
@@ -0,0 +1,586 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Zackly23/queue-app/events"
+	"github.com/Zackly23/queue-app/models"
+	notif "github.com/Zackly23/queue-app/proto/notificationpb"
+	"github.com/Zackly23/queue-app/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type AlbumInvitationResponse struct {
+	ID          uuid.UUID  `json:"id"`
+	AlbumID     uuid.UUID  `json:"album_id"`
+	Email       string     `json:"email"`
+	UserID      *uuid.UUID `json:"user_id,omitempty"`
+	Status      string     `json:"status"`
+	SentCount   int        `json:"sent_count"`
+	LastSentAt  time.Time  `json:"last_sent_at"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	RespondedAt *time.Time `json:"responded_at,omitempty"`
+}
+
+func invitationTTL() time.Duration {
+	days, err := strconv.Atoi(os.Getenv("INVITATION_TTL_DAYS"))
+	if err != nil || days <= 0 {
+		days = 7
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+func toInvitationResponse(invitation models.AlbumInvitation) AlbumInvitationResponse {
+	return AlbumInvitationResponse{
+		ID:          invitation.ID,
+		AlbumID:     invitation.AlbumID,
+		Email:       invitation.Email,
+		UserID:      invitation.UserID,
+		Status:      invitation.Status,
+		SentCount:   invitation.SentCount,
+		LastSentAt:  invitation.LastSentAt,
+		ExpiresAt:   invitation.ExpiresAt,
+		RespondedAt: invitation.RespondedAt,
+	}
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// createAlbumInvitation membuat undangan pending baru untuk email, atau mengembalikan undangan
+// pending/accepted yang sudah ada. Token hanya dikembalikan jika undangan perlu dikirim.
+func createAlbumInvitation(db *gorm.DB, album models.Album, inviterID uuid.UUID, email string) (models.AlbumInvitation, string, error) {
+	email = normalizeEmail(email)
+
+	var existing models.AlbumInvitation
+	err := db.Where("album_id = ? AND email = ? AND status IN ?", album.ID, email,
+		[]string{models.InvitationStatusPending, models.InvitationStatusAccepted}).
+		First(&existing).Error
+	if err == nil {
+		return existing, "", nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.AlbumInvitation{}, "", err
+	}
+
+	nonce, err := utils.NewInvitationNonce()
+	if err != nil {
+		return models.AlbumInvitation{}, "", err
+	}
+
+	invitation := models.AlbumInvitation{
+		ID:         uuid.New(),
+		AlbumID:    album.ID,
+		InviterID:  inviterID,
+		Email:      email,
+		Status:     models.InvitationStatusPending,
+		TokenNonce: nonce,
+		SentCount:  1,
+		LastSentAt: time.Now(),
+		ExpiresAt:  time.Now().Add(invitationTTL()),
+	}
+
+	if err := db.Create(&invitation).Error; err != nil {
+		return models.AlbumInvitation{}, "", err
+	}
+
+	token, err := utils.GenerateInvitationToken(invitation.ID, invitation.TokenNonce, invitation.ExpiresAt)
+	if err != nil {
+		return models.AlbumInvitation{}, "", err
+	}
+
+	return invitation, token, nil
+}
+
+// sendAlbumInvitation mengirim email undangan berisi link accept yang sudah ditandatangani.
+func sendAlbumInvitation(client notif.NotificationServiceClient, album models.Album, sharedBy string, invitation models.AlbumInvitation, token string) {
+	ctxNotif, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	acceptLink := utils.FrontendURL("invitations/accept?token=" + token)
+
+	_, err := client.SendNotification(ctxNotif, &notif.NotificationRequest{
+		To:      invitation.Email,
+		Subject: "Anda Telah Ditambahkan ke Album",
+		Type:    "album-invitation",
+		Name:    invitation.Email,
+		Body:    fmt.Sprintf("Klik link berikut untuk menerima undangan album: %s", acceptLink),
+		Metadata: map[string]string{
+			"album_title":   album.Title,
+			"album_link":    acceptLink,
+			"expires_at":    invitation.ExpiresAt.Format("02 January 2006"),
+			"platform_name": "PixoVaulty",
+			"platform_url":  "www.pixovaulty.com",
+			"shared_by":     sharedBy,
+		},
+	})
+	if err != nil {
+		log.Printf("Gagal mengirim undangan ke %s: %v", invitation.Email, err)
+	}
+}
+
+// publishInvitationEvent membuat notifikasi in-app bila email undangan milik user terdaftar.
+// Email undangan sendiri tetap dikirim lewat sendAlbumInvitation karena berisi link accept.
+func publishInvitationEvent(db *gorm.DB, album models.Album, inviter models.User, invitation models.AlbumInvitation) {
+	var invitee models.User
+	if err := db.Where("LOWER(email) = ?", normalizeEmail(invitation.Email)).First(&invitee).Error; err != nil {
+		return
+	}
+
+	events.Publish(events.Event{
+		Type:        models.NotificationEventAlbumInvitation,
+		RecipientID: invitee.ID,
+		ActorID:     inviter.ID,
+		AlbumID:     &album.ID,
+		Data:        map[string]string{"email_type": ""},
+	})
+}
+
+func displayName(user models.User) string {
+	if user.UserName != "" {
+		return user.UserName
+	}
+	return user.FirstName + " " + user.LastName
+}
+
+// canAccessRestrictedAlbum mengecek apakah user boleh melihat album restricted:
+// pemilik album, atau user yang sudah menerima undangan album tersebut.
+func canAccessRestrictedAlbum(db *gorm.DB, album models.Album, userID uuid.UUID) (bool, error) {
+	if album.UserID == userID {
+		return true, nil
+	}
+
+	var count int64
+	if err := db.Model(&models.AlbumInvitation{}).
+		Where("album_id = ? AND user_id = ? AND status = ?", album.ID, userID, models.InvitationStatusAccepted).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// acceptedInvitationCondition dipakai di query album untuk memfilter album restricted
+// yang undangannya sudah diterima user.
+const acceptedInvitationCondition = "EXISTS (SELECT 1 FROM album_invitations ai WHERE ai.album_id = albums.id AND ai.user_id = ? AND ai.status = 'accepted' AND ai.deleted_at IS NULL)"
+
+// removeTargetEmail menghapus email dari daftar TargetEmail album.
+func removeTargetEmail(db *gorm.DB, album *models.Album, email string) error {
+	var emailList []string
+	if len(album.TargetEmail) > 0 {
+		if err := json.Unmarshal(album.TargetEmail, &emailList); err != nil {
+			return err
+		}
+	}
+
+	filtered := []string{}
+	for _, existing := range emailList {
+		if normalizeEmail(existing) != normalizeEmail(email) {
+			filtered = append(filtered, existing)
+		}
+	}
+
+	marshaled, err := json.Marshal(filtered)
+	if err != nil {
+		return err
+	}
+
+	return db.Model(album).UpdateColumn("target_email", marshaled).Error
+}
+
+// removedTargetEmails mengembalikan email (sudah dinormalisasi) yang ada di daftar TargetEmail lama
+// tapi tidak ada lagi di daftar baru.
+func removedTargetEmails(previous json.RawMessage, emails []string) ([]string, error) {
+	var previousList []string
+	if len(previous) > 0 {
+		if err := json.Unmarshal(previous, &previousList); err != nil {
+			return nil, err
+		}
+	}
+
+	kept := map[string]bool{}
+	for _, email := range emails {
+		kept[normalizeEmail(email)] = true
+	}
+
+	var removed []string
+	for _, email := range previousList {
+		email = normalizeEmail(email)
+		if email != "" && !kept[email] {
+			kept[email] = true // cegah duplikat di hasil
+			removed = append(removed, email)
+		}
+	}
+	return removed, nil
+}
+
+// cancelTargetInvitations membatalkan undangan pending/accepted untuk email yang dihapus dari
+// TargetEmail, sehingga akses user yang sudah menerima ikut dicabut.
+func cancelTargetInvitations(db *gorm.DB, albumID uuid.UUID, emails []string) error {
+	if len(emails) == 0 {
+		return nil
+	}
+	return db.Model(&models.AlbumInvitation{}).
+		Where("album_id = ? AND email IN ? AND status IN ?", albumID, emails,
+			[]string{models.InvitationStatusPending, models.InvitationStatusAccepted}).
+		Update("status", models.InvitationStatusCancelled).Error
+}
+
+// loadInvitationFromToken memverifikasi token dan memastikan nonce masih sama dengan undangan di database.
+func loadInvitationFromToken(db *gorm.DB, token string) (models.AlbumInvitation, error) {
+	var invitation models.AlbumInvitation
+
+	invitationID, nonce, err := utils.ParseInvitationToken(token)
+	if err != nil {
+		return invitation, err
+	}
+
+	if err := db.Preload("Album").Preload("Inviter").First(&invitation, "id = ?", invitationID).Error; err != nil {
+		return invitation, fmt.Errorf("undangan tidak ditemukan")
+	}
+
+	expired, err := checkInvitationToken(&invitation, nonce, time.Now())
+	if err != nil {
+		return invitation, err
+	}
+	if expired {
+		db.Model(&invitation).Update("status", models.InvitationStatusExpired)
+	}
+
+	return invitation, nil
+}
+
+// checkInvitationToken mencocokkan nonce token dengan undangan. Token dari kiriman sebelum resend
+// ditolak, dan undangan pending yang sudah lewat ExpiresAt ditandai expired (expired = true).
+func checkInvitationToken(invitation *models.AlbumInvitation, nonce string, now time.Time) (bool, error) {
+	if invitation.TokenNonce != nonce {
+		return false, fmt.Errorf("token undangan sudah tidak berlaku")
+	}
+
+	if invitation.Status == models.InvitationStatusPending && now.After(invitation.ExpiresAt) {
+		invitation.Status = models.InvitationStatusExpired
+		return true, nil
+	}
+	return false, nil
+}
+
+// acceptAlbumInvitation mengikat undangan pending ke akun user.
+func acceptAlbumInvitation(db *gorm.DB, invitation *models.AlbumInvitation, userID uuid.UUID) error {
+	if invitation.Status != models.InvitationStatusPending {
+		return fmt.Errorf("undangan sudah %s", invitation.Status)
+	}
+
+	now := time.Now()
+
+	// Update bersyarat agar undangan tidak bisa diterima dua kali secara bersamaan
+	result := db.Model(&models.AlbumInvitation{}).
+		Where("id = ? AND status = ?", invitation.ID, models.InvitationStatusPending).
+		Updates(map[string]interface{}{
+			"status":       models.InvitationStatusAccepted,
+			"user_id":      userID,
+			"responded_at": now,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("undangan sudah tidak berstatus pending")
+	}
+
+	invitation.Status = models.InvitationStatusAccepted
+	invitation.UserID = &userID
+	invitation.RespondedAt = &now
+
+	return nil
+}
+
+func GetInvitationPreview(ctx *fiber.Ctx, db *gorm.DB) error {
+	invitation, err := loadInvitationFromToken(db, ctx.Params("token"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var userCount int64
+	if err := db.Model(&models.User{}).Where("LOWER(email) = ?", invitation.Email).Count(&userCount).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memeriksa akun"})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Undangan berhasil diambil",
+		"invitation": fiber.Map{
+			"album_id":    invitation.AlbumID,
+			"album_title": invitation.Album.Title,
+			"shared_by":   displayName(invitation.Inviter),
+			"email":       invitation.Email,
+			"status":      invitation.Status,
+			"expires_at":  invitation.ExpiresAt,
+		},
+		// Jika belum punya akun, frontend mengarahkan ke signup dengan invitationToken
+		"has_account": userCount > 0,
+	})
+}
+
+func AcceptInvitation(ctx *fiber.Ctx, db *gorm.DB) error {
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	type acceptRequest struct {
+		Token string `json:"token"`
+	}
+
+	var req acceptRequest
+	if err := ctx.BodyParser(&req); err != nil || req.Token == "" {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Token undangan wajib diisi"})
+	}
+
+	invitation, err := loadInvitationFromToken(db, req.Token)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if invitation.Status == models.InvitationStatusAccepted && invitation.UserID != nil && *invitation.UserID == userID {
+		return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message":  "Undangan sudah diterima sebelumnya",
+			"album_id": invitation.AlbumID,
+		})
+	}
+
+	if err := acceptAlbumInvitation(db, &invitation, userID); err != nil {
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":    "Undangan berhasil diterima",
+		"album_id":   invitation.AlbumID,
+		"invitation": toInvitationResponse(invitation),
+	})
+}
+
+func DeclineInvitation(ctx *fiber.Ctx, db *gorm.DB) error {
+	type declineRequest struct {
+		Token string `json:"token"`
+	}
+
+	var req declineRequest
+	if err := ctx.BodyParser(&req); err != nil || req.Token == "" {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Token undangan wajib diisi"})
+	}
+
+	invitation, err := loadInvitationFromToken(db, req.Token)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if invitation.Status != models.InvitationStatusPending {
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Undangan sudah " + invitation.Status})
+	}
+
+	now := time.Now()
+	if err := db.Model(&invitation).Updates(map[string]interface{}{
+		"status":       models.InvitationStatusDeclined,
+		"responded_at": now,
+	}).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menolak undangan"})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Undangan berhasil ditolak",
+	})
+}
+
+// findOwnedAlbum mengambil album berdasarkan parameter route dan memastikan user login adalah pemiliknya.
+func findOwnedAlbum(ctx *fiber.Ctx, db *gorm.DB, param string) (models.Album, uuid.UUID, error) {
+	var album models.Album
+
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return album, userID, fiber.NewError(fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	albumID, err := uuid.Parse(ctx.Params(param))
+	if err != nil {
+		return album, userID, fiber.NewError(fiber.StatusBadRequest, "Album ID tidak valid")
+	}
+
+	if err := db.First(&album, "id = ?", albumID).Error; err != nil {
+		return album, userID, fiber.NewError(fiber.StatusNotFound, "Album tidak ditemukan")
+	}
+
+	if album.UserID != userID {
+		return album, userID, fiber.NewError(fiber.StatusForbidden, "User tidak memiliki akses ke album ini")
+	}
+
+	return album, userID, nil
+}
+
+func fiberErrorResponse(ctx *fiber.Ctx, err error) error {
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return ctx.Status(fiberErr.Code).JSON(fiber.Map{"error": fiberErr.Message})
+	}
+	return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+}
+
+func GetAlbumInvitations(ctx *fiber.Ctx, db *gorm.DB) error {
+	album, _, err := findOwnedAlbum(ctx, db, "albumId")
+	if err != nil {
+		return fiberErrorResponse(ctx, err)
+	}
+
+	query := db.Where("album_id = ?", album.ID)
+	if status := ctx.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var invitations []models.AlbumInvitation
+	if err := query.Order("created_at DESC").Find(&invitations).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil undangan"})
+	}
+
+	response := []AlbumInvitationResponse{}
+	for _, invitation := range invitations {
+		response = append(response, toInvitationResponse(invitation))
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":     "Undangan berhasil diambil",
+		"invitations": response,
+	})
+}
+
+func ResendAlbumInvitation(ctx *fiber.Ctx, db *gorm.DB, client notif.NotificationServiceClient) error {
+	album, userID, err := findOwnedAlbum(ctx, db, "albumId")
+	if err != nil {
+		return fiberErrorResponse(ctx, err)
+	}
+
+	var invitation models.AlbumInvitation
+	if err := db.Where("id = ? AND album_id = ?", ctx.Params("invitationId"), album.ID).First(&invitation).Error; err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Undangan tidak ditemukan"})
+	}
+
+	if invitation.Status == models.InvitationStatusAccepted || invitation.Status == models.InvitationStatusCancelled {
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Undangan sudah " + invitation.Status})
+	}
+
+	// Nonce baru membuat token lama tidak berlaku lagi
+	nonce, err := utils.NewInvitationNonce()
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuat token undangan"})
+	}
+
+	invitation.TokenNonce = nonce
+	invitation.Status = models.InvitationStatusPending
+	invitation.SentCount++
+	invitation.LastSentAt = time.Now()
+	invitation.ExpiresAt = time.Now().Add(invitationTTL())
+	invitation.RespondedAt = nil
+
+	if err := db.Save(&invitation).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memperbarui undangan"})
+	}
+
+	token, err := utils.GenerateInvitationToken(invitation.ID, invitation.TokenNonce, invitation.ExpiresAt)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuat token undangan"})
+	}
+
+	var user models.User
+	if err := db.First(&user, "id = ?", userID).Error; err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User tidak ditemukan"})
+	}
+
+	go sendAlbumInvitation(client, album, displayName(user), invitation, token)
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":    "Undangan berhasil dikirim ulang",
+		"invitation": toInvitationResponse(invitation),
+	})
+}
+
+func CancelAlbumInvitation(ctx *fiber.Ctx, db *gorm.DB) error {
+	album, _, err := findOwnedAlbum(ctx, db, "albumId")
+	if err != nil {
+		return fiberErrorResponse(ctx, err)
+	}
+
+	var invitation models.AlbumInvitation
+	if err := db.Where("id = ? AND album_id = ?", ctx.Params("invitationId"), album.ID).First(&invitation).Error; err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Undangan tidak ditemukan"})
+	}
+
+	if invitation.Status == models.InvitationStatusCancelled {
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Undangan sudah dibatalkan"})
+	}
+
+	// Membatalkan undangan yang sudah diterima sekaligus mencabut akses user
+	if err := db.Model(&invitation).Update("status", models.InvitationStatusCancelled).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membatalkan undangan"})
+	}
+
+	if err := removeTargetEmail(db, &album, invitation.Email); err != nil {
+		log.Printf("Gagal menghapus target email %s: %v", invitation.Email, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Undangan berhasil dibatalkan",
+	})
+}
+
+// inviteTargetEmails membuat undangan untuk setiap email lalu mengirim emailnya di background.
+func inviteTargetEmails(db *gorm.DB, client notif.NotificationServiceClient, album models.Album, inviter models.User, emails []string) error {
+	type pendingInvitation struct {
+		invitation models.AlbumInvitation
+		token      string
+	}
+
+	var pendings []pendingInvitation
+	for _, email := range emails {
+		if strings.TrimSpace(email) == "" {
+			continue
+		}
+
+		invitation, token, err := createAlbumInvitation(db, album, inviter.ID, email)
+		if err != nil {
+			return fmt.Errorf("gagal membuat undangan untuk %s: %w", email, err)
+		}
+
+		// Token kosong berarti undangan aktif sudah ada, tidak perlu dikirim lagi
+		if token != "" {
+			pendings = append(pendings, pendingInvitation{invitation: invitation, token: token})
+			publishInvitationEvent(db, album, inviter, invitation)
+		}
+	}
+
+	sharedBy := displayName(inviter)
+
+	go func(album models.Album, pendings []pendingInvitation) {
+		var wg sync.WaitGroup
+
+		for _, pending := range pendings {
+			wg.Add(1)
+			go func(pending pendingInvitation) {
+				defer wg.Done()
+				sendAlbumInvitation(client, album, sharedBy, pending.invitation, pending.token)
+			}(pending)
+		}
+
+		wg.Wait()
+	}(album, pendings)
+
+	return nil
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Zackly23/queue-app/models"
+	"github.com/Zackly23/queue-app/utils"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// openInvitation mensimulasikan link undangan yang dibuka user: token diverifikasi lalu
+// dicocokkan dengan undangan seperti loadInvitationFromToken.
+func openInvitation(invitation *models.AlbumInvitation, token string, now time.Time) (bool, error) {
+	invitationID, nonce, err := utils.ParseInvitationToken(token)
+	if err != nil {
+		return false, err
+	}
+	if invitationID != invitation.ID {
+		return false, errors.New("token untuk undangan lain")
+	}
+	return checkInvitationToken(invitation, nonce, now)
+}
+
+func newInvitationToken(t *testing.T, invitation *models.AlbumInvitation) string {
+	t.Helper()
+	nonce, err := utils.NewInvitationNonce()
+	if err != nil {
+		t.Fatalf("NewInvitationNonce: %v", err)
+	}
+	invitation.TokenNonce = nonce
+
+	token, err := utils.GenerateInvitationToken(invitation.ID, nonce, invitation.ExpiresAt)
+	if err != nil {
+		t.Fatalf("GenerateInvitationToken: %v", err)
+	}
+	return token
+}
+
+func TestInvitationTokenLifecycle(t *testing.T) {
+	t.Setenv("JWT_SECRET_KEY", "rahasia-undangan")
+	t.Setenv("INVITATION_TTL_DAYS", "")
+
+	sentAt := time.Now()
+	invitation := models.AlbumInvitation{
+		ID:        uuid.New(),
+		Email:     "teman@example.com",
+		Status:    models.InvitationStatusPending,
+		ExpiresAt: sentAt.Add(invitationTTL()),
+	}
+	first := newInvitationToken(t, &invitation)
+
+	if expired, err := openInvitation(&invitation, first, sentAt.Add(time.Hour)); err != nil || expired {
+		t.Fatalf("token pertama: expired = %v, err = %v", expired, err)
+	}
+
+	// Resend mengganti nonce: link lama masih bertanda tangan sah tapi tidak berlaku lagi
+	invitation.ExpiresAt = sentAt.Add(2 * 24 * time.Hour).Add(invitationTTL())
+	second := newInvitationToken(t, &invitation)
+	if _, err := openInvitation(&invitation, first, sentAt.Add(2*24*time.Hour)); err == nil {
+		t.Error("token sebelum resend masih diterima")
+	}
+	if _, err := openInvitation(&invitation, second, sentAt.Add(2*24*time.Hour)); err != nil {
+		t.Errorf("token hasil resend ditolak: %v", err)
+	}
+
+	// Lewat ExpiresAt undangan pending berubah menjadi expired, cukup sekali
+	afterExpiry := invitation.ExpiresAt.Add(time.Minute)
+	nonce := invitation.TokenNonce
+	expired, err := checkInvitationToken(&invitation, nonce, afterExpiry)
+	if err != nil || !expired || invitation.Status != models.InvitationStatusExpired {
+		t.Fatalf("setelah expiry: expired = %v, status = %s, err = %v", expired, invitation.Status, err)
+	}
+	if expired, _ := checkInvitationToken(&invitation, nonce, afterExpiry); expired {
+		t.Error("undangan yang sudah expired ditandai expired lagi")
+	}
+
+	// Undangan yang sudah diterima tetap berlaku walau ExpiresAt terlewati
+	accepted := models.AlbumInvitation{
+		ID:         uuid.New(),
+		Status:     models.InvitationStatusAccepted,
+		TokenNonce: nonce,
+		ExpiresAt:  sentAt,
+	}
+	if expired, err := checkInvitationToken(&accepted, nonce, afterExpiry); err != nil || expired {
+		t.Errorf("undangan accepted: expired = %v, err = %v", expired, err)
+	}
+	if accepted.Status != models.InvitationStatusAccepted {
+		t.Errorf("status undangan accepted berubah menjadi %s", accepted.Status)
+	}
+}
+
+func TestParseInvitationTokenRejects(t *testing.T) {
+	t.Setenv("JWT_SECRET_KEY", "rahasia-undangan")
+	id := uuid.New()
+
+	valid, err := utils.GenerateInvitationToken(id, "nonce", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("GenerateInvitationToken: %v", err)
+	}
+	lapsed, _ := utils.GenerateInvitationToken(id, "nonce", time.Now().Add(-time.Minute))
+
+	// Token login ditandatangani dengan secret yang sama tapi bukan untuk undangan
+	login, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id":       id.String(),
+		"invitation_id": id.String(),
+		"exp":           time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte("rahasia-undangan"))
+
+	t.Setenv("JWT_SECRET_KEY", "kunci-lain")
+	foreign, _ := utils.GenerateInvitationToken(id, "nonce", time.Now().Add(time.Hour))
+	t.Setenv("JWT_SECRET_KEY", "rahasia-undangan")
+
+	rejected := map[string]string{
+		"jwt expired":    lapsed,
+		"secret berbeda": foreign,
+		"bukan undangan": login,
+		"terpotong":      valid[:len(valid)-4],
+		"kosong":         "",
+	}
+	for name, token := range rejected {
+		if _, _, err := utils.ParseInvitationToken(token); err == nil {
+			t.Errorf("%s: token diterima", name)
+		}
+	}
+
+	gotID, gotNonce, err := utils.ParseInvitationToken(valid)
+	if err != nil || gotID != id || gotNonce != "nonce" {
+		t.Errorf("token valid = %s %q %v", gotID, gotNonce, err)
+	}
+}
+
+func TestInvitationTTL(t *testing.T) {
+	day := 24 * time.Hour
+	for env, want := range map[string]time.Duration{"": 7 * day, "3": 3 * day, "0": 7 * day, "-2": 7 * day, "seminggu": 7 * day} {
+		t.Setenv("INVITATION_TTL_DAYS", env)
+		if got := invitationTTL(); got != want {
+			t.Errorf("INVITATION_TTL_DAYS=%q: ttl = %v, want %v", env, got, want)
+		}
+	}
+}
+
+func TestRemovedTargetEmails(t *testing.T) {
+	previous, _ := json.Marshal([]string{"Ani@Example.com", "budi@example.com", " cici@example.com ", "budi@example.com", ""})
+
+	removed, err := removedTargetEmails(previous, []string{"ani@example.com", "dodi@example.com"})
+	if err != nil {
+		t.Fatalf("removedTargetEmails: %v", err)
+	}
+	if want := []string{"budi@example.com", "cici@example.com"}; !reflect.DeepEqual(removed, want) {
+		t.Errorf("removed = %v, want %v", removed, want)
+	}
+
+	// Album yang sebelumnya tidak punya target email tidak membatalkan apa pun
+	if removed, err := removedTargetEmails(nil, []string{"ani@example.com"}); err != nil || len(removed) != 0 {
+		t.Errorf("tanpa daftar lama: removed = %v, err = %v", removed, err)
+	}
+	// Mengosongkan daftar membatalkan semua undangan
+	if removed, _ := removedTargetEmails(previous, nil); len(removed) != 3 {
+		t.Errorf("daftar dikosongkan: removed = %v", removed)
+	}
+	if _, err := removedTargetEmails(json.RawMessage(`{"email":"ani@example.com"}`), nil); err == nil {
+		t.Error("target_email bukan array tidak menghasilkan error")
+	}
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Zackly23/queue-app/models"
+	notif "github.com/Zackly23/queue-app/proto/notificationpb"
+	"github.com/Zackly23/queue-app/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func invoiceLink(invoice models.Invoice) string {
+	return utils.FrontendURL("settings/billing/invoices/" + invoice.ID.String())
+}
+
+// sendInvoiceReceipt mengirim kuitansi pembayaran lewat notification service lalu mencatat waktunya.
+func sendInvoiceReceipt(db *gorm.DB, client notif.NotificationServiceClient, invoice models.Invoice) error {
+	if client == nil {
+		return fmt.Errorf("notification client tidak tersedia")
+	}
+
+	ctxNotif, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	total := utils.FormatMoney(invoice.Total, invoice.Currency)
+	link := invoiceLink(invoice)
+	_, err := client.SendNotification(ctxNotif, &notif.NotificationRequest{
+		To:      invoice.BillingEmail,
+		Subject: "Kuitansi Pembayaran " + invoice.Number,
+		Type:    "invoice-receipt",
+		Name:    invoice.BillingName,
+		Body:    fmt.Sprintf("Pembayaran %s untuk invoice %s sudah kami terima. Unduh invoice: %s", total, invoice.Number, link),
+		Metadata: map[string]string{
+			"invoice_number": invoice.Number,
+			"invoice_link":   link,
+			"total":          total,
+			"tax":            utils.FormatMoney(invoice.TaxAmount, invoice.Currency),
+			"paid_at":        invoice.PaidAt.Format("02 January 2006"),
+			"payment_method": invoice.PaymentMethod,
+			"platform_name":  "PixoVaulty",
+			"platform_url":   "www.pixovaulty.com",
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return db.Model(&invoice).Update("emailed_at", time.Now()).Error
+}
+
+// sendPaymentReceipt mengirim kuitansi untuk pembayaran yang invoice-nya belum pernah dikirim,
+// dipanggil setelah notifikasi pembayaran diproses. Provider bisa mengirim beberapa notifikasi
+// lunas untuk satu transaksi (mis. capture lalu settlement), sehingga invoice diklaim dulu dengan
+// UPDATE bersyarat dan hanya pemanggil yang berhasil mengklaim yang mengirim email.
+func sendPaymentReceipt(db *gorm.DB, client notif.NotificationServiceClient, provider, orderID string) {
+	var invoice models.Invoice
+	err := db.Joins("JOIN payments ON payments.id = invoices.payment_id").
+		Where("payments.provider = ? AND payments.order_id = ? AND invoices.emailed_at IS NULL", provider, orderID).
+		First(&invoice).Error
+	if err != nil {
+		return
+	}
+
+	claim := db.Model(&models.Invoice{}).
+		Where("id = ? AND emailed_at IS NULL", invoice.ID).
+		Update("emailed_at", time.Now())
+	if claim.Error != nil || claim.RowsAffected == 0 {
+		return
+	}
+
+	if err := sendInvoiceReceipt(db, client, invoice); err != nil {
+		log.Printf("Gagal mengirim kuitansi %s: %v", invoice.Number, err)
+		// Klaim dilepas agar kuitansi bisa dikirim ulang
+		db.Model(&models.Invoice{}).Where("id = ?", invoice.ID).Update("emailed_at", nil)
+	}
+}
+
+// findUserInvoice mengambil invoice milik user yang login beserta barisnya.
+func findUserInvoice(ctx *fiber.Ctx, db *gorm.DB) (models.Invoice, error) {
+	var invoice models.Invoice
+
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return invoice, fiber.NewError(fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	invoiceID, err := uuid.Parse(ctx.Params("invoiceId"))
+	if err != nil {
+		return invoice, fiber.NewError(fiber.StatusBadRequest, "Invoice ID tidak valid")
+	}
+
+	if err := db.Preload("Lines", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("sort_order ASC")
+	}).First(&invoice, "id = ? AND user_id = ?", invoiceID, userID).Error; err != nil {
+		return invoice, fiber.NewError(fiber.StatusNotFound, "Invoice tidak ditemukan")
+	}
+	return invoice, nil
+}
+
+// GetInvoices mengambil daftar invoice user (terbaru dulu, cursor pagination).
+func GetInvoices(ctx *fiber.Ctx, db *gorm.DB) error {
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	limit := utils.ParseLimit(ctx.Query("limit"), 20, 100)
+	query := db.Where("user_id = ?", userID)
+
+	if cursor := ctx.Query("cursor"); cursor != "" {
+		issuedAt, id, err := utils.DecodeCursor(cursor)
+		if err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		query = query.Where("(issued_at, id) < (?, ?)", issuedAt, id)
+	}
+
+	var invoices []models.Invoice
+	if err := query.Order("issued_at DESC, id DESC").Limit(limit + 1).Find(&invoices).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil invoice"})
+	}
+
+	nextCursor := ""
+	if len(invoices) > limit {
+		invoices = invoices[:limit]
+		last := invoices[len(invoices)-1]
+		nextCursor = utils.EncodeCursor(last.IssuedAt, last.ID)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"invoices":    invoices,
+		"next_cursor": nextCursor,
+		"has_more":    nextCursor != "",
+	})
+}
+
+func GetInvoice(ctx *fiber.Ctx, db *gorm.DB) error {
+	invoice, err := findUserInvoice(ctx, db)
+	if err != nil {
+		return fiberErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{"invoice": invoice})
+}
+
+// DownloadInvoice mengirim invoice sebagai file PDF.
+func DownloadInvoice(ctx *fiber.Ctx, db *gorm.DB) error {
+	invoice, err := findUserInvoice(ctx, db)
+	if err != nil {
+		return fiberErrorResponse(ctx, err)
+	}
+
+	ctx.Set(fiber.HeaderContentType, "application/pdf")
+	ctx.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s.pdf"`, invoice.Number))
+	return ctx.Send(utils.RenderInvoicePDF(invoice))
+}
+
+// ResendInvoiceReceipt mengirim ulang kuitansi invoice ke email penagihan.
+func ResendInvoiceReceipt(ctx *fiber.Ctx, db *gorm.DB, client notif.NotificationServiceClient) error {
+	invoice, err := findUserInvoice(ctx, db)
+	if err != nil {
+		return fiberErrorResponse(ctx, err)
+	}
+
+	if invoice.EmailedAt != nil && time.Since(*invoice.EmailedAt) < time.Minute {
+		return ctx.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": "Tunggu sebentar sebelum mengirim ulang kuitansi"})
+	}
+
+	if err := sendInvoiceReceipt(db, client, invoice); err != nil {
+		log.Printf("Gagal mengirim kuitansi %s: %v", invoice.Number, err)
+		return ctx.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": "Gagal mengirim kuitansi"})
+	}
+
+	return ctx.JSON(fiber.Map{"message": "Kuitansi dikirim ke " + invoice.BillingEmail})
+}
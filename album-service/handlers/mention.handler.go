@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"unicode/utf8"
+
+	"github.com/Zackly23/queue-app/events"
+	"github.com/Zackly23/queue-app/models"
+	"github.com/Zackly23/queue-app/utils"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// syncMentions menyamakan tabel mention dengan @username di teks terbaru dan
+// mengembalikan user yang baru disebut (belum pernah disebut di sumber yang sama).
+func syncMentions(db *gorm.DB, sourceType string, sourceID, albumID, authorID uuid.UUID, text string) ([]models.User, error) {
+	usernames := utils.ParseMentions(text)
+
+	var users []models.User
+	if len(usernames) > 0 {
+		if err := db.Where("LOWER(user_name) IN ? AND id <> ?", usernames, authorID).Find(&users).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	userIDs := make([]uuid.UUID, 0, len(users))
+	for _, user := range users {
+		userIDs = append(userIDs, user.ID)
+	}
+
+	// Mention yang sudah dihapus dari teks ikut dihapus
+	cleanup := db.Where("source_type = ? AND source_id = ?", sourceType, sourceID)
+	if len(userIDs) > 0 {
+		cleanup = cleanup.Where("user_id NOT IN ?", userIDs)
+	}
+	if err := cleanup.Delete(&models.Mention{}).Error; err != nil {
+		return nil, err
+	}
+
+	var newUsers []models.User
+	for _, user := range users {
+		result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.Mention{
+			SourceType:    sourceType,
+			SourceID:      sourceID,
+			UserID:        user.ID,
+			AlbumID:       albumID,
+			MentionedByID: authorID,
+		})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected > 0 {
+			newUsers = append(newUsers, user)
+		}
+	}
+
+	return newUsers, nil
+}
+
+// notifyMentions mengirim event mention, hanya ke user yang bisa melihat album.
+func notifyMentions(db *gorm.DB, album models.Album, author models.User, users []models.User, sourceType string, sourceID uuid.UUID, text string) {
+	location := "description"
+	var commentID *uuid.UUID
+	if sourceType == models.MentionSourceComment {
+		location = "comment"
+		commentID = &sourceID
+	}
+
+	snippet := text
+	if utf8.RuneCountInString(snippet) > 200 {
+		snippet = string([]rune(snippet)[:200]) + "..."
+	}
+
+	for _, user := range users {
+		allowed, err := canViewAlbum(db, album, user.ID)
+		if err != nil || !allowed {
+			continue
+		}
+
+		albumID := album.ID
+		events.Publish(events.Event{
+			Type:        models.NotificationEventMention,
+			RecipientID: user.ID,
+			ActorID:     author.ID,
+			AlbumID:     &albumID,
+			CommentID:   commentID,
+			Data: map[string]string{
+				"email_type":   "mention",
+				"mentioned_by": displayName(author),
+				"location":     location,
+				"album_title":  album.Title,
+				"album_link":   utils.FrontendURL("albums/" + album.ID.String()),
+				"snippet":      snippet,
+			},
+		})
+	}
+}
+
+// processAlbumDescription menyimpan mention dari deskripsi album dan menambahkan #hashtag sebagai tag album.
+func processAlbumDescription(db *gorm.DB, album models.Album, author models.User) error {
+	if err := utils.AttachAlbumTags(db, album, utils.ParseHashtags(album.Description)); err != nil {
+		return err
+	}
+
+	newUsers, err := syncMentions(db, models.MentionSourceAlbum, album.ID, album.ID, author.ID, album.Description)
+	if err != nil {
+		return err
+	}
+
+	notifyMentions(db, album, author, newUsers, models.MentionSourceAlbum, album.ID, album.Description)
+	return nil
+}
+
+// processCommentText menyimpan mention dan hashtag dari isi komentar.
+func processCommentText(db *gorm.DB, album models.Album, comment models.AlbumComment, author models.User) error {
+	tags, err := utils.FindOrCreateTags(db, utils.ParseHashtags(comment.Comment))
+	if err != nil {
+		return err
+	}
+	if err := db.Model(&comment).Association("Tags").Replace(tags); err != nil {
+		return err
+	}
+
+	newUsers, err := syncMentions(db, models.MentionSourceComment, comment.ID, album.ID, author.ID, comment.Comment)
+	if err != nil {
+		return err
+	}
+
+	notifyMentions(db, album, author, newUsers, models.MentionSourceComment, comment.ID, comment.Comment)
+	return nil
+}
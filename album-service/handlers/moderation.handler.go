@@ -0,0 +1,560 @@
+package handlers
+
+import (
+	"strings"
+	"time"
+
+	"github.com/Zackly23/queue-app/config"
+	"github.com/Zackly23/queue-app/events"
+	"github.com/Zackly23/queue-app/models"
+	"github.com/Zackly23/queue-app/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var activeReportStatuses = []string{models.ReportStatusOpen, models.ReportStatusInReview}
+
+type ModerationReportResponse struct {
+	models.Report
+	Reporter    string `json:"reporter"`
+	ReportCount int64  `json:"report_count"` // laporan aktif untuk target yang sama
+}
+
+func findReport(ctx *fiber.Ctx, db *gorm.DB) (models.Report, error) {
+	var report models.Report
+	if err := db.Preload("Reporter").First(&report, "id = ?", ctx.Params("reportId")).Error; err != nil {
+		return report, fiber.NewError(fiber.StatusNotFound, "Laporan tidak ditemukan")
+	}
+	return report, nil
+}
+
+// GetModerationQueue mengambil antrean laporan (terlama dulu). Default hanya laporan
+// open dan in_review, bisa difilter dengan ?status=, ?target_type= dan ?reason=.
+func GetModerationQueue(ctx *fiber.Ctx, db *gorm.DB) error {
+	limit := utils.ParseLimit(ctx.Query("limit"), 20, 100)
+	query := db.Preload("Reporter")
+
+	if status := ctx.Query("status"); status != "" {
+		query = query.Where("status IN ?", strings.Split(status, ","))
+	} else {
+		query = query.Where("status IN ?", activeReportStatuses)
+	}
+	if targetType := ctx.Query("target_type"); targetType != "" {
+		query = query.Where("target_type = ?", targetType)
+	}
+	if reason := ctx.Query("reason"); reason != "" {
+		query = query.Where("reason = ?", reason)
+	}
+
+	if cursor := ctx.Query("cursor"); cursor != "" {
+		createdAt, id, err := utils.DecodeCursor(cursor)
+		if err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		query = query.Where("(created_at, id) > (?, ?)", createdAt, id)
+	}
+
+	var reports []models.Report
+	if err := query.Order("created_at ASC, id ASC").Limit(limit + 1).Find(&reports).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil antrean moderasi"})
+	}
+
+	nextCursor := ""
+	if len(reports) > limit {
+		reports = reports[:limit]
+		last := reports[len(reports)-1]
+		nextCursor = utils.EncodeCursor(last.CreatedAt, last.ID)
+	}
+
+	// Jumlah laporan aktif per target dalam satu query
+	targetIDs := []uuid.UUID{}
+	for _, report := range reports {
+		targetIDs = append(targetIDs, report.TargetID)
+	}
+	var counts []struct {
+		TargetType string
+		TargetID   uuid.UUID
+		Total      int64
+	}
+	if len(targetIDs) > 0 {
+		if err := db.Model(&models.Report{}).
+			Select("target_type, target_id, COUNT(*) AS total").
+			Where("target_id IN ? AND status IN ?", targetIDs, activeReportStatuses).
+			Group("target_type, target_id").
+			Scan(&counts).Error; err != nil {
+			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menghitung laporan"})
+		}
+	}
+	countByTarget := map[string]int64{}
+	for _, count := range counts {
+		countByTarget[count.TargetType+":"+count.TargetID.String()] = count.Total
+	}
+
+	response := []ModerationReportResponse{}
+	for _, report := range reports {
+		response = append(response, ModerationReportResponse{
+			Report:      report,
+			Reporter:    displayName(report.Reporter),
+			ReportCount: countByTarget[report.TargetType+":"+report.TargetID.String()],
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"reports":     response,
+		"next_cursor": nextCursor,
+		"has_more":    nextCursor != "",
+	})
+}
+
+// reportTargetSnapshot mengambil isi konten yang dilaporkan untuk ditinjau moderator,
+// termasuk konten yang sudah dihapus.
+func reportTargetSnapshot(db *gorm.DB, report models.Report) (fiber.Map, error) {
+	switch report.TargetType {
+	case models.ReportTargetAlbum:
+		var album models.Album
+		if err := db.Unscoped().First(&album, "id = ?", report.TargetID).Error; err != nil {
+			return nil, err
+		}
+		return fiber.Map{
+			"id":                album.ID,
+			"title":             album.Title,
+			"description":       album.Description,
+			"album_privacy":     album.AlbumPrivacy,
+			"moderation_status": album.ModerationStatus,
+			"deleted":           album.DeletedAt.Valid,
+		}, nil
+	case models.ReportTargetMedia:
+		var media models.Media
+		if err := db.Unscoped().First(&media, "id = ?", report.TargetID).Error; err != nil {
+			return nil, err
+		}
+		signedURL, err := utils.GeneratePresignedURL(config.S3Bucket.BucketName, utils.S3KeyFromURL(media.URL))
+		if err != nil {
+			return nil, err
+		}
+		return fiber.Map{
+			"id":                media.ID,
+			"album_id":          media.AlbumID,
+			"kind":              media.Kind,
+			"url":               signedURL,
+			"description":       media.Description,
+			"moderation_status": media.ModerationStatus,
+			"deleted":           media.DeletedAt.Valid,
+		}, nil
+	case models.ReportTargetComment:
+		var comment models.AlbumComment
+		if err := db.Unscoped().First(&comment, "id = ?", report.TargetID).Error; err != nil {
+			return nil, err
+		}
+		return fiber.Map{
+			"id":       comment.ID,
+			"album_id": comment.AlbumID,
+			"comment":  comment.Comment,
+			"status":   comment.Status,
+			"deleted":  comment.DeletedAt.Valid,
+		}, nil
+	}
+
+	var user models.User
+	if err := db.Unscoped().First(&user, "id = ?", report.TargetID).Error; err != nil {
+		return nil, err
+	}
+	return fiber.Map{
+		"id":              user.ID,
+		"name":            displayName(user),
+		"bio":             user.Bio,
+		"status":          user.Status,
+		"suspended_until": user.SuspendedUntil,
+	}, nil
+}
+
+// GetModerationReport mengambil detail laporan, isi konten, laporan lain untuk target
+// yang sama, dan riwayat tindakan moderator terhadap target tersebut.
+func GetModerationReport(ctx *fiber.Ctx, db *gorm.DB) error {
+	report, err := findReport(ctx, db)
+	if err != nil {
+		return fiberErrorResponse(ctx, err)
+	}
+
+	target, err := reportTargetSnapshot(db, report)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil konten yang dilaporkan"})
+	}
+
+	var related []models.Report
+	if err := db.Where("target_type = ? AND target_id = ? AND id <> ?", report.TargetType, report.TargetID, report.ID).
+		Order("created_at DESC").Limit(50).Find(&related).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil laporan terkait"})
+	}
+
+	var actions []models.ModerationAction
+	if err := db.Where("target_type = ? AND target_id = ?", report.TargetType, report.TargetID).
+		Order("created_at DESC").Find(&actions).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil riwayat moderasi"})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"report":          ModerationReportResponse{Report: report, Reporter: displayName(report.Reporter), ReportCount: int64(len(related)) + 1},
+		"target":          target,
+		"related_reports": related,
+		"actions":         actions,
+	})
+}
+
+// UpdateReportStatus mengambil laporan untuk ditinjau (in_review) atau menolaknya (dismissed).
+func UpdateReportStatus(ctx *fiber.Ctx, db *gorm.DB) error {
+	moderatorID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var req struct {
+		Status string `json:"status"`
+		Note   string `json:"note"`
+	}
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Format permintaan tidak valid"})
+	}
+	if req.Status != models.ReportStatusInReview && req.Status != models.ReportStatusDismissed {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Status harus in_review atau dismissed"})
+	}
+
+	report, err := findReport(ctx, db)
+	if err != nil {
+		return fiberErrorResponse(ctx, err)
+	}
+	if report.Status == models.ReportStatusResolved || report.Status == models.ReportStatusDismissed {
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Laporan sudah selesai ditangani"})
+	}
+
+	updates := map[string]interface{}{
+		"status":       req.Status,
+		"moderator_id": moderatorID,
+	}
+
+	errTx := db.Transaction(func(tx *gorm.DB) error {
+		if req.Status == models.ReportStatusDismissed {
+			updates["resolved_at"] = time.Now()
+			if err := tx.Create(&models.ModerationAction{
+				ReportID:      &report.ID,
+				ModeratorID:   moderatorID,
+				TargetType:    report.TargetType,
+				TargetID:      report.TargetID,
+				TargetOwnerID: report.TargetOwnerID,
+				Action:        models.ModerationActionDismiss,
+				Note:          strings.TrimSpace(req.Note),
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&report).Updates(updates).Error
+	})
+	if errTx != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengubah status laporan"})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"message": "Status laporan berhasil diubah",
+		"data":    report,
+	})
+}
+
+// applyModerationAction menerapkan keputusan moderator ke target laporan dan
+// mengembalikan album terkait (untuk link notifikasi).
+func applyModerationAction(tx *gorm.DB, report models.Report, action string, moderatorID uuid.UUID, suspendedUntil *time.Time) (*uuid.UUID, error) {
+	var albumID *uuid.UUID
+
+	switch report.TargetType {
+	case models.ReportTargetAlbum:
+		var album models.Album
+		if err := tx.Unscoped().First(&album, "id = ?", report.TargetID).Error; err != nil {
+			return nil, fiber.NewError(fiber.StatusNotFound, "Album tidak ditemukan")
+		}
+		albumID = &album.ID
+
+		switch action {
+		case models.ModerationActionHide, models.ModerationActionRestore:
+			if album.ModerationStatus == models.ModerationStatusRemoved {
+				return nil, fiber.NewError(fiber.StatusConflict, "Album sudah dihapus moderator")
+			}
+			status := models.ModerationStatusHidden
+			if action == models.ModerationActionRestore {
+				status = models.ModerationStatusVisible
+			}
+			if err := tx.Model(&album).Update("moderation_status", status).Error; err != nil {
+				return nil, err
+			}
+		case models.ModerationActionRemove:
+			// Album dan media masuk trash pemilik dengan waktu yang sama, sub-album ikut disembunyikan
+			removedAt := time.Now()
+			if err := tx.Model(&models.Media{}).Where("album_id = ?", album.ID).Update("deleted_at", removedAt).Error; err != nil {
+				return nil, err
+			}
+			if err := tx.Unscoped().Model(&album).Updates(map[string]interface{}{
+				"moderation_status": models.ModerationStatusRemoved,
+				"deleted_at":        removedAt,
+			}).Error; err != nil {
+				return nil, err
+			}
+			if album.Path != "" {
+				if err := tx.Model(&models.Album{}).
+					Where("path LIKE ? AND id <> ?", album.Path+"%", album.ID).
+					Update("moderation_status", models.ModerationStatusHidden).Error; err != nil {
+					return nil, err
+				}
+			}
+		}
+
+	case models.ReportTargetMedia:
+		var media models.Media
+		if err := tx.Unscoped().First(&media, "id = ?", report.TargetID).Error; err != nil {
+			return nil, fiber.NewError(fiber.StatusNotFound, "Media tidak ditemukan")
+		}
+		albumID = &media.AlbumID
+
+		switch action {
+		case models.ModerationActionHide, models.ModerationActionRestore:
+			if media.ModerationStatus == models.ModerationStatusRemoved {
+				return nil, fiber.NewError(fiber.StatusConflict, "Media sudah dihapus moderator")
+			}
+			status := models.ModerationStatusHidden
+			if action == models.ModerationActionRestore {
+				status = models.ModerationStatusVisible
+			}
+			if err := tx.Model(&media).Update("moderation_status", status).Error; err != nil {
+				return nil, err
+			}
+		case models.ModerationActionRemove:
+			if err := tx.Unscoped().Model(&media).Updates(map[string]interface{}{
+				"moderation_status": models.ModerationStatusRemoved,
+				"deleted_at":        time.Now(),
+			}).Error; err != nil {
+				return nil, err
+			}
+		}
+		if action != models.ModerationActionWarn && action != models.ModerationActionSuspend {
+			if err := refreshAlbumCover(tx, media.AlbumID); err != nil && err != gorm.ErrRecordNotFound {
+				return nil, err
+			}
+		}
+
+	case models.ReportTargetComment:
+		var comment models.AlbumComment
+		if err := tx.Unscoped().First(&comment, "id = ?", report.TargetID).Error; err != nil {
+			return nil, fiber.NewError(fiber.StatusNotFound, "Komentar tidak ditemukan")
+		}
+		albumID = &comment.AlbumID
+
+		switch action {
+		case models.ModerationActionHide:
+			if err := tx.Model(&comment).Update("status", models.CommentStatusHidden).Error; err != nil {
+				return nil, err
+			}
+		case models.ModerationActionRestore:
+			if comment.DeletedAt.Valid {
+				return nil, fiber.NewError(fiber.StatusConflict, "Komentar sudah dihapus")
+			}
+			if err := tx.Model(&comment).Update("status", models.CommentStatusVisible).Error; err != nil {
+				return nil, err
+			}
+		case models.ModerationActionRemove:
+			if _, err := deleteCommentThread(tx, comment.ID, moderatorID); err != nil {
+				return nil, err
+			}
+		}
+
+	case models.ReportTargetUser:
+		if action == models.ModerationActionHide || action == models.ModerationActionRemove {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Gunakan warn atau suspend untuk laporan user")
+		}
+		if action == models.ModerationActionRestore {
+			if err := tx.Model(&models.User{}).
+				Where("id = ? AND status = ?", report.TargetOwnerID, "suspended").
+				Updates(map[string]interface{}{"status": "active", "suspended_until": nil}).Error; err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Suspend berlaku untuk pemilik konten, apa pun jenis targetnya
+	if action == models.ModerationActionSuspend {
+		var owner models.User
+		if err := tx.First(&owner, "id = ?", report.TargetOwnerID).Error; err != nil {
+			return nil, fiber.NewError(fiber.StatusNotFound, "Pemilik konten tidak ditemukan")
+		}
+		if owner.Role != models.UserRoleUser {
+			return nil, fiber.NewError(fiber.StatusForbidden, "Moderator dan admin tidak bisa disuspend")
+		}
+
+		if err := tx.Model(&owner).Updates(map[string]interface{}{
+			"status":          "suspended",
+			"suspended_until": suspendedUntil,
+		}).Error; err != nil {
+			return nil, err
+		}
+
+		// Semua sesi login user dicabut
+		if err := tx.Model(&models.PersonalAccessToken{}).Where("user_id = ?", owner.ID).Update("revoked", true).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return albumID, nil
+}
+
+// TakeModerationAction menerapkan tindakan (hide, remove, warn, suspend, restore) untuk sebuah laporan.
+// Semua laporan aktif untuk target yang sama ikut diselesaikan, dan pemilik konten diberi notifikasi.
+func TakeModerationAction(ctx *fiber.Ctx, db *gorm.DB) error {
+	moderatorID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var req struct {
+		Action      string `json:"action"`
+		Note        string `json:"note"`
+		SuspendDays int    `json:"suspend_days"` // 0 = tanpa batas waktu
+	}
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Format permintaan tidak valid"})
+	}
+
+	switch req.Action {
+	case models.ModerationActionHide, models.ModerationActionRemove, models.ModerationActionWarn,
+		models.ModerationActionSuspend, models.ModerationActionRestore:
+	default:
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Action harus hide, remove, warn, suspend atau restore"})
+	}
+	if req.SuspendDays < 0 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "suspend_days tidak valid"})
+	}
+
+	report, err := findReport(ctx, db)
+	if err != nil {
+		return fiberErrorResponse(ctx, err)
+	}
+	if report.TargetOwnerID == moderatorID {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Tidak bisa memoderasi konten sendiri"})
+	}
+
+	var suspendedUntil *time.Time
+	if req.Action == models.ModerationActionSuspend && req.SuspendDays > 0 {
+		until := time.Now().AddDate(0, 0, req.SuspendDays)
+		suspendedUntil = &until
+	}
+
+	note := strings.TrimSpace(req.Note)
+	moderationAction := models.ModerationAction{
+		ReportID:       &report.ID,
+		ModeratorID:    moderatorID,
+		TargetType:     report.TargetType,
+		TargetID:       report.TargetID,
+		TargetOwnerID:  report.TargetOwnerID,
+		Action:         req.Action,
+		Note:           note,
+		SuspendedUntil: suspendedUntil,
+	}
+
+	var albumID *uuid.UUID
+	var resolvedCount int64
+	errTx := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if albumID, err = applyModerationAction(tx, report, req.Action, moderatorID, suspendedUntil); err != nil {
+			return err
+		}
+
+		if err := tx.Create(&moderationAction).Error; err != nil {
+			return err
+		}
+
+		result := tx.Model(&models.Report{}).
+			Where("target_type = ? AND target_id = ? AND status IN ?", report.TargetType, report.TargetID, activeReportStatuses).
+			Updates(map[string]interface{}{
+				"status":       models.ReportStatusResolved,
+				"moderator_id": moderatorID,
+				"resolved_at":  time.Now(),
+			})
+		resolvedCount = result.RowsAffected
+		return result.Error
+	})
+	if errTx != nil {
+		if fiberErr, ok := errTx.(*fiber.Error); ok {
+			return fiberErrorResponse(ctx, fiberErr)
+		}
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menerapkan tindakan moderasi"})
+	}
+
+	data := map[string]string{
+		"action":      req.Action,
+		"target_type": report.TargetType,
+		"note":        note,
+	}
+	if suspendedUntil != nil {
+		data["suspended_until"] = suspendedUntil.Format("02 Jan 2006 15:04")
+	}
+	event := events.Event{
+		Type:        models.NotificationEventModeration,
+		RecipientID: report.TargetOwnerID,
+		AlbumID:     albumID,
+		Data:        data,
+	}
+	if report.TargetType == models.ReportTargetComment {
+		event.CommentID = &report.TargetID
+	} else if report.TargetType == models.ReportTargetMedia {
+		event.MediaID = &report.TargetID
+	}
+	events.Publish(event)
+
+	return ctx.JSON(fiber.Map{
+		"message":          "Tindakan moderasi berhasil diterapkan",
+		"action":           moderationAction,
+		"resolved_reports": resolvedCount,
+	})
+}
+
+// GetModerationActions mengambil log audit tindakan moderator (terbaru dulu),
+// bisa difilter dengan ?target_type=&target_id=, ?moderator_id= atau ?owner_id=.
+func GetModerationActions(ctx *fiber.Ctx, db *gorm.DB) error {
+	limit := utils.ParseLimit(ctx.Query("limit"), 20, 100)
+	query := db.Model(&models.ModerationAction{})
+
+	if targetType := ctx.Query("target_type"); targetType != "" {
+		query = query.Where("target_type = ?", targetType)
+	}
+	if targetID := ctx.Query("target_id"); targetID != "" {
+		query = query.Where("target_id = ?", targetID)
+	}
+	if moderatorID := ctx.Query("moderator_id"); moderatorID != "" {
+		query = query.Where("moderator_id = ?", moderatorID)
+	}
+	if ownerID := ctx.Query("owner_id"); ownerID != "" {
+		query = query.Where("target_owner_id = ?", ownerID)
+	}
+
+	if cursor := ctx.Query("cursor"); cursor != "" {
+		createdAt, id, err := utils.DecodeCursor(cursor)
+		if err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		query = query.Where("(created_at, id) < (?, ?)", createdAt, id)
+	}
+
+	var actions []models.ModerationAction
+	if err := query.Order("created_at DESC, id DESC").Limit(limit + 1).Find(&actions).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil log moderasi"})
+	}
+
+	nextCursor := ""
+	if len(actions) > limit {
+		actions = actions[:limit]
+		last := actions[len(actions)-1]
+		nextCursor = utils.EncodeCursor(last.CreatedAt, last.ID)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"actions":     actions,
+		"next_cursor": nextCursor,
+		"has_more":    nextCursor != "",
+	})
+}
@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/Zackly23/queue-app/models"
+	"github.com/Zackly23/queue-app/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type NotificationResponse struct {
+	models.Notification
+	Actor       string `json:"actor,omitempty"`
+	ActorAvatar string `json:"actor_avatar,omitempty"`
+	IsRead      bool   `json:"is_read"`
+}
+
+// GetNotifications mengambil notifikasi in-app user (terbaru dulu, cursor pagination).
+// ?unread=true untuk notifikasi yang belum dibaca saja.
+func GetNotifications(ctx *fiber.Ctx, db *gorm.DB) error {
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	limit := utils.ParseLimit(ctx.Query("limit"), 20, 100)
+	query := db.Preload("Actor").Where("user_id = ?", userID)
+
+	if ctx.Query("unread") == "true" {
+		query = query.Where("read_at IS NULL")
+	}
+	if event := ctx.Query("event"); event != "" {
+		query = query.Where("event = ?", event)
+	}
+
+	if cursor := ctx.Query("cursor"); cursor != "" {
+		createdAt, id, err := utils.DecodeCursor(cursor)
+		if err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		query = query.Where("(created_at, id) < (?, ?)", createdAt, id)
+	}
+
+	var notifications []models.Notification
+	if err := query.Order("created_at DESC, id DESC").Limit(limit + 1).Find(&notifications).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil notifikasi"})
+	}
+
+	nextCursor := ""
+	if len(notifications) > limit {
+		notifications = notifications[:limit]
+		last := notifications[len(notifications)-1]
+		nextCursor = utils.EncodeCursor(last.CreatedAt, last.ID)
+	}
+
+	var unreadCount int64
+	if err := db.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&unreadCount).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menghitung notifikasi"})
+	}
+
+	response := []NotificationResponse{}
+	for _, notification := range notifications {
+		item := NotificationResponse{Notification: notification, IsRead: notification.ReadAt != nil}
+		if notification.Actor != nil {
+			item.Actor = displayName(*notification.Actor)
+			if item.ActorAvatar, err = avatarURL(*notification.Actor); err != nil {
+				return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal generate presigned URL"})
+			}
+		}
+		response = append(response, item)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"notifications": response,
+		"unread_count":  unreadCount,
+		"next_cursor":   nextCursor,
+		"has_more":      nextCursor != "",
+	})
+}
+
+func MarkNotificationRead(ctx *fiber.Ctx, db *gorm.DB) error {
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	notificationID, err := uuid.Parse(ctx.Params("notificationId"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Notification ID tidak valid"})
+	}
+
+	var notification models.Notification
+	if err := db.Where("id = ? AND user_id = ?", notificationID, userID).First(&notification).Error; err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Notifikasi tidak ditemukan"})
+	}
+
+	if notification.ReadAt == nil {
+		if err := db.Model(&notification).Update("read_at", time.Now()).Error; err != nil {
+			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menandai notifikasi"})
+		}
+	}
+
+	return ctx.JSON(fiber.Map{
+		"message":      "Notifikasi ditandai sudah dibaca",
+		"notification": notification,
+	})
+}
+
+func MarkAllNotificationsRead(ctx *fiber.Ctx, db *gorm.DB) error {
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	result := db.Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", time.Now())
+	if result.Error != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menandai notifikasi"})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"message":      "Semua notifikasi ditandai sudah dibaca",
+		"marked_count": result.RowsAffected,
+	})
+}
+
+// notificationPreferences menggabungkan preferensi user dengan nilai default untuk semua event.
+func notificationPreferences(config models.AccountConfig) map[string]string {
+	preferences := map[string]string{}
+	for event := range models.DefaultNotificationPreferences {
+		preferences[event] = config.NotificationDelivery(event)
+	}
+	return preferences
+}
+
+func GetNotificationPreferences(ctx *fiber.Ctx, db *gorm.DB) error {
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var accountConfig models.AccountConfig
+	if err := db.Where("user_id = ?", userID).First(&accountConfig).Error; err != nil && err != gorm.ErrRecordNotFound {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to retrieve account config"})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"preferences":      notificationPreferences(accountConfig),
+		"delivery_options": []string{models.NotificationDeliveryInApp, models.NotificationDeliveryEmail, models.NotificationDeliveryAll, models.NotificationDeliveryNone},
+	})
+}
+
+// UpdateNotificationPreferences menyimpan cara pengiriman per event, mis. {"preferences": {"follow": "none"}}.
+// Event yang tidak dikirim tetap memakai nilai sebelumnya.
+func UpdateNotificationPreferences(ctx *fiber.Ctx, db *gorm.DB) error {
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var req struct {
+		Preferences map[string]string `json:"preferences"`
+	}
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Format permintaan tidak valid"})
+	}
+
+	for event, delivery := range req.Preferences {
+		if _, ok := models.DefaultNotificationPreferences[event]; !ok {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Event notifikasi tidak dikenal: " + event})
+		}
+		if !models.IsNotificationDelivery(delivery) {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cara pengiriman tidak valid: " + delivery})
+		}
+	}
+
+	var accountConfig models.AccountConfig
+	if err := db.Where("user_id = ?", userID).First(&accountConfig).Error; err != nil {
+		if err != gorm.ErrRecordNotFound {
+			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to retrieve account config"})
+		}
+		accountConfig = models.AccountConfig{UserID: userID}
+	}
+
+	preferences := notificationPreferences(accountConfig)
+	for event, delivery := range req.Preferences {
+		preferences[event] = delivery
+	}
+
+	encoded, err := json.Marshal(preferences)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyimpan preferensi"})
+	}
+	accountConfig.NotificationPreferences = encoded
+
+	if err := db.Save(&accountConfig).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyimpan preferensi"})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"message":     "Preferensi notifikasi berhasil disimpan",
+		"preferences": preferences,
+	})
+}
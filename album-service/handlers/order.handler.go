@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"sort"
+
+	"github.com/Zackly23/queue-app/models"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type MediaOrderItem struct {
+	MediaID   uuid.UUID `json:"media_id"`
+	MediaType string    `json:"media_type,omitempty"` // kind media, hanya informasi di response
+}
+
+// albumMediaInOrder mengambil seluruh media aktif di album sesuai urutan manual.
+func albumMediaInOrder(db *gorm.DB, albumID uuid.UUID) ([]models.Media, error) {
+	var medias []models.Media
+	err := db.Where("album_id = ?", albumID).Order("position ASC, created_at ASC").Find(&medias).Error
+	return medias, err
+}
+
+// nextMediaPosition mengembalikan posisi setelah media terakhir di album (termasuk yang di trash).
+func nextMediaPosition(db *gorm.DB, albumID uuid.UUID) (int, error) {
+	var maxPosition int
+	err := db.Unscoped().Model(&models.Media{}).
+		Where("album_id = ?", albumID).
+		Select("COALESCE(MAX(position), 0)").
+		Scan(&maxPosition).Error
+	return maxPosition + 1, err
+}
+
+// albumCoverImage memilih gambar cover dari media aktif yang sudah terurut: cover pilihan pemilik
+// bila medianya tersedia dan tidak disembunyikan moderator, jika tidak media pertama yang terlihat.
+func albumCoverImage(coverMediaID *uuid.UUID, ordered []models.Media) string {
+	coverImage := ""
+	for _, media := range ordered {
+		if media.ModerationStatus != models.ModerationStatusVisible {
+			continue
+		}
+		if coverMediaID != nil && media.ID == *coverMediaID {
+			return media.CoverURL()
+		}
+		if coverImage == "" {
+			coverImage = media.CoverURL()
+		}
+	}
+	return coverImage
+}
+
+// refreshAlbumCover menghitung ulang cover_image album. cover_media_id pilihan pemilik tidak diubah,
+// sehingga cover kembali dipakai begitu medianya di-restore dari trash atau moderasi.
+func refreshAlbumCover(db *gorm.DB, albumID uuid.UUID) error {
+	var album models.Album
+	if err := db.First(&album, "id = ?", albumID).Error; err != nil {
+		return err
+	}
+
+	ordered, err := albumMediaInOrder(db, albumID)
+	if err != nil {
+		return err
+	}
+
+	return db.Model(&album).UpdateColumn("cover_image", albumCoverImage(album.CoverMediaID, ordered)).Error
+}
+
+func ReorderAlbumMedia(ctx *fiber.Ctx, db *gorm.DB) error {
+	album, _, err := findOwnedAlbum(ctx, db, "albumId")
+	if err != nil {
+		return fiberErrorResponse(ctx, err)
+	}
+
+	type reorderRequest struct {
+		Items []MediaOrderItem `json:"items"`
+	}
+
+	var req reorderRequest
+	if err := ctx.BodyParser(&req); err != nil || len(req.Items) == 0 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "items wajib diisi"})
+	}
+
+	medias, err := albumMediaInOrder(db, album.ID)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil media album"})
+	}
+
+	indexByID := map[uuid.UUID]int{}
+	for i, media := range medias {
+		indexByID[media.ID] = i
+	}
+
+	// Media yang dikirim menempati slot yang saat ini mereka pakai, sesuai urutan baru.
+	// Media yang tidak dikirim tetap di slotnya, sehingga urutan parsial juga bisa dipakai.
+	slots := []int{}
+	seen := map[uuid.UUID]bool{}
+	for _, item := range req.Items {
+		index, ok := indexByID[item.MediaID]
+		if !ok {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":    "Media tidak ditemukan di album",
+				"media_id": item.MediaID,
+			})
+		}
+		if seen[item.MediaID] {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":    "Media duplikat dalam urutan",
+				"media_id": item.MediaID,
+			})
+		}
+		seen[item.MediaID] = true
+		slots = append(slots, index)
+	}
+	sort.Ints(slots)
+
+	reordered := make([]models.Media, len(medias))
+	copy(reordered, medias)
+	for i, item := range req.Items {
+		reordered[slots[i]] = medias[indexByID[item.MediaID]]
+	}
+
+	errOrder := db.Transaction(func(tx *gorm.DB) error {
+		for i, media := range reordered {
+			position := i + 1
+			if media.Position == position {
+				continue
+			}
+
+			if err := tx.Model(&models.Media{}).Where("id = ?", media.ID).UpdateColumn("position", position).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if errOrder != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyimpan urutan media"})
+	}
+
+	// Cover otomatis mengikuti media pertama bila pemilik belum memilih cover
+	if err := refreshAlbumCover(db, album.ID); err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal update cover album"})
+	}
+
+	order := []MediaOrderItem{}
+	for _, media := range reordered {
+		order = append(order, MediaOrderItem{MediaID: media.ID, MediaType: media.Kind})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Urutan media berhasil disimpan",
+		"items":   order,
+	})
+}
+
+func SetAlbumCover(ctx *fiber.Ctx, db *gorm.DB) error {
+	album, _, err := findOwnedAlbum(ctx, db, "albumId")
+	if err != nil {
+		return fiberErrorResponse(ctx, err)
+	}
+
+	var req MediaOrderItem
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Format permintaan tidak valid"})
+	}
+
+	var coverMediaID *uuid.UUID
+	if req.MediaID != uuid.Nil {
+		coverMediaID = &req.MediaID
+	}
+
+	// media_id kosong mengembalikan cover otomatis
+	if coverMediaID != nil {
+		if err := db.Where("id = ? AND album_id = ?", req.MediaID, album.ID).First(&models.Media{}).Error; err != nil {
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Media tidak ditemukan di album"})
+		}
+	}
+
+	if err := db.Model(&album).UpdateColumn("cover_media_id", coverMediaID).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal update cover album"})
+	}
+
+	if err := refreshAlbumCover(db, album.ID); err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal update cover album"})
+	}
+
+	if err := db.First(&album, "id = ?", album.ID).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil album"})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":        "Cover album berhasil diupdate",
+		"cover_media_id": album.CoverMediaID,
+		"cover_image":    album.CoverImage,
+	})
+}
@@ -0,0 +1,388 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/Zackly23/queue-app/models"
+	"github.com/Zackly23/queue-app/payments"
+	notif "github.com/Zackly23/queue-app/proto/notificationpb"
+	"github.com/Zackly23/queue-app/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PaymentResponse struct {
+	ID                 uuid.UUID  `json:"id"`
+	UserSubscriptionID uuid.UUID  `json:"user_subscription_id"`
+	SubscriptionType   string     `json:"subscription_type"`
+	PlanVersion        int        `json:"plan_version"`
+	Provider           string     `json:"provider"`
+	OrderID            string     `json:"order_id"`
+	Method             string     `json:"method,omitempty"`
+	Amount             float64    `json:"amount"`
+	Currency           string     `json:"currency"`
+	Status             string     `json:"status"`
+	CheckoutURL        string     `json:"checkout_url,omitempty"`
+	ExpiresAt          *time.Time `json:"expires_at,omitempty"`
+	PaidAt             *time.Time `json:"paid_at,omitempty"`
+	RefundedAt         *time.Time `json:"refunded_at,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
+}
+
+func paymentResponse(payment models.Payment) PaymentResponse {
+	response := PaymentResponse{
+		ID:                 payment.ID,
+		UserSubscriptionID: payment.UserSubscriptionID,
+		SubscriptionType:   payment.UserSubscription.Subscription.SubscriptionType,
+		PlanVersion:        payment.UserSubscription.Subscription.Version,
+		Provider:           payment.Provider,
+		OrderID:            payment.OrderID,
+		Method:             payment.Method,
+		Amount:             payment.Amount,
+		Currency:           payment.Currency,
+		Status:             payment.Status,
+		ExpiresAt:          payment.ExpiresAt,
+		PaidAt:             payment.PaidAt,
+		RefundedAt:         payment.RefundedAt,
+		CreatedAt:          payment.CreatedAt,
+	}
+	// Link checkout hanya berguna selama pembayaran belum selesai
+	if payment.Status == models.PaymentStatusPending {
+		response.CheckoutURL = payment.CheckoutURL
+	}
+	return response
+}
+
+// paymentTransitionAllowed menentukan apakah pembayaran berstatus current boleh berpindah ke next.
+// Pembayaran hanya lunas sekali (notifikasi capture lalu settlement tidak diproses dua kali),
+// gagal/expired hanya dari pending, dan refund hanya dari paid.
+func paymentTransitionAllowed(current, next string) bool {
+	switch next {
+	case models.PaymentStatusPaid:
+		return current != models.PaymentStatusPaid && current != models.PaymentStatusRefunded
+	case models.PaymentStatusFailed, models.PaymentStatusExpired:
+		return current == models.PaymentStatusPending
+	case models.PaymentStatusRefunded:
+		return current == models.PaymentStatusPaid
+	}
+	// pending atau status yang tidak dikenal: hanya dicatat
+	return false
+}
+
+// applyPaymentEvent menerapkan perubahan status pembayaran beserta subscription-nya.
+// Transisi yang tidak berlaku dari status sekarang diabaikan, sehingga notifikasi yang
+// datang terlambat atau tidak berurutan tidak membatalkan pembayaran yang sudah selesai.
+func applyPaymentEvent(tx *gorm.DB, payment *models.Payment, event payments.Event, now time.Time) error {
+	if !paymentTransitionAllowed(payment.Status, event.Status) {
+		return nil
+	}
+
+	var record models.UserSubscription
+	if err := tx.First(&record, "id = ?", payment.UserSubscriptionID).Error; err != nil {
+		return err
+	}
+
+	updates := map[string]interface{}{"status": event.Status}
+	if event.ProviderReference != "" {
+		updates["provider_reference"] = event.ProviderReference
+	}
+
+	switch event.Status {
+	case models.PaymentStatusPaid:
+		if math.Abs(event.Amount-payment.Amount) >= 1 {
+			return fiber.NewError(fiber.StatusBadRequest, "Nominal pembayaran tidak sesuai tagihan")
+		}
+		updates["paid_at"] = now
+		if event.Method != "" {
+			updates["method"] = event.Method
+		}
+		if err := tx.Model(payment).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		if record.Status != models.UserSubscriptionPendingPayment {
+			// Checkout lama yang sudah digantikan tetap dibayar user, di-refund oleh refundSupersededPayment
+			log.Printf("Pembayaran %s diterima untuk subscription %s berstatus %s", payment.OrderID, record.ID, record.Status)
+			return nil
+		}
+		if event.Method != "" {
+			record.PaymentMethod = event.Method
+			payment.Method = event.Method
+			if err := tx.Model(&record).Update("payment_method", event.Method).Error; err != nil {
+				return err
+			}
+		}
+		if err := utils.ActivatePaidSubscription(tx, record, now); err != nil {
+			return err
+		}
+		_, err := utils.CreateInvoice(tx, *payment, now)
+		return err
+
+	case models.PaymentStatusFailed, models.PaymentStatusExpired:
+		if err := tx.Model(payment).Updates(updates).Error; err != nil {
+			return err
+		}
+		if record.Status != models.UserSubscriptionPendingPayment {
+			return nil
+		}
+		return tx.Model(&record).Update("status", models.UserSubscriptionCancelled).Error
+
+	case models.PaymentStatusRefunded:
+		delete(updates, "provider_reference") // referensi transaksi asal tetap disimpan
+		updates["refunded_at"] = now
+		if err := tx.Model(payment).Updates(updates).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Invoice{}).Where("payment_id = ?", payment.ID).Updates(map[string]interface{}{
+			"status":      models.InvoiceStatusRefunded,
+			"refunded_at": now,
+		}).Error; err != nil {
+			return err
+		}
+		if record.Status != models.UserSubscriptionActive {
+			return nil
+		}
+		if err := tx.Model(&record).Updates(map[string]interface{}{
+			"status":   models.UserSubscriptionRefunded,
+			"end_date": now,
+		}).Error; err != nil {
+			return err
+		}
+		return utils.RevertToDefaultPlan(tx, record.UserID, now)
+	}
+	return nil
+}
+
+// processPaymentEvent mencatat event lalu menerapkannya dalam satu transaksi. Event yang
+// sudah pernah dicatat dilewati, duplicate bernilai true.
+func processPaymentEvent(db *gorm.DB, provider string, event payments.Event) (duplicate bool, err error) {
+	err = db.Transaction(func(tx *gorm.DB) error {
+		paymentEvent := models.PaymentEvent{
+			Provider: provider,
+			EventID:  event.ID,
+			OrderID:  event.OrderID,
+			Status:   event.Status,
+			Payload:  event.Payload,
+		}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&paymentEvent)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			duplicate = true
+			return nil
+		}
+
+		var payment models.Payment
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&payment, "provider = ? AND order_id = ?", provider, event.OrderID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// mis. notifikasi uji dari dashboard provider, cukup dicatat
+			log.Printf("Notifikasi %s untuk order %s yang tidak dikenal", provider, event.OrderID)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Model(&paymentEvent).Update("payment_id", payment.ID).Error; err != nil {
+			return err
+		}
+		return applyPaymentEvent(tx, &payment, event, time.Now())
+	})
+	return duplicate, err
+}
+
+// HandlePaymentWebhook menerima notifikasi dari payment provider (/payments/webhook/:provider).
+// Respons non-2xx membuat provider mengirim ulang notifikasi. Pembayaran yang berhasil
+// menerbitkan invoice dan kuitansinya dikirim ke email user.
+func HandlePaymentWebhook(ctx *fiber.Ctx, db *gorm.DB, client notif.NotificationServiceClient) error {
+	gateway, err := payments.Get(ctx.Params("provider"))
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Payment provider tidak dikenal"})
+	}
+
+	event, err := gateway.ParseWebhook(http.Header(ctx.GetReqHeaders()), ctx.Body())
+	if errors.Is(err, payments.ErrInvalidSignature) {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	duplicate, err := processPaymentEvent(db, gateway.Name(), event)
+	if err != nil {
+		log.Printf("Gagal memproses notifikasi %s %s: %v", gateway.Name(), event.ID, err)
+		return fiberErrorResponse(ctx, err)
+	}
+
+	if !duplicate && event.Status == payments.StatusPaid {
+		go sendPaymentReceipt(db, client, gateway.Name(), event.OrderID)
+		go refundSupersededPayment(db, gateway.Name(), event.OrderID)
+	}
+
+	return ctx.JSON(fiber.Map{"message": "OK", "duplicate": duplicate})
+}
+
+// GetPayments mengambil riwayat pembayaran user (terbaru dulu, cursor pagination).
+func GetPayments(ctx *fiber.Ctx, db *gorm.DB) error {
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	limit := utils.ParseLimit(ctx.Query("limit"), 20, 100)
+	query := db.Preload("UserSubscription.Subscription", func(tx *gorm.DB) *gorm.DB {
+		return tx.Unscoped()
+	}).Where("user_id = ?", userID)
+
+	if status := ctx.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if cursor := ctx.Query("cursor"); cursor != "" {
+		createdAt, id, err := utils.DecodeCursor(cursor)
+		if err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		query = query.Where("(created_at, id) < (?, ?)", createdAt, id)
+	}
+
+	var records []models.Payment
+	if err := query.Order("created_at DESC, id DESC").Limit(limit + 1).Find(&records).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil riwayat pembayaran"})
+	}
+
+	nextCursor := ""
+	if len(records) > limit {
+		records = records[:limit]
+		last := records[len(records)-1]
+		nextCursor = utils.EncodeCursor(last.CreatedAt, last.ID)
+	}
+
+	response := []PaymentResponse{}
+	for _, payment := range records {
+		response = append(response, paymentResponse(payment))
+	}
+
+	return ctx.JSON(fiber.Map{
+		"payments":    response,
+		"next_cursor": nextCursor,
+		"has_more":    nextCursor != "",
+	})
+}
+
+// refundPayment mengembalikan seluruh pembayaran lewat provider. Refund yang langsung selesai
+// diterapkan seperti notifikasi refund, status StatusPending berarti menunggu notifikasi provider.
+func refundPayment(ctx context.Context, db *gorm.DB, payment models.Payment, reason string) (string, error) {
+	gateway, err := payments.Get(payment.Provider)
+	if err != nil {
+		return "", err
+	}
+
+	refund, err := gateway.Refund(ctx, payments.RefundRequest{
+		OrderID:           payment.OrderID,
+		ProviderReference: payment.ProviderReference,
+		Amount:            payment.Amount,
+		Reason:            reason,
+	})
+	if err != nil {
+		log.Printf("Refund %s gagal: %v", payment.OrderID, err)
+		return "", fiber.NewError(fiber.StatusBadGateway, "Provider menolak refund")
+	}
+
+	if err := db.Model(&payment).Update("refund_reason", reason).Error; err != nil {
+		return "", fiber.NewError(fiber.StatusInternalServerError, "Gagal menyimpan refund")
+	}
+
+	if refund.Status != payments.StatusRefunded {
+		return refund.Status, nil
+	}
+
+	// Notifikasi refund dari provider nanti tidak mengubah apa pun karena pembayaran sudah refunded
+	if _, err := processPaymentEvent(db, payment.Provider, payments.Event{
+		ID:                "refund:" + payment.OrderID,
+		OrderID:           payment.OrderID,
+		ProviderReference: refund.ProviderReference,
+		Status:            payments.StatusRefunded,
+		Amount:            payment.Amount,
+	}); err != nil {
+		return "", fiber.NewError(fiber.StatusInternalServerError, "Refund berhasil di provider tetapi gagal disimpan")
+	}
+	return refund.Status, nil
+}
+
+// refundSupersededPayment me-refund pembayaran yang masuk untuk checkout yang sudah digantikan
+// atau dibatalkan, karena subscription-nya tidak akan pernah diaktifkan.
+func refundSupersededPayment(db *gorm.DB, provider, orderID string) {
+	var payment models.Payment
+	if err := db.Preload("UserSubscription").
+		First(&payment, "provider = ? AND order_id = ?", provider, orderID).Error; err != nil {
+		return
+	}
+	if payment.Status != models.PaymentStatusPaid || payment.UserSubscription.Status != models.UserSubscriptionCancelled {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if _, err := refundPayment(ctx, db, payment, "Checkout sudah digantikan permintaan lain"); err != nil {
+		log.Printf("Gagal refund otomatis %s, perlu refund manual: %v", payment.OrderID, err)
+	}
+}
+
+// RefundPayment (admin) mengembalikan seluruh pembayaran lewat provider. Subscription yang
+// dibayar diakhiri dan user kembali ke plan default.
+func RefundPayment(ctx *fiber.Ctx, db *gorm.DB) error {
+	paymentID, err := uuid.Parse(ctx.Params("paymentId"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Payment ID tidak valid"})
+	}
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	var payment models.Payment
+	if err := db.First(&payment, "id = ?", paymentID).Error; err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Pembayaran tidak ditemukan"})
+	}
+	if payment.Status != models.PaymentStatusPaid {
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Hanya pembayaran yang sudah lunas yang bisa di-refund"})
+	}
+
+	status, err := refundPayment(ctx.UserContext(), db, payment, req.Reason)
+	if errors.Is(err, payments.ErrUnknownProvider) {
+		return ctx.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Payment provider tidak tersedia"})
+	}
+	if err != nil {
+		return fiberErrorResponse(ctx, err)
+	}
+
+	if status != payments.StatusRefunded {
+		return ctx.Status(fiber.StatusAccepted).JSON(fiber.Map{
+			"message": "Refund diproses provider, status diperbarui saat notifikasi diterima",
+		})
+	}
+
+	if err := db.Preload("UserSubscription.Subscription", func(tx *gorm.DB) *gorm.DB {
+		return tx.Unscoped()
+	}).First(&payment, "id = ?", payment.ID).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil pembayaran"})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"message": "Pembayaran di-refund",
+		"payment": paymentResponse(payment),
+	})
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Zackly23/queue-app/models"
+	"github.com/Zackly23/queue-app/payments"
+)
+
+// signedEvent membuat notifikasi bertanda tangan lewat provider fake lalu memverifikasinya
+// seperti yang dilakukan HandlePaymentWebhook.
+func signedEvent(t *testing.T, gateway *payments.Fake, orderID, status string, amount float64) (payments.Event, []byte) {
+	t.Helper()
+	header, body, err := gateway.SignedWebhook(orderID, status, amount)
+	if err != nil {
+		t.Fatalf("SignedWebhook: %v", err)
+	}
+	event, err := gateway.ParseWebhook(header, body)
+	if err != nil {
+		t.Fatalf("ParseWebhook: %v", err)
+	}
+	return event, body
+}
+
+// replayPaymentEvents menjalankan urutan notifikasi seperti processPaymentEvent: event dengan
+// ID yang sudah tercatat dilewati (unique index payment_events), sisanya diterapkan bila
+// transisinya berlaku. Mengembalikan status akhir dan jumlah event yang mengubah pembayaran.
+func replayPaymentEvents(status string, events []payments.Event) (string, int) {
+	seen := map[string]bool{}
+	applied := 0
+	for _, event := range events {
+		if seen[event.ID] {
+			continue
+		}
+		seen[event.ID] = true
+
+		if paymentTransitionAllowed(status, event.Status) {
+			status = event.Status
+			applied++
+		}
+	}
+	return status, applied
+}
+
+func TestPaymentWebhookSignature(t *testing.T) {
+	gateway := payments.NewFake("rahasia", "")
+	header, body, err := gateway.SignedWebhook("SUB-1", payments.StatusPaid, 50000)
+	if err != nil {
+		t.Fatalf("SignedWebhook: %v", err)
+	}
+
+	// Notifikasi yang dikirim ulang provider membawa event ID yang sama
+	first, err := gateway.ParseWebhook(header, body)
+	if err != nil {
+		t.Fatalf("ParseWebhook: %v", err)
+	}
+	again, err := gateway.ParseWebhook(header, body)
+	if err != nil {
+		t.Fatalf("ParseWebhook ulang: %v", err)
+	}
+	if first.ID != again.ID {
+		t.Errorf("event ID kiriman ulang = %s, want %s", again.ID, first.ID)
+	}
+	if first.OrderID != "SUB-1" || first.Status != payments.StatusPaid || first.Amount != 50000 {
+		t.Errorf("event = %+v", first)
+	}
+
+	tampered := append([]byte{}, body...)
+	tampered[len(tampered)-2] ^= 1
+	if _, err := gateway.ParseWebhook(header, tampered); !errors.Is(err, payments.ErrInvalidSignature) {
+		t.Errorf("body diubah: err = %v, want ErrInvalidSignature", err)
+	}
+
+	other := payments.NewFake("kunci-lain", "")
+	if _, err := other.ParseWebhook(header, body); !errors.Is(err, payments.ErrInvalidSignature) {
+		t.Errorf("secret berbeda: err = %v, want ErrInvalidSignature", err)
+	}
+}
+
+func TestPaymentWebhookSequences(t *testing.T) {
+	gateway := payments.NewFake("rahasia", "")
+	event := func(status string) payments.Event {
+		e, _ := signedEvent(t, gateway, "SUB-1", status, 50000)
+		return e
+	}
+
+	paid := event(payments.StatusPaid)
+	tests := []struct {
+		name    string
+		events  []payments.Event
+		status  string
+		applied int
+	}{
+		{
+			name:    "paid",
+			events:  []payments.Event{event(payments.StatusPending), paid},
+			status:  models.PaymentStatusPaid,
+			applied: 1,
+		},
+		{
+			name:    "duplicate delivery",
+			events:  []payments.Event{paid, paid, paid},
+			status:  models.PaymentStatusPaid,
+			applied: 1,
+		},
+		{
+			name:    "capture then settlement",
+			events:  []payments.Event{event(payments.StatusPaid), event(payments.StatusPaid)},
+			status:  models.PaymentStatusPaid,
+			applied: 1,
+		},
+		{
+			name:    "pending after paid",
+			events:  []payments.Event{paid, event(payments.StatusPending)},
+			status:  models.PaymentStatusPaid,
+			applied: 1,
+		},
+		{
+			name:    "expired after paid",
+			events:  []payments.Event{paid, event(payments.StatusExpired)},
+			status:  models.PaymentStatusPaid,
+			applied: 1,
+		},
+		{
+			name:    "failed after paid",
+			events:  []payments.Event{paid, event(payments.StatusFailed)},
+			status:  models.PaymentStatusPaid,
+			applied: 1,
+		},
+		{
+			name:    "paid after expired",
+			events:  []payments.Event{event(payments.StatusExpired), paid},
+			status:  models.PaymentStatusPaid,
+			applied: 2,
+		},
+		{
+			name:    "expired then failed",
+			events:  []payments.Event{event(payments.StatusExpired), event(payments.StatusFailed)},
+			status:  models.PaymentStatusExpired,
+			applied: 1,
+		},
+		{
+			name:    "refund",
+			events:  []payments.Event{paid, event(payments.StatusRefunded)},
+			status:  models.PaymentStatusRefunded,
+			applied: 2,
+		},
+		{
+			name:    "refund before payment",
+			events:  []payments.Event{event(payments.StatusRefunded), event(payments.StatusPending)},
+			status:  models.PaymentStatusPending,
+			applied: 0,
+		},
+		{
+			name:    "settlement after refund",
+			events:  []payments.Event{paid, event(payments.StatusRefunded), event(payments.StatusPaid)},
+			status:  models.PaymentStatusRefunded,
+			applied: 2,
+		},
+		{
+			name:    "unknown status",
+			events:  []payments.Event{event("partial_refund")},
+			status:  models.PaymentStatusPending,
+			applied: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, applied := replayPaymentEvents(models.PaymentStatusPending, tt.events)
+			if status != tt.status {
+				t.Errorf("status akhir = %s, want %s", status, tt.status)
+			}
+			if applied != tt.applied {
+				t.Errorf("event diterapkan = %d, want %d", applied, tt.applied)
+			}
+		})
+	}
+}
@@ -0,0 +1,394 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Zackly23/queue-app/models"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	planCodePattern     = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,49}$`)
+	planCurrencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+)
+
+type PlanResponse struct {
+	ID               uuid.UUID `json:"id"`
+	Code             string    `json:"code"`
+	Version          int       `json:"version"`
+	Name             string    `json:"name"`
+	Description      string    `json:"description,omitempty"`
+	Price            float64   `json:"price"`
+	Currency         string    `json:"currency"`
+	BillingInterval  string    `json:"billing_interval"`
+	StorageCapacity  float64   `json:"storage_capacity"`   // GB
+	MaximumMediaSize float64   `json:"maximum_media_size"` // GB
+	Features         []string  `json:"features"`
+	SortOrder        int       `json:"sort_order"`
+}
+
+type AdminPlanResponse struct {
+	PlanResponse
+	ExtraFeatures   json.RawMessage `json:"extra_features"` // isi Features tanpa batas storage dan media
+	IsActive        bool            `json:"is_active"`
+	IsPublic        bool            `json:"is_public"`
+	SubscriberCount int64           `json:"subscriber_count"` // user yang saat ini memakai versi ini
+	CreatedAt       time.Time       `json:"created_at"`
+}
+
+// PlanRequest dipakai untuk membuat dan mengubah plan, field yang tidak dikirim tidak diubah.
+type PlanRequest struct {
+	Code             string    `json:"code"`
+	Name             *string   `json:"name"`
+	Description      *string   `json:"description"`
+	Price            *float64  `json:"price"`
+	Currency         *string   `json:"currency"`
+	BillingInterval  *string   `json:"billing_interval"`
+	StorageCapacity  *float64  `json:"storage_capacity"`
+	MaximumMediaSize *float64  `json:"maximum_media_size"`
+	Features         *[]string `json:"features"`
+	IsPublic         *bool     `json:"is_public"`
+	SortOrder        *int      `json:"sort_order"`
+}
+
+func planResponse(plan models.Subscription) PlanResponse {
+	return PlanResponse{
+		ID:               plan.ID,
+		Code:             plan.Code,
+		Version:          plan.Version,
+		Name:             plan.SubscriptionType,
+		Description:      plan.Description,
+		Price:            plan.Price,
+		Currency:         plan.Currency,
+		BillingInterval:  plan.BillingInterval,
+		StorageCapacity:  plan.StorageCapacity,
+		MaximumMediaSize: plan.MaximumMediaSize,
+		Features:         plan.FeatureList(),
+		SortOrder:        plan.SortOrder,
+	}
+}
+
+// applyPlanRequest menerapkan field yang dikirim ke plan dan mengembalikan true bila ketentuan
+// plan (harga, interval, batas storage/media atau fitur) berubah.
+func applyPlanRequest(plan *models.Subscription, req PlanRequest) (bool, error) {
+	before := *plan
+
+	if req.Name != nil {
+		plan.SubscriptionType = strings.TrimSpace(*req.Name)
+	}
+	if req.Description != nil {
+		plan.Description = strings.TrimSpace(*req.Description)
+	}
+	if req.Price != nil {
+		plan.Price = *req.Price
+	}
+	if req.Currency != nil {
+		plan.Currency = strings.ToUpper(strings.TrimSpace(*req.Currency))
+	}
+	if req.BillingInterval != nil {
+		plan.BillingInterval = *req.BillingInterval
+	}
+	if req.StorageCapacity != nil {
+		plan.StorageCapacity = *req.StorageCapacity
+	}
+	if req.MaximumMediaSize != nil {
+		plan.MaximumMediaSize = *req.MaximumMediaSize
+	}
+	if req.IsPublic != nil {
+		plan.IsPublic = *req.IsPublic
+	}
+	if req.SortOrder != nil {
+		plan.SortOrder = *req.SortOrder
+	}
+
+	featuresChanged := false
+	if req.Features != nil {
+		features := []string{}
+		for _, feature := range *req.Features {
+			if feature = strings.TrimSpace(feature); feature != "" {
+				features = append(features, feature)
+			}
+		}
+		marshaled, err := json.Marshal(features)
+		if err != nil {
+			return false, err
+		}
+		featuresChanged = string(marshaled) != string(before.Features)
+		plan.Features = marshaled
+	}
+
+	if err := validatePlan(*plan); err != nil {
+		return false, err
+	}
+
+	termsChanged := featuresChanged ||
+		plan.Price != before.Price ||
+		plan.Currency != before.Currency ||
+		plan.BillingInterval != before.BillingInterval ||
+		plan.StorageCapacity != before.StorageCapacity ||
+		plan.MaximumMediaSize != before.MaximumMediaSize
+	return termsChanged, nil
+}
+
+func validatePlan(plan models.Subscription) error {
+	switch {
+	case plan.SubscriptionType == "" || len(plan.SubscriptionType) > 50:
+		return fiber.NewError(fiber.StatusBadRequest, "Nama plan wajib diisi (maksimal 50 karakter)")
+	case plan.Price < 0:
+		return fiber.NewError(fiber.StatusBadRequest, "Harga tidak boleh negatif")
+	case !planCurrencyPattern.MatchString(plan.Currency):
+		return fiber.NewError(fiber.StatusBadRequest, "Currency harus kode 3 huruf, misalnya IDR")
+	case plan.BillingInterval != models.BillingIntervalMonth && plan.BillingInterval != models.BillingIntervalYear:
+		return fiber.NewError(fiber.StatusBadRequest, "billing_interval harus month atau year")
+	case plan.StorageCapacity <= 0:
+		return fiber.NewError(fiber.StatusBadRequest, "storage_capacity harus lebih dari 0")
+	case plan.MaximumMediaSize <= 0 || plan.MaximumMediaSize > plan.StorageCapacity:
+		return fiber.NewError(fiber.StatusBadRequest, "maximum_media_size harus lebih dari 0 dan tidak melebihi storage_capacity")
+	}
+	return nil
+}
+
+// activePlan mengambil versi plan yang sedang ditawarkan berdasarkan kode plan.
+func activePlan(db *gorm.DB, code string) (models.Subscription, error) {
+	var plan models.Subscription
+	err := db.Where("code = ? AND is_active = ?", code, true).Order("version DESC").First(&plan).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return plan, fiber.NewError(fiber.StatusNotFound, "Plan tidak ditemukan")
+	}
+	return plan, err
+}
+
+// createPlanVersion menyimpan versi plan baru. is_public di-update terpisah karena nilai false
+// diabaikan saat create (default true).
+func createPlanVersion(tx *gorm.DB, plan *models.Subscription) error {
+	if err := tx.Create(plan).Error; err != nil {
+		return err
+	}
+	if !plan.IsPublic {
+		return tx.Model(plan).Update("is_public", false).Error
+	}
+	return nil
+}
+
+// GetPlans menampilkan katalog plan yang sedang ditawarkan (?billing_interval= opsional), tanpa login.
+func GetPlans(ctx *fiber.Ctx, db *gorm.DB) error {
+	query := db.Where("is_active = ? AND is_public = ?", true, true)
+	if interval := ctx.Query("billing_interval"); interval != "" {
+		query = query.Where("billing_interval = ?", interval)
+	}
+
+	var plans []models.Subscription
+	if err := query.Order("sort_order ASC, price ASC").Find(&plans).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil daftar plan"})
+	}
+
+	responses := []PlanResponse{}
+	for _, plan := range plans {
+		responses = append(responses, planResponse(plan))
+	}
+
+	return ctx.JSON(fiber.Map{
+		"message": "Daftar plan berhasil diambil",
+		"plans":   responses,
+	})
+}
+
+// GetPlan menampilkan versi terbaru satu plan berdasarkan kode, tanpa login.
+func GetPlan(ctx *fiber.Ctx, db *gorm.DB) error {
+	plan, err := activePlan(db, ctx.Params("code"))
+	if err != nil {
+		return fiberErrorResponse(ctx, err)
+	}
+	if !plan.IsPublic {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Plan tidak ditemukan"})
+	}
+
+	return ctx.JSON(fiber.Map{"plan": planResponse(plan)})
+}
+
+// GetAdminPlans menampilkan semua versi plan (?code= untuk satu plan) beserta jumlah pelanggannya.
+func GetAdminPlans(ctx *fiber.Ctx, db *gorm.DB) error {
+	query := db.Model(&models.Subscription{})
+	if code := ctx.Query("code"); code != "" {
+		query = query.Where("code = ?", code)
+	}
+
+	var plans []models.Subscription
+	if err := query.Order("sort_order ASC, code ASC, version DESC").Find(&plans).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil daftar plan"})
+	}
+
+	var counts []struct {
+		SubscriptionID uuid.UUID
+		Total          int64
+	}
+	if err := db.Model(&models.User{}).
+		Select("subscription_id, COUNT(*) AS total").
+		Group("subscription_id").
+		Scan(&counts).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menghitung pelanggan plan"})
+	}
+	subscribers := map[uuid.UUID]int64{}
+	for _, count := range counts {
+		subscribers[count.SubscriptionID] = count.Total
+	}
+
+	responses := []AdminPlanResponse{}
+	for _, plan := range plans {
+		responses = append(responses, AdminPlanResponse{
+			PlanResponse:    planResponse(plan),
+			ExtraFeatures:   plan.Features,
+			IsActive:        plan.IsActive,
+			IsPublic:        plan.IsPublic,
+			SubscriberCount: subscribers[plan.ID],
+			CreatedAt:       plan.CreatedAt,
+		})
+	}
+
+	return ctx.JSON(fiber.Map{"plans": responses})
+}
+
+// CreatePlan membuat plan baru (versi 1).
+func CreatePlan(ctx *fiber.Ctx, db *gorm.DB) error {
+	var req PlanRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Format permintaan tidak valid"})
+	}
+
+	req.Code = strings.ToLower(strings.TrimSpace(req.Code))
+	if !planCodePattern.MatchString(req.Code) {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Kode plan hanya boleh huruf kecil, angka dan -"})
+	}
+
+	var existing int64
+	if err := db.Unscoped().Model(&models.Subscription{}).Where("code = ?", req.Code).Count(&existing).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memeriksa kode plan"})
+	}
+	if existing > 0 {
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Kode plan sudah dipakai"})
+	}
+
+	plan := models.Subscription{
+		ID:              uuid.New(),
+		Code:            req.Code,
+		Version:         1,
+		Currency:        "IDR",
+		BillingInterval: models.BillingIntervalMonth,
+		Features:        json.RawMessage(`[]`),
+		IsActive:        true,
+		IsPublic:        true,
+	}
+	if _, err := applyPlanRequest(&plan, req); err != nil {
+		return fiberErrorResponse(ctx, err)
+	}
+
+	if err := createPlanVersion(db, &plan); err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyimpan plan"})
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Plan berhasil dibuat",
+		"plan":    planResponse(plan),
+	})
+}
+
+// planCommitments menghitung user yang terikat pada ketentuan versi plan: yang sedang memakainya,
+// serta yang sudah menyetujui penawarannya (downgrade terjadwal atau menunggu pembayaran).
+func planCommitments(db *gorm.DB, planID uuid.UUID) (int64, error) {
+	var users int64
+	if err := db.Model(&models.User{}).Where("subscription_id = ?", planID).Count(&users).Error; err != nil {
+		return 0, err
+	}
+
+	var pending int64
+	err := db.Model(&models.UserSubscription{}).
+		Where("subscription_id = ? AND status IN ?", planID, []string{
+			models.UserSubscriptionActive,
+			models.UserSubscriptionScheduled,
+			models.UserSubscriptionPendingPayment,
+		}).
+		Count(&pending).Error
+	return users + pending, err
+}
+
+// UpdatePlan mengubah plan berdasarkan kode. Bila ketentuan plan berubah dan versi saat ini sudah
+// punya pelanggan, dibuat versi baru dan versi lama berhenti ditawarkan, sehingga pelanggan lama
+// tetap dengan ketentuan lamanya. Perubahan lain (nama, deskripsi, urutan) langsung diterapkan.
+func UpdatePlan(ctx *fiber.Ctx, db *gorm.DB) error {
+	var req PlanRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Format permintaan tidak valid"})
+	}
+
+	current, err := activePlan(db, ctx.Params("code"))
+	if err != nil {
+		return fiberErrorResponse(ctx, err)
+	}
+
+	plan := current
+	termsChanged, err := applyPlanRequest(&plan, req)
+	if err != nil {
+		return fiberErrorResponse(ctx, err)
+	}
+
+	subscriberCount, err := planCommitments(db, current.ID)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menghitung pelanggan plan"})
+	}
+
+	newVersion := termsChanged && subscriberCount > 0
+	errTx := db.Transaction(func(tx *gorm.DB) error {
+		if !newVersion {
+			return tx.Save(&plan).Error
+		}
+
+		if err := tx.Model(&current).Update("is_active", false).Error; err != nil {
+			return err
+		}
+
+		plan.ID = uuid.New()
+		plan.Version = current.Version + 1
+		plan.CreatedAt = time.Time{}
+		plan.UpdatedAt = time.Time{}
+		return createPlanVersion(tx, &plan)
+	})
+	if errTx != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyimpan plan"})
+	}
+
+	message := "Plan berhasil diubah"
+	if newVersion {
+		message = "Versi baru plan dibuat, pelanggan lama tetap memakai versi sebelumnya"
+	}
+
+	return ctx.JSON(fiber.Map{
+		"message":     message,
+		"new_version": newVersion,
+		"plan":        planResponse(plan),
+	})
+}
+
+// RetirePlan menghentikan penawaran plan. Pelanggan yang sudah ada tetap memakai versinya.
+func RetirePlan(ctx *fiber.Ctx, db *gorm.DB) error {
+	code := ctx.Params("code")
+	if code == models.DefaultPlanCode {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Plan default untuk user baru tidak bisa dihentikan"})
+	}
+
+	plan, err := activePlan(db, code)
+	if err != nil {
+		return fiberErrorResponse(ctx, err)
+	}
+
+	if err := db.Model(&plan).Update("is_active", false).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menghentikan plan"})
+	}
+
+	return ctx.JSON(fiber.Map{"message": "Plan tidak lagi ditawarkan"})
+}
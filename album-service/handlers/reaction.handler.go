@@ -0,0 +1,413 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Zackly23/queue-app/events"
+	"github.com/Zackly23/queue-app/models"
+	"github.com/Zackly23/queue-app/realtime"
+	"github.com/Zackly23/queue-app/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// reactionTarget menjelaskan album atau media yang diberi reaksi.
+type reactionTarget struct {
+	ID            uuid.UUID
+	Table         string // tabel yang menyimpan counter: albums / media
+	ReactionTable string // album_reactions / media_reactions
+	Column        string // album_id / media_id
+	Album         models.Album
+}
+
+func albumReactionTarget(album models.Album) reactionTarget {
+	return reactionTarget{ID: album.ID, Table: "albums", ReactionTable: "album_reactions", Column: "album_id", Album: album}
+}
+
+func mediaReactionTarget(media models.Media) reactionTarget {
+	return reactionTarget{ID: media.ID, Table: "media", ReactionTable: "media_reactions", Column: "media_id", Album: media.Album}
+}
+
+func (t reactionTarget) newReaction(userID uuid.UUID, reactionType string) interface{} {
+	if t.Table == "media" {
+		return &models.MediaReaction{MediaID: t.ID, UserID: userID, Type: reactionType}
+	}
+	return &models.AlbumReaction{AlbumID: t.ID, UserID: userID, Type: reactionType}
+}
+
+type ReactionUser struct {
+	UserID     uuid.UUID `json:"user_id"`
+	User       string    `json:"user"`
+	UserAvatar string    `json:"user_avatar"`
+	Type       string    `json:"type"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// userReaction mengambil jenis reaksi user pada target, kosong bila belum bereaksi.
+func userReaction(db *gorm.DB, target reactionTarget, userID uuid.UUID) (string, error) {
+	var types []string
+	err := db.Table(target.ReactionTable).
+		Where(target.Column+" = ? AND user_id = ?", target.ID, userID).
+		Pluck("type", &types).Error
+	if err != nil || len(types) == 0 {
+		return "", err
+	}
+	return types[0], nil
+}
+
+// adjustReactionCount menambah/mengurangi counter total dan per jenis dalam satu statement
+// sehingga aman dari update bersamaan.
+func adjustReactionCount(tx *gorm.DB, target reactionTarget, reactionType string, delta int) error {
+	return tx.Exec(`UPDATE `+target.Table+` SET
+		likes_count = GREATEST(likes_count + ?, 0),
+		reaction_counts = jsonb_set(
+			COALESCE(reaction_counts, '{}'::jsonb),
+			ARRAY[?::text],
+			to_jsonb(GREATEST(COALESCE((reaction_counts->>?)::int, 0) + ?, 0))
+		)
+		WHERE id = ?`, delta, reactionType, reactionType, delta, target.ID).Error
+}
+
+// setReaction menyimpan reaksi user dan mengembalikan reaksi sebelumnya (kosong bila belum ada).
+func setReaction(db *gorm.DB, target reactionTarget, userID uuid.UUID, reactionType string) (string, error) {
+	var previous string
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Dua percobaan: bila insert kalah cepat dari request lain, reaksi yang sudah ada di-update
+		for attempt := 0; attempt < 2; attempt++ {
+			var types []string
+			if err := tx.Table(target.ReactionTable).
+				Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where(target.Column+" = ? AND user_id = ?", target.ID, userID).
+				Pluck("type", &types).Error; err != nil {
+				return err
+			}
+
+			if len(types) > 0 {
+				previous = types[0]
+				if previous == reactionType {
+					return nil
+				}
+
+				if err := tx.Table(target.ReactionTable).
+					Where(target.Column+" = ? AND user_id = ?", target.ID, userID).
+					Updates(map[string]interface{}{"type": reactionType, "updated_at": time.Now()}).Error; err != nil {
+					return err
+				}
+				if err := adjustReactionCount(tx, target, previous, -1); err != nil {
+					return err
+				}
+				return adjustReactionCount(tx, target, reactionType, 1)
+			}
+
+			result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(target.newReaction(userID, reactionType))
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected > 0 {
+				return adjustReactionCount(tx, target, reactionType, 1)
+			}
+		}
+
+		return errors.New("reaksi sedang diproses, coba lagi")
+	})
+
+	return previous, err
+}
+
+// removeReaction menghapus reaksi user dan mengembalikan jenis reaksi yang dihapus.
+func removeReaction(db *gorm.DB, target reactionTarget, userID uuid.UUID) (string, error) {
+	var removed string
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var types []string
+		if err := tx.Raw(`DELETE FROM `+target.ReactionTable+` WHERE `+target.Column+` = ? AND user_id = ? RETURNING type`,
+			target.ID, userID).Scan(&types).Error; err != nil {
+			return err
+		}
+		if len(types) == 0 {
+			return nil
+		}
+
+		removed = types[0]
+		return adjustReactionCount(tx, target, removed, -1)
+	})
+
+	return removed, err
+}
+
+// reactionSummary mengambil counter terbaru target.
+func reactionSummary(db *gorm.DB, target reactionTarget) (fiber.Map, error) {
+	var summary struct {
+		LikesCount     uint
+		ReactionCounts json.RawMessage
+	}
+	if err := db.Table(target.Table).Select("likes_count, reaction_counts").
+		Where("id = ?", target.ID).Scan(&summary).Error; err != nil {
+		return nil, err
+	}
+
+	counts := map[string]int{}
+	if len(summary.ReactionCounts) > 0 {
+		if err := json.Unmarshal(summary.ReactionCounts, &counts); err != nil {
+			return nil, err
+		}
+	}
+
+	return fiber.Map{
+		"likes_count":     summary.LikesCount,
+		"reaction_counts": counts,
+	}, nil
+}
+
+// findReactionTarget mengambil album (route :albumId) atau media (route :mediaId) yang bisa dilihat user.
+func findReactionTarget(ctx *fiber.Ctx, db *gorm.DB, userID uuid.UUID) (reactionTarget, error) {
+	return loadReactionTarget(db, ctx.Params("mediaId"), ctx.Params("albumId"), userID)
+}
+
+// loadReactionTarget mengambil media bila mediaID diisi, jika tidak album, lalu memastikan user
+// boleh melihatnya. Media yang disembunyikan moderator hanya bisa direaksi pemilik album.
+func loadReactionTarget(db *gorm.DB, mediaID string, albumID string, userID uuid.UUID) (reactionTarget, error) {
+	var target reactionTarget
+
+	if mediaID != "" {
+		var media models.Media
+		if err := db.Preload("Album").First(&media, "id = ?", mediaID).Error; err != nil {
+			return target, fiber.NewError(fiber.StatusNotFound, "Media tidak ditemukan")
+		}
+		if media.ModerationStatus != models.ModerationStatusVisible && media.Album.UserID != userID {
+			return target, fiber.NewError(fiber.StatusNotFound, "Media tidak ditemukan")
+		}
+		target = mediaReactionTarget(media)
+	} else {
+		var album models.Album
+		if err := db.First(&album, "id = ?", albumID).Error; err != nil {
+			return target, fiber.NewError(fiber.StatusNotFound, "Album tidak ditemukan")
+		}
+		target = albumReactionTarget(album)
+	}
+
+	allowed, err := canViewAlbum(db, target.Album, userID)
+	if err != nil {
+		return target, fiber.NewError(fiber.StatusInternalServerError, "Gagal memeriksa akses album")
+	}
+	if !allowed {
+		return target, fiber.NewError(fiber.StatusForbidden, "User tidak memiliki akses ke album ini")
+	}
+
+	return target, nil
+}
+
+// publishReactionEvent memberi tahu pemilik album saat ada reaksi baru (bukan ganti jenis reaksi).
+func publishReactionEvent(target reactionTarget, userID uuid.UUID, reactionType string) {
+	event := events.Event{
+		Type:        models.NotificationEventReaction,
+		RecipientID: target.Album.UserID,
+		ActorID:     userID,
+		AlbumID:     &target.Album.ID,
+		Data:        map[string]string{"reaction": reactionType},
+	}
+	if target.Table == "media" {
+		mediaID := target.ID
+		event.MediaID = &mediaID
+	}
+	events.Publish(event)
+}
+
+// broadcastReactionSummary mengirim counter terbaru ke semua client yang membuka album.
+func broadcastReactionSummary(target reactionTarget, summary fiber.Map) {
+	data := fiber.Map{
+		"target":          strings.TrimSuffix(target.Column, "_id"),
+		"target_id":       target.ID,
+		"likes_count":     summary["likes_count"],
+		"reaction_counts": summary["reaction_counts"],
+	}
+	realtime.Broadcast(realtime.AlbumChannel(target.Album.ID), realtime.MessageReactionUpdated, data)
+}
+
+// SetReaction memberi atau mengganti reaksi user pada album/media.
+func SetReaction(ctx *fiber.Ctx, db *gorm.DB) error {
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var req struct {
+		Type string `json:"type"`
+	}
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Format permintaan tidak valid"})
+	}
+	if !models.IsReactionType(req.Type) {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":          "Jenis reaksi tidak valid",
+			"reaction_types": models.ReactionTypes,
+		})
+	}
+
+	target, err := findReactionTarget(ctx, db, userID)
+	if err != nil {
+		return fiberErrorResponse(ctx, err)
+	}
+
+	previous, err := setReaction(db, target, userID, req.Type)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyimpan reaksi"})
+	}
+	if previous == "" {
+		publishReactionEvent(target, userID, req.Type)
+	}
+
+	summary, err := reactionSummary(db, target)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil jumlah reaksi"})
+	}
+
+	broadcastReactionSummary(target, summary)
+
+	summary["message"] = "Reaksi berhasil disimpan"
+	summary["reaction"] = req.Type
+	summary["previous_reaction"] = previous
+	return ctx.JSON(summary)
+}
+
+// DeleteReaction menghapus reaksi user pada album/media.
+func DeleteReaction(ctx *fiber.Ctx, db *gorm.DB) error {
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	target, err := findReactionTarget(ctx, db, userID)
+	if err != nil {
+		return fiberErrorResponse(ctx, err)
+	}
+
+	removed, err := removeReaction(db, target, userID)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menghapus reaksi"})
+	}
+
+	summary, err := reactionSummary(db, target)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil jumlah reaksi"})
+	}
+
+	broadcastReactionSummary(target, summary)
+
+	summary["message"] = "Reaksi berhasil dihapus"
+	summary["removed_reaction"] = removed
+	return ctx.JSON(summary)
+}
+
+// GetReactions mengambil daftar user yang bereaksi (terbaru dulu, cursor pagination),
+// bisa difilter dengan ?type=love.
+func GetReactions(ctx *fiber.Ctx, db *gorm.DB) error {
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	target, err := findReactionTarget(ctx, db, userID)
+	if err != nil {
+		return fiberErrorResponse(ctx, err)
+	}
+
+	limit := utils.ParseLimit(ctx.Query("limit"), 20, 100)
+	query := db.Table(target.ReactionTable+" r").
+		Select("r.id, r.user_id, r.type, r.created_at, u.first_name, u.last_name, u.user_name, u.profile_picture").
+		Joins("JOIN users u ON u.id = r.user_id AND u.deleted_at IS NULL").
+		Where("r."+target.Column+" = ?", target.ID).
+		Where(notBlockedByCondition("r.user_id"), userID)
+
+	if reactionType := ctx.Query("type"); reactionType != "" {
+		if !models.IsReactionType(reactionType) {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Jenis reaksi tidak valid"})
+		}
+		query = query.Where("r.type = ?", reactionType)
+	}
+
+	if cursor := ctx.Query("cursor"); cursor != "" {
+		createdAt, id, err := utils.DecodeCursor(cursor)
+		if err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		query = query.Where("(r.created_at, r.id) < (?, ?)", createdAt, id)
+	}
+
+	var rows []struct {
+		ID             uuid.UUID
+		UserID         uuid.UUID
+		Type           string
+		CreatedAt      time.Time
+		FirstName      string
+		LastName       string
+		UserName       string
+		ProfilePicture string
+	}
+	if err := query.Order("r.created_at DESC, r.id DESC").Limit(limit + 1).Scan(&rows).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil daftar reaksi"})
+	}
+
+	nextCursor := ""
+	if len(rows) > limit {
+		rows = rows[:limit]
+		nextCursor = utils.EncodeCursor(rows[len(rows)-1].CreatedAt, rows[len(rows)-1].ID)
+	}
+
+	reactions := []ReactionUser{}
+	for _, row := range rows {
+		user := models.User{FirstName: row.FirstName, LastName: row.LastName, UserName: row.UserName, ProfilePicture: row.ProfilePicture}
+		avatar, err := avatarURL(user)
+		if err != nil {
+			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal generate presigned URL"})
+		}
+
+		reactions = append(reactions, ReactionUser{
+			UserID:     row.UserID,
+			User:       displayName(user),
+			UserAvatar: avatar,
+			Type:       row.Type,
+			CreatedAt:  row.CreatedAt,
+		})
+	}
+
+	summary, err := reactionSummary(db, target)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil jumlah reaksi"})
+	}
+
+	summary["reactions"] = reactions
+	summary["next_cursor"] = nextCursor
+	summary["has_more"] = nextCursor != ""
+	return ctx.JSON(summary)
+}
+
+// toggleLike dipakai endpoint like lama: hapus reaksi bila sudah ada, jika belum beri reaksi "like".
+// Target harus sudah diambil lewat loadReactionTarget.
+func toggleLike(ctx *fiber.Ctx, db *gorm.DB, target reactionTarget, userID uuid.UUID) error {
+	removed, err := removeReaction(db, target, userID)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menghapus data like"})
+	}
+	if removed != "" {
+		if summary, err := reactionSummary(db, target); err == nil {
+			broadcastReactionSummary(target, summary)
+		}
+		return ctx.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Unlike berhasil"})
+	}
+
+	if _, err := setReaction(db, target, userID, models.ReactionLike); err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyimpan data like"})
+	}
+	publishReactionEvent(target, userID, models.ReactionLike)
+	if summary, err := reactionSummary(db, target); err == nil {
+		broadcastReactionSummary(target, summary)
+	}
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Like berhasil"})
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Zackly23/queue-app/models"
+	"github.com/Zackly23/queue-app/realtime"
+	"github.com/Zackly23/queue-app/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/valyala/fasthttp"
+	"gorm.io/gorm"
+)
+
+const (
+	maxRealtimeChannels = 20
+	realtimeHeartbeat   = 25 * time.Second
+)
+
+// resolveRealtimeChannels memvalidasi ?channels=album:<id>,user. "user" selalu berarti
+// channel milik user yang login; channel album hanya untuk album yang bisa dilihat.
+func resolveRealtimeChannels(db *gorm.DB, query string, userID uuid.UUID) ([]string, []uuid.UUID, error) {
+	if strings.TrimSpace(query) == "" {
+		query = "user"
+	}
+
+	seen := map[string]bool{}
+	channels := []string{}
+	albumIDs := []uuid.UUID{}
+
+	for _, raw := range strings.Split(query, ",") {
+		raw = strings.TrimSpace(raw)
+		var channel string
+
+		switch {
+		case raw == "user" || raw == "user:me" || raw == realtime.UserChannel(userID):
+			channel = realtime.UserChannel(userID)
+		case strings.HasPrefix(raw, "album:"):
+			albumID, err := uuid.Parse(strings.TrimPrefix(raw, "album:"))
+			if err != nil {
+				return nil, nil, fiber.NewError(fiber.StatusBadRequest, "Album ID tidak valid: "+raw)
+			}
+
+			var album models.Album
+			if err := db.First(&album, "id = ?", albumID).Error; err != nil {
+				return nil, nil, fiber.NewError(fiber.StatusNotFound, "Album tidak ditemukan: "+raw)
+			}
+			allowed, err := canViewAlbum(db, album, userID)
+			if err != nil {
+				return nil, nil, fiber.NewError(fiber.StatusInternalServerError, "Gagal memeriksa akses album")
+			}
+			if !allowed {
+				return nil, nil, fiber.NewError(fiber.StatusForbidden, "User tidak memiliki akses ke album "+raw)
+			}
+
+			channel = realtime.AlbumChannel(albumID)
+			if !seen[channel] {
+				albumIDs = append(albumIDs, albumID)
+			}
+		default:
+			return nil, nil, fiber.NewError(fiber.StatusBadRequest, "Channel tidak dikenal: "+raw)
+		}
+
+		if seen[channel] {
+			continue
+		}
+		seen[channel] = true
+		channels = append(channels, channel)
+	}
+
+	if len(channels) > maxRealtimeChannels {
+		return nil, nil, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Maksimal %d channel per koneksi", maxRealtimeChannels))
+	}
+
+	return channels, albumIDs, nil
+}
+
+// lostAlbumAccess mengembalikan album pertama yang tidak lagi bisa dilihat user
+// (album dihapus, privasi diubah, atau akses dicabut) selama stream berjalan.
+func lostAlbumAccess(db *gorm.DB, albumIDs []uuid.UUID, userID uuid.UUID) (uuid.UUID, bool) {
+	for _, albumID := range albumIDs {
+		var album models.Album
+		if err := db.First(&album, "id = ?", albumID).Error; err != nil {
+			return albumID, true
+		}
+		if allowed, err := canViewAlbum(db, album, userID); err == nil && !allowed {
+			return albumID, true
+		}
+	}
+	return uuid.Nil, false
+}
+
+func writeServerEvent(w *bufio.Writer, eventType string, payload []byte) error {
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", eventType, payload); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// StreamRealtime membuka stream Server-Sent Events untuk channel album dan user.
+// Pesan datang dari Redis pub/sub sehingga event dari replica mana pun ikut terkirim.
+func StreamRealtime(ctx *fiber.Ctx, db *gorm.DB) error {
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	channels, albumIDs, err := resolveRealtimeChannels(db, ctx.Query("channels"), userID)
+	if err != nil {
+		return fiberErrorResponse(ctx, err)
+	}
+
+	streamCtx, cancel := context.WithCancel(context.Background())
+	pubsub, err := realtime.Subscribe(streamCtx, channels)
+	if err != nil {
+		cancel()
+		return ctx.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Gagal berlangganan channel realtime"})
+	}
+
+	ctx.Set("Content-Type", "text/event-stream")
+	ctx.Set("Cache-Control", "no-cache")
+	ctx.Set("Connection", "keep-alive")
+	ctx.Set("X-Accel-Buffering", "no") // agar nginx tidak menahan stream
+
+	ctx.Context().SetBodyStreamWriter(fasthttp.StreamWriter(func(w *bufio.Writer) {
+		defer cancel()
+		defer pubsub.Close()
+
+		ready, _ := json.Marshal(fiber.Map{"channels": channels})
+		if err := writeServerEvent(w, "ready", ready); err != nil {
+			return
+		}
+
+		messages := pubsub.Channel()
+		ticker := time.NewTicker(realtimeHeartbeat)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+
+				var message realtime.Message
+				if err := json.Unmarshal([]byte(msg.Payload), &message); err != nil {
+					log.Printf("Pesan realtime tidak valid: %v", err)
+					continue
+				}
+				if err := writeServerEvent(w, message.Type, []byte(msg.Payload)); err != nil {
+					return // client sudah menutup koneksi
+				}
+
+			case <-ticker.C:
+				if albumID, lost := lostAlbumAccess(db, albumIDs, userID); lost {
+					payload, _ := json.Marshal(fiber.Map{"channel": realtime.AlbumChannel(albumID)})
+					writeServerEvent(w, "access_revoked", payload)
+					return
+				}
+				if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+					return
+				}
+				if err := w.Flush(); err != nil {
+					return
+				}
+			}
+		}
+	}))
+
+	return nil
+}
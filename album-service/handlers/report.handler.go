@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/Zackly23/queue-app/models"
+	"github.com/Zackly23/queue-app/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const maxReportDetailsLength = 1000
+
+// reportTargetOwner memastikan target laporan ada dan bisa dilihat pelapor,
+// lalu mengembalikan pemilik konten tersebut.
+func reportTargetOwner(db *gorm.DB, targetType string, targetID uuid.UUID, reporterID uuid.UUID) (uuid.UUID, error) {
+	var album models.Album
+	var ownerID uuid.UUID
+
+	switch targetType {
+	case models.ReportTargetAlbum:
+		if err := db.First(&album, "id = ?", targetID).Error; err != nil {
+			return uuid.Nil, fiber.NewError(fiber.StatusNotFound, "Album tidak ditemukan")
+		}
+		ownerID = album.UserID
+	case models.ReportTargetMedia:
+		var media models.Media
+		if err := db.Preload("Album").First(&media, "id = ?", targetID).Error; err != nil {
+			return uuid.Nil, fiber.NewError(fiber.StatusNotFound, "Media tidak ditemukan")
+		}
+		album = media.Album
+		ownerID = album.UserID
+	case models.ReportTargetComment:
+		var comment models.AlbumComment
+		if err := db.Preload("Album").First(&comment, "id = ?", targetID).Error; err != nil {
+			return uuid.Nil, fiber.NewError(fiber.StatusNotFound, "Komentar tidak ditemukan")
+		}
+		album = comment.Album
+		ownerID = comment.UserID
+	case models.ReportTargetUser:
+		var user models.User
+		if err := db.Select("id").First(&user, "id = ?", targetID).Error; err != nil {
+			return uuid.Nil, fiber.NewError(fiber.StatusNotFound, "User not found")
+		}
+		return user.ID, nil
+	default:
+		return uuid.Nil, fiber.NewError(fiber.StatusBadRequest, "target_type harus album, media, comment atau user")
+	}
+
+	allowed, err := canViewAlbum(db, album, reporterID)
+	if err != nil {
+		return uuid.Nil, fiber.NewError(fiber.StatusInternalServerError, "Gagal memeriksa akses album")
+	}
+	if !allowed {
+		return uuid.Nil, fiber.NewError(fiber.StatusForbidden, "User tidak memiliki akses ke album ini")
+	}
+
+	return ownerID, nil
+}
+
+// CreateReport melaporkan album, media, komentar atau user ke moderator.
+func CreateReport(ctx *fiber.Ctx, db *gorm.DB) error {
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var req struct {
+		TargetType string `json:"target_type"`
+		TargetID   string `json:"target_id"`
+		Reason     string `json:"reason"`
+		Details    string `json:"details"`
+	}
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Format permintaan tidak valid"})
+	}
+
+	targetID, err := uuid.Parse(req.TargetID)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "target_id tidak valid"})
+	}
+	if !models.IsReportReason(req.Reason) {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Alasan laporan tidak valid",
+			"reasons": models.ReportReasons,
+		})
+	}
+
+	details := strings.TrimSpace(req.Details)
+	if len([]rune(details)) > maxReportDetailsLength {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Detail laporan terlalu panjang"})
+	}
+
+	ownerID, err := reportTargetOwner(db, req.TargetType, targetID, userID)
+	if err != nil {
+		return fiberErrorResponse(ctx, err)
+	}
+	if ownerID == userID {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Tidak bisa melaporkan konten sendiri"})
+	}
+
+	// Satu user hanya punya satu laporan aktif untuk target yang sama
+	var existing int64
+	if err := db.Model(&models.Report{}).
+		Where("reporter_id = ? AND target_type = ? AND target_id = ? AND status IN ?", userID, req.TargetType, targetID,
+			[]string{models.ReportStatusOpen, models.ReportStatusInReview}).
+		Count(&existing).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memeriksa laporan"})
+	}
+	if existing > 0 {
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Konten ini sudah Anda laporkan dan sedang ditinjau"})
+	}
+
+	report := models.Report{
+		ReporterID:    userID,
+		TargetType:    req.TargetType,
+		TargetID:      targetID,
+		TargetOwnerID: ownerID,
+		Reason:        req.Reason,
+		Details:       details,
+		Status:        models.ReportStatusOpen,
+	}
+	if err := db.Create(&report).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyimpan laporan"})
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Laporan berhasil dikirim",
+		"data":    report,
+	})
+}
+
+// GetMyReports mengambil laporan yang dibuat user beserta statusnya (terbaru dulu).
+func GetMyReports(ctx *fiber.Ctx, db *gorm.DB) error {
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	limit := utils.ParseLimit(ctx.Query("limit"), 20, 100)
+	query := db.Where("reporter_id = ?", userID)
+
+	if cursor := ctx.Query("cursor"); cursor != "" {
+		createdAt, id, err := utils.DecodeCursor(cursor)
+		if err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		query = query.Where("(created_at, id) < (?, ?)", createdAt, id)
+	}
+
+	var reports []models.Report
+	if err := query.Order("created_at DESC, id DESC").Limit(limit + 1).Find(&reports).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil laporan"})
+	}
+
+	nextCursor := ""
+	if len(reports) > limit {
+		reports = reports[:limit]
+		last := reports[len(reports)-1]
+		nextCursor = utils.EncodeCursor(last.CreatedAt, last.ID)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"reports":     reports,
+		"reasons":     models.ReportReasons,
+		"next_cursor": nextCursor,
+		"has_more":    nextCursor != "",
+	})
+}
@@ -0,0 +1,673 @@
+package handlers
+
+import (
+	"html"
+	"strings"
+	"time"
+
+	"github.com/Zackly23/queue-app/config"
+	"github.com/Zackly23/queue-app/models"
+	"github.com/Zackly23/queue-app/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	SearchTypeAll    = "all"
+	SearchTypeAlbums = "albums"
+	SearchTypeMedia  = "media"
+	SearchTypeUsers  = "users"
+	SearchTypeTags   = "tags"
+)
+
+const (
+	searchMaxQueryLength = 200
+	searchFacetLimit     = 10
+	searchTagMatchBoost  = 0.1 // album yang cocok lewat tag tetap muncul walau judulnya tidak cocok
+
+	// Konfigurasi "simple" harus sama dengan kolom search_vector di migrations/search.migration.go
+	searchTsQuery = "websearch_to_tsquery('simple', ?)"
+
+	// Penanda highlight dari ts_headline, diganti <mark> setelah teks di-escape
+	searchHighlightStart = "\x02"
+	searchHighlightStop  = "\x03"
+)
+
+var (
+	searchTitleHeadline = "HighlightAll=true, StartSel=" + searchHighlightStart + ", StopSel=" + searchHighlightStop
+	searchTextHeadline  = "MaxFragments=2, MaxWords=20, MinWords=5, FragmentDelimiter=\" ... \", StartSel=" + searchHighlightStart + ", StopSel=" + searchHighlightStop
+)
+
+// searchAlbumTagCondition cocok bila salah satu tag album mengandung kata yang dicari.
+const searchAlbumTagCondition = "EXISTS (SELECT 1 FROM album_album_tags aat JOIN album_tags t ON t.id = aat.album_tag_id WHERE aat.album_id = albums.id AND t.deleted_at IS NULL AND t.search_vector @@ " + searchTsQuery + ")"
+
+// searchFilter adalah parameter pencarian yang dipakai semua jenis hasil.
+type searchFilter struct {
+	Query    string
+	Tag      string
+	Kind     string
+	OwnerID  *uuid.UUID
+	ViewerID uuid.UUID
+	AsOf     time.Time // record yang dibuat setelah asOf tidak ikut agar halaman berikutnya stabil
+}
+
+type searchHit struct {
+	ID   uuid.UUID
+	Rank float64
+}
+
+type SearchHighlight struct {
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Name        string `json:"name,omitempty"`
+}
+
+type SearchAlbumResult struct {
+	AlbumID      uuid.UUID       `json:"album_id"`
+	Title        string          `json:"title"`
+	Description  string          `json:"description"`
+	AlbumPrivacy string          `json:"album_privacy"`
+	ThumbnailURL string          `json:"thumbnail_url"`
+	LikesCount   uint            `json:"likes_count"`
+	ViewCount    uint            `json:"view_count"`
+	Rank         float64         `json:"rank"`
+	Highlight    SearchHighlight `json:"highlight"`
+	UserDetail   UserDetail      `json:"user_detail"`
+}
+
+type SearchMediaResult struct {
+	MediaID      uuid.UUID       `json:"media_id"`
+	AlbumID      uuid.UUID       `json:"album_id"`
+	AlbumTitle   string          `json:"album_title"`
+	Kind         string          `json:"kind"`
+	Title        string          `json:"title"`
+	Description  string          `json:"description"`
+	ThumbnailURL string          `json:"thumbnail_url"`
+	LikesCount   uint            `json:"likes_count"`
+	Rank         float64         `json:"rank"`
+	Highlight    SearchHighlight `json:"highlight"`
+	UserDetail   UserDetail      `json:"user_detail"`
+}
+
+type SearchUserResult struct {
+	UserID         uuid.UUID       `json:"user_id"`
+	UserName       string          `json:"user_name,omitempty"`
+	FullName       string          `json:"full_name"`
+	Bio            string          `json:"bio,omitempty"`
+	ProfilePicture string          `json:"profile_picture,omitempty"`
+	Rank           float64         `json:"rank"`
+	Highlight      SearchHighlight `json:"highlight"`
+}
+
+type SearchTagResult struct {
+	TagID      uuid.UUID       `json:"tag_id"`
+	TagName    string          `json:"tag_name"`
+	AlbumCount int64           `json:"album_count"`
+	Rank       float64         `json:"rank"`
+	Highlight  SearchHighlight `json:"highlight"`
+}
+
+type SearchFacet struct {
+	Value string `json:"value"`
+	Label string `json:"label,omitempty"`
+	Count int64  `json:"count"`
+}
+
+// highlightHTML meng-escape hasil ts_headline lalu mengganti penanda highlight dengan <mark>,
+// sehingga client bisa merender highlight tanpa risiko HTML dari konten user.
+func highlightHTML(text string) string {
+	text = html.EscapeString(text)
+	text = strings.ReplaceAll(text, searchHighlightStart, "<mark>")
+	return strings.ReplaceAll(text, searchHighlightStop, "</mark>")
+}
+
+// searchableAlbums membatasi query albums ke album yang boleh dilihat viewer: album sendiri,
+// atau album publik/restricted (dengan undangan) yang tidak disembunyikan moderator, tidak berada
+// di bawah parent yang tertutup, pemiliknya tidak saling memblokir dengan viewer, dan bukan milik
+// akun privat yang belum di-follow viewer.
+func searchableAlbums(query *gorm.DB, f searchFilter) *gorm.DB {
+	return query.
+		Where("albums.deleted_at IS NULL AND albums.created_at <= ?", f.AsOf).
+		Where(`albums.user_id = ? OR (albums.moderation_status = ?
+			AND (albums.album_privacy = 'public' OR (albums.album_privacy = 'restricted' AND `+acceptedInvitationCondition+`))
+			AND `+visibleAncestorsCondition+`
+			AND `+notBlockedByCondition("albums.user_id")+`
+			AND NOT EXISTS (SELECT 1 FROM user_blocks vb WHERE vb.blocker_id = ? AND vb.blocked_id = albums.user_id)
+			AND `+followerOnlyCondition("albums.user_id")+`)`,
+			f.ViewerID, models.ModerationStatusVisible, f.ViewerID, f.ViewerID, f.ViewerID, f.ViewerID, f.ViewerID)
+}
+
+// applyAlbumFilters menerapkan filter facet tag dan owner ke query yang berisi tabel albums.
+func applyAlbumFilters(query *gorm.DB, f searchFilter) *gorm.DB {
+	if f.Tag != "" {
+		query = query.Where(`EXISTS (SELECT 1 FROM album_album_tags fat JOIN album_tags ft ON ft.id = fat.album_tag_id
+			WHERE fat.album_id = albums.id AND ft.deleted_at IS NULL AND ft.tag_name = ?)`, f.Tag)
+	}
+	if f.OwnerID != nil {
+		query = query.Where("albums.user_id = ?", *f.OwnerID)
+	}
+	return query
+}
+
+// matchedAlbums adalah album yang cocok dengan kata kunci, lengkap dengan rank.
+func matchedAlbums(db *gorm.DB, f searchFilter) *gorm.DB {
+	query := db.Table("albums").
+		Select(`albums.id, albums.user_id, CAST(ts_rank_cd(albums.search_vector, `+searchTsQuery+`)
+			+ CASE WHEN `+searchAlbumTagCondition+` THEN ? ELSE 0 END AS double precision) AS rank`,
+			f.Query, f.Query, searchTagMatchBoost).
+		Where("albums.search_vector @@ "+searchTsQuery+" OR "+searchAlbumTagCondition, f.Query, f.Query)
+	query = applyAlbumFilters(searchableAlbums(query, f), f)
+
+	// Filter jenis media: album yang berisi media jenis tersebut
+	if f.Kind != "" {
+		query = query.Where("EXISTS (SELECT 1 FROM media km WHERE km.album_id = albums.id AND km.kind = ? AND km.deleted_at IS NULL)", f.Kind)
+	}
+	return query
+}
+
+// matchedMedia adalah media (foto, video, dll) yang deskripsinya cocok dengan kata kunci.
+func matchedMedia(db *gorm.DB, f searchFilter) *gorm.DB {
+	query := db.Table("media").
+		Select("media.id, media.kind, albums.user_id, CAST(ts_rank_cd(media.search_vector, "+searchTsQuery+") AS double precision) AS rank", f.Query).
+		Joins("JOIN albums ON albums.id = media.album_id").
+		Where("media.search_vector @@ "+searchTsQuery, f.Query).
+		Where("media.deleted_at IS NULL AND media.created_at <= ?", f.AsOf).
+		Where("media.moderation_status = ? OR albums.user_id = ?", models.ModerationStatusVisible, f.ViewerID)
+	query = applyAlbumFilters(searchableAlbums(query, f), f)
+
+	if f.Kind != "" {
+		query = query.Where("media.kind = ?", f.Kind)
+	}
+	return query
+}
+
+// matchedUsers adalah user aktif yang nama atau bio-nya cocok, kecuali yang saling memblokir dengan viewer.
+func matchedUsers(db *gorm.DB, f searchFilter) *gorm.DB {
+	return db.Table("users").
+		Select("users.id, CAST(ts_rank_cd(users.search_vector, "+searchTsQuery+") AS double precision) AS rank", f.Query).
+		Where("users.search_vector @@ "+searchTsQuery, f.Query).
+		Where("users.deleted_at IS NULL AND users.status = ? AND users.created_at <= ?", "active", f.AsOf).
+		Where(notBlockedByCondition("users.id"), f.ViewerID).
+		Where("NOT EXISTS (SELECT 1 FROM user_blocks vb WHERE vb.blocker_id = ? AND vb.blocked_id = users.id)", f.ViewerID)
+}
+
+// matchedTags adalah tag yang cocok dan dipakai minimal satu album yang boleh dilihat viewer.
+func matchedTags(db *gorm.DB, f searchFilter) *gorm.DB {
+	albums := searchableAlbums(db.Table("albums").Select("albums.id"), f)
+
+	return db.Table("album_tags").
+		Select("album_tags.id, CAST(ts_rank_cd(album_tags.search_vector, "+searchTsQuery+") AS double precision) AS rank", f.Query).
+		Where("album_tags.search_vector @@ "+searchTsQuery, f.Query).
+		Where("album_tags.deleted_at IS NULL").
+		Where("EXISTS (SELECT 1 FROM album_album_tags aat WHERE aat.album_tag_id = album_tags.id AND aat.album_id IN (?))", albums)
+}
+
+// searchPage mengambil satu halaman hasil terurut berdasarkan rank, dengan cursor (rank, id).
+func searchPage(db *gorm.DB, matched *gorm.DB, f searchFilter, cursor string, limit int) ([]searchHit, string, error) {
+	query := db.Table("(?) AS r", matched).Select("r.id, r.rank")
+	if cursor != "" {
+		_, rank, id, err := utils.DecodeScoreCursor(cursor)
+		if err != nil {
+			return nil, "", fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		query = query.Where("(r.rank, r.id) < (CAST(? AS double precision), CAST(? AS uuid))", rank, id)
+	}
+
+	var hits []searchHit
+	if err := query.Order("r.rank DESC, r.id DESC").Limit(limit + 1).Scan(&hits).Error; err != nil {
+		return nil, "", err
+	}
+
+	nextCursor := ""
+	if len(hits) > limit {
+		hits = hits[:limit]
+		last := hits[len(hits)-1]
+		nextCursor = utils.EncodeScoreCursor(f.AsOf, last.Rank, last.ID)
+	}
+	return hits, nextCursor, nil
+}
+
+func hitIDs(hits []searchHit) []uuid.UUID {
+	ids := make([]uuid.UUID, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ID
+	}
+	return ids
+}
+
+func presignedOrEmpty(url string) (string, error) {
+	if url == "" {
+		return "", nil
+	}
+	return utils.GeneratePresignedURL(config.S3Bucket.BucketName, utils.S3KeyFromURL(url))
+}
+
+func searchAlbums(db *gorm.DB, f searchFilter, cursor string, limit int) ([]SearchAlbumResult, string, error) {
+	hits, nextCursor, err := searchPage(db, matchedAlbums(db, f), f, cursor, limit)
+	if err != nil || len(hits) == 0 {
+		return []SearchAlbumResult{}, nextCursor, err
+	}
+
+	// Highlight hanya dihitung untuk album di halaman ini
+	var rows []struct {
+		ID                   uuid.UUID
+		Title                string
+		Description          string
+		AlbumPrivacy         string
+		CoverImage           string
+		LikesCount           uint
+		ViewCount            uint
+		UserID               uuid.UUID
+		FirstName            string
+		LastName             string
+		ProfilePicture       string
+		TitleHighlight       string
+		DescriptionHighlight string
+	}
+	if err := db.Table("albums").
+		Select(`albums.id, albums.title, albums.description, albums.album_privacy, albums.cover_image, albums.likes_count, albums.view_count,
+			users.id AS user_id, users.first_name, users.last_name, users.profile_picture,
+			ts_headline('simple', albums.title, `+searchTsQuery+`, ?) AS title_highlight,
+			ts_headline('simple', coalesce(albums.description, ''), `+searchTsQuery+`, ?) AS description_highlight`,
+			f.Query, searchTitleHeadline, f.Query, searchTextHeadline).
+		Joins("JOIN users ON users.id = albums.user_id").
+		Where("albums.id IN ?", hitIDs(hits)).
+		Scan(&rows).Error; err != nil {
+		return nil, "", err
+	}
+
+	byID := map[uuid.UUID]int{}
+	for i, row := range rows {
+		byID[row.ID] = i
+	}
+
+	results := []SearchAlbumResult{}
+	for _, hit := range hits {
+		i, ok := byID[hit.ID]
+		if !ok {
+			continue
+		}
+		row := rows[i]
+
+		thumbnailURL, err := presignedOrEmpty(row.CoverImage)
+		if err != nil {
+			return nil, "", err
+		}
+		profilePicture, err := presignedOrEmpty(row.ProfilePicture)
+		if err != nil {
+			return nil, "", err
+		}
+
+		results = append(results, SearchAlbumResult{
+			AlbumID:      row.ID,
+			Title:        row.Title,
+			Description:  row.Description,
+			AlbumPrivacy: row.AlbumPrivacy,
+			ThumbnailURL: thumbnailURL,
+			LikesCount:   row.LikesCount,
+			ViewCount:    row.ViewCount,
+			Rank:         hit.Rank,
+			Highlight: SearchHighlight{
+				Title:       highlightHTML(row.TitleHighlight),
+				Description: highlightHTML(row.DescriptionHighlight),
+			},
+			UserDetail: UserDetail{
+				UserID:         row.UserID,
+				FirstName:      row.FirstName,
+				LastName:       row.LastName,
+				FullName:       row.FirstName + " " + row.LastName,
+				ProfilePicture: profilePicture,
+			},
+		})
+	}
+	return results, nextCursor, nil
+}
+
+func searchMedia(db *gorm.DB, f searchFilter, cursor string, limit int) ([]SearchMediaResult, string, error) {
+	hits, nextCursor, err := searchPage(db, matchedMedia(db, f), f, cursor, limit)
+	if err != nil || len(hits) == 0 {
+		return []SearchMediaResult{}, nextCursor, err
+	}
+
+	var rows []struct {
+		ID                   uuid.UUID
+		AlbumID              uuid.UUID
+		AlbumTitle           string
+		Kind                 string
+		URL                  string
+		ThumbnailURL         string
+		Title                string
+		Description          string
+		LikesCount           uint
+		UserID               uuid.UUID
+		FirstName            string
+		LastName             string
+		TitleHighlight       string
+		DescriptionHighlight string
+	}
+	if err := db.Table("media").
+		Select(`media.id, media.album_id, albums.title AS album_title, media.kind, media.url, media.thumbnail_url,
+			media.title, media.description, media.likes_count, users.id AS user_id, users.first_name, users.last_name,
+			ts_headline('simple', coalesce(media.title, ''), `+searchTsQuery+`, ?) AS title_highlight,
+			ts_headline('simple', coalesce(media.description, ''), `+searchTsQuery+`, ?) AS description_highlight`,
+			f.Query, searchTitleHeadline, f.Query, searchTextHeadline).
+		Joins("JOIN albums ON albums.id = media.album_id").
+		Joins("JOIN users ON users.id = albums.user_id").
+		Where("media.id IN ?", hitIDs(hits)).
+		Scan(&rows).Error; err != nil {
+		return nil, "", err
+	}
+
+	byID := map[uuid.UUID]int{}
+	for i, row := range rows {
+		byID[row.ID] = i
+	}
+
+	results := []SearchMediaResult{}
+	for _, hit := range hits {
+		i, ok := byID[hit.ID]
+		if !ok {
+			continue
+		}
+		row := rows[i]
+
+		media := models.Media{Kind: row.Kind, URL: row.URL, ThumbnailURL: row.ThumbnailURL}
+		thumbnailURL, err := presignedOrEmpty(media.CoverURL())
+		if err != nil {
+			return nil, "", err
+		}
+
+		results = append(results, SearchMediaResult{
+			MediaID:      row.ID,
+			AlbumID:      row.AlbumID,
+			AlbumTitle:   row.AlbumTitle,
+			Kind:         row.Kind,
+			Title:        row.Title,
+			Description:  row.Description,
+			ThumbnailURL: thumbnailURL,
+			LikesCount:   row.LikesCount,
+			Rank:         hit.Rank,
+			Highlight: SearchHighlight{
+				Title:       highlightHTML(row.TitleHighlight),
+				Description: highlightHTML(row.DescriptionHighlight),
+			},
+			UserDetail: UserDetail{
+				UserID:    row.UserID,
+				FirstName: row.FirstName,
+				LastName:  row.LastName,
+				FullName:  row.FirstName + " " + row.LastName,
+			},
+		})
+	}
+	return results, nextCursor, nil
+}
+
+func searchUsers(db *gorm.DB, f searchFilter, cursor string, limit int) ([]SearchUserResult, string, error) {
+	hits, nextCursor, err := searchPage(db, matchedUsers(db, f), f, cursor, limit)
+	if err != nil || len(hits) == 0 {
+		return []SearchUserResult{}, nextCursor, err
+	}
+
+	var rows []struct {
+		ID             uuid.UUID
+		UserName       string
+		FirstName      string
+		LastName       string
+		Bio            string
+		ProfilePicture string
+		NameHighlight  string
+		BioHighlight   string
+	}
+	if err := db.Table("users").
+		Select(`users.id, users.user_name, users.first_name, users.last_name, users.bio, users.profile_picture,
+			ts_headline('simple', users.first_name || ' ' || users.last_name, `+searchTsQuery+`, ?) AS name_highlight,
+			ts_headline('simple', coalesce(users.bio, ''), `+searchTsQuery+`, ?) AS bio_highlight`,
+			f.Query, searchTitleHeadline, f.Query, searchTextHeadline).
+		Where("users.id IN ?", hitIDs(hits)).
+		Scan(&rows).Error; err != nil {
+		return nil, "", err
+	}
+
+	byID := map[uuid.UUID]int{}
+	for i, row := range rows {
+		byID[row.ID] = i
+	}
+
+	results := []SearchUserResult{}
+	for _, hit := range hits {
+		i, ok := byID[hit.ID]
+		if !ok {
+			continue
+		}
+		row := rows[i]
+
+		profilePicture, err := presignedOrEmpty(row.ProfilePicture)
+		if err != nil {
+			return nil, "", err
+		}
+
+		results = append(results, SearchUserResult{
+			UserID:         row.ID,
+			UserName:       row.UserName,
+			FullName:       row.FirstName + " " + row.LastName,
+			Bio:            row.Bio,
+			ProfilePicture: profilePicture,
+			Rank:           hit.Rank,
+			Highlight: SearchHighlight{
+				Name:        highlightHTML(row.NameHighlight),
+				Description: highlightHTML(row.BioHighlight),
+			},
+		})
+	}
+	return results, nextCursor, nil
+}
+
+func searchTags(db *gorm.DB, f searchFilter, cursor string, limit int) ([]SearchTagResult, string, error) {
+	hits, nextCursor, err := searchPage(db, matchedTags(db, f), f, cursor, limit)
+	if err != nil || len(hits) == 0 {
+		return []SearchTagResult{}, nextCursor, err
+	}
+
+	albums := searchableAlbums(db.Table("albums").Select("albums.id"), f)
+
+	var rows []struct {
+		ID            uuid.UUID
+		TagName       string
+		AlbumCount    int64
+		NameHighlight string
+	}
+	if err := db.Table("album_tags").
+		Select(`album_tags.id, album_tags.tag_name,
+			(SELECT COUNT(*) FROM album_album_tags aat WHERE aat.album_tag_id = album_tags.id AND aat.album_id IN (?)) AS album_count,
+			ts_headline('simple', album_tags.tag_name, `+searchTsQuery+`, ?) AS name_highlight`,
+			albums, f.Query, searchTitleHeadline).
+		Where("album_tags.id IN ?", hitIDs(hits)).
+		Scan(&rows).Error; err != nil {
+		return nil, "", err
+	}
+
+	byID := map[uuid.UUID]int{}
+	for i, row := range rows {
+		byID[row.ID] = i
+	}
+
+	results := []SearchTagResult{}
+	for _, hit := range hits {
+		i, ok := byID[hit.ID]
+		if !ok {
+			continue
+		}
+		row := rows[i]
+		results = append(results, SearchTagResult{
+			TagID:      row.ID,
+			TagName:    row.TagName,
+			AlbumCount: row.AlbumCount,
+			Rank:       hit.Rank,
+			Highlight:  SearchHighlight{Name: highlightHTML(row.NameHighlight)},
+		})
+	}
+	return results, nextCursor, nil
+}
+
+// searchFacets menghitung jumlah hasil album dan media per tag, jenis media dan pemilik,
+// dengan filter yang sedang aktif, untuk ditampilkan sebagai pilihan filter lanjutan.
+func searchFacets(db *gorm.DB, f searchFilter) (fiber.Map, error) {
+	tags := []SearchFacet{}
+	if err := db.Table("(?) AS m", matchedAlbums(db, f)).
+		Select("t.tag_name AS value, COUNT(DISTINCT m.id) AS count").
+		Joins("JOIN album_album_tags aat ON aat.album_id = m.id").
+		Joins("JOIN album_tags t ON t.id = aat.album_tag_id AND t.deleted_at IS NULL").
+		Group("t.tag_name").
+		Order("count DESC, value ASC").
+		Limit(searchFacetLimit).
+		Scan(&tags).Error; err != nil {
+		return nil, err
+	}
+
+	kinds := []SearchFacet{}
+	if err := db.Table("(?) AS m", matchedMedia(db, f)).
+		Select("m.kind AS value, COUNT(*) AS count").
+		Group("m.kind").
+		Order("count DESC, value ASC").
+		Scan(&kinds).Error; err != nil {
+		return nil, err
+	}
+
+	owners := []SearchFacet{}
+	if err := db.Raw(`SELECT o.user_id AS value, users.first_name || ' ' || users.last_name AS label, COUNT(*) AS count
+		FROM ((SELECT user_id FROM (?) AS ma) UNION ALL (SELECT user_id FROM (?) AS mm)) AS o
+		JOIN users ON users.id = o.user_id
+		GROUP BY o.user_id, users.first_name, users.last_name
+		ORDER BY count DESC, label ASC
+		LIMIT ?`, matchedAlbums(db, f), matchedMedia(db, f), searchFacetLimit).
+		Scan(&owners).Error; err != nil {
+		return nil, err
+	}
+
+	return fiber.Map{
+		"tags":   tags,
+		"kinds":  kinds,
+		"owners": owners,
+	}, nil
+}
+
+// Search mencari album, media, user dan tag secara global dengan full-text search Postgres.
+// Query: ?q= (wajib, mendukung "frasa", OR dan -kata), ?type=all|albums|media|users|tags,
+// filter album/media ?tag=, ?kind=, ?owner_id=, dan ?cursor= (hanya untuk type selain all).
+// Facet dikembalikan di halaman pertama untuk type all, albums dan media.
+func Search(ctx *fiber.Ctx, db *gorm.DB) error {
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	q := strings.TrimSpace(ctx.Query("q"))
+	if q == "" {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Kata kunci pencarian wajib diisi"})
+	}
+	if len(q) > searchMaxQueryLength {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Kata kunci pencarian terlalu panjang"})
+	}
+
+	searchType := ctx.Query("type", SearchTypeAll)
+	switch searchType {
+	case SearchTypeAll, SearchTypeAlbums, SearchTypeMedia, SearchTypeUsers, SearchTypeTags:
+	default:
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Type harus all, albums, media, users atau tags"})
+	}
+
+	filter := searchFilter{
+		Query:    q,
+		Tag:      utils.NormalizeTag(ctx.Query("tag")),
+		Kind:     ctx.Query("kind"),
+		ViewerID: userID,
+		AsOf:     time.Now(),
+	}
+	// Filter tag lewat alias diarahkan ke tag utamanya
+	if filter.Tag != "" {
+		if tag, err := utils.FindTag(db, filter.Tag); err == nil {
+			filter.Tag = tag.TagName
+		}
+	}
+	if filter.Kind != "" {
+		if _, ok := models.LookupMediaKind(filter.Kind); !ok {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Jenis media tidak dikenal"})
+		}
+	}
+	if ownerID := ctx.Query("owner_id"); ownerID != "" {
+		parsed, err := uuid.Parse(ownerID)
+		if err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Owner ID tidak valid"})
+		}
+		filter.OwnerID = &parsed
+	}
+
+	cursor := ctx.Query("cursor")
+	if cursor != "" {
+		if searchType == SearchTypeAll {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cursor hanya bisa dipakai dengan type tertentu"})
+		}
+		if filter.AsOf, _, _, err = utils.DecodeScoreCursor(cursor); err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
+
+	limit := utils.ParseLimit(ctx.Query("limit"), 20, 50)
+	if searchType == SearchTypeAll {
+		limit = utils.ParseLimit(ctx.Query("limit"), 5, 20)
+	}
+
+	response := fiber.Map{"query": q, "type": searchType}
+	include := func(t string) bool { return searchType == SearchTypeAll || searchType == t }
+
+	if include(SearchTypeAlbums) {
+		albums, nextCursor, err := searchAlbums(db, filter, cursor, limit)
+		if err != nil {
+			return searchErrorResponse(ctx, err)
+		}
+		response["albums"] = fiber.Map{"results": albums, "next_cursor": nextCursor, "has_more": nextCursor != ""}
+	}
+
+	if include(SearchTypeMedia) {
+		media, nextCursor, err := searchMedia(db, filter, cursor, limit)
+		if err != nil {
+			return searchErrorResponse(ctx, err)
+		}
+		response["media"] = fiber.Map{"results": media, "next_cursor": nextCursor, "has_more": nextCursor != ""}
+	}
+
+	// Filter tag, kind dan owner hanya berlaku untuk album dan media
+	if include(SearchTypeUsers) {
+		users, nextCursor, err := searchUsers(db, filter, cursor, limit)
+		if err != nil {
+			return searchErrorResponse(ctx, err)
+		}
+		response["users"] = fiber.Map{"results": users, "next_cursor": nextCursor, "has_more": nextCursor != ""}
+	}
+
+	if include(SearchTypeTags) {
+		tags, nextCursor, err := searchTags(db, filter, cursor, limit)
+		if err != nil {
+			return searchErrorResponse(ctx, err)
+		}
+		response["tags"] = fiber.Map{"results": tags, "next_cursor": nextCursor, "has_more": nextCursor != ""}
+	}
+
+	if cursor == "" && (searchType == SearchTypeAll || searchType == SearchTypeAlbums || searchType == SearchTypeMedia) {
+		facets, err := searchFacets(db, filter)
+		if err != nil {
+			return searchErrorResponse(ctx, err)
+		}
+		response["facets"] = facets
+	}
+
+	return ctx.JSON(response)
+}
+
+func searchErrorResponse(ctx *fiber.Ctx, err error) error {
+	if _, ok := err.(*fiber.Error); ok {
+		return fiberErrorResponse(ctx, err)
+	}
+	return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal melakukan pencarian"})
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import "testing"
+
+func TestHighlightHTML(t *testing.T) {
+	mark := func(text string) string {
+		return searchHighlightStart + text + searchHighlightStop
+	}
+
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "plain", text: "Liburan ke Bali", want: "Liburan ke Bali"},
+		{name: "single match", text: "Liburan ke " + mark("Bali"), want: "Liburan ke <mark>Bali</mark>"},
+		{name: "multiple matches", text: mark("Sunset") + " di " + mark("Bali"), want: "<mark>Sunset</mark> di <mark>Bali</mark>"},
+		{
+			name: "user html escaped",
+			text: `<script>alert("x")</script> ` + mark("Bali"),
+			want: `&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt; <mark>Bali</mark>`,
+		},
+		{name: "literal mark tag escaped", text: "<mark>palsu</mark>", want: "&lt;mark&gt;palsu&lt;/mark&gt;"},
+		{name: "ampersand", text: "Tom & " + mark("Jerry"), want: "Tom &amp; <mark>Jerry</mark>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := highlightHTML(tt.text); got != tt.want {
+				t.Errorf("highlightHTML(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,276 @@
+package handlers
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/Zackly23/queue-app/models"
+	"github.com/Zackly23/queue-app/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Batas jarak Hamming (dari 64 bit dHash)
+const (
+	similarDefaultDistance   = 10
+	duplicateDefaultDistance = 4
+	maxSimilarDistance       = 16
+)
+
+// hammingDistanceSQL menghitung jarak Hamming antara media.perceptual_hash dan parameter hash.
+const hammingDistanceSQL = "length(replace(CAST(CAST(media.perceptual_hash # CAST(? AS bigint) AS bit(64)) AS text), '0', ''))"
+
+type SimilarMediaResponse struct {
+	MediaID      uuid.UUID `json:"media_id"`
+	AlbumID      uuid.UUID `json:"album_id"`
+	AlbumTitle   string    `json:"album_title"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	Size         float32   `json:"size"`
+	Distance     int       `json:"distance"`
+	Similarity   float64   `json:"similarity"` // 1 = identik
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type DuplicateMedia struct {
+	MediaID      uuid.UUID `json:"media_id"`
+	AlbumID      uuid.UUID `json:"album_id"`
+	AlbumTitle   string    `json:"album_title"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	Size         float32   `json:"size"`
+	IsReference  bool      `json:"is_reference"`
+	Keep         bool      `json:"keep"` // saran media yang dipertahankan (yang paling lama)
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type DuplicateGroup struct {
+	Media         []DuplicateMedia `json:"media"`
+	ReclaimableMB float32          `json:"reclaimable_mb"` // kuota yang kembali bila selain media keep dihapus
+}
+
+type hashedMediaRow struct {
+	ID             uuid.UUID
+	AlbumID        uuid.UUID
+	AlbumTitle     string
+	Kind           string
+	URL            string
+	ThumbnailURL   string
+	Size           float32
+	IsReference    bool
+	PerceptualHash int64
+	Distance       int
+	CreatedAt      time.Time
+}
+
+func parseMaxDistance(value string, fallback int) int {
+	distance, err := strconv.Atoi(value)
+	if err != nil || distance < 0 {
+		return fallback
+	}
+	if distance > maxSimilarDistance {
+		return maxSimilarDistance
+	}
+	return distance
+}
+
+// ownHashedImages adalah gambar milik user yang sudah punya perceptual hash.
+func ownHashedImages(db *gorm.DB, userID uuid.UUID) *gorm.DB {
+	return db.Table("media").
+		Select(`media.id, media.album_id, albums.title AS album_title, media.kind, media.url, media.thumbnail_url,
+			media.size, media.is_reference, media.perceptual_hash, media.created_at`).
+		Joins("JOIN albums ON albums.id = media.album_id AND albums.deleted_at IS NULL").
+		Where("albums.user_id = ? AND media.kind = ? AND media.perceptual_hash IS NOT NULL AND media.deleted_at IS NULL", userID, models.MediaKindImage)
+}
+
+func mediaThumbnail(row hashedMediaRow) (string, error) {
+	return presignedOrEmpty(models.Media{Kind: row.Kind, URL: row.URL, ThumbnailURL: row.ThumbnailURL}.CoverURL())
+}
+
+// FindSimilarMedia mencari gambar di album milik user yang mirip dengan sebuah gambar,
+// berdasarkan jarak Hamming perceptual hash (?max_distance=, default 10).
+func FindSimilarMedia(ctx *fiber.Ctx, db *gorm.DB) error {
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var media models.Media
+	if err := db.Preload("Album").First(&media, "id = ?", ctx.Params("mediaId")).Error; err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Media tidak ditemukan"})
+	}
+	if media.ModerationStatus != models.ModerationStatusVisible && media.Album.UserID != userID {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Media tidak ditemukan"})
+	}
+
+	allowed, err := canViewAlbum(db, media.Album, userID)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memeriksa akses album"})
+	}
+	if !allowed {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "User tidak memiliki akses ke album ini"})
+	}
+
+	if media.Kind != models.MediaKindImage {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Pencarian gambar mirip hanya untuk gambar"})
+	}
+	if media.PerceptualHash == nil {
+		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": "Gambar belum diproses, coba lagi nanti"})
+	}
+
+	maxDistance := parseMaxDistance(ctx.Query("max_distance"), similarDefaultDistance)
+	limit := utils.ParseLimit(ctx.Query("limit"), 20, 100)
+
+	var rows []hashedMediaRow
+	if err := ownHashedImages(db, userID).
+		Select(`media.id, media.album_id, albums.title AS album_title, media.kind, media.url, media.thumbnail_url,
+			media.size, media.created_at, `+hammingDistanceSQL+` AS distance`, *media.PerceptualHash).
+		Where("media.id <> ?", media.ID).
+		Where(hammingDistanceSQL+" <= ?", *media.PerceptualHash, maxDistance).
+		Order("distance ASC, media.created_at DESC").
+		Limit(limit).
+		Scan(&rows).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mencari gambar mirip"})
+	}
+
+	results := []SimilarMediaResponse{}
+	for _, row := range rows {
+		thumbnailURL, err := mediaThumbnail(row)
+		if err != nil {
+			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal generate presigned URL"})
+		}
+
+		results = append(results, SimilarMediaResponse{
+			MediaID:      row.ID,
+			AlbumID:      row.AlbumID,
+			AlbumTitle:   row.AlbumTitle,
+			ThumbnailURL: thumbnailURL,
+			Size:         row.Size,
+			Distance:     row.Distance,
+			Similarity:   1 - float64(row.Distance)/64,
+			CreatedAt:    row.CreatedAt,
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"media_id":     media.ID,
+		"max_distance": maxDistance,
+		"results":      results,
+	})
+}
+
+// groupNearDuplicates mengelompokkan gambar dengan jarak Hamming <= maxDistance (union-find),
+// sehingga A~B dan B~C masuk satu grup walau A dan C sedikit lebih jauh.
+func groupNearDuplicates(rows []hashedMediaRow, maxDistance int) [][]hashedMediaRow {
+	parent := make([]int, len(rows))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	for i := range rows {
+		for j := i + 1; j < len(rows); j++ {
+			if utils.HammingDistance(rows[i].PerceptualHash, rows[j].PerceptualHash) <= maxDistance {
+				if a, b := find(i), find(j); a != b {
+					parent[b] = a
+				}
+			}
+		}
+	}
+
+	members := map[int][]hashedMediaRow{}
+	for i, row := range rows {
+		root := find(i)
+		members[root] = append(members[root], row)
+	}
+
+	groups := [][]hashedMediaRow{}
+	for _, group := range members {
+		if len(group) > 1 {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+// GetDuplicateMediaReport mengelompokkan gambar yang hampir identik di semua album milik user
+// (?max_distance=, default 4) beserta perkiraan kuota yang bisa dikosongkan per grup.
+func GetDuplicateMediaReport(ctx *fiber.Ctx, db *gorm.DB) error {
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	maxDistance := parseMaxDistance(ctx.Query("max_distance"), duplicateDefaultDistance)
+	limit := utils.ParseLimit(ctx.Query("limit"), 50, 200)
+
+	var rows []hashedMediaRow
+	if err := ownHashedImages(db, userID).Order("media.created_at ASC, media.id ASC").Scan(&rows).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil gambar"})
+	}
+
+	var pending int64
+	if err := db.Table("media").
+		Joins("JOIN albums ON albums.id = media.album_id AND albums.deleted_at IS NULL").
+		Where("albums.user_id = ? AND media.kind = ? AND media.perceptual_hash IS NULL AND media.deleted_at IS NULL", userID, models.MediaKindImage).
+		Count(&pending).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menghitung gambar"})
+	}
+
+	groups := []DuplicateGroup{}
+	var totalReclaimable float32
+	for _, members := range groupNearDuplicates(rows, maxDistance) {
+		// Urutan rows sudah dari yang paling lama, media pertama disarankan untuk dipertahankan
+		group := DuplicateGroup{Media: []DuplicateMedia{}}
+		for i, row := range members {
+			thumbnailURL, err := mediaThumbnail(row)
+			if err != nil {
+				return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal generate presigned URL"})
+			}
+
+			keep := i == 0
+			if !keep && !row.IsReference {
+				group.ReclaimableMB += row.Size
+			}
+			group.Media = append(group.Media, DuplicateMedia{
+				MediaID:      row.ID,
+				AlbumID:      row.AlbumID,
+				AlbumTitle:   row.AlbumTitle,
+				ThumbnailURL: thumbnailURL,
+				Size:         row.Size,
+				IsReference:  row.IsReference,
+				Keep:         keep,
+				CreatedAt:    row.CreatedAt,
+			})
+		}
+		totalReclaimable += group.ReclaimableMB
+		groups = append(groups, group)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].ReclaimableMB != groups[j].ReclaimableMB {
+			return groups[i].ReclaimableMB > groups[j].ReclaimableMB
+		}
+		return len(groups[i].Media) > len(groups[j].Media)
+	})
+
+	totalGroups := len(groups)
+	if len(groups) > limit {
+		groups = groups[:limit]
+	}
+
+	return ctx.JSON(fiber.Map{
+		"groups":               groups,
+		"total_groups":         totalGroups,
+		"total_reclaimable_mb": totalReclaimable,
+		"scanned_images":       len(rows),
+		"pending_images":       pending, // gambar yang belum di-hash, belum ikut dibandingkan
+		"max_distance":         maxDistance,
+	})
+}
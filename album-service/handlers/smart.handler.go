@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/Zackly23/queue-app/models"
+	"github.com/Zackly23/queue-app/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	smartFilterDateLayout = "2006-01-02"
+	maxSmartAlbumMedia    = 1000 // GetAlbum memuat seluruh isi album sekaligus
+	maxSmartFilterTags    = 20
+)
+
+type SmartAlbumSummary struct {
+	MediaCount int
+	ImageCount int
+	VideoCount int
+	CoverURL   string // URL S3 belum di-presign
+}
+
+// parseSmartFilter memvalidasi smart_filter (JSON) dan menyimpan tag dalam bentuk nama tag utama.
+func parseSmartFilter(db *gorm.DB, raw string) (json.RawMessage, error) {
+	var filter models.SmartAlbumFilter
+	if raw != "" {
+		if err := json.Unmarshal([]byte(raw), &filter); err != nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "smart_filter tidak valid")
+		}
+	}
+
+	if len(filter.Tags) > maxSmartFilterTags {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Maksimal 20 tag pada smart album")
+	}
+	tags := []string{}
+	for _, name := range utils.NormalizeTags(filter.Tags) {
+		// Alias diarahkan ke tag utama, tag yang belum ada tetap disimpan agar cocok bila dibuat nanti
+		if tag, err := utils.FindTag(db, name); err == nil {
+			name = tag.TagName
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		tags = append(tags, name)
+	}
+	filter.Tags = utils.NormalizeTags(tags)
+
+	var from, to time.Time
+	var err error
+	if filter.DateFrom != "" {
+		if from, err = time.Parse(smartFilterDateLayout, filter.DateFrom); err != nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "date_from harus berformat YYYY-MM-DD")
+		}
+	}
+	if filter.DateTo != "" {
+		if to, err = time.Parse(smartFilterDateLayout, filter.DateTo); err != nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "date_to harus berformat YYYY-MM-DD")
+		}
+	}
+	if filter.DateFrom != "" && filter.DateTo != "" && to.Before(from) {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "date_to tidak boleh sebelum date_from")
+	}
+
+	for _, kind := range filter.Kinds {
+		if _, ok := models.LookupMediaKind(kind); !ok {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Jenis media tidak dikenal: "+kind)
+		}
+	}
+
+	if filter.OwnerID != nil {
+		var owner models.User
+		if err := db.Select("id").First(&owner, "id = ?", *filter.OwnerID).Error; err != nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "owner_id tidak ditemukan")
+		}
+	}
+
+	return json.Marshal(filter)
+}
+
+// smartAlbumMedia adalah media yang memenuhi filter smart album dan boleh dilihat viewer.
+// Tanpa owner_id isi dibatasi ke media pemilik smart album, kecuali liked_by_me yang
+// mencakup media siapa pun yang diberi reaksi oleh pemilik smart album.
+func smartAlbumMedia(db *gorm.DB, album models.Album, viewerID uuid.UUID) (*gorm.DB, error) {
+	var filter models.SmartAlbumFilter
+	if len(album.SmartFilter) > 0 {
+		if err := json.Unmarshal(album.SmartFilter, &filter); err != nil {
+			return nil, err
+		}
+	}
+
+	query := db.Table("media").
+		Joins("JOIN albums ON albums.id = media.album_id").
+		Where("media.deleted_at IS NULL").
+		Where("media.moderation_status = ? OR albums.user_id = ?", models.ModerationStatusVisible, viewerID)
+	query = searchableAlbums(query, searchFilter{ViewerID: viewerID, AsOf: time.Now()})
+
+	if filter.OwnerID != nil {
+		query = query.Where("albums.user_id = ?", *filter.OwnerID)
+	} else if !filter.LikedByMe {
+		query = query.Where("albums.user_id = ?", album.UserID)
+	}
+
+	if filter.LikedByMe {
+		query = query.Where("EXISTS (SELECT 1 FROM media_reactions mr WHERE mr.media_id = media.id AND mr.user_id = ?)", album.UserID)
+	}
+
+	if len(filter.Tags) > 0 {
+		query = query.Where(`EXISTS (SELECT 1 FROM album_album_tags sat JOIN album_tags st ON st.id = sat.album_tag_id
+			WHERE sat.album_id = albums.id AND st.deleted_at IS NULL
+			AND (st.tag_name IN ? OR st.id IN (SELECT tag_id FROM tag_aliases WHERE alias IN ?)))`, filter.Tags, filter.Tags)
+	}
+
+	if filter.DateFrom != "" {
+		if from, err := time.Parse(smartFilterDateLayout, filter.DateFrom); err == nil {
+			query = query.Where(takenAtSQL+" >= ?", from)
+		}
+	}
+	if filter.DateTo != "" {
+		if to, err := time.Parse(smartFilterDateLayout, filter.DateTo); err == nil {
+			query = query.Where(takenAtSQL+" < ?", to.AddDate(0, 0, 1))
+		}
+	}
+
+	if len(filter.Kinds) > 0 {
+		query = query.Where("media.kind IN ?", filter.Kinds)
+	}
+
+	return query, nil
+}
+
+// loadSmartAlbumMedia mengambil isi smart album, terbaru berdasarkan waktu pengambilan.
+func loadSmartAlbumMedia(db *gorm.DB, album models.Album, viewerID uuid.UUID) ([]models.Media, error) {
+	query, err := smartAlbumMedia(db, album, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	var medias []models.Media
+	err = query.Select("media.*").
+		Order(takenAtSQL + " DESC, media.id DESC").
+		Limit(maxSmartAlbumMedia).
+		Find(&medias).Error
+	return medias, err
+}
+
+// smartAlbumSummary menghitung jumlah media dan cover (media terbaru) smart album.
+func smartAlbumSummary(db *gorm.DB, album models.Album, viewerID uuid.UUID) (SmartAlbumSummary, error) {
+	var summary SmartAlbumSummary
+	query, err := smartAlbumMedia(db, album, viewerID)
+	if err != nil {
+		return summary, err
+	}
+
+	var counts []struct {
+		Kind  string
+		Total int
+	}
+	if err := query.Session(&gorm.Session{}).Select("media.kind, COUNT(*) AS total").Group("media.kind").Scan(&counts).Error; err != nil {
+		return summary, err
+	}
+
+	for _, count := range counts {
+		summary.MediaCount += count.Total
+		switch count.Kind {
+		case models.MediaKindImage:
+			summary.ImageCount = count.Total
+		case models.MediaKindVideo:
+			summary.VideoCount = count.Total
+		}
+	}
+
+	var cover []models.Media
+	if err := query.Select("media.*").Order(takenAtSQL + " DESC, media.id DESC").Limit(1).Find(&cover).Error; err != nil {
+		return summary, err
+	}
+	if len(cover) > 0 {
+		summary.CoverURL = cover[0].CoverURL()
+	}
+
+	return summary, nil
+}
+
+// rejectSmartAlbumUpload dipakai sebelum menambah media ke album, smart album tidak punya media sendiri.
+func rejectSmartAlbumUpload(album models.Album) error {
+	if album.AlbumType == models.AlbumTypeSmart {
+		return fiber.NewError(fiber.StatusBadRequest, "Media tidak bisa ditambahkan ke smart album")
+	}
+	return nil
+}
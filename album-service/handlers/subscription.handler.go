@@ -0,0 +1,322 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/Zackly23/queue-app/models"
+	"github.com/Zackly23/queue-app/payments"
+	"github.com/Zackly23/queue-app/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type SubscriptionChangeRequest struct {
+	SubscriptionID uuid.UUID `json:"subscription_id"` // ID versi plan tujuan
+	PaymentMethod  string    `json:"payment_method"`
+}
+
+// SubscriptionQuote adalah rincian biaya perubahan plan.
+type SubscriptionQuote struct {
+	Plan        PlanResponse `json:"plan"`
+	ChangeType  string       `json:"change_type"`  // new, upgrade, downgrade
+	EffectiveAt time.Time    `json:"effective_at"` // downgrade berlaku di akhir periode berjalan
+	PeriodEnd   time.Time    `json:"period_end"`
+	Price       float64      `json:"price"`
+	Credit      float64      `json:"credit"` // nilai sisa periode berjalan
+	AmountDue   float64      `json:"amount_due"`
+	Currency    string       `json:"currency"`
+}
+
+func roundMoney(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// currentUserSubscription adalah periode langganan user yang sedang berjalan, nil bila tidak ada.
+func currentUserSubscription(db *gorm.DB, userID uuid.UUID, now time.Time) (*models.UserSubscription, error) {
+	var current models.UserSubscription
+	err := db.Preload("Subscription", func(tx *gorm.DB) *gorm.DB {
+		return tx.Unscoped()
+	}).
+		Where("user_id = ? AND status IN ? AND start_date <= ? AND end_date > ?",
+			userID, []string{models.UserSubscriptionActive, models.UserSubscriptionFreeTier}, now, now).
+		Order("start_date DESC").
+		First(&current).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &current, nil
+}
+
+// quoteSubscriptionChange menghitung biaya pindah ke plan lain. Upgrade (harga per bulan lebih
+// tinggi) berlaku sekarang dengan periode baru, dipotong credit sisa periode berjalan yang dinilai
+// dengan harga versi plan saat ini. Credit yang melebihi harga plan baru memperpanjang periode.
+// Downgrade dijadwalkan di akhir periode berjalan dan ditagih penuh saat mulai berlaku.
+func quoteSubscriptionChange(current *models.UserSubscription, plan models.Subscription, now time.Time) SubscriptionQuote {
+	quote := SubscriptionQuote{
+		Plan:        planResponse(plan),
+		ChangeType:  models.SubscriptionChangeNew,
+		EffectiveAt: now,
+		PeriodEnd:   plan.PeriodEnd(now),
+		Price:       plan.Price,
+		AmountDue:   roundMoney(plan.Price),
+		Currency:    plan.Currency,
+	}
+	if current == nil {
+		return quote
+	}
+
+	currentPlan := current.Subscription
+	isUpgrade := plan.MonthlyPrice() > currentPlan.MonthlyPrice() ||
+		(plan.MonthlyPrice() == currentPlan.MonthlyPrice() && plan.StorageCapacity > currentPlan.StorageCapacity)
+
+	if !isUpgrade {
+		quote.ChangeType = models.SubscriptionChangeDowngrade
+		quote.EffectiveAt = current.EndDate
+		quote.PeriodEnd = plan.PeriodEnd(current.EndDate)
+		return quote
+	}
+
+	quote.ChangeType = models.SubscriptionChangeUpgrade
+	if currentPlan.Currency == plan.Currency {
+		total := current.EndDate.Sub(current.StartDate)
+		remaining := current.EndDate.Sub(now)
+		if total > 0 && remaining > 0 {
+			quote.Credit = roundMoney(currentPlan.Price * remaining.Seconds() / total.Seconds())
+		}
+	}
+
+	quote.AmountDue = roundMoney(math.Max(0, plan.Price-quote.Credit))
+	if leftover := quote.Credit - plan.Price; leftover > 0 && plan.Price > 0 {
+		period := quote.PeriodEnd.Sub(now)
+		quote.PeriodEnd = quote.PeriodEnd.Add(time.Duration(float64(period) * leftover / plan.Price))
+	}
+	return quote
+}
+
+// prepareSubscriptionChange memvalidasi plan tujuan dan menghitung biayanya.
+func prepareSubscriptionChange(db *gorm.DB, userID uuid.UUID, planID uuid.UUID, now time.Time) (SubscriptionQuote, *models.UserSubscription, models.Subscription, error) {
+	var plan models.Subscription
+	if err := db.First(&plan, "id = ? AND is_active = ?", planID, true).Error; err != nil {
+		return SubscriptionQuote{}, nil, plan, fiber.NewError(fiber.StatusNotFound, "Plan tidak ditemukan atau tidak lagi ditawarkan")
+	}
+
+	current, err := currentUserSubscription(db, userID, now)
+	if err != nil {
+		return SubscriptionQuote{}, nil, plan, err
+	}
+	if current != nil && current.SubscriptionID == plan.ID {
+		return SubscriptionQuote{}, nil, plan, fiber.NewError(fiber.StatusBadRequest, "Plan tersebut sedang dipakai")
+	}
+
+	// Storage yang sudah terpakai harus muat di plan tujuan
+	storageUsed, err := utils.CalculateStorageUsed(db, userID)
+	if err != nil {
+		return SubscriptionQuote{}, nil, plan, err
+	}
+	if storageUsed > plan.StorageCapacity*1024 {
+		return SubscriptionQuote{}, nil, plan, fiber.NewError(fiber.StatusConflict,
+			fmt.Sprintf("Storage terpakai %.2f GB melebihi kapasitas plan %.2f GB", storageUsed/1024, plan.StorageCapacity))
+	}
+
+	return quoteSubscriptionChange(current, plan, now), current, plan, nil
+}
+
+// PreviewSubscriptionChange menampilkan rincian biaya pindah plan (?subscription_id=) tanpa menyimpan.
+func PreviewSubscriptionChange(ctx *fiber.Ctx, db *gorm.DB) error {
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	planID, err := uuid.Parse(ctx.Query("subscription_id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "subscription_id tidak valid"})
+	}
+
+	quote, _, _, err := prepareSubscriptionChange(db, userID, planID, time.Now())
+	if err != nil {
+		return fiberErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{"quote": quote})
+}
+
+// ChangeSubscription memindahkan user ke plan lain. Upgrade langsung berlaku dengan biaya prorata,
+// downgrade dijadwalkan di akhir periode berjalan. Permintaan baru menggantikan jadwal sebelumnya.
+// Upgrade yang masih harus dibayar menunggu notifikasi pembayaran dari provider (lihat
+// HandlePaymentWebhook) dan respons berisi link checkout.
+func ChangeSubscription(ctx *fiber.Ctx, db *gorm.DB) error {
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req SubscriptionChangeRequest
+	if err := ctx.BodyParser(&req); err != nil || req.SubscriptionID == uuid.Nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "subscription_id wajib diisi",
+		})
+	}
+
+	now := time.Now()
+	quote, current, plan, err := prepareSubscriptionChange(db, userID, req.SubscriptionID, now)
+	if err != nil {
+		return fiberErrorResponse(ctx, err)
+	}
+
+	needsPayment := quote.ChangeType != models.SubscriptionChangeDowngrade && quote.AmountDue > 0
+	var gateway payments.Gateway
+	if needsPayment {
+		if gateway, err = payments.Default(); err != nil {
+			return ctx.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": "Pembayaran belum tersedia",
+			})
+		}
+	}
+
+	record := models.UserSubscription{
+		UserID:         userID,
+		SubscriptionID: plan.ID,
+		PaymentMethod:  req.PaymentMethod,
+		StartDate:      quote.EffectiveAt,
+		EndDate:        quote.PeriodEnd,
+		Amount:         float32(quote.AmountDue),
+		Currency:       quote.Currency,
+		Credit:         float32(quote.Credit),
+		ChangeType:     quote.ChangeType,
+	}
+	if current != nil {
+		record.PreviousID = &current.ID
+	}
+	var payment models.Payment
+	var superseded []models.Payment // checkout lama yang ditutup di provider setelah commit
+
+	errTx := db.Transaction(func(tx *gorm.DB) error {
+		// Jadwal dan checkout sebelumnya digantikan permintaan ini
+		if err := tx.Model(&models.UserSubscription{}).
+			Where("user_id = ? AND status IN ?", userID,
+				[]string{models.UserSubscriptionScheduled, models.UserSubscriptionPendingPayment}).
+			Update("status", models.UserSubscriptionCancelled).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ? AND status = ?", userID, models.PaymentStatusPending).
+			Find(&superseded).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Payment{}).
+			Where("user_id = ? AND status = ?", userID, models.PaymentStatusPending).
+			Update("status", models.PaymentStatusExpired).Error; err != nil {
+			return err
+		}
+
+		if quote.ChangeType == models.SubscriptionChangeDowngrade {
+			record.Status = models.UserSubscriptionScheduled
+			return tx.Create(&record).Error
+		}
+
+		if needsPayment {
+			record.Status = models.UserSubscriptionPendingPayment
+			if err := tx.Create(&record).Error; err != nil {
+				return err
+			}
+			payment = models.Payment{
+				UserID:             userID,
+				UserSubscriptionID: record.ID,
+				Provider:           gateway.Name(),
+				OrderID:            utils.NewPaymentOrderID(),
+				Amount:             quote.AmountDue,
+				Currency:           quote.Currency,
+				Status:             models.PaymentStatusPending,
+			}
+			if quote.Credit > 0 {
+				expiresAt := now.Add(utils.UpgradeCheckoutTTL)
+				payment.ExpiresAt = &expiresAt
+			}
+			return tx.Create(&payment).Error
+		}
+
+		if current != nil {
+			if err := tx.Model(&models.UserSubscription{}).Where("id = ?", current.ID).Updates(map[string]interface{}{
+				"status":   models.UserSubscriptionExpired,
+				"end_date": now,
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		record.Status = models.UserSubscriptionActive
+		if err := tx.Create(&record).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.User{}).Where("id = ?", userID).Update("subscription_id", plan.ID).Error
+	})
+	if errTx != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Gagal mengubah subscription",
+		})
+	}
+	if len(superseded) > 0 {
+		go utils.CancelCheckouts(superseded)
+	}
+
+	if needsPayment {
+		if err := utils.OpenCheckout(ctx.UserContext(), db, gateway, &payment, plan); err != nil {
+			log.Printf("Checkout %s gagal: %v", payment.OrderID, err)
+			db.Model(&record).Update("status", models.UserSubscriptionCancelled)
+			return ctx.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+				"error": "Gagal membuat checkout pembayaran",
+			})
+		}
+
+		payment.UserSubscription = record
+		payment.UserSubscription.Subscription = plan
+		return ctx.Status(fiber.StatusAccepted).JSON(fiber.Map{
+			"message":           "Selesaikan pembayaran untuk mengaktifkan plan",
+			"quote":             quote,
+			"user_subscription": record,
+			"payment":           paymentResponse(payment),
+			"checkout_url":      payment.CheckoutURL,
+		})
+	}
+
+	message := "Subscription updated successfully"
+	if quote.ChangeType == models.SubscriptionChangeDowngrade {
+		message = "Downgrade dijadwalkan di akhir periode berjalan"
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":           message,
+		"quote":             quote,
+		"user_subscription": record,
+	})
+}
+
+// CancelScheduledSubscription membatalkan downgrade yang belum berlaku.
+func CancelScheduledSubscription(ctx *fiber.Ctx, db *gorm.DB) error {
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	result := db.Model(&models.UserSubscription{}).
+		Where("user_id = ? AND status = ?", userID, models.UserSubscriptionScheduled).
+		Update("status", models.UserSubscriptionCancelled)
+	if result.Error != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membatalkan perubahan subscription"})
+	}
+	if result.RowsAffected == 0 {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Tidak ada perubahan subscription yang dijadwalkan"})
+	}
+
+	return ctx.JSON(fiber.Map{"message": "Perubahan subscription dibatalkan"})
+}
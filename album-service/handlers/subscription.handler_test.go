@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Zackly23/queue-app/models"
+)
+
+func TestQuoteSubscriptionChange(t *testing.T) {
+	now := time.Date(2026, 5, 16, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	plan := func(price float64, interval string, storage float64, currency string) models.Subscription {
+		return models.Subscription{Price: price, BillingInterval: interval, StorageCapacity: storage, Currency: currency}
+	}
+	basic := plan(50000, models.BillingIntervalMonth, 10, "IDR")
+	pro := plan(100000, models.BillingIntervalMonth, 100, "IDR")
+
+	// current membuat periode berjalan [now-elapsed, now+remaining) untuk plan tertentu
+	current := func(p models.Subscription, elapsed, remaining time.Duration) *models.UserSubscription {
+		return &models.UserSubscription{StartDate: now.Add(-elapsed), EndDate: now.Add(remaining), Subscription: p}
+	}
+
+	tests := []struct {
+		name        string
+		current     *models.UserSubscription
+		plan        models.Subscription
+		changeType  string
+		effectiveAt time.Time
+		periodEnd   time.Time
+		credit      float64
+		amountDue   float64
+	}{
+		{
+			name:        "new subscription",
+			plan:        pro,
+			changeType:  models.SubscriptionChangeNew,
+			effectiveAt: now,
+			periodEnd:   time.Date(2026, 6, 16, 0, 0, 0, 0, time.UTC),
+			amountDue:   100000,
+		},
+		{
+			name:        "new yearly subscription",
+			plan:        plan(1000000, models.BillingIntervalYear, 100, "IDR"),
+			changeType:  models.SubscriptionChangeNew,
+			effectiveAt: now,
+			periodEnd:   time.Date(2027, 5, 16, 0, 0, 0, 0, time.UTC),
+			amountDue:   1000000,
+		},
+		{
+			name:        "upgrade halfway through period",
+			current:     current(basic, 15*day, 15*day),
+			plan:        pro,
+			changeType:  models.SubscriptionChangeUpgrade,
+			effectiveAt: now,
+			periodEnd:   time.Date(2026, 6, 16, 0, 0, 0, 0, time.UTC),
+			credit:      25000,
+			amountDue:   75000,
+		},
+		{
+			name:        "upgrade credit rounded to cents",
+			current:     current(plan(99999.99, models.BillingIntervalMonth, 10, "IDR"), 20*day, 10*day),
+			plan:        plan(150000, models.BillingIntervalMonth, 100, "IDR"),
+			changeType:  models.SubscriptionChangeUpgrade,
+			effectiveAt: now,
+			periodEnd:   time.Date(2026, 6, 16, 0, 0, 0, 0, time.UTC),
+			credit:      33333.33,
+			amountDue:   116666.67,
+		},
+		{
+			name:        "same price with more storage is an upgrade",
+			current:     current(plan(100000, models.BillingIntervalMonth, 50, "IDR"), 10*day, 20*day),
+			plan:        pro,
+			changeType:  models.SubscriptionChangeUpgrade,
+			effectiveAt: now,
+			periodEnd:   time.Date(2026, 6, 16, 0, 0, 0, 0, time.UTC),
+			credit:      66666.67,
+			amountDue:   33333.33,
+		},
+		{
+			name:        "credit above new price extends period",
+			current:     current(plan(1200000, models.BillingIntervalYear, 10, "IDR"), 10*day, 10*day),
+			plan:        plan(150000, models.BillingIntervalMonth, 100, "IDR"),
+			changeType:  models.SubscriptionChangeUpgrade,
+			effectiveAt: now,
+			// 600.000 credit: 150.000 untuk periode baru (31 hari), sisa 450.000 = 3 x 31 hari
+			periodEnd: time.Date(2026, 6, 16, 0, 0, 0, 0, time.UTC).Add(93 * day),
+			credit:    600000,
+			amountDue: 0,
+		},
+		{
+			name:        "no credit across currencies",
+			current:     current(plan(10, models.BillingIntervalMonth, 10, "USD"), 15*day, 15*day),
+			plan:        pro,
+			changeType:  models.SubscriptionChangeUpgrade,
+			effectiveAt: now,
+			periodEnd:   time.Date(2026, 6, 16, 0, 0, 0, 0, time.UTC),
+			amountDue:   100000,
+		},
+		{
+			name:        "no credit once period has ended",
+			current:     current(basic, 30*day, 0),
+			plan:        pro,
+			changeType:  models.SubscriptionChangeUpgrade,
+			effectiveAt: now,
+			periodEnd:   time.Date(2026, 6, 16, 0, 0, 0, 0, time.UTC),
+			amountDue:   100000,
+		},
+		{
+			name:        "downgrade scheduled at period end",
+			current:     current(pro, 15*day, 15*day),
+			plan:        basic,
+			changeType:  models.SubscriptionChangeDowngrade,
+			effectiveAt: now.Add(15 * day),
+			periodEnd:   now.Add(15*day).AddDate(0, 1, 0),
+			amountDue:   50000,
+		},
+		{
+			name:        "same price and storage is a downgrade",
+			current:     current(pro, 15*day, 15*day),
+			plan:        plan(100000, models.BillingIntervalMonth, 100, "IDR"),
+			changeType:  models.SubscriptionChangeDowngrade,
+			effectiveAt: now.Add(15 * day),
+			periodEnd:   now.Add(15*day).AddDate(0, 1, 0),
+			amountDue:   100000,
+		},
+		{
+			name:        "yearly plan compared by monthly price",
+			current:     current(pro, 15*day, 15*day),
+			plan:        plan(1000000, models.BillingIntervalYear, 200, "IDR"), // 83.333 per bulan
+			changeType:  models.SubscriptionChangeDowngrade,
+			effectiveAt: now.Add(15 * day),
+			periodEnd:   now.Add(15*day).AddDate(1, 0, 0),
+			amountDue:   1000000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quote := quoteSubscriptionChange(tt.current, tt.plan, now)
+
+			if quote.ChangeType != tt.changeType {
+				t.Errorf("change_type = %s, want %s", quote.ChangeType, tt.changeType)
+			}
+			if !quote.EffectiveAt.Equal(tt.effectiveAt) {
+				t.Errorf("effective_at = %v, want %v", quote.EffectiveAt, tt.effectiveAt)
+			}
+			if !quote.PeriodEnd.Equal(tt.periodEnd) {
+				t.Errorf("period_end = %v, want %v", quote.PeriodEnd, tt.periodEnd)
+			}
+			if quote.Credit != tt.credit {
+				t.Errorf("credit = %v, want %v", quote.Credit, tt.credit)
+			}
+			if quote.AmountDue != tt.amountDue {
+				t.Errorf("amount_due = %v, want %v", quote.AmountDue, tt.amountDue)
+			}
+			if quote.Price != tt.plan.Price || quote.Currency != tt.plan.Currency {
+				t.Errorf("price = %v %s, want %v %s", quote.Price, quote.Currency, tt.plan.Price, tt.plan.Currency)
+			}
+		})
+	}
+}
@@ -0,0 +1,316 @@
+package handlers
+
+import (
+	"strings"
+	"time"
+
+	"github.com/Zackly23/queue-app/models"
+	"github.com/Zackly23/queue-app/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type TagUsage struct {
+	TagID      uuid.UUID `json:"tag_id"`
+	TagName    string    `json:"tag_name"`
+	UsageCount int64     `json:"usage_count"` // jumlah album yang boleh dilihat user
+}
+
+type UserTagStat struct {
+	TagID      uuid.UUID `json:"tag_id"`
+	TagName    string    `json:"tag_name"`
+	AlbumCount int64     `json:"album_count"`
+	MediaCount int64     `json:"media_count"`
+	ViewCount  int64     `json:"view_count"`
+	LastUsedAt time.Time `json:"last_used_at"`
+}
+
+type TagAlbumResponse struct {
+	AlbumID      uuid.UUID  `json:"album_id"`
+	Title        string     `json:"title"`
+	Description  string     `json:"description"`
+	MediaCount   int        `json:"media_count"`
+	ImageCount   int        `json:"image_count"`
+	VideoCount   int        `json:"video_count"`
+	ThumbnailURL string     `json:"thumbnail_url"`
+	LikesCount   uint       `json:"likes_count"`
+	LastUpdate   string     `json:"last_update"`
+	UserDetail   UserDetail `json:"user_detail"`
+}
+
+// escapeLike meng-escape karakter wildcard LIKE; "_" termasuk karakter yang valid di tag.
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
+
+// AutocompleteTags mencari tag berdasarkan awalan nama atau alias (?q=), diurutkan dari yang
+// paling banyak dipakai di album yang boleh dilihat user.
+func AutocompleteTags(ctx *fiber.Ctx, db *gorm.DB) error {
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	prefix := utils.NormalizeTag(ctx.Query("q"))
+	if prefix == "" {
+		return ctx.JSON(fiber.Map{"tags": []TagUsage{}})
+	}
+	limit := utils.ParseLimit(ctx.Query("limit"), 10, 50)
+	pattern := escapeLike(prefix) + "%"
+
+	albums := searchableAlbums(db.Table("albums").Select("albums.id"), searchFilter{ViewerID: userID, AsOf: time.Now()})
+
+	tags := []TagUsage{}
+	if err := db.Table("album_tags").
+		Select("album_tags.id AS tag_id, album_tags.tag_name, COUNT(aat.album_id) AS usage_count").
+		Joins("JOIN album_album_tags aat ON aat.album_tag_id = album_tags.id AND aat.album_id IN (?)", albums).
+		Where("album_tags.deleted_at IS NULL").
+		Where("album_tags.tag_name LIKE ? OR EXISTS (SELECT 1 FROM tag_aliases ta WHERE ta.tag_id = album_tags.id AND ta.alias LIKE ?)", pattern, pattern).
+		Group("album_tags.id, album_tags.tag_name").
+		Order("usage_count DESC, album_tags.tag_name ASC").
+		Limit(limit).
+		Scan(&tags).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil tag"})
+	}
+
+	return ctx.JSON(fiber.Map{"tags": tags})
+}
+
+// GetAlbumsByTag mengambil album yang memiliki tag tertentu (terbaru dulu). Nama tag boleh berupa
+// alias, tag utamanya dikembalikan di field tag agar client bisa mengarahkan ke URL yang benar.
+func GetAlbumsByTag(ctx *fiber.Ctx, db *gorm.DB) error {
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	tag, err := utils.FindTag(db, ctx.Params("tagName"))
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Tag tidak ditemukan"})
+	}
+
+	limit := utils.ParseLimit(ctx.Query("limit"), 20, 50)
+	query := searchableAlbums(db.Model(&models.Album{}), searchFilter{ViewerID: userID, AsOf: time.Now()}).
+		Where("EXISTS (SELECT 1 FROM album_album_tags aat WHERE aat.album_id = albums.id AND aat.album_tag_id = ?)", tag.ID)
+
+	var total int64
+	if ctx.Query("cursor") == "" {
+		if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menghitung album"})
+		}
+	}
+
+	if cursor := ctx.Query("cursor"); cursor != "" {
+		createdAt, id, err := utils.DecodeCursor(cursor)
+		if err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		query = query.Where("(albums.created_at, albums.id) < (?, ?)", createdAt, id)
+	}
+
+	var albums []models.Album
+	if err := query.Preload("Media").Preload("User").
+		Order("albums.created_at DESC, albums.id DESC").
+		Limit(limit + 1).
+		Find(&albums).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil album"})
+	}
+
+	nextCursor := ""
+	if len(albums) > limit {
+		albums = albums[:limit]
+		last := albums[len(albums)-1]
+		nextCursor = utils.EncodeCursor(last.CreatedAt, last.ID)
+	}
+
+	now := time.Now()
+	response := []TagAlbumResponse{}
+	for _, album := range albums {
+		thumbnailURL, err := presignedOrEmpty(album.CoverImage)
+		if err != nil {
+			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal generate presigned URL"})
+		}
+
+		imageCount, videoCount := countMediaKinds(album.Media)
+		response = append(response, TagAlbumResponse{
+			AlbumID:      album.ID,
+			Title:        album.Title,
+			Description:  album.Description,
+			MediaCount:   len(album.Media),
+			ImageCount:   imageCount,
+			VideoCount:   videoCount,
+			ThumbnailURL: thumbnailURL,
+			LikesCount:   album.LikesCount,
+			LastUpdate:   lastUpdateLabel(album.UpdatedAt, now),
+			UserDetail: UserDetail{
+				UserID:    album.User.ID,
+				FirstName: album.User.FirstName,
+				LastName:  album.User.LastName,
+				FullName:  album.User.FirstName + " " + album.User.LastName,
+			},
+		})
+	}
+
+	result := fiber.Map{
+		"tag":         tag,
+		"albums":      response,
+		"next_cursor": nextCursor,
+		"has_more":    nextCursor != "",
+	}
+	if ctx.Query("cursor") == "" {
+		result["total"] = total
+	}
+	return ctx.JSON(result)
+}
+
+// GetUserTagStats mengambil statistik tag dari album milik user: jumlah album, media dan view
+// per tag. User lain hanya melihat statistik dari album yang boleh mereka lihat.
+func GetUserTagStats(ctx *fiber.Ctx, db *gorm.DB) error {
+	viewerID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	ownerID, err := uuid.Parse(ctx.Params("userId"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "User ID tidak valid"})
+	}
+
+	blocked, err := hasBlockBetween(db, viewerID, ownerID)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memeriksa status blokir"})
+	}
+	if blocked {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	albums := searchableAlbums(db.Table("albums").Select(`albums.id, albums.view_count, albums.created_at,
+		(SELECT COUNT(*) FROM media m WHERE m.album_id = albums.id AND m.deleted_at IS NULL) AS media_count`),
+		searchFilter{ViewerID: viewerID, AsOf: time.Now()}).
+		Where("albums.user_id = ?", ownerID)
+
+	stats := []UserTagStat{}
+	if err := db.Table("(?) AS a", albums).
+		Select(`t.id AS tag_id, t.tag_name, COUNT(*) AS album_count,
+			COALESCE(SUM(a.media_count), 0) AS media_count,
+			COALESCE(SUM(a.view_count), 0) AS view_count,
+			MAX(a.created_at) AS last_used_at`).
+		Joins("JOIN album_album_tags aat ON aat.album_id = a.id").
+		Joins("JOIN album_tags t ON t.id = aat.album_tag_id AND t.deleted_at IS NULL").
+		Group("t.id, t.tag_name").
+		Order("album_count DESC, t.tag_name ASC").
+		Scan(&stats).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil statistik tag"})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"user_id":    ownerID,
+		"tags":       stats,
+		"total_tags": len(stats),
+	})
+}
+
+func findTagByID(ctx *fiber.Ctx, db *gorm.DB) (models.AlbumTag, error) {
+	var tag models.AlbumTag
+	if err := db.Preload("Aliases").First(&tag, "id = ?", ctx.Params("tagId")).Error; err != nil {
+		return tag, fiber.NewError(fiber.StatusNotFound, "Tag tidak ditemukan")
+	}
+	return tag, nil
+}
+
+// MergeTag menggabungkan tag ke tag lain: semua album dan komentar pindah ke target,
+// dan nama tag lama menjadi alias dari target.
+func MergeTag(ctx *fiber.Ctx, db *gorm.DB) error {
+	var req struct {
+		TargetTagID uuid.UUID `json:"target_tag_id"`
+	}
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Format permintaan tidak valid"})
+	}
+
+	source, err := findTagByID(ctx, db)
+	if err != nil {
+		return fiberErrorResponse(ctx, err)
+	}
+	if source.ID == req.TargetTagID {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Tag tidak bisa digabung ke dirinya sendiri"})
+	}
+
+	var target models.AlbumTag
+	if err := db.First(&target, "id = ?", req.TargetTagID).Error; err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Tag tujuan tidak ditemukan"})
+	}
+
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		return utils.MergeTags(tx, source, target)
+	}); err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menggabungkan tag"})
+	}
+
+	if err := db.Preload("Aliases").First(&target, "id = ?", target.ID).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil tag"})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"message": "Tag berhasil digabung",
+		"data":    target,
+	})
+}
+
+// AddTagAlias menambahkan alias untuk tag, sehingga input dengan nama alias masuk ke tag tersebut.
+func AddTagAlias(ctx *fiber.Ctx, db *gorm.DB) error {
+	var req struct {
+		Alias string `json:"alias"`
+	}
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Format permintaan tidak valid"})
+	}
+
+	alias := utils.NormalizeTag(req.Alias)
+	if alias == "" {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Alias tidak valid"})
+	}
+
+	tag, err := findTagByID(ctx, db)
+	if err != nil {
+		return fiberErrorResponse(ctx, err)
+	}
+
+	// Alias tidak boleh sama dengan tag yang masih dipakai, gunakan merge untuk itu
+	existing, err := utils.FindTag(db, alias)
+	if err == nil {
+		if existing.ID == tag.ID {
+			return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Alias sudah mengarah ke tag ini"})
+		}
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error":  "Nama tersebut sudah dipakai tag lain, gunakan merge",
+			"tag_id": existing.ID,
+		})
+	}
+	if err != gorm.ErrRecordNotFound {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memeriksa alias"})
+	}
+
+	tagAlias := models.TagAlias{Alias: alias, TagID: tag.ID}
+	if err := db.Create(&tagAlias).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyimpan alias"})
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Alias berhasil ditambahkan",
+		"data":    tagAlias,
+	})
+}
+
+func DeleteTagAlias(ctx *fiber.Ctx, db *gorm.DB) error {
+	result := db.Where("alias = ?", utils.NormalizeTag(ctx.Params("alias"))).Delete(&models.TagAlias{})
+	if result.Error != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menghapus alias"})
+	}
+	if result.RowsAffected == 0 {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Alias tidak ditemukan"})
+	}
+
+	return ctx.JSON(fiber.Map{"message": "Alias berhasil dihapus"})
+}
@@ -0,0 +1,276 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/Zackly23/queue-app/models"
+	"github.com/Zackly23/queue-app/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type TrashedAlbumResponse struct {
+	AlbumID    uuid.UUID `json:"album_id"`
+	Title      string    `json:"title"`
+	MediaCount int       `json:"media_count"`
+	Size       float32   `json:"size"`
+	TrashedAt  time.Time `json:"trashed_at"`
+	PurgeAt    time.Time `json:"purge_at"`
+}
+
+type TrashedMediaResponse struct {
+	MediaID     uuid.UUID `json:"media_id"`
+	AlbumID     uuid.UUID `json:"album_id"`
+	AlbumTitle  string    `json:"album_title"`
+	MediaType   string    `json:"media_type"` // kind media: "image", "video", ...
+	Description string    `json:"description"`
+	Size        float32   `json:"size"`
+	TrashedAt   time.Time `json:"trashed_at"`
+	PurgeAt     time.Time `json:"purge_at"`
+}
+
+// findTrashedAlbum mengambil album milik user yang sedang berada di trash.
+func findTrashedAlbum(ctx *fiber.Ctx, db *gorm.DB) (models.Album, error) {
+	var album models.Album
+
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return album, fiber.NewError(fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	albumID, err := uuid.Parse(ctx.Params("albumId"))
+	if err != nil {
+		return album, fiber.NewError(fiber.StatusBadRequest, "Album ID tidak valid")
+	}
+
+	if err := db.Unscoped().
+		Where("id = ? AND user_id = ? AND deleted_at IS NOT NULL", albumID, userID).
+		First(&album).Error; err != nil {
+		return album, fiber.NewError(fiber.StatusNotFound, "Album tidak ditemukan di trash")
+	}
+
+	return album, nil
+}
+
+// findTrashedMedia mengambil media di trash yang albumnya milik user.
+func findTrashedMedia(ctx *fiber.Ctx, db *gorm.DB) (models.Media, models.Album, error) {
+	var media models.Media
+	var album models.Album
+
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return media, album, fiber.NewError(fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	mediaID, err := uuid.Parse(ctx.Params("mediaId"))
+	if err != nil {
+		return media, album, fiber.NewError(fiber.StatusBadRequest, "Media ID tidak valid")
+	}
+
+	if err := db.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", mediaID).First(&media).Error; err != nil {
+		return media, album, fiber.NewError(fiber.StatusNotFound, "Media tidak ditemukan di trash")
+	}
+
+	if err := db.Unscoped().Where("id = ? AND user_id = ?", media.AlbumID, userID).First(&album).Error; err != nil {
+		return media, album, fiber.NewError(fiber.StatusNotFound, "Media tidak ditemukan di trash")
+	}
+
+	return media, album, nil
+}
+
+func GetTrash(ctx *fiber.Ctx, db *gorm.DB) error {
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	retention := utils.TrashRetention()
+
+	var trashedAlbums []models.Album
+	if err := db.Unscoped().
+		Where("user_id = ? AND deleted_at IS NOT NULL", userID).
+		Order("deleted_at DESC").
+		Find(&trashedAlbums).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil album di trash"})
+	}
+
+	trashedIDs := make([]uuid.UUID, 0, len(trashedAlbums))
+	for _, album := range trashedAlbums {
+		trashedIDs = append(trashedIDs, album.ID)
+	}
+
+	var trashedAlbumMedias []models.Media
+	if len(trashedIDs) > 0 {
+		if err := db.Unscoped().Where("album_id IN ? AND deleted_at IS NOT NULL", trashedIDs).Find(&trashedAlbumMedias).Error; err != nil {
+			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil media album di trash"})
+		}
+	}
+	albumMedias := map[uuid.UUID][]models.Media{}
+	for _, media := range trashedAlbumMedias {
+		albumMedias[media.AlbumID] = append(albumMedias[media.AlbumID], media)
+	}
+
+	albums := []TrashedAlbumResponse{}
+	for _, album := range trashedAlbums {
+		// Hanya media yang ikut ter-trash bersama album, media yang dihapus lebih dulu tidak dihitung
+		var count int
+		var size float32
+		for _, media := range albumMedias[album.ID] {
+			if media.DeletedAt.Time.Before(album.DeletedAt.Time) {
+				continue
+			}
+			count++
+			size += media.Size
+		}
+
+		albums = append(albums, TrashedAlbumResponse{
+			AlbumID:    album.ID,
+			Title:      album.Title,
+			MediaCount: count,
+			Size:       size,
+			TrashedAt:  album.DeletedAt.Time,
+			PurgeAt:    album.DeletedAt.Time.Add(retention),
+		})
+	}
+
+	// Media yang dihapus satu per satu dari album yang masih aktif
+	var activeAlbums []models.Album
+	if err := db.Select("id", "title").Where("user_id = ?", userID).Find(&activeAlbums).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil album user"})
+	}
+
+	activeTitles := make(map[uuid.UUID]string, len(activeAlbums))
+	activeIDs := make([]uuid.UUID, 0, len(activeAlbums))
+	for _, album := range activeAlbums {
+		activeTitles[album.ID] = album.Title
+		activeIDs = append(activeIDs, album.ID)
+	}
+
+	var trashedMedias []models.Media
+	if len(activeIDs) > 0 {
+		if err := db.Unscoped().
+			Where("album_id IN ? AND deleted_at IS NOT NULL", activeIDs).
+			Order("deleted_at DESC").
+			Find(&trashedMedias).Error; err != nil {
+			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil media di trash"})
+		}
+	}
+
+	medias := []TrashedMediaResponse{}
+	for _, media := range trashedMedias {
+		medias = append(medias, TrashedMediaResponse{
+			MediaID:     media.ID,
+			AlbumID:     media.AlbumID,
+			AlbumTitle:  activeTitles[media.AlbumID],
+			MediaType:   media.Kind,
+			Description: media.Description,
+			Size:        media.Size,
+			TrashedAt:   media.DeletedAt.Time,
+			PurgeAt:     media.DeletedAt.Time.Add(retention),
+		})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":          "Trash berhasil diambil",
+		"albums":           albums,
+		"album_medias":     medias,
+		"retention_days":   int(retention.Hours() / 24),
+		"quota_grace_days": int(utils.TrashQuotaGrace().Hours() / 24),
+	})
+}
+
+func RestoreAlbum(ctx *fiber.Ctx, db *gorm.DB) error {
+	album, err := findTrashedAlbum(ctx, db)
+	if err != nil {
+		return fiberErrorResponse(ctx, err)
+	}
+
+	if album.ModerationStatus == models.ModerationStatusRemoved {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Album dihapus oleh moderator dan tidak bisa dikembalikan"})
+	}
+
+	trashedAt := album.DeletedAt.Time
+
+	errRestore := db.Transaction(func(tx *gorm.DB) error {
+		// Hanya media yang ikut ter-trash bersama album yang dikembalikan
+		if err := tx.Unscoped().Model(&models.Media{}).
+			Where("album_id = ? AND deleted_at >= ?", album.ID, trashedAt).
+			Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+
+		return tx.Unscoped().Model(&album).Update("deleted_at", nil).Error
+	})
+
+	if errRestore != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengembalikan album"})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":  "Album berhasil dikembalikan",
+		"album_id": album.ID,
+	})
+}
+
+func PurgeTrashedAlbum(ctx *fiber.Ctx, db *gorm.DB) error {
+	album, err := findTrashedAlbum(ctx, db)
+	if err != nil {
+		return fiberErrorResponse(ctx, err)
+	}
+
+	if err := utils.PurgeAlbum(db, album); err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Gagal menghapus album secara permanen",
+		})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Album berhasil dihapus permanen",
+	})
+}
+
+func RestoreMedia(ctx *fiber.Ctx, db *gorm.DB) error {
+	media, album, err := findTrashedMedia(ctx, db)
+	if err != nil {
+		return fiberErrorResponse(ctx, err)
+	}
+
+	if album.DeletedAt.Valid {
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Album media ini ada di trash, kembalikan albumnya terlebih dahulu",
+		})
+	}
+
+	if media.ModerationStatus == models.ModerationStatusRemoved {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Media dihapus oleh moderator dan tidak bisa dikembalikan"})
+	}
+
+	if err := db.Unscoped().Model(&media).Update("deleted_at", nil).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengembalikan media"})
+	}
+
+	// Media yang di-restore bisa jadi cover pilihan pemilik atau media pertama album
+	if err := refreshAlbumCover(db, media.AlbumID); err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal update cover album"})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Media berhasil dikembalikan",
+	})
+}
+
+func PurgeTrashedMedia(ctx *fiber.Ctx, db *gorm.DB) error {
+	media, _, err := findTrashedMedia(ctx, db)
+	if err != nil {
+		return fiberErrorResponse(ctx, err)
+	}
+
+	if err := utils.PurgeMedia(db, media); err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menghapus media secara permanen"})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Media berhasil dihapus permanen",
+	})
+}
@@ -0,0 +1,355 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/Zackly23/queue-app/models"
+	"github.com/Zackly23/queue-app/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type AlbumBreadcrumb struct {
+	AlbumID      uuid.UUID `json:"album_id"`
+	Title        string    `json:"title"`
+	AlbumPrivacy string    `json:"album_privacy"`
+}
+
+type ChildAlbumResponse struct {
+	AlbumID          uuid.UUID `json:"album_id"`
+	Title            string    `json:"title"`
+	Description      string    `json:"description"`
+	AlbumPrivacy     string    `json:"album_privacy"`
+	EffectivePrivacy string    `json:"effective_privacy"`
+	ChildCount       int64     `json:"child_count"`
+	UpdatedAt        string    `json:"updated_at"`
+}
+
+// visibleAncestorsCondition memastikan tidak ada ancestor album yang private, disembunyikan moderator,
+// atau restricted tanpa undangan yang diterima user (privacy diwariskan dari parent ke child).
+const visibleAncestorsCondition = `NOT EXISTS (SELECT 1 FROM albums anc WHERE anc.deleted_at IS NULL AND anc.path <> '' AND anc.id <> albums.id AND albums.path LIKE anc.path || '%' AND (anc.album_privacy = 'private' OR anc.moderation_status <> 'visible' OR (anc.album_privacy = 'restricted' AND NOT EXISTS (SELECT 1 FROM album_invitations ai WHERE ai.album_id = anc.id AND ai.user_id = ? AND ai.status = 'accepted' AND ai.deleted_at IS NULL))))`
+
+var privacyRank = map[string]int{
+	"public":     0,
+	"restricted": 1,
+	"private":    2,
+}
+
+// albumPath membentuk materialized path album berdasarkan path parent-nya.
+func albumPath(parent *models.Album, albumID uuid.UUID) string {
+	return utils.AlbumPath(parent, albumID)
+}
+
+// albumPathIDs mengembalikan ID album dari root sampai album itu sendiri.
+func albumPathIDs(album models.Album) []uuid.UUID {
+	ids := []uuid.UUID{}
+	for _, part := range strings.Split(strings.Trim(album.Path, "/"), "/") {
+		if id, err := uuid.Parse(part); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		ids = append(ids, album.ID)
+	}
+	return ids
+}
+
+// albumAncestors mengambil album dari root sampai album itu sendiri, urut sesuai path.
+func albumAncestors(db *gorm.DB, album models.Album) ([]models.Album, error) {
+	ids := albumPathIDs(album)
+
+	var albums []models.Album
+	if err := db.Where("id IN ?", ids).Find(&albums).Error; err != nil {
+		return nil, err
+	}
+
+	byID := map[uuid.UUID]models.Album{}
+	for _, a := range albums {
+		byID[a.ID] = a
+	}
+
+	ordered := []models.Album{}
+	for _, id := range ids {
+		if a, ok := byID[id]; ok {
+			ordered = append(ordered, a)
+		}
+	}
+	return ordered, nil
+}
+
+// effectiveAlbumPrivacy adalah privacy paling ketat di antara album dan seluruh ancestor-nya.
+func effectiveAlbumPrivacy(ancestors []models.Album) string {
+	effective := "public"
+	for _, a := range ancestors {
+		if privacyRank[a.AlbumPrivacy] > privacyRank[effective] {
+			effective = a.AlbumPrivacy
+		}
+	}
+	return effective
+}
+
+// canViewAlbum mengecek akses user ke album dengan memperhitungkan privacy seluruh ancestor.
+func canViewAlbum(db *gorm.DB, album models.Album, userID uuid.UUID) (bool, error) {
+	if album.UserID == userID {
+		return true, nil
+	}
+
+	// Album disembunyikan dari user yang diblokir pemiliknya
+	blocked, err := isBlockedBy(db, album.UserID, userID)
+	if err != nil || blocked {
+		return false, err
+	}
+
+	// Album akun privat hanya terlihat oleh follower yang sudah diterima
+	visible, err := canSeePrivateContent(db, album.UserID, userID)
+	if err != nil || !visible {
+		return false, err
+	}
+
+	ancestors, err := albumAncestors(db, album)
+	if err != nil {
+		return false, err
+	}
+
+	for _, a := range ancestors {
+		// Album (atau parent-nya) yang disembunyikan moderator hanya terlihat oleh pemiliknya
+		if a.ModerationStatus != models.ModerationStatusVisible {
+			return false, nil
+		}
+
+		switch a.AlbumPrivacy {
+		case "private":
+			return false, nil
+		case "restricted":
+			allowed, err := canAccessRestrictedAlbum(db, a, userID)
+			if err != nil || !allowed {
+				return false, err
+			}
+		}
+	}
+
+	return true, nil
+}
+
+// visibleChildAlbums membatasi sub-album untuk viewer selain pemilik: hanya yang tidak dimoderasi,
+// bisa diakses sesuai privacy, tanpa blokir dua arah, dan bukan milik akun privat yang belum di-follow.
+func visibleChildAlbums(query *gorm.DB, viewerID uuid.UUID) *gorm.DB {
+	return query.Where(`albums.moderation_status = ?
+		AND (albums.album_privacy = 'public' OR (albums.album_privacy = 'restricted' AND `+acceptedInvitationCondition+`))
+		AND `+notBlockedByCondition("albums.user_id")+`
+		AND NOT EXISTS (SELECT 1 FROM user_blocks vb WHERE vb.blocker_id = ? AND vb.blocked_id = albums.user_id)
+		AND `+followerOnlyCondition("albums.user_id"),
+		models.ModerationStatusVisible, viewerID, viewerID, viewerID, viewerID)
+}
+
+func GetAlbumChildren(ctx *fiber.Ctx, db *gorm.DB) error {
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	albumID, err := uuid.Parse(ctx.Params("albumId"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Album ID tidak valid"})
+	}
+
+	var album models.Album
+	if err := db.First(&album, "id = ?", albumID).Error; err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Album tidak ditemukan"})
+	}
+
+	allowed, err := canViewAlbum(db, album, userID)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memeriksa akses album"})
+	}
+	if !allowed {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "User tidak diperbolehkan melihat album"})
+	}
+
+	ancestors, err := albumAncestors(db, album)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil ancestor album"})
+	}
+	parentPrivacy := effectiveAlbumPrivacy(ancestors)
+
+	// Viewer selain pemilik hanya melihat (dan menghitung) sub-album yang boleh ia buka
+	childrenOf := func(parentID uuid.UUID) *gorm.DB {
+		query := db.Model(&models.Album{}).Where("albums.parent_id = ?", parentID)
+		if album.UserID != userID {
+			query = visibleChildAlbums(query, userID)
+		}
+		return query
+	}
+
+	var children []models.Album
+	if err := childrenOf(album.ID).Order("title ASC").Find(&children).Error; err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil sub-album"})
+	}
+
+	response := []ChildAlbumResponse{}
+	for _, child := range children {
+		var childCount int64
+		childrenOf(child.ID).Count(&childCount)
+
+		effective := child.AlbumPrivacy
+		if privacyRank[parentPrivacy] > privacyRank[effective] {
+			effective = parentPrivacy
+		}
+
+		response = append(response, ChildAlbumResponse{
+			AlbumID:          child.ID,
+			Title:            child.Title,
+			Description:      child.Description,
+			AlbumPrivacy:     child.AlbumPrivacy,
+			EffectivePrivacy: effective,
+			ChildCount:       childCount,
+			UpdatedAt:        child.UpdatedAt.Format("02 January 2006"),
+		})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":  "Sub-album berhasil diambil",
+		"album_id": album.ID,
+		"children": response,
+	})
+}
+
+func GetAlbumBreadcrumb(ctx *fiber.Ctx, db *gorm.DB) error {
+	userID, err := utils.GetUserID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	albumID, err := uuid.Parse(ctx.Params("albumId"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Album ID tidak valid"})
+	}
+
+	var album models.Album
+	if err := db.First(&album, "id = ?", albumID).Error; err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Album tidak ditemukan"})
+	}
+
+	allowed, err := canViewAlbum(db, album, userID)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memeriksa akses album"})
+	}
+	if !allowed {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "User tidak diperbolehkan melihat album"})
+	}
+
+	ancestors, err := albumAncestors(db, album)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil breadcrumb"})
+	}
+
+	breadcrumb := []AlbumBreadcrumb{}
+	for _, a := range ancestors {
+		breadcrumb = append(breadcrumb, AlbumBreadcrumb{
+			AlbumID:      a.ID,
+			Title:        a.Title,
+			AlbumPrivacy: a.AlbumPrivacy,
+		})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":           "Breadcrumb berhasil diambil",
+		"breadcrumb":        breadcrumb,
+		"effective_privacy": effectiveAlbumPrivacy(ancestors),
+	})
+}
+
+// albumMovePaths menghitung path lama dan baru album yang dipindah ke parent (nil = root).
+// Album tidak boleh dipindah ke dirinya sendiri atau ke salah satu turunannya.
+func albumMovePaths(album models.Album, parent *models.Album) (string, string, error) {
+	if parent != nil && (parent.ID == album.ID || strings.Contains(parent.Path, "/"+album.ID.String()+"/")) {
+		return "", "", fiber.NewError(fiber.StatusConflict, "Album tidak bisa dipindah ke dalam sub-albumnya sendiri")
+	}
+
+	oldPath := album.Path
+	if oldPath == "" {
+		oldPath = albumPath(nil, album.ID)
+	}
+	return oldPath, albumPath(parent, album.ID), nil
+}
+
+// rebaseAlbumPath mengganti prefix oldPath pada path turunan dengan newPath.
+func rebaseAlbumPath(path, oldPath, newPath string) string {
+	return newPath + strings.TrimPrefix(path, oldPath)
+}
+
+func MoveAlbum(ctx *fiber.Ctx, db *gorm.DB) error {
+	album, userID, err := findOwnedAlbum(ctx, db, "albumId")
+	if err != nil {
+		return fiberErrorResponse(ctx, err)
+	}
+
+	type moveRequest struct {
+		ParentID string `json:"parent_id"` // kosong = pindah ke root
+	}
+
+	var req moveRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Format permintaan tidak valid"})
+	}
+
+	var parent *models.Album
+	if req.ParentID != "" {
+		parentID, err := uuid.Parse(req.ParentID)
+		if err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Parent ID tidak valid"})
+		}
+
+		var parentAlbum models.Album
+		if err := db.Where("id = ? AND user_id = ?", parentID, userID).First(&parentAlbum).Error; err != nil {
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Parent album tidak ditemukan"})
+		}
+		parent = &parentAlbum
+	}
+
+	oldPath, newPath, err := albumMovePaths(album, parent)
+	if err != nil {
+		return fiberErrorResponse(ctx, err)
+	}
+
+	errMove := db.Transaction(func(tx *gorm.DB) error {
+		var parentID *uuid.UUID
+		if parent != nil {
+			parentID = &parent.ID
+		}
+
+		if err := tx.Model(&album).Updates(map[string]interface{}{
+			"parent_id": parentID,
+			"path":      newPath,
+		}).Error; err != nil {
+			return err
+		}
+
+		// Perbarui path semua turunan album, termasuk yang ada di trash agar tetap benar saat di-restore
+		var descendants []models.Album
+		if err := tx.Unscoped().Select("id", "path").
+			Where("path LIKE ? AND id <> ?", oldPath+"%", album.ID).
+			Find(&descendants).Error; err != nil {
+			return err
+		}
+		for _, descendant := range descendants {
+			if err := tx.Unscoped().Model(&models.Album{}).Where("id = ?", descendant.ID).
+				Update("path", rebaseAlbumPath(descendant.Path, oldPath, newPath)).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if errMove != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memindahkan album"})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":   "Album berhasil dipindahkan",
+		"album_id":  album.ID,
+		"parent_id": req.ParentID,
+		"path":      newPath,
+	})
+}
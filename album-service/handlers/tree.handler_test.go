@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Zackly23/queue-app/models"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// albumTree menyimpan album dalam memori dan memindahkannya seperti MoveAlbum:
+// album diberi path baru, lalu setiap turunan (LIKE oldPath%) di-rebase.
+type albumTree map[string]*models.Album
+
+func (tree albumTree) add(name string, parent string) {
+	album := &models.Album{ID: uuid.New()}
+	if parent != "" {
+		album.ParentID = &tree[parent].ID
+		album.Path = albumPath(tree[parent], album.ID)
+	} else {
+		album.Path = albumPath(nil, album.ID)
+	}
+	tree[name] = album
+}
+
+func (tree albumTree) move(name string, parent string) error {
+	album := tree[name]
+	var target *models.Album
+	if parent != "" {
+		target = tree[parent]
+	}
+
+	oldPath, newPath, err := albumMovePaths(*album, target)
+	if err != nil {
+		return err
+	}
+	for _, other := range tree {
+		if other.ID != album.ID && strings.HasPrefix(other.Path, oldPath) {
+			other.Path = rebaseAlbumPath(other.Path, oldPath, newPath)
+		}
+	}
+	album.Path = newPath
+	album.ParentID = nil
+	if target != nil {
+		album.ParentID = &target.ID
+	}
+	return nil
+}
+
+// pathOf menyusun path yang diharapkan dari rantai nama album.
+func (tree albumTree) pathOf(names ...string) string {
+	path := "/"
+	for _, name := range names {
+		path += tree[name].ID.String() + "/"
+	}
+	return path
+}
+
+func TestMoveAlbumRewritesDescendantPaths(t *testing.T) {
+	tree := albumTree{}
+	tree.add("liburan", "")
+	tree.add("2025", "liburan")
+	tree.add("bali", "2025")
+	tree.add("ubud", "bali")
+	tree.add("arsip", "")
+
+	// Subtree bali dipindah ke arsip, ubud ikut berpindah
+	if err := tree.move("bali", "arsip"); err != nil {
+		t.Fatalf("pindah bali: %v", err)
+	}
+	if got, want := tree["bali"].Path, tree.pathOf("arsip", "bali"); got != want {
+		t.Errorf("path bali = %s, want %s", got, want)
+	}
+	if got, want := tree["ubud"].Path, tree.pathOf("arsip", "bali", "ubud"); got != want {
+		t.Errorf("path ubud = %s, want %s", got, want)
+	}
+	if got, want := tree["2025"].Path, tree.pathOf("liburan", "2025"); got != want {
+		t.Errorf("path 2025 ikut berubah menjadi %s", got)
+	}
+
+	// Kembali ke root
+	if err := tree.move("bali", ""); err != nil {
+		t.Fatalf("pindah bali ke root: %v", err)
+	}
+	if tree["bali"].ParentID != nil || tree["ubud"].Path != tree.pathOf("bali", "ubud") {
+		t.Errorf("setelah ke root: bali parent = %v, ubud path = %s", tree["bali"].ParentID, tree["ubud"].Path)
+	}
+}
+
+func TestMoveAlbumRejectsCycles(t *testing.T) {
+	tree := albumTree{}
+	tree.add("keluarga", "")
+	tree.add("anak", "keluarga")
+	tree.add("cucu", "anak")
+
+	for _, target := range []string{"keluarga", "anak", "cucu"} {
+		err := tree.move("keluarga", target)
+		var fiberErr *fiber.Error
+		if !errors.As(err, &fiberErr) || fiberErr.Code != fiber.StatusConflict {
+			t.Errorf("pindah keluarga ke %s: err = %v, want 409", target, err)
+		}
+	}
+	if tree["cucu"].Path != tree.pathOf("keluarga", "anak", "cucu") {
+		t.Errorf("path cucu berubah setelah pemindahan ditolak: %s", tree["cucu"].Path)
+	}
+
+	// Album lama tanpa path tetap terdeteksi sebagai leluhur lewat ID di path parent
+	legacy := models.Album{ID: tree["keluarga"].ID}
+	if _, _, err := albumMovePaths(legacy, tree["cucu"]); err == nil {
+		t.Error("album tanpa path bisa dipindah ke turunannya")
+	}
+	oldPath, newPath, err := albumMovePaths(legacy, nil)
+	if err != nil || oldPath != albumPath(nil, legacy.ID) || newPath != oldPath {
+		t.Errorf("album tanpa path ke root = %s -> %s, %v", oldPath, newPath, err)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/Zackly23/queue-app/events"
 	"github.com/Zackly23/queue-app/models"
 	"github.com/Zackly23/queue-app/utils"
 	"github.com/go-playground/validator/v10"
@@ -68,17 +69,26 @@ func GetUserData(ctx *fiber.Ctx, db *gorm.DB) error {
 		})
 	}
 
-	areYouFollowThisUser := false
-	var existingFollow models.Following
+	// Profil disembunyikan dari user yang diblokir pemiliknya
+	blocked, errBlock := isBlockedBy(db, userID, userLoginID)
+	if errBlock != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Gagal memeriksa status blokir",
+		})
+	}
+	if blocked {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
 
-	if err := db.Where("user_id = ? AND following_id = ?", userLoginID, userID).First(&existingFollow).Error; err == nil {
-		areYouFollowThisUser = true
-	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
-		// Jika error bukan karena data tidak ditemukan, maka return error
+	followStatusToUser, errFollow := followStatus(db, userLoginID, userID)
+	if errFollow != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Gagal memeriksa status follow",
 		})
 	}
+	areYouFollowThisUser := followStatusToUser == models.FollowStatusAccepted
 
 
 	if err := db.Preload("AccountConfig").Preload("Subscription").Preload("Following").First(&user, userID).Error; err != nil {
@@ -92,7 +102,7 @@ func GetUserData(ctx *fiber.Ctx, db *gorm.DB) error {
 	var userStats UserStatResponse
 
 	// Get All Album related to the user
-	if err := db.Preload("AlbumImages").Preload("AlbumVideos").Where("user_id = ?", user.ID).Find(&albums).Error; err != nil {
+	if err := db.Preload("Media").Where("user_id = ?", user.ID).Find(&albums).Error; err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to retrieve user albums",
 		})
@@ -101,28 +111,21 @@ func GetUserData(ctx *fiber.Ctx, db *gorm.DB) error {
 	// fmt.Println("ada ga :" ,albums)
 
 	// Loop through albums
-	var storageUsed float32
-	storageUsed = 0.00
 	for _, album := range albums {
 
 		// fmt.Println(album.Title)
 		// Count media
-		userStats.MediaCount += len(album.AlbumImages) + len(album.AlbumVideos)
-
-		// Sum image sizes
-		var sumSizeImage float32
-		for _, image := range album.AlbumImages {
-			sumSizeImage += image.Size
-		}
-
-		// Sum video sizes
-		var sumSizeVideo float32
-		for _, video := range album.AlbumVideos {
-			sumSizeVideo += video.Size
-		}
+		userStats.MediaCount += len(album.Media)
+	}
 
-		storageUsed += sumSizeImage + sumSizeVideo
+	// Storage termasuk media di trash yang masih dalam masa grace
+	storageUsedMB, errStorage := utils.CalculateStorageUsed(db, user.ID)
+	if errStorage != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to calculate storage usage",
+		})
 	}
+	storageUsed := float32(storageUsedMB)
 
 	if storageUsed < 1024 {
 		userStats.StorageUsed = fmt.Sprintf("%.2f MB", storageUsed)
@@ -157,14 +160,14 @@ func GetUserData(ctx *fiber.Ctx, db *gorm.DB) error {
 
 	// Ambil jumlah user yang di-follow oleh user ini (following)
 	var followingCount int64
-	if err := db.Model(&models.Following{}).Where("user_id = ?", user.ID).Count(&followingCount).Error; err != nil {
+	if err := db.Model(&models.Following{}).Where("user_id = ? AND status = ?", user.ID, models.FollowStatusAccepted).Count(&followingCount).Error; err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mendapatkan jumlah following"})
 	}
 	userStats.FollowingCount = followingCount
 
 	// Ambil jumlah follower (user lain yang mengikuti user ini)
 	var followersCount int64
-	if err := db.Model(&models.Following{}).Where("following_id = ?", user.ID).Count(&followersCount).Error; err != nil {
+	if err := db.Model(&models.Following{}).Where("following_id = ? AND status = ?", user.ID, models.FollowStatusAccepted).Count(&followersCount).Error; err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mendapatkan jumlah follower"})
 	}
 	userStats.FollowersCount = followersCount
@@ -197,6 +200,8 @@ func GetUserData(ctx *fiber.Ctx, db *gorm.DB) error {
 		Subscription:  user.Subscription.SubscriptionType,
 		TagPreference: user.TagPreference,
 		AreYouFollowingUser: areYouFollowThisUser,
+		FollowStatus:   followStatusToUser,
+		IsPrivate:      user.AccountConfig.IsPrivate,
 		CreatedAt:      user.CreatedAt,
 		UpdatedAt:      user.UpdatedAt,
 	}
@@ -284,7 +289,7 @@ func UpdateUserData(ctx *fiber.Ctx, db *gorm.DB) error {
 	// fmt.Println("preference : ", req.TagPreference)
 
 	existingUser.Bio = req.Bio
-	existingUser.TagPreference = req.TagPreference
+	existingUser.TagPreference = utils.NormalizeTags(req.TagPreference)
 	existingUser.Address = req.Address
 	existingUser.JobTitle = req.JobTitle
 	existingUser.State = req.State
@@ -378,30 +383,77 @@ func FollowUser(ctx *fiber.Ctx, db *gorm.DB) error {
 		})
 	}
 
-	// Cek apakah user sedang follow user lain
+	if userToFollowParseID == userLoginID {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Tidak bisa follow diri sendiri",
+		})
+	}
+
+	// Cek apakah user sedang follow (atau sudah mengirim permintaan follow) ke user lain
 	var existingFollow models.Following
 	err := db.
 		Where("user_id = ? AND following_id = ?", userLoginID, userToFollowParseID).
 		First(&existingFollow).Error
 
 	if err == nil {
-		// Sudah follow, maka lakukan unfollow (hapus)
+		// Sudah follow / request pending, maka lakukan unfollow atau batalkan request (hapus)
 		if errFollow := db.Unscoped().Delete(&existingFollow).Error; errFollow != nil {
 			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 				"error": "Gagal melakukan unfollow",
 			})
 		}
+
+		message := "Berhasil unfollow"
+		if existingFollow.Status == models.FollowStatusPending {
+			message = "Permintaan follow dibatalkan"
+		}
 		return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
-			"message": "Berhasil unfollow",
+			"message": message,
 			"is_user_now_follow": false,
+			"follow_status": "",
 		})
 	}
 
 	if errors.Is(err, gorm.ErrRecordNotFound) {
+		var userToFollow models.User
+		if err := db.Select("id").First(&userToFollow, "id = ?", userToFollowParseID).Error; err != nil {
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "User not found",
+			})
+		}
+
+		blocked, errBlock := hasBlockBetween(db, userLoginID, userToFollowParseID)
+		if errBlock != nil {
+			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Gagal memeriksa status blokir",
+			})
+		}
+		if blocked {
+			return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Tidak bisa follow user ini",
+			})
+		}
+
+		// Akun privat harus menyetujui follow terlebih dulu
+		private, errPrivate := isPrivateAccount(db, userToFollowParseID)
+		if errPrivate != nil {
+			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Gagal memeriksa privasi akun",
+			})
+		}
+
+		status := models.FollowStatusAccepted
+		eventType := models.NotificationEventFollow
+		if private {
+			status = models.FollowStatusPending
+			eventType = models.NotificationEventFollowRequest
+		}
+
 		// Belum follow, maka buat follow baru
 		newFollow := models.Following{
 			UserID:      userLoginID,
 			FollowingID: userToFollowParseID,
+			Status:      status,
 			CreatedAt:   time.Now(),
 		}
 		if err := db.Create(&newFollow).Error; err != nil {
@@ -409,98 +461,27 @@ func FollowUser(ctx *fiber.Ctx, db *gorm.DB) error {
 				"error": "Gagal melakukan follow",
 			})
 		}
-		return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
-			"message": "Berhasil follow",
-			"is_user_now_follow": true,
-		})
-	}
 
-	// Jika error selain not found
-	return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-		"error": "Gagal memproses follow",
-	})
-}
-
-func ChangeSubscription(ctx *fiber.Ctx, db *gorm.DB) error {
-	// Define request body struct
-	type SubscriptionBodyReq struct {
-		TypeID        uint   `json:"type_id"`
-		PaymentMethod string `json:"payment_method"`
-	}
-
-	var req SubscriptionBodyReq
-	if err := ctx.BodyParser(&req); err != nil {
-		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
-	}
-
-	// Get user ID from context
-	userID, err := utils.GetUserID(ctx)
-	if err != nil {
-		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Unauthorized",
-		})
-	}
-
-	// Find user
-	var user models.User
-	if err := db.Preload("Subscription").Preload("UserSubscriptions").First(&user, "id = ?", userID).Error; err != nil {
-		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "User not found",
-		})
-	}
-
-	// Find new subscription type
-	var newSubType models.Subscription
-	if err := db.First(&newSubType, req.TypeID).Error; err != nil {
-		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Subscription type not found",
+		events.Publish(events.Event{
+			Type:        eventType,
+			RecipientID: userToFollowParseID,
+			ActorID:     userLoginID,
 		})
-	}
 
-	// Change current UserSubscription status to Expired
-	if len(user.UserSubscriptions) > 0 {
-		for i := range user.UserSubscriptions {
-			us := &user.UserSubscriptions[i]
-			if us.Status == "Active" {
-				us.Status = "Expired"
-				us.EndDate = time.Now()
-				if err := db.Save(us).Error; err != nil {
-					return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-						"error": "Failed to expire current subscription",
-					})
-				}
-			}
+		message := "Berhasil follow"
+		if private {
+			message = "Permintaan follow terkirim"
 		}
-	}
-
-	// Create new UserSubscription
-	newUserSub := models.UserSubscription{
-		UserID:         user.ID,
-		SubscriptionID: newSubType.ID,
-		Status:         "Active",
-		PaymentMethod:  req.PaymentMethod,
-		StartDate:      time.Now(),
-		EndDate:      time.Now().AddDate(0, 1, 0), // Example: 1 month from now
-	}
-	if err := db.Create(&newUserSub).Error; err != nil {
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to create new subscription",
-		})
-	}
-
-	// Update user's Subscription reference
-	user.SubscriptionID = newSubType.ID
-	if err := db.Save(&user).Error; err != nil {
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to update user subscription",
+		return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": message,
+			"is_user_now_follow": !private,
+			"follow_status": status,
 		})
 	}
 
-	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
-		"message":      "Subscription updated successfully",
-		"subscription": newSubType,
+	// Jika error selain not found
+	return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": "Gagal memproses follow",
 	})
 }
 
@@ -518,7 +499,7 @@ func GetSubscriptionHistory(ctx *fiber.Ctx, db *gorm.DB) error {
 	}
 
 	var subscriptions []models.UserSubscription
-	query := db.Preload("User").Preload("Subscription").Where("user_id = ?", userID)
+	query := db.Preload("User").Preload("Subscription").Preload("Invoice").Where("user_id = ?", userID)
 
 	// Filter by date range if provided
 	if startDateStr != "" && endDateStr != "" {
@@ -547,26 +528,47 @@ func GetSubscriptionHistory(ctx *fiber.Ctx, db *gorm.DB) error {
 	}
 
 	type SubscriptionHistoryResponse struct {
-		ID               uuid.UUID `json:"id"`
-		CustomerName     string    `json:"customer_name"`
-		PaymentMethod    string    `json:"payment_method"`
-		SubscriptionType string    `json:"subscription_type"`
-		Amount           float32   `json:"amount"`
-		Status           string    `json:"status"`
-		CreatedAt        string    `json:"created_at"`
+		ID               uuid.UUID  `json:"id"`
+		CustomerName     string     `json:"customer_name"`
+		PaymentMethod    string     `json:"payment_method"`
+		SubscriptionType string     `json:"subscription_type"`
+		PlanVersion      int        `json:"plan_version"`
+		ChangeType       string     `json:"change_type,omitempty"`
+		Amount           float32    `json:"amount"`
+		Credit           float32    `json:"credit"`
+		Currency         string     `json:"currency,omitempty"`
+		Status           string     `json:"status"`
+		StartDate        string     `json:"start_date"`
+		EndDate          string     `json:"end_date"`
+		CreatedAt        string     `json:"created_at"`
+		InvoiceID        *uuid.UUID `json:"invoice_id,omitempty"`
+		InvoiceNumber    string     `json:"invoice_number,omitempty"`
+		InvoiceStatus    string     `json:"invoice_status,omitempty"`
 	}
 
 	var response []SubscriptionHistoryResponse
 	for _, sub := range subscriptions {
-		response = append(response, SubscriptionHistoryResponse{
+		item := SubscriptionHistoryResponse{
 			ID:               sub.ID,
 			CustomerName:     sub.User.FirstName + " " + sub.User.LastName,
 			PaymentMethod:    sub.PaymentMethod,
 			SubscriptionType: sub.Subscription.SubscriptionType,
+			PlanVersion:      sub.Subscription.Version,
+			ChangeType:       sub.ChangeType,
 			Amount:           sub.Amount,
+			Credit:           sub.Credit,
+			Currency:         sub.Currency,
 			Status:           sub.Status,
+			StartDate:        sub.StartDate.Format("2006-01-02 15:04:05"),
+			EndDate:          sub.EndDate.Format("2006-01-02 15:04:05"),
 			CreatedAt:        sub.CreatedAt.Format("2006-01-02 15:04:05"),
-		})
+		}
+		if sub.Invoice != nil {
+			item.InvoiceID = &sub.Invoice.ID
+			item.InvoiceNumber = sub.Invoice.Number
+			item.InvoiceStatus = sub.Invoice.Status
+		}
+		response = append(response, item)
 	}
 
 	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
@@ -4,18 +4,16 @@ import (
 	"log"
 	"time"
 
-	"github.com/Zackly23/queue-app/config"
 	"github.com/Zackly23/queue-app/models"
 	"github.com/Zackly23/queue-app/utils"
 	"gorm.io/gorm"
 )
 
 func CleanUpUnusedFiles(db *gorm.DB) {
-	bucketName := config.S3Bucket.BucketName
 	threshold := time.Now().AddDate(0, 0, -60) // 60 hari terakhir
 
 	var albums []models.Album
-	if err := db.Preload("AlbumImages").Preload("AlbumVideos").
+	if err := db.Preload("Media").
 		Where("updated_at < ?", threshold).
 		Find(&albums).Error; err != nil {
 		log.Println("Gagal Mengambil Data Album:", err)
@@ -23,25 +21,14 @@ func CleanUpUnusedFiles(db *gorm.DB) {
 	}
 
 	for _, album := range albums {
-		// Hapus gambar dari S3 dan database
-		for _, img := range album.AlbumImages {
-			if err := utils.DeleteFromS3(img.ImageURL, bucketName); err != nil {
-				log.Printf("Gagal hapus file %s: %v", img.ImageURL, err)
+		// Hapus media dari S3 dan database
+		for _, media := range album.Media {
+			if err := utils.ReleaseMediaObject(db, media); err != nil {
+				log.Printf("Gagal hapus file %s: %v", media.URL, err)
 				continue
 			}
-			if err := db.Delete(&img).Error; err != nil {
-				log.Printf("Gagal hapus record image dari DB: %v", err)
-			}
-		}
-
-		// Hapus video dari S3 dan database
-		for _, vid := range album.AlbumVideos {
-			if err := utils.DeleteFromS3(vid.VideoURL, bucketName); err != nil {
-				log.Printf("Gagal hapus file %s: %v", vid.VideoURL, err)
-				continue
-			}
-			if err := db.Delete(&vid).Error; err != nil {
-				log.Printf("Gagal hapus record video dari DB: %v", err)
+			if err := db.Delete(&media).Error; err != nil {
+				log.Printf("Gagal hapus record media dari DB: %v", err)
 			}
 		}
 
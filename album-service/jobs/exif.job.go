@@ -0,0 +1,73 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Zackly23/queue-app/models"
+	"github.com/Zackly23/queue-app/utils"
+	"gorm.io/gorm"
+)
+
+const captureInfoBatchSize = 200
+
+// BackfillCaptureInfo membaca EXIF (waktu pengambilan dan GPS) foto JPEG yang di-upload sebelum
+// fitur timeline dan peta ada. Foto tanpa EXIF tetap ditandai sudah dipindai agar tidak dibaca ulang.
+func BackfillCaptureInfo(db *gorm.DB) {
+	processed, failed := 0, 0
+
+	for {
+		var medias []models.Media
+		if err := db.Where("kind = ? AND exif_scanned = ? AND type IN ?", models.MediaKindImage, false, utils.ExifImageTypes()).
+			Order("created_at ASC, id ASC").
+			Offset(failed).
+			Limit(captureInfoBatchSize).
+			Find(&medias).Error; err != nil {
+			log.Println("Gagal Mengambil Media untuk EXIF:", err)
+			return
+		}
+		if len(medias) == 0 {
+			break
+		}
+
+		for _, media := range medias {
+			info, err := readMediaCaptureInfo(media)
+			if err != nil {
+				// Gagal membaca file dari S3, dilewati dengan offset dan dicoba lagi pada run berikutnya
+				log.Printf("Gagal membaca EXIF media %s: %v", media.ID, err)
+				failed++
+				continue
+			}
+
+			if err := db.Model(&media).UpdateColumns(map[string]interface{}{
+				"captured_at":  info.CapturedAt,
+				"latitude":     info.Latitude,
+				"longitude":    info.Longitude,
+				"exif_scanned": true,
+			}).Error; err != nil {
+				log.Printf("Gagal menyimpan EXIF media %s: %v", media.ID, err)
+				failed++
+				continue
+			}
+			processed++
+		}
+	}
+
+	log.Printf("Backfill EXIF selesai: %d berhasil, %d gagal", processed, failed)
+}
+
+// readMediaCaptureInfo membaca EXIF dari object S3. File yang tidak punya EXIF tidak dianggap error.
+func readMediaCaptureInfo(media models.Media) (utils.CaptureInfo, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	body, err := utils.OpenS3Object(ctx, utils.S3KeyFromURL(media.URL))
+	if err != nil {
+		return utils.CaptureInfo{}, err
+	}
+	defer body.Close()
+
+	info, _ := utils.ReadCaptureInfo(body)
+	return info, nil
+}
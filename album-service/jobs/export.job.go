@@ -0,0 +1,167 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/Zackly23/queue-app/config"
+	"github.com/Zackly23/queue-app/models"
+	notif "github.com/Zackly23/queue-app/proto/notificationpb"
+	"github.com/Zackly23/queue-app/utils"
+	"gorm.io/gorm"
+)
+
+// ProcessAlbumExport membuat arsip ZIP album di S3 lalu mengirim link download ke user.
+// ZIP ditulis ke pipe dan langsung di-upload secara multipart, jadi tidak pernah ditampung utuh.
+func ProcessAlbumExport(db *gorm.DB, client notif.NotificationServiceClient, exportID string) {
+	var export models.AlbumExport
+	if err := db.Preload("Album").Preload("User").First(&export, "id = ?", exportID).Error; err != nil {
+		log.Printf("Export album %s tidak ditemukan: %v", exportID, err)
+		return
+	}
+
+	// Hanya export pending yang diproses, mencegah export yang sama dikerjakan dua kali
+	claim := db.Model(&models.AlbumExport{}).
+		Where("id = ? AND status = ?", export.ID, models.ExportStatusPending).
+		Update("status", models.ExportStatusProcessing)
+	if claim.Error != nil {
+		log.Printf("Gagal update status export %s: %v", export.ID, claim.Error)
+		return
+	}
+	if claim.RowsAffected == 0 {
+		return
+	}
+
+	query := db.Model(&models.Media{}).Joins("JOIN albums ON albums.id = media.album_id")
+	if export.Album.AlbumType == models.AlbumTypeSmart {
+		// Isi smart album sudah dihitung handler saat export diminta dan bisa berasal dari album mana pun
+		query = query.Where("media.id IN ?", []string(export.MediaIDs))
+	} else {
+		query = query.Where("media.album_id = ?", export.AlbumID)
+		if len(export.MediaIDs) > 0 {
+			query = query.Where("media.id IN ?", []string(export.MediaIDs))
+		}
+	}
+	// Media yang disembunyikan moderator hanya ikut di export milik pemilik album media tersebut
+	query = query.Where("media.moderation_status = ? OR albums.user_id = ?", models.ModerationStatusVisible, export.UserID)
+
+	var medias []models.Media
+	if err := query.Order("media.position ASC, media.created_at ASC").Find(&medias).Error; err != nil {
+		failAlbumExport(db, export, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Hour)
+	defer cancel()
+
+	key := fmt.Sprintf("exports/album_%s/%s.zip", export.AlbumID, export.ID)
+	reader, writer := io.Pipe()
+
+	go func() {
+		writer.CloseWithError(utils.WriteMediaZip(ctx, writer, medias, nil))
+	}()
+
+	if _, err := utils.UploadStreamToS3(ctx, key, reader, "application/zip"); err != nil {
+		reader.CloseWithError(err)
+		failAlbumExport(db, export, err)
+		return
+	}
+
+	ttl := utils.ArchiveLinkTTL()
+	link, err := utils.GeneratePresignedURLWithExpiry(config.S3Bucket.BucketName, key, ttl)
+	if err != nil {
+		failAlbumExport(db, export, err)
+		return
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+	if err := db.Model(&export).Updates(map[string]interface{}{
+		"status":       models.ExportStatusCompleted,
+		"object_key":   key,
+		"media_count":  len(medias),
+		"completed_at": now,
+		"expires_at":   expiresAt,
+	}).Error; err != nil {
+		log.Printf("Gagal update export %s: %v", export.ID, err)
+		return
+	}
+
+	ctxNotif, cancelNotif := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelNotif()
+
+	_, err = client.SendNotification(ctxNotif, &notif.NotificationRequest{
+		To:      export.User.Email,
+		Subject: "Arsip Album Siap Diunduh",
+		Type:    "album-export",
+		Name:    export.User.FirstName + " " + export.User.LastName,
+		Body:    fmt.Sprintf("Arsip album %s siap diunduh: %s", export.Album.Title, link),
+		Metadata: map[string]string{
+			"album_title":   export.Album.Title,
+			"download_link": link,
+			"media_count":   fmt.Sprintf("%d", len(medias)),
+			"expires_at":    expiresAt.Format("02 January 2006 15:04"),
+			"platform_name": "PixoVaulty",
+			"platform_url":  "www.pixovaulty.com",
+		},
+	})
+	if err != nil {
+		log.Printf("Gagal mengirim notifikasi export ke %s: %v", export.User.Email, err)
+	}
+}
+
+// ResumeAlbumExports dipanggil saat service start untuk melanjutkan export yang belum selesai.
+// Export berstatus processing terputus oleh restart, sehingga diulang dari awal.
+func ResumeAlbumExports(db *gorm.DB, client notif.NotificationServiceClient) {
+	if err := db.Model(&models.AlbumExport{}).
+		Where("status = ?", models.ExportStatusProcessing).
+		Update("status", models.ExportStatusPending).Error; err != nil {
+		log.Println("Gagal Mengembalikan Export Album:", err)
+		return
+	}
+
+	var exports []models.AlbumExport
+	if err := db.Select("id").Where("status = ?", models.ExportStatusPending).Order("created_at ASC").Find(&exports).Error; err != nil {
+		log.Println("Gagal Mengambil Export Album:", err)
+		return
+	}
+
+	for _, export := range exports {
+		ProcessAlbumExport(db, client, export.ID.String())
+	}
+}
+
+func failAlbumExport(db *gorm.DB, export models.AlbumExport, cause error) {
+	log.Printf("Export album %s gagal: %v", export.ID, cause)
+
+	if err := db.Model(&export).Updates(map[string]interface{}{
+		"status": models.ExportStatusFailed,
+		"error":  cause.Error(),
+	}).Error; err != nil {
+		log.Printf("Gagal update status export %s: %v", export.ID, err)
+	}
+}
+
+// CleanupExpiredExports menghapus arsip ZIP yang link-nya sudah tidak berlaku.
+func CleanupExpiredExports(db *gorm.DB) {
+	var exports []models.AlbumExport
+	if err := db.Where("status = ? AND expires_at < ?", models.ExportStatusCompleted, time.Now()).Find(&exports).Error; err != nil {
+		log.Println("Gagal Mengambil Export Album:", err)
+		return
+	}
+
+	for _, export := range exports {
+		url := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", config.S3Bucket.BucketName, config.S3Bucket.Region, export.ObjectKey)
+		if err := utils.DeleteFromS3(url, config.S3Bucket.BucketName); err != nil {
+			log.Printf("Gagal hapus arsip %s: %v", export.ObjectKey, err)
+			continue
+		}
+
+		if err := db.Model(&export).Update("status", models.ExportStatusExpired).Error; err != nil {
+			log.Printf("Gagal update export %s: %v", export.ID, err)
+		}
+	}
+}
@@ -0,0 +1,421 @@
+package jobs
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Zackly23/queue-app/models"
+	"github.com/Zackly23/queue-app/utils"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// importSource adalah satu file yang akan diimport, baik dari ZIP maupun upload folder.
+type importSource struct {
+	Name string
+	Size int64
+	Open func() (io.ReadCloser, error)
+}
+
+// listImportSources mengambil daftar file media dan manifest (bila ada) dari direktori import.
+func listImportSources(albumImport models.AlbumImport) ([]importSource, *utils.ImportManifest, func(), error) {
+	var sources []importSource
+	var manifest *utils.ImportManifest
+	closeFn := func() {}
+
+	readManifest := func(name string, open func() (io.ReadCloser, error)) error {
+		r, err := open()
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+
+		parsed, err := utils.ParseImportManifest(name, r)
+		if err != nil {
+			return err
+		}
+		manifest = &parsed
+		return nil
+	}
+
+	switch albumImport.SourceType {
+	case "zip":
+		archive, err := zip.OpenReader(filepath.Join(albumImport.SourcePath, "archive.zip"))
+		if err != nil {
+			return nil, nil, closeFn, fmt.Errorf("zip tidak valid: %w", err)
+		}
+		closeFn = func() { archive.Close() }
+
+		for _, file := range archive.File {
+			base := path.Base(file.Name)
+			// Lewati folder dan file sistem (mis. __MACOSX, .DS_Store)
+			if file.FileInfo().IsDir() || strings.HasPrefix(file.Name, "__MACOSX/") || strings.HasPrefix(base, ".") {
+				continue
+			}
+
+			file := file
+			if utils.IsImportManifest(file.Name) {
+				if err := readManifest(file.Name, file.Open); err != nil {
+					return nil, nil, closeFn, err
+				}
+				continue
+			}
+
+			sources = append(sources, importSource{
+				Name: file.Name,
+				Size: int64(file.UncompressedSize64),
+				Open: file.Open,
+			})
+		}
+
+	case "files":
+		dir := filepath.Join(albumImport.SourcePath, "files")
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, nil, closeFn, err
+		}
+
+		for _, entry := range entries {
+			if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+				continue
+			}
+
+			info, err := entry.Info()
+			if err != nil {
+				return nil, nil, closeFn, err
+			}
+
+			fullPath := filepath.Join(dir, entry.Name())
+			open := func() (io.ReadCloser, error) { return os.Open(fullPath) }
+
+			if utils.IsImportManifest(entry.Name()) {
+				if err := readManifest(entry.Name(), open); err != nil {
+					return nil, nil, closeFn, err
+				}
+				continue
+			}
+
+			sources = append(sources, importSource{Name: entry.Name(), Size: info.Size(), Open: open})
+		}
+
+	default:
+		return nil, nil, closeFn, fmt.Errorf("sumber import %s tidak dikenal", albumImport.SourceType)
+	}
+
+	// Manifest yang di-upload terpisah menggantikan manifest di dalam arsip
+	for _, name := range []string{"manifest.json", "manifest.csv"} {
+		fullPath := filepath.Join(albumImport.SourcePath, name)
+		if _, err := os.Stat(fullPath); err == nil {
+			if err := readManifest(name, func() (io.ReadCloser, error) { return os.Open(fullPath) }); err != nil {
+				return nil, nil, closeFn, err
+			}
+		}
+	}
+
+	return sources, manifest, closeFn, nil
+}
+
+// ProcessAlbumImport membuat album dan media dari file import di background.
+// Progress dan error setiap file disimpan di AlbumImportItem.
+func ProcessAlbumImport(db *gorm.DB, importID string) {
+	var albumImport models.AlbumImport
+	if err := db.First(&albumImport, "id = ?", importID).Error; err != nil {
+		log.Printf("Import album %s tidak ditemukan: %v", importID, err)
+		return
+	}
+	defer os.RemoveAll(albumImport.SourcePath)
+
+	startedAt := time.Now()
+	db.Model(&albumImport).Updates(map[string]interface{}{
+		"status":     models.ImportStatusProcessing,
+		"started_at": startedAt,
+	})
+
+	var user models.User
+	if err := db.Preload("Subscription").First(&user, "id = ?", albumImport.UserID).Error; err != nil {
+		failAlbumImport(db, albumImport, err)
+		return
+	}
+
+	sources, manifest, closeSources, err := listImportSources(albumImport)
+	defer closeSources()
+	if err != nil {
+		failAlbumImport(db, albumImport, err)
+		return
+	}
+	if manifest == nil {
+		manifest = &utils.ImportManifest{}
+	}
+
+	// Nilai dari form lebih diutamakan daripada manifest
+	title := firstNonEmpty(albumImport.Title, manifest.Title, "Imported Album")
+	description := firstNonEmpty(albumImport.Description, manifest.Description)
+	// album_privacy dari form sudah divalidasi handler, nilai manifest belum
+	manifestPrivacy := manifest.AlbumPrivacy
+	if !models.ValidAlbumPrivacy(manifestPrivacy) {
+		manifestPrivacy = ""
+	}
+	privacy := firstNonEmpty(albumImport.AlbumPrivacy, manifestPrivacy, models.AlbumPrivacyPrivate)
+
+	items := make([]models.AlbumImportItem, len(sources))
+	for i, source := range sources {
+		items[i] = models.AlbumImportItem{
+			ImportID: albumImport.ID,
+			FileName: source.Name,
+			Size:     float32(source.Size) / (1024 * 1024),
+			Status:   models.ImportItemStatusPending,
+		}
+	}
+	if len(items) > 0 {
+		if err := db.Create(&items).Error; err != nil {
+			failAlbumImport(db, albumImport, err)
+			return
+		}
+	}
+
+	var parent *models.Album
+	if albumImport.ParentID != nil {
+		var parentAlbum models.Album
+		if err := db.Where("id = ? AND user_id = ?", albumImport.ParentID, user.ID).First(&parentAlbum).Error; err != nil {
+			failAlbumImport(db, albumImport, fmt.Errorf("parent album tidak ditemukan"))
+			return
+		}
+		parent = &parentAlbum
+	}
+
+	album := models.Album{
+		UserID:       user.ID,
+		ParentID:     albumImport.ParentID,
+		Title:        title,
+		Description:  description,
+		AlbumPrivacy: privacy,
+	}
+	if err := db.Create(&album).Error; err != nil {
+		failAlbumImport(db, albumImport, err)
+		return
+	}
+	album.Path = utils.AlbumPath(parent, album.ID)
+	db.Model(&album).Update("path", album.Path)
+
+	db.Model(&albumImport).Updates(map[string]interface{}{
+		"album_id":    album.ID,
+		"title":       title,
+		"total_items": len(items),
+	})
+
+	tags := append([]string{}, albumImport.Tags...)
+	tags = append(tags, manifest.Tags...)
+
+	// Kuota dihitung sekali di awal lalu ditambah setiap file yang berhasil diimport
+	storageUsed, err := utils.CalculateStorageUsed(db, user.ID)
+	if err != nil {
+		failAlbumImport(db, albumImport, err)
+		return
+	}
+	storageCapacity := user.Subscription.StorageCapacity * 1024 // GB ke MB
+	maxMediaSize := user.Subscription.MaximumMediaSize          // MB
+
+	ctx := context.Background()
+	position := 0
+	processed, failed := 0, 0
+	coverImage := ""
+
+	for i, source := range sources {
+		item := items[i]
+		status, errMessage := models.ImportItemStatusImported, ""
+
+		entry, hasEntry := manifest.FindFile(source.Name)
+		mimeType := mime.TypeByExtension(strings.ToLower(path.Ext(source.Name)))
+		kind, supported := models.MediaKindForMime(mimeType)
+		sizeMB := float64(source.Size) / (1024 * 1024)
+
+		switch {
+		case !supported:
+			status, errMessage = models.ImportItemStatusSkipped, "format file tidak didukung"
+		case maxMediaSize > 0 && sizeMB > maxMediaSize:
+			status, errMessage = models.ImportItemStatusFailed, fmt.Sprintf("ukuran file melebihi batas %.0f MB", maxMediaSize)
+		case storageCapacity > 0 && storageUsed+sizeMB > storageCapacity:
+			status, errMessage = models.ImportItemStatusFailed, "kapasitas penyimpanan sudah penuh"
+		default:
+			media, err := importMediaFile(ctx, db, album, source, kind, mimeType, position+1)
+			if err != nil {
+				status, errMessage = models.ImportItemStatusFailed, err.Error()
+				break
+			}
+
+			if hasEntry {
+				db.Model(&media).Updates(map[string]interface{}{
+					"title":       entry.Title,
+					"description": entry.Description,
+				})
+				tags = append(tags, entry.Tags...)
+			}
+
+			position++
+			storageUsed += sizeMB
+			item.MediaID = &media.ID
+			if coverImage == "" {
+				coverImage = media.CoverURL()
+			}
+		}
+
+		processed++
+		if status == models.ImportItemStatusFailed {
+			failed++
+		}
+
+		db.Model(&item).Updates(map[string]interface{}{
+			"status":   status,
+			"error":    errMessage,
+			"media_id": item.MediaID,
+		})
+		db.Model(&albumImport).Updates(map[string]interface{}{
+			"processed_items": processed,
+			"failed_items":    failed,
+		})
+	}
+
+	if err := utils.AttachAlbumTags(db, album, uniqueStrings(tags)); err != nil {
+		log.Printf("Gagal menyimpan tags import %s: %v", albumImport.ID, err)
+	}
+
+	if coverImage != "" {
+		db.Model(&album).Update("cover_image", coverImage)
+	}
+
+	finalStatus := models.ImportStatusCompleted
+	switch {
+	case position == 0 && len(sources) > 0:
+		finalStatus = models.ImportStatusFailed
+	case failed > 0:
+		finalStatus = models.ImportStatusCompletedWithErrors
+	}
+
+	db.Model(&albumImport).Updates(map[string]interface{}{
+		"status":       finalStatus,
+		"completed_at": time.Now(),
+	})
+}
+
+// importMediaFile meng-upload satu file ke S3 (stream) dan membuat record Media-nya.
+func importMediaFile(ctx context.Context, db *gorm.DB, album models.Album, source importSource, kind models.MediaKind, mimeType string, position int) (models.Media, error) {
+	reader, err := source.Open()
+	if err != nil {
+		return models.Media{}, fmt.Errorf("gagal membaca file: %w", err)
+	}
+	defer reader.Close()
+
+	// Nama file di arsip bisa sama di folder berbeda, jadi key S3 diawali id media
+	mediaID := uuid.New()
+	key := kind.StorageFolder + "/albums/album_" + album.ID.String() + "/" + mediaID.String() + "_" + path.Base(source.Name)
+
+	// Batasi pembacaan sesuai ukuran yang dilaporkan arsip
+	url, err := utils.UploadStreamToS3(ctx, key, io.LimitReader(reader, source.Size), mimeType)
+	if err != nil {
+		return models.Media{}, fmt.Errorf("gagal mengupload ke S3: %w", err)
+	}
+
+	media := models.Media{
+		ID:             mediaID,
+		AlbumID:        album.ID,
+		Kind:           kind.Name,
+		URL:            url,
+		ThumbnailURL:   kind.DefaultThumbnail,
+		Size:           float32(source.Size) / (1024 * 1024),
+		Type:           mimeType,
+		Position:       position,
+		PerceptualHash: hashImportSource(source, kind, mimeType),
+		ExifScanned:    true,
+	}
+	if capture := importCaptureInfo(source, kind, mimeType); capture.CapturedAt != nil || capture.Latitude != nil {
+		media.CapturedAt = capture.CapturedAt
+		media.Latitude = capture.Latitude
+		media.Longitude = capture.Longitude
+	}
+
+	if err := db.Create(&media).Error; err != nil {
+		return models.Media{}, err
+	}
+
+	return media, nil
+}
+
+// hashImportSource membuka ulang file import untuk menghitung perceptual hash gambar.
+func hashImportSource(source importSource, kind models.MediaKind, mimeType string) *int64 {
+	if kind.Name != models.MediaKindImage || !utils.IsHashableImage(mimeType) {
+		return nil
+	}
+
+	reader, err := source.Open()
+	if err != nil {
+		return nil
+	}
+	defer reader.Close()
+
+	hash, err := hashImageReader(io.LimitReader(reader, source.Size))
+	if err != nil {
+		log.Printf("Gagal menghitung hash gambar %s: %v", source.Name, err)
+		return nil
+	}
+	return &hash
+}
+
+// importCaptureInfo membaca EXIF (waktu pengambilan dan GPS) dari file import JPEG.
+func importCaptureInfo(source importSource, kind models.MediaKind, mimeType string) utils.CaptureInfo {
+	if kind.Name != models.MediaKindImage || !utils.HasExif(mimeType) {
+		return utils.CaptureInfo{}
+	}
+
+	reader, err := source.Open()
+	if err != nil {
+		return utils.CaptureInfo{}
+	}
+	defer reader.Close()
+
+	info, _ := utils.ReadCaptureInfo(io.LimitReader(reader, source.Size))
+	return info
+}
+
+func failAlbumImport(db *gorm.DB, albumImport models.AlbumImport, cause error) {
+	log.Printf("Import album %s gagal: %v", albumImport.ID, cause)
+
+	if err := db.Model(&albumImport).Updates(map[string]interface{}{
+		"status":       models.ImportStatusFailed,
+		"error":        cause.Error(),
+		"completed_at": time.Now(),
+	}).Error; err != nil {
+		log.Printf("Gagal update status import %s: %v", albumImport.ID, err)
+	}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if strings.TrimSpace(value) != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func uniqueStrings(values []string) []string {
+	seen := map[string]bool{}
+	result := []string{}
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" || seen[value] {
+			continue
+		}
+		seen[value] = true
+		result = append(result, value)
+	}
+	return result
+}
@@ -0,0 +1,22 @@
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"github.com/Zackly23/queue-app/models"
+	"gorm.io/gorm"
+)
+
+func ExpireAlbumInvitations(db *gorm.DB) {
+	result := db.Model(&models.AlbumInvitation{}).
+		Where("status = ? AND expires_at < ?", models.InvitationStatusPending, time.Now()).
+		Update("status", models.InvitationStatusExpired)
+
+	if result.Error != nil {
+		log.Println("Gagal Mengupdate Undangan Expired:", result.Error)
+		return
+	}
+
+	log.Printf("%d undangan album ditandai expired", result.RowsAffected)
+}
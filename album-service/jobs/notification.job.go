@@ -0,0 +1,205 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Zackly23/queue-app/events"
+	"github.com/Zackly23/queue-app/models"
+	notif "github.com/Zackly23/queue-app/proto/notificationpb"
+	"github.com/Zackly23/queue-app/realtime"
+	"github.com/Zackly23/queue-app/utils"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// NotificationDispatcher menyimpan notifikasi in-app dan/atau mengirim email untuk setiap
+// domain event sesuai preferensi penerima di AccountConfig.
+func NotificationDispatcher(db *gorm.DB, client notif.NotificationServiceClient) events.Handler {
+	return func(event events.Event) {
+		// Aksi terhadap diri sendiri tidak perlu dinotifikasi
+		if event.RecipientID == event.ActorID {
+			return
+		}
+
+		var recipient models.User
+		if err := db.Preload("AccountConfig").First(&recipient, "id = ?", event.RecipientID).Error; err != nil {
+			log.Printf("Penerima notifikasi %s tidak ditemukan: %v", event.RecipientID, err)
+			return
+		}
+
+		delivery := recipient.AccountConfig.NotificationDelivery(event.Type)
+		if delivery == models.NotificationDeliveryNone {
+			return
+		}
+
+		// User yang memblokir actor tidak menerima notifikasi dari actor tersebut
+		var blocked int64
+		if err := db.Model(&models.UserBlock{}).
+			Where("blocker_id = ? AND blocked_id = ?", event.RecipientID, event.ActorID).
+			Count(&blocked).Error; err != nil || blocked > 0 {
+			return
+		}
+
+		// ActorID kosong untuk notifikasi sistem, mis. keputusan moderator
+		var actor models.User
+		var actorID *uuid.UUID
+		if event.ActorID != uuid.Nil {
+			if err := db.First(&actor, "id = ?", event.ActorID).Error; err != nil {
+				log.Printf("Actor notifikasi %s tidak ditemukan: %v", event.ActorID, err)
+				return
+			}
+			actorID = &actor.ID
+		}
+
+		var album models.Album
+		if event.AlbumID != nil {
+			if err := db.First(&album, "id = ?", event.AlbumID).Error; err != nil {
+				log.Printf("Album notifikasi %s tidak ditemukan: %v", event.AlbumID, err)
+				return
+			}
+		}
+
+		message, link := notificationMessage(event, actor, album)
+
+		if delivery == models.NotificationDeliveryInApp || delivery == models.NotificationDeliveryAll {
+			stored := map[string]string{}
+			for key, value := range event.Data {
+				if key != "email_type" {
+					stored[key] = value
+				}
+			}
+			data, _ := json.Marshal(stored)
+			notification := models.Notification{
+				UserID:    recipient.ID,
+				ActorID:   actorID,
+				Event:     event.Type,
+				AlbumID:   event.AlbumID,
+				MediaID:   event.MediaID,
+				CommentID: event.CommentID,
+				Message:   message,
+				Link:      link,
+				Data:      data,
+			}
+			if err := db.Create(&notification).Error; err != nil {
+				log.Printf("Gagal menyimpan notifikasi untuk %s: %v", recipient.ID, err)
+			} else {
+				realtime.Broadcast(realtime.UserChannel(recipient.ID), realtime.MessageNotificationCreated, notification)
+			}
+		}
+
+		// email_type kosong berarti event tanpa email (mis. undangan yang emailnya dikirim terpisah)
+		emailType, ok := event.Data["email_type"]
+		if !ok {
+			emailType = "notification"
+		}
+		if client == nil || emailType == "" {
+			return
+		}
+		if delivery != models.NotificationDeliveryEmail && delivery != models.NotificationDeliveryAll {
+			return
+		}
+
+		metadata := map[string]string{
+			"message":       message,
+			"link":          link,
+			"platform_name": "PixoVaulty",
+			"platform_url":  "www.pixovaulty.com",
+		}
+		for key, value := range event.Data {
+			metadata[key] = value
+		}
+
+		ctxNotif, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		if _, err := client.SendNotification(ctxNotif, &notif.NotificationRequest{
+			To:       recipient.Email,
+			Subject:  message,
+			Type:     emailType,
+			Name:     recipient.FirstName + " " + recipient.LastName,
+			Body:     message,
+			Metadata: metadata,
+		}); err != nil {
+			log.Printf("Gagal mengirim email notifikasi ke %s: %v", recipient.Email, err)
+		}
+	}
+}
+
+func notificationMessage(event events.Event, actor models.User, album models.Album) (string, string) {
+	actorName := actor.UserName
+	if actorName == "" {
+		actorName = actor.FirstName + " " + actor.LastName
+	}
+
+	albumLink := ""
+	if event.AlbumID != nil {
+		albumLink = utils.FrontendURL("albums/" + event.AlbumID.String())
+	}
+
+	switch event.Type {
+	case models.NotificationEventFollow:
+		return fmt.Sprintf("%s mulai mengikuti Anda", actorName), utils.FrontendURL("users/" + actor.ID.String())
+	case models.NotificationEventFollowRequest:
+		return fmt.Sprintf("%s ingin mengikuti Anda", actorName), utils.FrontendURL("follow-requests")
+	case models.NotificationEventFollowAccepted:
+		return fmt.Sprintf("%s menerima permintaan follow Anda", actorName), utils.FrontendURL("users/" + actor.ID.String())
+	case models.NotificationEventReaction:
+		target := "album"
+		if event.MediaID != nil {
+			target = "media di album"
+		}
+		return fmt.Sprintf("%s memberi reaksi %s pada %s %s", actorName, event.Data["reaction"], target, album.Title), albumLink
+	case models.NotificationEventComment:
+		return fmt.Sprintf("%s mengomentari album %s", actorName, album.Title), albumLink
+	case models.NotificationEventCommentReply:
+		return fmt.Sprintf("%s membalas komentar Anda di album %s", actorName, album.Title), albumLink
+	case models.NotificationEventMention:
+		return fmt.Sprintf("%s menyebut Anda di album %s", actorName, album.Title), albumLink
+	case models.NotificationEventModeration:
+		return moderationMessage(event, album), albumLink
+	case models.NotificationEventAlbumInvitation:
+		return fmt.Sprintf("%s mengundang Anda ke album %s", actorName, album.Title), utils.FrontendURL("invitations")
+	case models.NotificationEventPaymentDue:
+		return fmt.Sprintf("Selesaikan pembayaran %s untuk plan %s agar perubahan langganan berlaku",
+			event.Data["amount"], event.Data["plan"]), event.Data["checkout_url"]
+	}
+
+	return fmt.Sprintf("Aktivitas baru dari %s", actorName), albumLink
+}
+
+// moderationMessage menjelaskan keputusan moderator tanpa menyebut nama moderatornya.
+func moderationMessage(event events.Event, album models.Album) string {
+	target := map[string]string{
+		models.ReportTargetAlbum:   "Album " + album.Title,
+		models.ReportTargetMedia:   "Media di album " + album.Title,
+		models.ReportTargetComment: "Komentar Anda di album " + album.Title,
+		models.ReportTargetUser:    "Profil Anda",
+	}[event.Data["target_type"]]
+
+	var message string
+	switch event.Data["action"] {
+	case models.ModerationActionHide:
+		message = fmt.Sprintf("%s disembunyikan oleh moderator", target)
+	case models.ModerationActionRemove:
+		message = fmt.Sprintf("%s dihapus oleh moderator", target)
+	case models.ModerationActionRestore:
+		message = fmt.Sprintf("%s dipulihkan oleh moderator", target)
+	case models.ModerationActionSuspend:
+		message = "Akun Anda ditangguhkan oleh moderator"
+		if until := event.Data["suspended_until"]; until != "" {
+			message += " hingga " + until
+		}
+	default:
+		message = fmt.Sprintf("Anda menerima peringatan dari moderator terkait %s", strings.ToLower(target))
+	}
+
+	if note := event.Data["note"]; note != "" {
+		message += ": " + note
+	}
+	return message
+}
@@ -0,0 +1,80 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"time"
+
+	"github.com/Zackly23/queue-app/models"
+	"github.com/Zackly23/queue-app/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	perceptualHashBatchSize = 200
+	maxHashFileSize         = 50 * 1024 * 1024 // gambar lebih besar dari ini dilewati
+)
+
+// hashImageReader membaca gambar ke memori (DifferenceHash butuh Seek) lalu menghitung dHash-nya.
+func hashImageReader(reader io.Reader) (int64, error) {
+	data, err := io.ReadAll(io.LimitReader(reader, maxHashFileSize))
+	if err != nil {
+		return 0, err
+	}
+	return utils.DifferenceHash(bytes.NewReader(data))
+}
+
+// BackfillPerceptualHashes menghitung perceptual hash untuk gambar yang di-upload sebelum
+// fitur pencarian gambar mirip ada. Diproses per batch dari file di S3.
+func BackfillPerceptualHashes(db *gorm.DB) {
+	processed, failed := 0, 0
+
+	for {
+		var medias []models.Media
+		if err := db.Where("kind = ? AND perceptual_hash IS NULL AND type IN ?", models.MediaKindImage, utils.HashableImageTypes()).
+			Order("created_at ASC, id ASC").
+			Offset(failed).
+			Limit(perceptualHashBatchSize).
+			Find(&medias).Error; err != nil {
+			log.Println("Gagal Mengambil Media untuk Hash:", err)
+			return
+		}
+		if len(medias) == 0 {
+			break
+		}
+
+		for _, media := range medias {
+			hash, err := hashMediaObject(media)
+			if err != nil {
+				// Media yang gagal tetap NULL dan dilewati dengan offset pada batch berikutnya
+				log.Printf("Gagal hash media %s: %v", media.ID, err)
+				failed++
+				continue
+			}
+
+			if err := db.Model(&media).UpdateColumn("perceptual_hash", hash).Error; err != nil {
+				log.Printf("Gagal menyimpan hash media %s: %v", media.ID, err)
+				failed++
+				continue
+			}
+			processed++
+		}
+	}
+
+	log.Printf("Backfill perceptual hash selesai: %d berhasil, %d gagal", processed, failed)
+}
+
+func hashMediaObject(media models.Media) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	body, err := utils.OpenS3Object(ctx, utils.S3KeyFromURL(media.URL))
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+
+	return hashImageReader(body)
+}
@@ -1,10 +1,14 @@
 package jobs
 
 import (
+	"context"
 	"log"
 	"time"
 
+	"github.com/Zackly23/queue-app/events"
 	"github.com/Zackly23/queue-app/models"
+	"github.com/Zackly23/queue-app/payments"
+	"github.com/Zackly23/queue-app/utils"
 	"gorm.io/gorm"
 )
 
@@ -29,3 +33,179 @@ func UpdateSubscriptionType(db *gorm.DB) {
 		}
 	}
 }
+
+// ApplyScheduledSubscriptions memberlakukan downgrade yang dijadwalkan saat periode sebelumnya berakhir.
+// Downgrade ke plan berbayar ditagih lebih dulu: user dipindah ke plan default, subscription
+// menunggu pembayaran dan baru aktif lewat notifikasi pembayaran dari provider.
+func ApplyScheduledSubscriptions(db *gorm.DB) {
+	now := time.Now()
+
+	var scheduled []models.UserSubscription
+	if err := db.Preload("Subscription", func(tx *gorm.DB) *gorm.DB {
+		return tx.Unscoped()
+	}).Where("status = ? AND start_date <= ?", models.UserSubscriptionScheduled, now).
+		Order("start_date ASC").
+		Find(&scheduled).Error; err != nil {
+		log.Println("Gagal Mengambil Subscription Terjadwal:", err)
+		return
+	}
+
+	for _, sub := range scheduled {
+		var err error
+		if sub.Amount > 0 {
+			err = chargeScheduledSubscription(db, sub, now)
+		} else {
+			err = db.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Model(&models.UserSubscription{}).
+					Where("user_id = ? AND status IN ? AND id <> ?", sub.UserID,
+						[]string{models.UserSubscriptionActive, models.UserSubscriptionFreeTier}, sub.ID).
+					Update("status", models.UserSubscriptionExpired).Error; err != nil {
+					return err
+				}
+
+				if err := tx.Model(&sub).Update("status", models.UserSubscriptionActive).Error; err != nil {
+					return err
+				}
+
+				return tx.Model(&models.User{}).Where("id = ?", sub.UserID).Update("subscription_id", sub.SubscriptionID).Error
+			})
+		}
+		if err != nil {
+			log.Printf("Gagal memberlakukan subscription %s: %v", sub.ID, err)
+		}
+	}
+}
+
+// chargeScheduledSubscription membuat tagihan dan checkout untuk downgrade berbayar yang jatuh tempo,
+// lalu memberi tahu user lewat notifikasi.
+func chargeScheduledSubscription(db *gorm.DB, sub models.UserSubscription, now time.Time) error {
+	gateway, gatewayErr := payments.Default()
+
+	var payment models.Payment
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.UserSubscription{}).
+			Where("user_id = ? AND status IN ?", sub.UserID,
+				[]string{models.UserSubscriptionActive, models.UserSubscriptionFreeTier}).
+			Update("status", models.UserSubscriptionExpired).Error; err != nil {
+			return err
+		}
+
+		// Tanpa payment provider plan berbayar tidak bisa ditagih, downgrade dibatalkan
+		status := models.UserSubscriptionPendingPayment
+		if gatewayErr != nil {
+			status = models.UserSubscriptionCancelled
+		}
+		if err := tx.Model(&sub).Update("status", status).Error; err != nil {
+			return err
+		}
+
+		if err := utils.RevertToDefaultPlan(tx, sub.UserID, now); err != nil {
+			return err
+		}
+		if gatewayErr != nil {
+			return nil
+		}
+
+		payment = models.Payment{
+			UserID:             sub.UserID,
+			UserSubscriptionID: sub.ID,
+			Provider:           gateway.Name(),
+			OrderID:            utils.NewPaymentOrderID(),
+			Amount:             float64(sub.Amount),
+			Currency:           sub.Currency,
+			Status:             models.PaymentStatusPending,
+		}
+		return tx.Create(&payment).Error
+	})
+	if err != nil {
+		return err
+	}
+	if gatewayErr != nil {
+		return gatewayErr
+	}
+
+	if err := utils.OpenCheckout(context.Background(), db, gateway, &payment, sub.Subscription); err != nil {
+		db.Model(&sub).Update("status", models.UserSubscriptionCancelled)
+		return err
+	}
+
+	events.Publish(events.Event{
+		Type:        models.NotificationEventPaymentDue,
+		RecipientID: sub.UserID,
+		Data: map[string]string{
+			"plan":         sub.Subscription.SubscriptionType,
+			"amount":       utils.FormatMoney(payment.Amount, payment.Currency),
+			"checkout_url": payment.CheckoutURL,
+		},
+	})
+	return nil
+}
+
+// ExpireStalePayments membatalkan checkout yang tidak pernah dibayar dan tidak mendapat
+// notifikasi expire dari provider.
+func ExpireStalePayments(db *gorm.DB) {
+	now := time.Now()
+	threshold := now.Add(-48 * time.Hour)
+
+	var stale []models.Payment
+	if err := db.Where("status = ? AND (expires_at < ? OR (expires_at IS NULL AND created_at < ?))",
+		models.PaymentStatusPending, now, threshold).
+		Find(&stale).Error; err != nil {
+		log.Println("Gagal Mengambil Pembayaran Tertunda:", err)
+		return
+	}
+
+	var expired []models.Payment
+	for _, payment := range stale {
+		claimed := false
+		err := db.Transaction(func(tx *gorm.DB) error {
+			result := tx.Model(&models.Payment{}).
+				Where("id = ? AND status = ?", payment.ID, models.PaymentStatusPending).
+				Update("status", models.PaymentStatusExpired)
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+			claimed = true
+
+			return tx.Model(&models.UserSubscription{}).
+				Where("id = ? AND status = ?", payment.UserSubscriptionID, models.UserSubscriptionPendingPayment).
+				Update("status", models.UserSubscriptionCancelled).Error
+		})
+		if err != nil {
+			log.Printf("Gagal membatalkan pembayaran %s: %v", payment.OrderID, err)
+		} else if claimed {
+			expired = append(expired, payment)
+		}
+	}
+
+	utils.CancelCheckouts(expired)
+}
+
+// BackfillInvoices menerbitkan invoice untuk pembayaran lunas yang belum punya invoice,
+// mis. pembayaran yang diterima sebelum invoice diperkenalkan.
+func BackfillInvoices(db *gorm.DB) {
+	var paid []models.Payment
+	if err := db.Where("status IN ? AND paid_at IS NOT NULL", []string{models.PaymentStatusPaid, models.PaymentStatusRefunded}).
+		Where("NOT EXISTS (SELECT 1 FROM invoices WHERE invoices.user_subscription_id = payments.user_subscription_id)").
+		Order("paid_at ASC").
+		Find(&paid).Error; err != nil {
+		log.Println("Gagal Mengambil Pembayaran Tanpa Invoice:", err)
+		return
+	}
+
+	for _, payment := range paid {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			invoice, err := utils.CreateInvoice(tx, payment, *payment.PaidAt)
+			if err != nil || payment.Status != models.PaymentStatusRefunded {
+				return err
+			}
+			return tx.Model(&invoice).Updates(map[string]interface{}{
+				"status":      models.InvoiceStatusRefunded,
+				"refunded_at": payment.RefundedAt,
+			}).Error
+		})
+		if err != nil {
+			log.Printf("Gagal membuat invoice pembayaran %s: %v", payment.OrderID, err)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package jobs
+
+import (
+	"fmt"
+
+	"github.com/Zackly23/queue-app/models"
+	"github.com/Zackly23/queue-app/utils"
+	"gorm.io/gorm"
+)
+
+// NormalizeAlbumTags mengubah nama tag lama menjadi slug dari utils.NormalizeTag. Tag yang setelah
+// dinormalisasi bernama sama (mis. "Travel" dan "travel ") digabung ke satu tag dengan utils.MergeTags.
+// Tag yang namanya sudah normal tidak disentuh, sehingga aman dijalankan setiap startup.
+func NormalizeAlbumTags(db *gorm.DB) error {
+	var tags []models.AlbumTag
+	if err := db.Order("created_at ASC").Find(&tags).Error; err != nil {
+		return err
+	}
+
+	groups := map[string][]models.AlbumTag{}
+	order := []string{}
+	for _, tag := range tags {
+		name := utils.NormalizeTag(tag.TagName)
+		if name == "" {
+			continue
+		}
+		if _, ok := groups[name]; !ok {
+			order = append(order, name)
+		}
+		groups[name] = append(groups[name], tag)
+	}
+
+	for _, name := range order {
+		group := groups[name]
+		if len(group) == 1 && group[0].TagName == name {
+			continue
+		}
+
+		// Tag yang sudah bernama normal dipertahankan, selain itu tag tertua
+		target := group[0]
+		for _, tag := range group {
+			if tag.TagName == name {
+				target = tag
+				break
+			}
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			for _, tag := range group {
+				if tag.ID == target.ID {
+					continue
+				}
+				if err := utils.MergeTags(tx, tag, target); err != nil {
+					return err
+				}
+			}
+			return tx.Model(&target).Update("tag_name", name).Error
+		})
+		if err != nil {
+			return fmt.Errorf("gagal normalisasi tag %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,40 @@
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"github.com/Zackly23/queue-app/models"
+	"github.com/Zackly23/queue-app/utils"
+	"gorm.io/gorm"
+)
+
+func PurgeExpiredTrash(db *gorm.DB) {
+	cutoff := time.Now().Add(-utils.TrashRetention())
+
+	// Album di trash beserta seluruh isinya
+	var albums []models.Album
+	if err := db.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).Find(&albums).Error; err != nil {
+		log.Println("Gagal Mengambil Album di Trash:", err)
+		return
+	}
+
+	for _, album := range albums {
+		if err := utils.PurgeAlbum(db, album); err != nil {
+			log.Printf("Gagal purge album %s: %v", album.ID, err)
+		}
+	}
+
+	// Media yang di-trash satu per satu
+	var medias []models.Media
+	if err := db.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).Find(&medias).Error; err != nil {
+		log.Println("Gagal Mengambil Media di Trash:", err)
+		return
+	}
+
+	for _, media := range medias {
+		if err := utils.PurgeMedia(db, media); err != nil {
+			log.Printf("Gagal purge media %s: %v", media.ID, err)
+		}
+	}
+}
@@ -18,7 +18,9 @@ import (
 	"github.com/robfig/cron/v3"
 
 	"github.com/Zackly23/queue-app/config"
+	"github.com/Zackly23/queue-app/events"
 	"github.com/Zackly23/queue-app/jobs"
+	"github.com/Zackly23/queue-app/payments"
 	notif "github.com/Zackly23/queue-app/proto/notificationpb"
 
 	"github.com/Zackly23/queue-app/routes"
@@ -51,6 +53,14 @@ func main() {
 
 	log.Println("Database connected successfully")
 
+	// nama tag lama menjadi slug, tag kembar digabung
+	if err := jobs.NormalizeAlbumTags(db); err != nil {
+		log.Println("Gagal normalisasi tag:", err)
+	}
+
+	// pembayaran lunas yang belum punya invoice
+	jobs.BackfillInvoices(db)
+
 	// setup cron job
 	cronJob := cron.New(cron.WithLocation(time.FixedZone("Asia/Jakarta", 7*60*60)))
 
@@ -64,6 +74,41 @@ func main() {
 		jobs.UpdateSubscriptionType(db)
 	})
 
+	cronJob.AddFunc("5 * * * *", func() {
+		log.Println("Menjalankan cron: ApplyScheduledSubscriptions")
+		jobs.ApplyScheduledSubscriptions(db)
+	})
+
+	cronJob.AddFunc("15 * * * *", func() {
+		log.Println("Menjalankan cron: ExpireStalePayments")
+		jobs.ExpireStalePayments(db)
+	})
+
+	cronJob.AddFunc("30 0 * * *", func() {
+		log.Println("Menjalankan cron: ExpireAlbumInvitations")
+		jobs.ExpireAlbumInvitations(db)
+	})
+
+	cronJob.AddFunc("0 3 * * *", func() {
+		log.Println("Menjalankan cron: PurgeExpiredTrash")
+		jobs.PurgeExpiredTrash(db)
+	})
+
+	cronJob.AddFunc("30 3 * * *", func() {
+		log.Println("Menjalankan cron: CleanupExpiredExports")
+		jobs.CleanupExpiredExports(db)
+	})
+
+	cronJob.AddFunc("0 4 * * *", func() {
+		log.Println("Menjalankan cron: BackfillPerceptualHashes")
+		jobs.BackfillPerceptualHashes(db)
+	})
+
+	cronJob.AddFunc("30 4 * * *", func() {
+		log.Println("Menjalankan cron: BackfillCaptureInfo")
+		jobs.BackfillCaptureInfo(db)
+	})
+
 	// cronJob.AddFunc("@every 1m", func() {
 	// 	log.Println("Menjalankan cron setiap 1 menit (testing)")
 	// })
@@ -80,6 +125,15 @@ func main() {
 
     client := notif.NewNotificationServiceClient(conn)
 
+	// payment provider dari env (PAYMENT_PROVIDER, MIDTRANS_SERVER_KEY, FAKE_PAYMENT_SECRET)
+	payments.Setup()
+
+	// notifikasi in-app / email untuk event sosial
+	events.Subscribe(jobs.NotificationDispatcher(db, client))
+
+	// export album yang terputus saat service berhenti
+	go jobs.ResumeAlbumExports(db, client)
+
 	// Init Fiber
 	app := fiber.New()
 
@@ -0,0 +1,65 @@
+package migrations
+
+import (
+	"fmt"
+
+	"github.com/Zackly23/queue-app/models"
+	"gorm.io/gorm"
+)
+
+// legacyMediaTables adalah tabel media lama per jenis yang digabung ke tabel media.
+var legacyMediaTables = []struct {
+	Table     string
+	Kind      string
+	URLColumn string
+	Thumbnail string // ekspresi SQL untuk thumbnail_url
+}{
+	{Table: "album_images", Kind: models.MediaKindImage, URLColumn: "image_url", Thumbnail: "NULL"},
+	{Table: "album_videos", Kind: models.MediaKindVideo, URLColumn: "video_url", Thumbnail: "thumbnail_url"},
+}
+
+// MigrateLegacyMedia memindahkan data album_images dan album_videos ke tabel media dengan ID yang sama,
+// sehingga media_likes dan cover_media_id tetap valid. Tabel lama di-rename menjadi *_legacy
+// agar migrasi tidak berjalan dua kali dan data lama masih bisa diperiksa.
+// Harus dijalankan sebelum AutoMigrate model lain karena media_likes kini punya foreign key ke media.
+func MigrateLegacyMedia(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.Media{}); err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		migrator := tx.Migrator()
+
+		for _, legacy := range legacyMediaTables {
+			if !migrator.HasTable(legacy.Table) {
+				continue
+			}
+
+			position := "0"
+			if migrator.HasColumn(legacy.Table, "position") {
+				position = "position"
+			}
+
+			if err := tx.Exec(fmt.Sprintf(`INSERT INTO media
+				(id, album_id, kind, url, thumbnail_url, description, likes_count, size, type, position, created_at, updated_at, deleted_at)
+				SELECT id, album_id, ?, %s, %s, description, likes_count, size, type, %s, created_at, updated_at, deleted_at
+				FROM %s
+				ON CONFLICT (id) DO NOTHING`, legacy.URLColumn, legacy.Thumbnail, position, legacy.Table), legacy.Kind).Error; err != nil {
+				return fmt.Errorf("gagal memindahkan %s: %w", legacy.Table, err)
+			}
+
+			if err := migrator.RenameTable(legacy.Table, legacy.Table+"_legacy"); err != nil {
+				return fmt.Errorf("gagal rename %s: %w", legacy.Table, err)
+			}
+		}
+
+		// Like untuk media yang sudah tidak ada akan melanggar foreign key baru
+		if migrator.HasTable("media_likes") {
+			if err := tx.Exec(`DELETE FROM media_likes WHERE media_id NOT IN (SELECT id FROM media)`).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
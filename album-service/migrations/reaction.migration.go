@@ -0,0 +1,73 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+// legacyLikeTables adalah tabel like lama yang di-rename menjadi tabel reaksi.
+var legacyLikeTables = []struct {
+	Table     string
+	NewTable  string
+	Target    string   // kolom target (album_id / media_id)
+	OldIndexes []string // index lama yang digantikan index baru dari AutoMigrate
+}{
+	{
+		Table:     "album_likes",
+		NewTable:  "album_reactions",
+		Target:    "album_id",
+		OldIndexes: []string{"idx_album_likes_album_id", "idx_album_likes_user_id", "idx_album_likes_deleted_at"},
+	},
+	{
+		Table:     "media_likes",
+		NewTable:  "media_reactions",
+		Target:    "media_id",
+		OldIndexes: []string{"idx_user_media", "idx_media_likes_deleted_at"},
+	},
+}
+
+// MigrateLikesToReactions me-rename album_likes dan media_likes menjadi tabel reaksi. Like yang
+// sudah di-unlike (soft delete) dan like ganda dibuang lebih dulu agar unique index (target, user)
+// bisa dibuat. Kolom type akan ditambahkan AutoMigrate dengan default "like".
+// Harus dijalankan sebelum AutoMigrate.
+func MigrateLikesToReactions(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		migrator := tx.Migrator()
+
+		for _, legacy := range legacyLikeTables {
+			if !migrator.HasTable(legacy.Table) || migrator.HasTable(legacy.NewTable) {
+				continue
+			}
+
+			if migrator.HasColumn(legacy.Table, "deleted_at") {
+				if err := tx.Exec(`DELETE FROM ` + legacy.Table + ` WHERE deleted_at IS NOT NULL`).Error; err != nil {
+					return err
+				}
+			}
+
+			// Sisakan like paling awal bila satu user tercatat lebih dari sekali
+			if err := tx.Exec(`DELETE FROM ` + legacy.Table + ` a USING ` + legacy.Table + ` b
+				WHERE a.` + legacy.Target + ` = b.` + legacy.Target + ` AND a.user_id = b.user_id
+				AND (a.created_at, a.id) > (b.created_at, b.id)`).Error; err != nil {
+				return err
+			}
+
+			for _, index := range legacy.OldIndexes {
+				if err := tx.Exec(`DROP INDEX IF EXISTS ` + index).Error; err != nil {
+					return err
+				}
+			}
+
+			if err := migrator.RenameTable(legacy.Table, legacy.NewTable); err != nil {
+				return err
+			}
+
+			if migrator.HasColumn(legacy.NewTable, "deleted_at") {
+				if err := tx.Exec(`ALTER TABLE ` + legacy.NewTable + ` DROP COLUMN deleted_at`).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+}
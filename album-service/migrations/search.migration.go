@@ -0,0 +1,41 @@
+package migrations
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// searchVectors adalah kolom tsvector per tabel yang dicari oleh endpoint search.
+// Memakai konfigurasi "simple" (tanpa stemming) karena konten campuran bahasa Indonesia dan Inggris.
+var searchVectors = []struct {
+	Table      string
+	Expression string
+}{
+	{Table: "albums", Expression: `setweight(to_tsvector('simple', coalesce(title, '')), 'A') ||
+		setweight(to_tsvector('simple', coalesce(description, '')), 'B')`},
+	{Table: "media", Expression: `setweight(to_tsvector('simple', coalesce(title, '')), 'A') ||
+		setweight(to_tsvector('simple', coalesce(description, '')), 'B')`},
+	{Table: "album_tags", Expression: `to_tsvector('simple', coalesce(tag_name, ''))`},
+	{Table: "users", Expression: `setweight(to_tsvector('simple', coalesce(first_name, '') || ' ' || coalesce(last_name, '') || ' ' || coalesce(user_name, '')), 'A') ||
+		setweight(to_tsvector('simple', coalesce(bio, '')), 'C')`},
+}
+
+// MigrateSearchVectors menambahkan kolom generated search_vector beserta index GIN.
+// Kolom ini tidak ada di model agar tidak disentuh AutoMigrate, sehingga harus dijalankan
+// setelah AutoMigrate membuat tabelnya.
+func MigrateSearchVectors(db *gorm.DB) error {
+	for _, target := range searchVectors {
+		if err := db.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN IF NOT EXISTS search_vector tsvector
+			GENERATED ALWAYS AS (%s) STORED`, target.Table, target.Expression)).Error; err != nil {
+			return fmt.Errorf("gagal menambahkan search_vector ke %s: %w", target.Table, err)
+		}
+
+		if err := db.Exec(fmt.Sprintf(`CREATE INDEX IF NOT EXISTS idx_%s_search_vector ON %s USING GIN (search_vector)`,
+			target.Table, target.Table)).Error; err != nil {
+			return fmt.Errorf("gagal membuat index search_vector %s: %w", target.Table, err)
+		}
+	}
+
+	return nil
+}
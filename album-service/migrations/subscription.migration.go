@@ -0,0 +1,38 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+// MigrateSubscriptionPlans mengisi kode plan untuk subscription lama (dari nama plan, mis. "Basic" menjadi "basic")
+// sebagai versi 1, agar unique index (code, version) bisa dibuat oleh AutoMigrate.
+// Harus dijalankan sebelum migrasi lain yang memanggil AutoMigrate, termasuk MigrateLegacyMedia,
+// karena AutoMigrate model apa pun yang berelasi ke User ikut membuat kolom code dan index-nya.
+func MigrateSubscriptionPlans(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if !migrator.HasTable("subscriptions") || migrator.HasColumn("subscriptions", "code") {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`ALTER TABLE subscriptions
+			ADD COLUMN code varchar(50) NOT NULL DEFAULT '',
+			ADD COLUMN version bigint NOT NULL DEFAULT 1`).Error; err != nil {
+			return err
+		}
+
+		// Nama plan ganda mendapat nomor versi berurutan
+		return tx.Exec(`UPDATE subscriptions s
+			SET code = p.code, version = p.version
+			FROM (
+				SELECT id,
+					trim(both '-' from lower(regexp_replace(subscription_type, '[^a-zA-Z0-9]+', '-', 'g'))) AS code,
+					ROW_NUMBER() OVER (
+						PARTITION BY lower(regexp_replace(subscription_type, '[^a-zA-Z0-9]+', '-', 'g'))
+						ORDER BY created_at, id
+					) AS version
+				FROM subscriptions
+			) p
+			WHERE p.id = s.id`).Error
+	})
+}
@@ -10,61 +10,81 @@ import (
 
 type AlbumTag struct {
 	ID        uuid.UUID      `gorm:"type:uuid;default:uuid_generate_v4()" json:"id"`
-	TagName   string         `gorm:"not null;uniqueIndex" json:"tag_name"`
+	TagName   string         `gorm:"not null;uniqueIndex" json:"tag_name"` // slug hasil utils.NormalizeTag
+	Aliases   []TagAlias     `gorm:"foreignKey:TagID" json:"aliases,omitempty"`
 	CreatedAt time.Time      `gorm:"autoCreateTime" json:"created_at"`
 	UpdatedAt time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
 }
 
-type AlbumImage struct {
-	ID          uuid.UUID      `gorm:"type:uuid;default:uuid_generate_v4()" json:"id"`
-	AlbumID     uuid.UUID      `gorm:"type:uuid;not null;index" json:"album_id"`
-	Album       Album          `gorm:"foreignKey:AlbumID" json:"album,omitempty"` // optional
-	ImageURL    string         `gorm:"not null;type:varchar(255)" json:"image_url"`
-	Description string         `json:"description,omitempty"`
-	LikesCount  uint           `gorm:"default:0" json:"likes_count"`
-	Size        float32        `json:"size"`
-	Type        string         `json:"type"`
-	CreatedAt   time.Time      `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt   time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
-}
-
-type AlbumVideo struct {
-	ID           uuid.UUID      `gorm:"type:uuid;default:uuid_generate_v4()" json:"id"`
-	AlbumID      uuid.UUID      `gorm:"type:uuid;not null;index" json:"album_id"`
-	Album        Album          `gorm:"foreignKey:AlbumID" json:"album,omitempty"` // optional
-	VideoURL     string         `gorm:"not null;type:varchar(255)" json:"video_url"`
-	Description  string         `json:"description,omitempty"`
-	LikesCount   uint           `gorm:"default:0" json:"likes_count"`
-	Size         float32        `json:"size"`
-	Type         string         `json:"type"`
-	ThumbnailURL string         `gorm:"type:varchar(255)" json:"thumbnail_url,omitempty"`
-	CreatedAt    time.Time      `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt    time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
-	DeletedAt    gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+// TagAlias mengarahkan nama tag lain (mis. tag yang sudah di-merge) ke tag utama.
+type TagAlias struct {
+	Alias     string    `gorm:"primaryKey;type:varchar(50)" json:"alias"`
+	TagID     uuid.UUID `gorm:"type:uuid;not null;index" json:"tag_id"`
+	Tag       AlbumTag  `gorm:"foreignKey:TagID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
 }
 
 type Album struct {
 	ID           uuid.UUID       `gorm:"type:uuid;default:uuid_generate_v4()" json:"id"`
 	UserID       uuid.UUID       `gorm:"type:uuid;not null;index" json:"user_id"`
 	User      	 User      `gorm:"foreignKey:UserID;references:ID" json:"user,omitempty"`
+	ParentID     *uuid.UUID      `gorm:"type:uuid;index" json:"parent_id,omitempty"`
+	Path         string          `gorm:"type:text;index" json:"path,omitempty"` // "/root_id/.../album_id/"
+	Parent       *Album          `gorm:"foreignKey:ParentID;references:ID" json:"parent,omitempty"`
+	SourceAlbumID *uuid.UUID     `gorm:"type:uuid;index" json:"source_album_id,omitempty"` // album asal bila hasil duplikasi
+	Collections  []Collection    `gorm:"many2many:album_collections" json:"collections,omitempty"`
 	Tags         []AlbumTag      `gorm:"many2many:album_album_tags" json:"tags,omitempty"`
 	Title        string          `gorm:"not null" json:"title"`
 	Description  string          `json:"description,omitempty"`
 	CoverImage   string          `gorm:"type:varchar(255)" json:"cover_image,omitempty"`
+	CoverMediaID *uuid.UUID      `gorm:"type:uuid" json:"cover_media_id,omitempty"` // cover yang dipilih manual oleh pemilik
 	AlbumPrivacy string          `json:"album_privacy"`
-	AlbumImages  []AlbumImage    `gorm:"foreignKey:AlbumID" json:"album_images,omitempty"`
-	AlbumVideos  []AlbumVideo    `gorm:"foreignKey:AlbumID" json:"album_videos,omitempty"`
+	AlbumType    string          `gorm:"type:varchar(20);not null;default:regular;index" json:"album_type"` // regular atau smart
+	SmartFilter  json.RawMessage `gorm:"type:jsonb" json:"smart_filter,omitempty"` // SmartAlbumFilter, hanya untuk smart album
+	AllowDownload bool           `gorm:"not null;default:true" json:"allow_download"` // viewer boleh download ZIP album
+	ModerationStatus string      `gorm:"type:varchar(20);not null;default:visible;index" json:"moderation_status"` // hidden/removed oleh moderator
+	Media        []Media         `gorm:"foreignKey:AlbumID" json:"media,omitempty"`
 	Comments	 []AlbumComment  `gorm:"foreignKey:AlbumID" json:"album_comments,omitempty"`
 	TargetEmail  json.RawMessage `gorm:"type:jsonb" json:"target_email,omitempty"`
 	ViewCount    uint           	`gorm:"default:0" json:"view_count"`
-	LikesCount   uint           `gorm:"default:0" json:"likes_count"`
+	LikesCount   uint           `gorm:"default:0" json:"likes_count"` // total semua reaksi
+	ReactionCounts json.RawMessage `gorm:"type:jsonb;not null;default:'{}'" json:"reaction_counts"` // jumlah per jenis reaksi
 	CreatedAt    time.Time       `gorm:"autoCreateTime" json:"created_at"`
 	UpdatedAt    time.Time       `gorm:"autoUpdateTime" json:"updated_at"`
 	DeletedAt    gorm.DeletedAt  `gorm:"index" json:"deleted_at,omitempty"`
 }
 
+const (
+	AlbumTypeRegular = "regular"
+	AlbumTypeSmart   = "smart" // isi album dihitung dari SmartFilter saat dibaca, tidak punya media sendiri
+)
+
+const (
+	AlbumPrivacyPublic     = "public"
+	AlbumPrivacyRestricted = "restricted" // hanya pemilik dan email undangan yang sudah menerima
+	AlbumPrivacyPrivate    = "private"
+)
+
+// ValidAlbumPrivacy mengecek apakah nilai album_privacy dikenal.
+func ValidAlbumPrivacy(privacy string) bool {
+	switch privacy {
+	case AlbumPrivacyPublic, AlbumPrivacyRestricted, AlbumPrivacyPrivate:
+		return true
+	}
+	return false
+}
+
+// SmartAlbumFilter adalah kriteria media untuk smart album. Semua kriteria yang diisi harus terpenuhi.
+type SmartAlbumFilter struct {
+	Tags      []string   `json:"tags,omitempty"`        // tag album (sudah dinormalisasi), cukup salah satu cocok
+	DateFrom  string     `json:"date_from,omitempty"`   // YYYY-MM-DD, waktu pengambilan (EXIF) atau upload
+	DateTo    string     `json:"date_to,omitempty"`     // YYYY-MM-DD, inklusif
+	Kinds     []string   `json:"kinds,omitempty"`       // image, video, ...
+	OwnerID   *uuid.UUID `json:"owner_id,omitempty"`    // default pemilik smart album, kecuali liked_by_me
+	LikedByMe bool       `json:"liked_by_me,omitempty"` // media yang diberi reaksi oleh pemilik smart album
+}
+
 type TempMedia struct {
 	ID        uuid.UUID      `gorm:"type:uuid;default:uuid_generate_v4()" json:"id"`
 	MediaURL  string         `gorm:"not null;type:varchar(255)" json:"media_url"`
@@ -77,36 +97,34 @@ type TempMedia struct {
 }
 
 
-type AlbumLike struct {
-	ID        uuid.UUID      `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
-	AlbumID   uuid.UUID `json:"album_id" gorm:"type:uuid;not null;index"`
-	Album     Album     `gorm:"foreignKey:AlbumID;references:ID" json:"album,omitempty"`
-	UserID    uuid.UUID `json:"user_id" gorm:"type:uuid;not null;index"`
-	User      User      `gorm:"foreignKey:UserID;references:ID" json:"user,omitempty"`
-	CreatedAt time.Time      `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
-}
-
+const (
+	CommentStatusVisible = "visible"
+	CommentStatusHidden  = "hidden" // disembunyikan pemilik album, hanya terlihat oleh penulis dan pemilik
+)
 
-type MediaLike struct {
-	ID        uuid.UUID      `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
-	MediaID   uuid.UUID      `json:"media_id" gorm:"type:uuid;not null;index:idx_user_media,unique"`
-	UserID    uuid.UUID      `json:"user_id" gorm:"type:uuid;not null;index:idx_user_media,unique"`
-	User      User           `gorm:"foreignKey:UserID;references:ID" json:"user,omitempty"`
-	CreatedAt time.Time      `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+type AlbumComment struct {
+	ID          uuid.UUID      `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	AlbumID     uuid.UUID      `json:"album_id" gorm:"type:uuid;not null;index"`
+	Album       Album          `gorm:"foreignKey:AlbumID;references:ID" json:"album,omitempty"`
+	MediaID     *uuid.UUID     `json:"media_id,omitempty" gorm:"type:uuid;index"` // komentar pada media tertentu
+	ParentID    *uuid.UUID     `json:"parent_id,omitempty" gorm:"type:uuid;index"` // balasan dari komentar lain
+	UserID      uuid.UUID      `json:"user_id" gorm:"type:uuid;not null;index"`
+	User        User           `gorm:"foreignKey:UserID;references:ID" json:"user,omitempty"`
+	Comment     string         `json:"comment" gorm:"type:text;not null"`
+	Status      string         `json:"status" gorm:"type:varchar(20);not null;default:visible"`
+	EditedAt    *time.Time     `json:"edited_at,omitempty"`
+	DeletedByID *uuid.UUID     `json:"-" gorm:"type:uuid"`
+	Edits       []AlbumCommentEdit `gorm:"foreignKey:CommentID" json:"edits,omitempty"`
+	Tags        []AlbumTag     `gorm:"many2many:album_comment_tags" json:"tags,omitempty"` // dari #hashtag di komentar
+	CreatedAt   time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt   time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
 }
 
-type AlbumComment struct {
-	ID        uuid.UUID      `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
-	AlbumID   uuid.UUID      `json:"album_id" gorm:"type:uuid;not null;index"`
-	Album     Album          `gorm:"foreignKey:AlbumID;references:ID" json:"album,omitempty"`
-	UserID    uuid.UUID      `json:"user_id" gorm:"type:uuid;not null;index"`
-	User      User           `gorm:"foreignKey:UserID;references:ID" json:"user,omitempty"`
-	Comment   string         `json:"comment" gorm:"type:text;not null"`
-	CreatedAt time.Time      `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+// AlbumCommentEdit menyimpan isi komentar sebelum diedit.
+type AlbumCommentEdit struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	CommentID uuid.UUID `json:"comment_id" gorm:"type:uuid;not null;index"`
+	Comment   string    `json:"comment" gorm:"type:text;not null"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
 }
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserBlock: blocker menyembunyikan profil, album dan komentarnya dari blocked,
+// dan keduanya tidak bisa saling follow.
+type UserBlock struct {
+	BlockerID uuid.UUID `gorm:"type:uuid;not null;primaryKey" json:"blocker_id"`
+	BlockedID uuid.UUID `gorm:"type:uuid;not null;primaryKey;index" json:"blocked_id"`
+	Blocked   User      `gorm:"foreignKey:BlockedID" json:"-"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+const (
+	ExportStatusPending    = "pending"
+	ExportStatusProcessing = "processing"
+	ExportStatusCompleted  = "completed"
+	ExportStatusFailed     = "failed"
+	ExportStatusExpired    = "expired"
+)
+
+// AlbumExport adalah arsip ZIP album yang terlalu besar untuk di-stream langsung.
+type AlbumExport struct {
+	ID          uuid.UUID      `gorm:"type:uuid;default:uuid_generate_v4()" json:"id"`
+	AlbumID     uuid.UUID      `gorm:"type:uuid;not null;index" json:"album_id"`
+	Album       Album          `gorm:"foreignKey:AlbumID;references:ID" json:"album,omitempty"`
+	UserID      uuid.UUID      `gorm:"type:uuid;not null;index" json:"user_id"` // user yang meminta export
+	User        User           `gorm:"foreignKey:UserID;references:ID" json:"user,omitempty"`
+	MediaIDs    pq.StringArray `gorm:"type:text[]" json:"media_ids,omitempty"` // kosong = seluruh media album
+	Status      string         `gorm:"type:varchar(20);not null;default:pending;index" json:"status"`
+	MediaCount  int            `json:"media_count"`
+	TotalSize   float32        `json:"total_size"` // MB
+	ObjectKey   string         `json:"-"`
+	Error       string         `json:"error,omitempty"`
+	CompletedAt *time.Time     `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time     `json:"expires_at,omitempty"`
+	CreatedAt   time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt   time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+}
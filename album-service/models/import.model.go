@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+const (
+	ImportStatusPending             = "pending"
+	ImportStatusProcessing          = "processing"
+	ImportStatusCompleted           = "completed"
+	ImportStatusCompletedWithErrors = "completed_with_errors"
+	ImportStatusFailed              = "failed"
+
+	ImportItemStatusPending  = "pending"
+	ImportItemStatusImported = "imported"
+	ImportItemStatusSkipped  = "skipped"
+	ImportItemStatusFailed   = "failed"
+)
+
+// AlbumImport adalah job pembuatan album dari ZIP atau upload folder.
+type AlbumImport struct {
+	ID             uuid.UUID         `gorm:"type:uuid;default:uuid_generate_v4()" json:"id"`
+	UserID         uuid.UUID         `gorm:"type:uuid;not null;index" json:"user_id"`
+	User           User              `gorm:"foreignKey:UserID;references:ID" json:"user,omitempty"`
+	AlbumID        *uuid.UUID        `gorm:"type:uuid;index" json:"album_id,omitempty"` // terisi setelah album dibuat
+	ParentID       *uuid.UUID        `gorm:"type:uuid" json:"parent_id,omitempty"`
+	Title          string            `json:"title"`
+	Description    string            `json:"description,omitempty"`
+	AlbumPrivacy   string            `json:"album_privacy"`
+	Tags           pq.StringArray    `gorm:"type:text[]" json:"tags,omitempty"`
+	SourceType     string            `gorm:"type:varchar(20)" json:"source_type"` // "zip" atau "files"
+	SourcePath     string            `json:"-"`                                   // direktori sementara berisi file upload
+	Status         string            `gorm:"type:varchar(30);not null;default:pending;index" json:"status"`
+	TotalItems     int               `json:"total_items"`
+	ProcessedItems int               `json:"processed_items"`
+	FailedItems    int               `json:"failed_items"`
+	Error          string            `json:"error,omitempty"`
+	Items          []AlbumImportItem `gorm:"foreignKey:ImportID" json:"items,omitempty"`
+	StartedAt      *time.Time        `json:"started_at,omitempty"`
+	CompletedAt    *time.Time        `json:"completed_at,omitempty"`
+	CreatedAt      time.Time         `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt      time.Time         `gorm:"autoUpdateTime" json:"updated_at"`
+	DeletedAt      gorm.DeletedAt    `gorm:"index" json:"deleted_at,omitempty"`
+}
+
+type AlbumImportItem struct {
+	ID        uuid.UUID      `gorm:"type:uuid;default:uuid_generate_v4()" json:"id"`
+	ImportID  uuid.UUID      `gorm:"type:uuid;not null;index" json:"import_id"`
+	FileName  string         `gorm:"not null" json:"file_name"`
+	Size      float32        `json:"size"` // MB
+	Status    string         `gorm:"type:varchar(20);not null;default:pending" json:"status"`
+	Error     string         `json:"error,omitempty"`
+	MediaID   *uuid.UUID     `gorm:"type:uuid" json:"media_id,omitempty"`
+	CreatedAt time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	InvitationStatusPending   = "pending"
+	InvitationStatusAccepted  = "accepted"
+	InvitationStatusDeclined  = "declined"
+	InvitationStatusExpired   = "expired"
+	InvitationStatusCancelled = "cancelled"
+)
+
+type AlbumInvitation struct {
+	ID          uuid.UUID      `gorm:"type:uuid;default:uuid_generate_v4()" json:"id"`
+	AlbumID     uuid.UUID      `gorm:"type:uuid;not null;index" json:"album_id"`
+	Album       Album          `gorm:"foreignKey:AlbumID;references:ID" json:"album,omitempty"`
+	InviterID   uuid.UUID      `gorm:"type:uuid;not null;index" json:"inviter_id"`
+	Inviter     User           `gorm:"foreignKey:InviterID;references:ID" json:"inviter,omitempty"`
+	Email       string         `gorm:"not null;index" json:"email"`
+	UserID      *uuid.UUID     `gorm:"type:uuid;index" json:"user_id,omitempty"` // terisi saat undangan diterima
+	Status      string         `gorm:"type:varchar(20);not null;default:pending;index" json:"status"`
+	TokenNonce  string         `gorm:"type:varchar(64);not null" json:"-"` // diganti setiap resend agar token lama tidak berlaku
+	AllowDownload bool         `gorm:"not null;default:true" json:"allow_download"` // izin download ZIP untuk user undangan ini
+	SentCount   int            `gorm:"default:1" json:"sent_count"`
+	LastSentAt  time.Time      `json:"last_sent_at"`
+	ExpiresAt   time.Time      `gorm:"not null" json:"expires_at"`
+	RespondedAt *time.Time     `json:"responded_at,omitempty"`
+	CreatedAt   time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt   time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	InvoiceStatusPaid     = "paid"
+	InvoiceStatusRefunded = "refunded"
+
+	InvoiceLinePlan   = "plan"
+	InvoiceLineCredit = "credit" // potongan proration dari periode sebelumnya
+	InvoiceLineTax    = "tax"
+)
+
+// Invoice adalah bukti tagihan UserSubscription yang sudah dibayar. Data penagihan disalin
+// dari User saat invoice terbit agar perubahan profil tidak mengubah invoice lama.
+type Invoice struct {
+	ID                 uuid.UUID     `gorm:"type:uuid;default:uuid_generate_v4()" json:"id"`
+	Number             string        `gorm:"type:varchar(30);not null;uniqueIndex" json:"number"` // INV-<tahun>-<urutan>
+	UserID             uuid.UUID     `gorm:"type:uuid;not null;index" json:"user_id"`
+	UserSubscriptionID uuid.UUID     `gorm:"type:uuid;not null;uniqueIndex" json:"user_subscription_id"`
+	PaymentID          *uuid.UUID    `gorm:"type:uuid;index" json:"payment_id,omitempty"`
+	Status             string        `gorm:"type:varchar(20);not null;default:paid;index" json:"status"`
+	Currency           string        `gorm:"type:varchar(3);not null" json:"currency"`
+	Subtotal           float64       `json:"subtotal"` // sebelum pajak
+	TaxName            string        `gorm:"type:varchar(30)" json:"tax_name,omitempty"`
+	TaxRate            float64       `json:"tax_rate"` // persen, harga plan sudah termasuk pajak
+	TaxAmount          float64       `json:"tax_amount"`
+	Total              float64       `json:"total"`
+	PaymentMethod      string        `gorm:"type:varchar(100)" json:"payment_method,omitempty"`
+	BillingName        string        `json:"billing_name"`
+	BillingEmail       string        `json:"billing_email"`
+	BillingCompany     string        `json:"billing_company,omitempty"`
+	BillingAddress     string        `json:"billing_address,omitempty"`
+	BillingCity        string        `json:"billing_city,omitempty"`
+	BillingState       string        `json:"billing_state,omitempty"`
+	BillingZipCode     string        `json:"billing_zip_code,omitempty"`
+	BillingCountry     string        `json:"billing_country,omitempty"`
+	Lines              []InvoiceLine `gorm:"foreignKey:InvoiceID;constraint:OnDelete:CASCADE" json:"lines,omitempty"`
+	IssuedAt           time.Time     `gorm:"not null" json:"issued_at"`
+	PaidAt             time.Time     `gorm:"not null" json:"paid_at"`
+	RefundedAt         *time.Time    `json:"refunded_at,omitempty"`
+	EmailedAt          *time.Time    `json:"emailed_at,omitempty"` // kuitansi terakhir dikirim
+	CreatedAt          time.Time     `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt          time.Time     `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+type InvoiceLine struct {
+	ID          uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4()" json:"id"`
+	InvoiceID   uuid.UUID `gorm:"type:uuid;not null;index" json:"invoice_id"`
+	Kind        string    `gorm:"type:varchar(20);not null" json:"kind"`
+	Description string    `json:"description"`
+	Quantity    int       `gorm:"not null;default:1" json:"quantity"`
+	UnitPrice   float64   `json:"unit_price"`
+	Amount      float64   `json:"amount"` // sebelum pajak, kecuali baris pajak
+	SortOrder   int       `json:"sort_order"`
+}
+
+// InvoiceSequence menyimpan nomor invoice terakhir per tahun. Nomor diambil di transaksi
+// yang sama dengan pembuatan invoice sehingga urutannya tidak berlubang.
+type InvoiceSequence struct {
+	Year       int   `gorm:"primaryKey;autoIncrement:false"`
+	LastNumber int64 `gorm:"not null"`
+}
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	MediaKindImage = "image"
+	MediaKindVideo = "video"
+)
+
+// MediaKind mendeskripsikan satu jenis media yang bisa disimpan di album.
+// Jenis baru (audio, document, ...) cukup didaftarkan lewat RegisterMediaKind.
+type MediaKind struct {
+	Name             string
+	MimePrefixes     []string // prefix mime type yang termasuk jenis ini, mis. "image/"
+	StorageFolder    string   // folder S3, mis. "images"
+	DefaultThumbnail string   // dipakai bila thumbnail tidak bisa dibuat dari file-nya
+	UseFileAsCover   bool     // file-nya sendiri bisa dipakai sebagai cover album
+}
+
+var mediaKinds = map[string]MediaKind{}
+
+func RegisterMediaKind(kind MediaKind) {
+	mediaKinds[kind.Name] = kind
+}
+
+func LookupMediaKind(name string) (MediaKind, bool) {
+	kind, ok := mediaKinds[name]
+	return kind, ok
+}
+
+// MediaKindForMime mencari jenis media berdasarkan mime type file.
+func MediaKindForMime(mimeType string) (MediaKind, bool) {
+	for _, kind := range mediaKinds {
+		for _, prefix := range kind.MimePrefixes {
+			if strings.HasPrefix(mimeType, prefix) {
+				return kind, true
+			}
+		}
+	}
+	return MediaKind{}, false
+}
+
+func init() {
+	RegisterMediaKind(MediaKind{
+		Name:           MediaKindImage,
+		MimePrefixes:   []string{"image/"},
+		StorageFolder:  "images",
+		UseFileAsCover: true,
+	})
+	RegisterMediaKind(MediaKind{
+		Name:             MediaKindVideo,
+		MimePrefixes:     []string{"video/"},
+		StorageFolder:    "videos",
+		DefaultThumbnail: "https://s3-pixovaulty.s3.ap-southeast-1.amazonaws.com/images/default/default_video_thumb.png",
+	})
+}
+
+type Media struct {
+	ID               uuid.UUID       `gorm:"type:uuid;default:uuid_generate_v4()" json:"id"`
+	AlbumID          uuid.UUID       `gorm:"type:uuid;not null;index" json:"album_id"`
+	Album            Album           `gorm:"foreignKey:AlbumID" json:"album,omitempty"`   // optional
+	Kind             string          `gorm:"type:varchar(20);not null;index" json:"kind"` // "image", "video", ...
+	URL              string          `gorm:"not null;type:varchar(255)" json:"url"`
+	ThumbnailURL     string          `gorm:"type:varchar(255)" json:"thumbnail_url,omitempty"`
+	Title            string          `json:"title,omitempty"`
+	Description      string          `json:"description,omitempty"`
+	LikesCount       uint            `gorm:"default:0" json:"likes_count"`                            // total semua reaksi
+	ReactionCounts   json.RawMessage `gorm:"type:jsonb;not null;default:'{}'" json:"reaction_counts"` // jumlah per jenis reaksi
+	Size             float32         `json:"size"`
+	Type             string          `json:"type"`                                                                     // mime type
+	Position         int             `gorm:"default:0;index" json:"position"`                                          // urutan manual dalam album
+	Metadata         json.RawMessage `gorm:"type:jsonb" json:"metadata,omitempty"`                                     // data khusus per jenis media
+	SourceMediaID    *uuid.UUID      `gorm:"type:uuid;index" json:"source_media_id,omitempty"`                         // media asal bila hasil duplikasi album
+	IsReference      bool            `gorm:"not null;default:false" json:"is_reference"`                               // berbagi file S3 dengan media lain, tidak dihitung ke kuota
+	ModerationStatus string          `gorm:"type:varchar(20);not null;default:visible;index" json:"moderation_status"` // hidden/removed oleh moderator
+	PerceptualHash   *int64          `gorm:"index" json:"-"`                                                           // dHash 64-bit untuk pencarian gambar mirip, nil bila belum/tidak bisa di-hash
+	CapturedAt       *time.Time      `gorm:"index" json:"captured_at,omitempty"`                                       // waktu foto diambil (EXIF, jam kamera)
+	Latitude         *float64        `gorm:"index:idx_media_location" json:"latitude,omitempty"`
+	Longitude        *float64        `gorm:"index:idx_media_location" json:"longitude,omitempty"`
+	ExifScanned      bool            `gorm:"not null;default:false" json:"-"` // EXIF sudah dibaca (walau kosong), agar backfill tidak mengulang
+	CreatedAt        time.Time       `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt        time.Time       `gorm:"autoUpdateTime" json:"updated_at"`
+	DeletedAt        gorm.DeletedAt  `gorm:"index" json:"deleted_at,omitempty"`
+}
+
+func (Media) TableName() string {
+	return "media"
+}
+
+// CoverURL adalah URL yang dipakai saat media ini dijadikan cover album.
+func (m Media) CoverURL() string {
+	if kind, ok := LookupMediaKind(m.Kind); ok && kind.UseFileAsCover {
+		return m.URL
+	}
+	return m.ThumbnailURL
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	MentionSourceAlbum   = "album"   // deskripsi album
+	MentionSourceComment = "comment" // isi komentar
+)
+
+// Mention mencatat @username yang disebut di deskripsi album atau komentar.
+type Mention struct {
+	ID            uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4()" json:"id"`
+	SourceType    string    `gorm:"type:varchar(20);not null;uniqueIndex:idx_mention_source" json:"source_type"`
+	SourceID      uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_mention_source" json:"source_id"`
+	UserID        uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_mention_source;index" json:"user_id"` // user yang disebut
+	User          User      `gorm:"foreignKey:UserID;references:ID" json:"user,omitempty"`
+	AlbumID       uuid.UUID `gorm:"type:uuid;not null;index" json:"album_id"`
+	MentionedByID uuid.UUID `gorm:"type:uuid;not null" json:"mentioned_by_id"`
+	CreatedAt     time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
@@ -6,17 +6,33 @@ func GetModels() []interface{} {
 		&PersonalAccessToken{},
 		&AccountConfig{},
 		&AlbumTag{},
+		&TagAlias{},
 		&Album{},
-		&AlbumImage{},
-		&AlbumVideo{},
+		&Media{},
 		&TempMedia{},
 		&AlbumComment{},
-		&AlbumLike{},
-		&MediaLike{},
+		&AlbumCommentEdit{},
+		&Mention{},
+		&Notification{},
+		&AlbumReaction{},
+		&MediaReaction{},
 		// &Permission{},
 		&UserSubscription{},
 		&Subscription{},
 		&Following{},
+		&UserBlock{},
+		&Report{},
+		&ModerationAction{},
+		&AlbumInvitation{},
+		&Collection{},
+		&AlbumExport{},
+		&AlbumImport{},
+		&AlbumImportItem{},
+		&Payment{},
+		&PaymentEvent{},
+		&Invoice{},
+		&InvoiceLine{},
+		&InvoiceSequence{},
 
 	}
 }
\ No newline at end of file
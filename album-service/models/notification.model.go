@@ -0,0 +1,80 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	NotificationEventFollow          = "follow"
+	NotificationEventFollowRequest   = "follow_request"
+	NotificationEventFollowAccepted  = "follow_accepted"
+	NotificationEventReaction        = "reaction"
+	NotificationEventComment         = "comment"
+	NotificationEventCommentReply    = "comment_reply"
+	NotificationEventMention         = "mention"
+	NotificationEventAlbumInvitation = "album_invitation"
+	NotificationEventModeration      = "moderation"
+	NotificationEventPaymentDue      = "payment_due" // perubahan plan menunggu pembayaran
+)
+
+const (
+	NotificationDeliveryInApp = "in_app"
+	NotificationDeliveryEmail = "email"
+	NotificationDeliveryAll   = "all" // in-app dan email
+	NotificationDeliveryNone  = "none"
+)
+
+// DefaultNotificationPreferences dipakai bila user belum mengatur preferensi untuk suatu event.
+var DefaultNotificationPreferences = map[string]string{
+	NotificationEventFollow:          NotificationDeliveryInApp,
+	NotificationEventFollowRequest:   NotificationDeliveryInApp,
+	NotificationEventFollowAccepted:  NotificationDeliveryInApp,
+	NotificationEventReaction:        NotificationDeliveryInApp,
+	NotificationEventComment:         NotificationDeliveryInApp,
+	NotificationEventCommentReply:    NotificationDeliveryInApp,
+	NotificationEventMention:         NotificationDeliveryAll,
+	NotificationEventAlbumInvitation: NotificationDeliveryInApp, // email undangan selalu dikirim karena berisi link accept
+	NotificationEventModeration:      NotificationDeliveryAll,
+	NotificationEventPaymentDue:      NotificationDeliveryAll,
+}
+
+func IsNotificationDelivery(delivery string) bool {
+	switch delivery {
+	case NotificationDeliveryInApp, NotificationDeliveryEmail, NotificationDeliveryAll, NotificationDeliveryNone:
+		return true
+	}
+	return false
+}
+
+// NotificationDelivery mengambil cara pengiriman event sesuai preferensi user.
+func (c AccountConfig) NotificationDelivery(event string) string {
+	preferences := map[string]string{}
+	if len(c.NotificationPreferences) > 0 {
+		_ = json.Unmarshal(c.NotificationPreferences, &preferences)
+	}
+
+	if delivery, ok := preferences[event]; ok && IsNotificationDelivery(delivery) {
+		return delivery
+	}
+	return DefaultNotificationPreferences[event]
+}
+
+// Notification adalah notifikasi in-app milik satu user.
+type Notification struct {
+	ID        uuid.UUID       `gorm:"type:uuid;default:uuid_generate_v4()" json:"id"`
+	UserID    uuid.UUID       `gorm:"type:uuid;not null;index:idx_notification_user_created" json:"user_id"` // penerima
+	ActorID   *uuid.UUID      `gorm:"type:uuid" json:"actor_id,omitempty"`
+	Actor     *User           `gorm:"foreignKey:ActorID;references:ID" json:"-"`
+	Event     string          `gorm:"type:varchar(30);not null" json:"event"`
+	AlbumID   *uuid.UUID      `gorm:"type:uuid;index" json:"album_id,omitempty"`
+	MediaID   *uuid.UUID      `gorm:"type:uuid" json:"media_id,omitempty"`
+	CommentID *uuid.UUID      `gorm:"type:uuid" json:"comment_id,omitempty"`
+	Message   string          `gorm:"type:text" json:"message"`
+	Link      string          `json:"link,omitempty"`
+	Data      json.RawMessage `gorm:"type:jsonb" json:"data,omitempty"`
+	ReadAt    *time.Time      `gorm:"index" json:"read_at,omitempty"`
+	CreatedAt time.Time       `gorm:"autoCreateTime;index:idx_notification_user_created" json:"created_at"`
+}
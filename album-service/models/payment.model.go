@@ -0,0 +1,51 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	PaymentStatusPending  = "pending"
+	PaymentStatusPaid     = "paid"
+	PaymentStatusFailed   = "failed"
+	PaymentStatusExpired  = "expired"
+	PaymentStatusRefunded = "refunded"
+)
+
+// Payment adalah tagihan satu UserSubscription di payment provider.
+type Payment struct {
+	ID                 uuid.UUID        `gorm:"type:uuid;default:uuid_generate_v4()" json:"id"`
+	UserID             uuid.UUID        `gorm:"type:uuid;not null;index" json:"user_id"`
+	UserSubscriptionID uuid.UUID        `gorm:"type:uuid;not null;index" json:"user_subscription_id"`
+	UserSubscription   UserSubscription `gorm:"foreignKey:UserSubscriptionID;references:ID" json:"user_subscription,omitempty"`
+	Provider           string           `gorm:"type:varchar(30);not null" json:"provider"`
+	OrderID            string           `gorm:"type:varchar(64);not null;uniqueIndex" json:"order_id"` // id tagihan yang dikirim ke provider
+	ProviderReference  string           `gorm:"type:varchar(255)" json:"provider_reference,omitempty"`
+	Method             string           `gorm:"type:varchar(50)" json:"method,omitempty"` // diisi dari notifikasi provider
+	Amount             float64          `gorm:"not null" json:"amount"`
+	Currency           string           `gorm:"type:varchar(3);not null" json:"currency"`
+	Status             string           `gorm:"type:varchar(20);not null;default:pending;index" json:"status"`
+	CheckoutURL        string           `gorm:"type:text" json:"checkout_url,omitempty"`
+	ExpiresAt          *time.Time       `json:"expires_at,omitempty"`
+	PaidAt             *time.Time       `json:"paid_at,omitempty"`
+	RefundedAt         *time.Time       `json:"refunded_at,omitempty"`
+	RefundReason       string           `json:"refund_reason,omitempty"`
+	CreatedAt          time.Time        `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt          time.Time        `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// PaymentEvent mencatat notifikasi provider yang sudah diproses, provider mengirim ulang
+// notifikasi yang sama sehingga (provider, event_id) dijaga unik.
+type PaymentEvent struct {
+	ID        uuid.UUID       `gorm:"type:uuid;default:uuid_generate_v4()" json:"id"`
+	Provider  string          `gorm:"type:varchar(30);not null;uniqueIndex:idx_payment_event_provider_event" json:"provider"`
+	EventID   string          `gorm:"type:varchar(255);not null;uniqueIndex:idx_payment_event_provider_event" json:"event_id"`
+	PaymentID *uuid.UUID      `gorm:"type:uuid;index" json:"payment_id,omitempty"`
+	OrderID   string          `gorm:"type:varchar(64)" json:"order_id"`
+	Status    string          `gorm:"type:varchar(20)" json:"status"`
+	Payload   json.RawMessage `gorm:"type:jsonb" json:"payload,omitempty"`
+	CreatedAt time.Time       `gorm:"autoCreateTime" json:"created_at"`
+}
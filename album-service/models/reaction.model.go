@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ReactionLike  = "like"
+	ReactionLove  = "love"
+	ReactionHaha  = "haha"
+	ReactionWow   = "wow"
+	ReactionSad   = "sad"
+	ReactionAngry = "angry"
+)
+
+// ReactionTypes adalah jenis reaksi yang bisa dipilih user.
+var ReactionTypes = []string{ReactionLike, ReactionLove, ReactionHaha, ReactionWow, ReactionSad, ReactionAngry}
+
+func IsReactionType(reactionType string) bool {
+	for _, t := range ReactionTypes {
+		if t == reactionType {
+			return true
+		}
+	}
+	return false
+}
+
+// AlbumReaction menggantikan AlbumLike, satu user hanya punya satu reaksi per album.
+type AlbumReaction struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	AlbumID   uuid.UUID `json:"album_id" gorm:"type:uuid;not null;uniqueIndex:idx_album_reaction_user"`
+	Album     Album     `gorm:"foreignKey:AlbumID;references:ID" json:"album,omitempty"`
+	UserID    uuid.UUID `json:"user_id" gorm:"type:uuid;not null;uniqueIndex:idx_album_reaction_user;index"`
+	User      User      `gorm:"foreignKey:UserID;references:ID" json:"user,omitempty"`
+	Type      string    `json:"type" gorm:"type:varchar(20);not null;default:like;index"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// MediaReaction menggantikan MediaLike, satu user hanya punya satu reaksi per media.
+type MediaReaction struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	MediaID   uuid.UUID `json:"media_id" gorm:"type:uuid;not null;uniqueIndex:idx_media_reaction_user"`
+	Media     Media     `gorm:"foreignKey:MediaID;references:ID" json:"media,omitempty"`
+	UserID    uuid.UUID `json:"user_id" gorm:"type:uuid;not null;uniqueIndex:idx_media_reaction_user;index"`
+	User      User      `gorm:"foreignKey:UserID;references:ID" json:"user,omitempty"`
+	Type      string    `json:"type" gorm:"type:varchar(20);not null;default:like;index"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
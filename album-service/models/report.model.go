@@ -0,0 +1,79 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ReportTargetAlbum   = "album"
+	ReportTargetMedia   = "media"
+	ReportTargetComment = "comment"
+	ReportTargetUser    = "user"
+)
+
+// ReportReasons adalah kode alasan yang bisa dipilih saat melaporkan konten.
+var ReportReasons = []string{"spam", "harassment", "hate_speech", "nudity", "violence", "copyright", "impersonation", "other"}
+
+func IsReportReason(reason string) bool {
+	for _, r := range ReportReasons {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	ReportStatusOpen      = "open"
+	ReportStatusInReview  = "in_review"
+	ReportStatusResolved  = "resolved"  // ditindak moderator
+	ReportStatusDismissed = "dismissed" // tidak melanggar
+)
+
+const (
+	ModerationActionHide    = "hide"
+	ModerationActionRemove  = "remove"
+	ModerationActionWarn    = "warn"
+	ModerationActionSuspend = "suspend"
+	ModerationActionRestore = "restore" // membatalkan hide atau suspend
+	ModerationActionDismiss = "dismiss"
+)
+
+// Status moderasi album dan media. Konten hidden/removed hanya terlihat oleh pemiliknya.
+const (
+	ModerationStatusVisible = "visible"
+	ModerationStatusHidden  = "hidden"
+	ModerationStatusRemoved = "removed"
+)
+
+type Report struct {
+	ID            uuid.UUID  `gorm:"type:uuid;default:uuid_generate_v4()" json:"id"`
+	ReporterID    uuid.UUID  `gorm:"type:uuid;not null;index" json:"reporter_id"`
+	Reporter      User       `gorm:"foreignKey:ReporterID" json:"-"`
+	TargetType    string     `gorm:"type:varchar(20);not null;index:idx_report_target" json:"target_type"` // album, media, comment, user
+	TargetID      uuid.UUID  `gorm:"type:uuid;not null;index:idx_report_target" json:"target_id"`
+	TargetOwnerID uuid.UUID  `gorm:"type:uuid;not null;index" json:"target_owner_id"` // pemilik konten yang dilaporkan
+	Reason        string     `gorm:"type:varchar(30);not null" json:"reason"`
+	Details       string     `gorm:"type:text" json:"details,omitempty"`
+	Status        string     `gorm:"type:varchar(20);not null;default:open;index" json:"status"`
+	ModeratorID   *uuid.UUID `gorm:"type:uuid" json:"moderator_id,omitempty"` // moderator yang menangani
+	ResolvedAt    *time.Time `json:"resolved_at,omitempty"`
+	CreatedAt     time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt     time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// ModerationAction adalah catatan audit setiap keputusan moderator.
+type ModerationAction struct {
+	ID             uuid.UUID  `gorm:"type:uuid;default:uuid_generate_v4()" json:"id"`
+	ReportID       *uuid.UUID `gorm:"type:uuid;index" json:"report_id,omitempty"`
+	ModeratorID    uuid.UUID  `gorm:"type:uuid;not null;index" json:"moderator_id"`
+	TargetType     string     `gorm:"type:varchar(20);not null;index:idx_moderation_target" json:"target_type"`
+	TargetID       uuid.UUID  `gorm:"type:uuid;not null;index:idx_moderation_target" json:"target_id"`
+	TargetOwnerID  uuid.UUID  `gorm:"type:uuid;not null;index" json:"target_owner_id"`
+	Action         string     `gorm:"type:varchar(20);not null" json:"action"`
+	Note           string     `gorm:"type:text" json:"note,omitempty"`
+	SuspendedUntil *time.Time `json:"suspended_until,omitempty"`
+	CreatedAt      time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
@@ -2,6 +2,8 @@ package models
 
 import (
 	"encoding/json"
+	"fmt"
+	"math"
 	"time"
 
 	"github.com/google/uuid"
@@ -34,6 +36,8 @@ type User struct {
 	Status           string          `json:"status,omitempty" gorm:"type:varchar(50);default:active"`
 	SubscriptionFreeStatus           string          `json:"subscription_free_status,omitempty" gorm:"type:varchar(50);default:active"`
 	DeactivateUntil  time.Time       `json:"deactivate_until,omitempty"`
+	SuspendedUntil   *time.Time      `json:"suspended_until,omitempty"` // diisi saat Status "suspended" oleh moderator
+	Role             string          `json:"role" gorm:"type:varchar(20);not null;default:user"`
 	ProfilePicture   string          `json:"profile_picture,omitempty"`
 	AgreeTermService bool			  `json:"agree_term_service"`
 	AccountConfig    AccountConfig   `json:"account_config,omitempty" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
@@ -43,6 +47,12 @@ type User struct {
 }
 
 
+const (
+	UserRoleUser      = "user"
+	UserRoleModerator = "moderator"
+	UserRoleAdmin     = "admin"
+)
+
 type AccountConfig struct {
 	ID                  uuid.UUID      `gorm:"type:uuid;default:uuid_generate_v4()" json:"id"`
 	UserID              uuid.UUID      `gorm:"type:uuid;not null;index" json:"user_id"`
@@ -50,6 +60,8 @@ type AccountConfig struct {
 	TwoFactorAuthMethod string         `json:"two_factor_auth_method" gorm:"type:varchar(50)"`
 	TwoFactorAuthDevice string         `json:"two_factor_auth_device" gorm:"type:varchar(100)"`
 	SecretTOTP			string		   `json:"secret_totp" gorm:"type:varchar(255)"`
+	NotificationPreferences json.RawMessage `json:"notification_preferences,omitempty" gorm:"type:jsonb"` // event -> in_app/email/all/none
+	IsPrivate           bool           `json:"is_private" gorm:"default:false"` // follow harus disetujui pemilik akun
 	CreatedAt           time.Time      `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt           time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
 	DeletedAt           gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
@@ -62,17 +74,87 @@ type Permission struct {
 	Name string    `json:"name" gorm:"type:varchar(100)"` //open profile
 }
 
+const (
+	BillingIntervalMonth = "month"
+	BillingIntervalYear  = "year"
+
+	DefaultPlanCode = "basic" // plan gratis untuk user baru
+)
+
+// Subscription adalah satu versi plan. Perubahan harga atau batas plan yang sudah punya pelanggan
+// membuat versi baru, sehingga user lama tetap menunjuk ke versi (dan ketentuan) saat berlangganan.
 type Subscription struct {
 	ID                uuid.UUID       `gorm:"type:uuid;default:uuid_generate_v4()" json:"id"`
-	SubscriptionType  string          `json:"subscription_type" gorm:"type:varchar(50)"`
-	StorageCapacity   float64         `json:"storage_capacity"`
-	MaximumMediaSize  float64         `json:"maximum_media_size"`
-	Features          json.RawMessage `json:"features" gorm:"type:jsonb"`
+	Code              string          `json:"code" gorm:"type:varchar(50);not null;default:'';uniqueIndex:idx_subscription_code_version"` // sama untuk semua versi plan
+	Version           int             `json:"version" gorm:"not null;default:1;uniqueIndex:idx_subscription_code_version"`
+	SubscriptionType  string          `json:"subscription_type" gorm:"type:varchar(50)"` // nama plan
+	Description       string          `json:"description,omitempty" gorm:"type:text"`
+	Price             float64         `json:"price" gorm:"not null;default:0"`
+	Currency          string          `json:"currency" gorm:"type:varchar(3);not null;default:IDR"`
+	BillingInterval   string          `json:"billing_interval" gorm:"type:varchar(10);not null;default:month"`
+	StorageCapacity   float64         `json:"storage_capacity"` // GB
+	MaximumMediaSize  float64         `json:"maximum_media_size"` // GB
+	Features          json.RawMessage `json:"features" gorm:"type:jsonb"` // fitur tambahan selain batas storage dan media
+	IsActive          bool            `json:"is_active" gorm:"not null;default:true;index"` // versi yang sedang ditawarkan
+	IsPublic          bool            `json:"is_public" gorm:"not null;default:true"` // tampil di katalog plan
+	SortOrder         int             `json:"sort_order" gorm:"not null;default:0"`
 	CreatedAt         time.Time       `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt         time.Time       `json:"updated_at" gorm:"autoUpdateTime"`
 	DeletedAt         gorm.DeletedAt  `json:"deleted_at,omitempty" gorm:"index"`
 }
 
+// PeriodEnd adalah akhir satu periode tagihan plan yang dimulai pada start.
+func (s Subscription) PeriodEnd(start time.Time) time.Time {
+	if s.BillingInterval == BillingIntervalYear {
+		return start.AddDate(1, 0, 0)
+	}
+	return start.AddDate(0, 1, 0)
+}
+
+// MonthlyPrice adalah harga plan per bulan, untuk membandingkan plan dengan interval berbeda.
+func (s Subscription) MonthlyPrice() float64 {
+	if s.BillingInterval == BillingIntervalYear {
+		return s.Price / 12
+	}
+	return s.Price
+}
+
+// FeatureList adalah fitur plan untuk katalog. Batas storage dan ukuran media selalu dibentuk
+// dari nilai sebenarnya, diikuti fitur tambahan dari Features.
+func (s Subscription) FeatureList() []string {
+	features := []string{
+		fmt.Sprintf("%s Storage", formatGigabytes(s.StorageCapacity)),
+		fmt.Sprintf("Max %s media upload", formatGigabytes(s.MaximumMediaSize)),
+	}
+
+	var extras []string
+	if len(s.Features) > 0 && json.Unmarshal(s.Features, &extras) == nil {
+		features = append(features, extras...)
+	}
+	return features
+}
+
+func formatGigabytes(size float64) string {
+	if size > 0 && size < 1 {
+		return fmt.Sprintf("%g MB", math.Round(size*1000))
+	}
+	return fmt.Sprintf("%g GB", size)
+}
+
+const (
+	UserSubscriptionActive         = "Active"
+	UserSubscriptionFreeTier       = "Free Tier"
+	UserSubscriptionExpired        = "Expired"
+	UserSubscriptionScheduled      = "Scheduled" // downgrade yang berlaku di akhir periode berjalan
+	UserSubscriptionCancelled      = "Cancelled"
+	UserSubscriptionPendingPayment = "Pending Payment" // menunggu notifikasi pembayaran dari provider
+	UserSubscriptionRefunded       = "Refunded"
+
+	SubscriptionChangeNew       = "new"
+	SubscriptionChangeUpgrade   = "upgrade"
+	SubscriptionChangeDowngrade = "downgrade"
+)
+
 type UserSubscription struct {
 	ID             uuid.UUID      `gorm:"type:uuid;default:uuid_generate_v4()" json:"id"`
 	UserID         uuid.UUID      `gorm:"type:uuid;not null" json:"user_id"`
@@ -82,16 +164,27 @@ type UserSubscription struct {
 	PaymentMethod  string		  `gorm:"type:varchar(100)" json:"payment_method"`
 	StartDate      time.Time      `json:"start_date" gorm:"not null"`
 	EndDate        time.Time      `json:"end_date" gorm:"not null"`
-	Amount		   float32		  `json:"amount" gorm:""`
-	Status 		   string		  `json:"status" gorm:"type:varchar(50)"`
+	Amount		   float32		  `json:"amount" gorm:""` // yang ditagih setelah dipotong Credit
+	Currency       string         `gorm:"type:varchar(3)" json:"currency,omitempty"`
+	Credit         float32        `json:"credit"` // sisa nilai periode sebelumnya (proration)
+	ChangeType     string         `gorm:"type:varchar(20)" json:"change_type,omitempty"` // new, upgrade, downgrade
+	PreviousID     *uuid.UUID     `gorm:"type:uuid" json:"previous_id,omitempty"` // user subscription yang digantikan
+	Invoice        *Invoice       `gorm:"foreignKey:UserSubscriptionID" json:"invoice,omitempty"` // hanya untuk periode yang dibayar
+	Status 		   string		  `json:"status" gorm:"type:varchar(50);index"`
 	CreatedAt      time.Time      `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt      time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
 	DeletedAt      gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
 }
 
+const (
+	FollowStatusPending  = "pending" // menunggu persetujuan akun privat
+	FollowStatusAccepted = "accepted"
+)
+
 type Following struct {
 	UserID      uuid.UUID      `gorm:"type:uuid;not null;primaryKey" json:"user_id"`        // yang follow
 	FollowingID uuid.UUID      `gorm:"type:uuid;not null;primaryKey" json:"following_id"`   // yang di-follow
+	Status      string         `gorm:"type:varchar(20);not null;default:accepted;index" json:"status"`
 	User        User           `gorm:"foreignKey:UserID" json:"user"`
 	Following   User           `gorm:"foreignKey:FollowingID" json:"following"`
 	CreatedAt   time.Time      `json:"created_at" gorm:"autoCreateTime"`
@@ -0,0 +1,114 @@
+package payments
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const FakeSignatureHeader = "X-Fake-Signature"
+
+// Fake adalah provider lokal untuk development dan testing. Checkout tidak menagih apa pun,
+// notifikasi dikirim manual (lihat SignedWebhook) dan ditandatangani HMAC-SHA256 body.
+type Fake struct {
+	secret      string
+	checkoutURL string
+}
+
+type fakeNotification struct {
+	EventID string  `json:"event_id"`
+	OrderID string  `json:"order_id"`
+	Status  string  `json:"status"`
+	Amount  float64 `json:"amount"`
+	Method  string  `json:"method,omitempty"`
+}
+
+func NewFake(secret, checkoutURL string) *Fake {
+	if checkoutURL == "" {
+		checkoutURL = "http://localhost:3001/fake-checkout"
+	}
+	return &Fake{secret: secret, checkoutURL: checkoutURL}
+}
+
+func (f *Fake) Name() string {
+	return "fake"
+}
+
+func (f *Fake) sign(body []byte) string {
+	mac := hmac.New(sha256.New, []byte(f.secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (f *Fake) CreateCheckout(ctx context.Context, req CheckoutRequest) (Checkout, error) {
+	expiresAt := time.Now().Add(24 * time.Hour)
+	if req.ExpiresAt != nil {
+		expiresAt = *req.ExpiresAt
+	}
+	return Checkout{
+		ProviderReference: "fake-" + req.OrderID,
+		CheckoutURL:       f.checkoutURL + "?order_id=" + url.QueryEscape(req.OrderID),
+		ExpiresAt:         &expiresAt,
+	}, nil
+}
+
+func (f *Fake) ParseWebhook(header http.Header, body []byte) (Event, error) {
+	signature := header.Get(FakeSignatureHeader)
+	if signature == "" || !hmac.Equal([]byte(signature), []byte(f.sign(body))) {
+		return Event{}, ErrInvalidSignature
+	}
+
+	var notif fakeNotification
+	if err := json.Unmarshal(body, &notif); err != nil {
+		return Event{}, fmt.Errorf("notifikasi fake tidak valid: %w", err)
+	}
+	if notif.EventID == "" || notif.OrderID == "" {
+		return Event{}, fmt.Errorf("notifikasi fake tanpa event_id atau order_id")
+	}
+
+	return Event{
+		ID:                notif.EventID,
+		OrderID:           notif.OrderID,
+		ProviderReference: "fake-" + notif.OrderID,
+		Status:            notif.Status,
+		Amount:            notif.Amount,
+		Method:            notif.Method,
+		Payload:           body,
+	}, nil
+}
+
+func (f *Fake) Refund(ctx context.Context, req RefundRequest) (Refund, error) {
+	return Refund{ProviderReference: req.ProviderReference, Status: StatusRefunded}, nil
+}
+
+func (f *Fake) Cancel(ctx context.Context, orderID string) error {
+	return nil
+}
+
+// SignedWebhook membuat body dan header notifikasi bertanda tangan untuk dikirim ke
+// endpoint webhook, mis. untuk mensimulasikan pembayaran berhasil di lingkungan lokal.
+func (f *Fake) SignedWebhook(orderID, status string, amount float64) (http.Header, []byte, error) {
+	body, err := json.Marshal(fakeNotification{
+		EventID: uuid.NewString(),
+		OrderID: orderID,
+		Status:  status,
+		Amount:  amount,
+		Method:  "fake",
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	header := http.Header{}
+	header.Set(FakeSignatureHeader, f.sign(body))
+	header.Set("Content-Type", "application/json")
+	return header, body, nil
+}
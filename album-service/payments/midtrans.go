@@ -0,0 +1,226 @@
+package payments
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	midtransSnapSandbox    = "https://app.sandbox.midtrans.com/snap/v1"
+	midtransSnapProduction = "https://app.midtrans.com/snap/v1"
+	midtransAPISandbox     = "https://api.sandbox.midtrans.com/v2"
+	midtransAPIProduction  = "https://api.midtrans.com/v2"
+)
+
+// Midtrans adalah adapter Midtrans Snap. Notifikasi diverifikasi dengan signature_key
+// SHA512(order_id + status_code + gross_amount + server key).
+type Midtrans struct {
+	serverKey string
+	snapURL   string
+	apiURL    string
+	client    *http.Client
+}
+
+func NewMidtrans(serverKey string, production bool) *Midtrans {
+	gateway := &Midtrans{
+		serverKey: serverKey,
+		snapURL:   midtransSnapSandbox,
+		apiURL:    midtransAPISandbox,
+		client:    &http.Client{Timeout: 15 * time.Second},
+	}
+	if production {
+		gateway.snapURL = midtransSnapProduction
+		gateway.apiURL = midtransAPIProduction
+	}
+	return gateway
+}
+
+func (m *Midtrans) Name() string {
+	return "midtrans"
+}
+
+func (m *Midtrans) do(ctx context.Context, url string, payload interface{}, out interface{}) (int, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.SetBasicAuth(m.serverKey, "")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return resp.StatusCode, fmt.Errorf("respons midtrans tidak valid (HTTP %d): %w", resp.StatusCode, err)
+	}
+	return resp.StatusCode, nil
+}
+
+func (m *Midtrans) CreateCheckout(ctx context.Context, req CheckoutRequest) (Checkout, error) {
+	if req.Currency != "" && req.Currency != "IDR" {
+		return Checkout{}, fmt.Errorf("midtrans hanya mendukung IDR, bukan %s", req.Currency)
+	}
+
+	payload := map[string]interface{}{
+		"transaction_details": map[string]interface{}{
+			"order_id":     req.OrderID,
+			"gross_amount": int64(math.Round(req.Amount)),
+		},
+		"customer_details": map[string]interface{}{
+			"first_name": req.CustomerName,
+			"email":      req.CustomerEmail,
+		},
+		"custom_field1": req.Description,
+	}
+	if req.ExpiresAt != nil {
+		payload["expiry"] = map[string]interface{}{
+			"unit":     "minutes",
+			"duration": int64(math.Ceil(time.Until(*req.ExpiresAt).Minutes())),
+		}
+	}
+
+	var resp struct {
+		Token         string   `json:"token"`
+		RedirectURL   string   `json:"redirect_url"`
+		ErrorMessages []string `json:"error_messages"`
+	}
+	status, err := m.do(ctx, m.snapURL+"/transactions", payload, &resp)
+	if err != nil {
+		return Checkout{}, err
+	}
+	if status >= 300 || resp.Token == "" {
+		return Checkout{}, fmt.Errorf("midtrans menolak checkout (HTTP %d): %s", status, strings.Join(resp.ErrorMessages, "; "))
+	}
+
+	return Checkout{ProviderReference: resp.Token, CheckoutURL: resp.RedirectURL, ExpiresAt: req.ExpiresAt}, nil
+}
+
+type midtransNotification struct {
+	OrderID           string `json:"order_id"`
+	TransactionID     string `json:"transaction_id"`
+	TransactionStatus string `json:"transaction_status"`
+	FraudStatus       string `json:"fraud_status"`
+	StatusCode        string `json:"status_code"`
+	GrossAmount       string `json:"gross_amount"`
+	PaymentType       string `json:"payment_type"`
+	SignatureKey      string `json:"signature_key"`
+}
+
+func (m *Midtrans) signature(orderID, statusCode, grossAmount string) string {
+	sum := sha512.Sum512([]byte(orderID + statusCode + grossAmount + m.serverKey))
+	return hex.EncodeToString(sum[:])
+}
+
+func (m *Midtrans) ParseWebhook(header http.Header, body []byte) (Event, error) {
+	var notif midtransNotification
+	if err := json.Unmarshal(body, &notif); err != nil {
+		return Event{}, fmt.Errorf("notifikasi midtrans tidak valid: %w", err)
+	}
+
+	expected := m.signature(notif.OrderID, notif.StatusCode, notif.GrossAmount)
+	if notif.SignatureKey == "" || subtle.ConstantTimeCompare([]byte(expected), []byte(notif.SignatureKey)) != 1 {
+		return Event{}, ErrInvalidSignature
+	}
+
+	var amount float64
+	fmt.Sscanf(notif.GrossAmount, "%f", &amount)
+
+	event := Event{
+		ID:                notif.TransactionID + ":" + notif.TransactionStatus,
+		OrderID:           notif.OrderID,
+		ProviderReference: notif.TransactionID,
+		Amount:            amount,
+		Method:            notif.PaymentType,
+		Payload:           body,
+	}
+	if notif.FraudStatus != "" {
+		event.ID += ":" + notif.FraudStatus
+	}
+
+	switch notif.TransactionStatus {
+	case "capture":
+		switch notif.FraudStatus {
+		case "accept", "":
+			event.Status = StatusPaid
+		case "deny":
+			event.Status = StatusFailed
+		default: // challenge, menunggu review fraud di dashboard
+			event.Status = StatusPending
+		}
+	case "settlement":
+		event.Status = StatusPaid
+	case "pending":
+		event.Status = StatusPending
+	case "deny", "cancel", "failure":
+		event.Status = StatusFailed
+	case "expire":
+		event.Status = StatusExpired
+	case "refund":
+		event.Status = StatusRefunded
+	}
+	// partial_refund dan status lain dicatat tanpa mengubah pembayaran
+
+	return event, nil
+}
+
+func (m *Midtrans) Refund(ctx context.Context, req RefundRequest) (Refund, error) {
+	payload := map[string]interface{}{
+		"refund_key": req.OrderID + "-refund", // refund ulang dengan key yang sama tidak diproses dua kali
+		"amount":     int64(math.Round(req.Amount)),
+		"reason":     req.Reason,
+	}
+
+	var resp struct {
+		StatusCode    string `json:"status_code"`
+		StatusMessage string `json:"status_message"`
+		TransactionID string `json:"transaction_id"`
+	}
+	if _, err := m.do(ctx, m.apiURL+"/"+req.OrderID+"/refund", payload, &resp); err != nil {
+		return Refund{}, err
+	}
+
+	switch resp.StatusCode {
+	case "200":
+		return Refund{ProviderReference: resp.TransactionID, Status: StatusRefunded}, nil
+	case "201":
+		return Refund{ProviderReference: resp.TransactionID, Status: StatusPending}, nil
+	}
+	return Refund{}, fmt.Errorf("midtrans menolak refund (%s): %s", resp.StatusCode, resp.StatusMessage)
+}
+
+// Cancel meng-expire transaksi yang masih pending. Transaksi yang belum pernah dibuat (user
+// belum memilih metode bayar di halaman Snap) dianggap selesai, pembayaran yang tetap masuk
+// lewat token tersebut di-refund otomatis saat notifikasinya diterima.
+func (m *Midtrans) Cancel(ctx context.Context, orderID string) error {
+	var resp struct {
+		StatusCode    string `json:"status_code"`
+		StatusMessage string `json:"status_message"`
+	}
+	if _, err := m.do(ctx, m.apiURL+"/"+orderID+"/expire", map[string]interface{}{}, &resp); err != nil {
+		return err
+	}
+
+	switch resp.StatusCode {
+	case "200", "404", "407": // berhasil di-expire, tidak ditemukan, atau sudah expire
+		return nil
+	}
+	return fmt.Errorf("midtrans menolak expire (%s): %s", resp.StatusCode, resp.StatusMessage)
+}
@@ -0,0 +1,119 @@
+package payments
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func midtransBody(t *testing.T, m *Midtrans, notif midtransNotification) []byte {
+	t.Helper()
+	if notif.SignatureKey == "" {
+		notif.SignatureKey = m.signature(notif.OrderID, notif.StatusCode, notif.GrossAmount)
+	}
+	body, err := json.Marshal(notif)
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+	return body
+}
+
+func TestMidtransParseWebhook(t *testing.T) {
+	m := NewMidtrans("server-key", false)
+
+	tests := []struct {
+		name   string
+		status string
+		fraud  string
+		want   string
+	}{
+		{name: "capture accepted", status: "capture", fraud: "accept", want: StatusPaid},
+		{name: "capture challenged", status: "capture", fraud: "challenge", want: StatusPending},
+		{name: "capture denied", status: "capture", fraud: "deny", want: StatusFailed},
+		{name: "settlement", status: "settlement", want: StatusPaid},
+		{name: "pending", status: "pending", want: StatusPending},
+		{name: "cancel", status: "cancel", want: StatusFailed},
+		{name: "expire", status: "expire", want: StatusExpired},
+		{name: "refund", status: "refund", want: StatusRefunded},
+		{name: "partial refund ignored", status: "partial_refund", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := midtransBody(t, m, midtransNotification{
+				OrderID:           "SUB-1",
+				TransactionID:     "trx-1",
+				TransactionStatus: tt.status,
+				FraudStatus:       tt.fraud,
+				StatusCode:        "200",
+				GrossAmount:       "50000.00",
+				PaymentType:       "credit_card",
+			})
+
+			event, err := m.ParseWebhook(http.Header{}, body)
+			if err != nil {
+				t.Fatalf("ParseWebhook: %v", err)
+			}
+			if event.Status != tt.want {
+				t.Errorf("status = %q, want %q", event.Status, tt.want)
+			}
+			if event.OrderID != "SUB-1" || event.Amount != 50000 || event.Method != "credit_card" {
+				t.Errorf("event = %+v", event)
+			}
+		})
+	}
+}
+
+// Capture kartu kredit lalu settlement untuk transaksi yang sama adalah dua notifikasi lunas
+// dengan event ID berbeda, sehingga keduanya sampai ke handler dan pembayaran harus idempoten.
+func TestMidtransCaptureThenSettlementIDs(t *testing.T) {
+	m := NewMidtrans("server-key", false)
+	parse := func(status, fraud string) Event {
+		body := midtransBody(t, m, midtransNotification{
+			OrderID: "SUB-1", TransactionID: "trx-1", TransactionStatus: status, FraudStatus: fraud,
+			StatusCode: "200", GrossAmount: "50000.00",
+		})
+		event, err := m.ParseWebhook(http.Header{}, body)
+		if err != nil {
+			t.Fatalf("ParseWebhook: %v", err)
+		}
+		return event
+	}
+
+	capture := parse("capture", "accept")
+	settlement := parse("settlement", "")
+	if capture.ID == settlement.ID {
+		t.Errorf("capture dan settlement memakai event ID yang sama: %s", capture.ID)
+	}
+	if capture.Status != StatusPaid || settlement.Status != StatusPaid {
+		t.Errorf("status = %s, %s, want keduanya %s", capture.Status, settlement.Status, StatusPaid)
+	}
+	if again := parse("settlement", ""); again.ID != settlement.ID {
+		t.Errorf("settlement yang dikirim ulang = %s, want %s", again.ID, settlement.ID)
+	}
+}
+
+func TestMidtransInvalidSignature(t *testing.T) {
+	m := NewMidtrans("server-key", false)
+	notif := midtransNotification{OrderID: "SUB-1", TransactionStatus: "settlement", StatusCode: "200", GrossAmount: "50000.00"}
+
+	tests := []struct {
+		name      string
+		signature string
+	}{
+		{name: "missing", signature: ""},
+		{name: "other key", signature: NewMidtrans("kunci-lain", false).signature("SUB-1", "200", "50000.00")},
+		{name: "other amount", signature: m.signature("SUB-1", "200", "1.00")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notif.SignatureKey = tt.signature
+			body, _ := json.Marshal(notif)
+			if _, err := m.ParseWebhook(http.Header{}, body); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("err = %v, want ErrInvalidSignature", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Status pembayaran yang dilaporkan provider, sama dengan models.PaymentStatus*.
+const (
+	StatusPending  = "pending"
+	StatusPaid     = "paid"
+	StatusFailed   = "failed"
+	StatusExpired  = "expired"
+	StatusRefunded = "refunded"
+)
+
+var (
+	ErrInvalidSignature = errors.New("signature webhook tidak valid")
+	ErrUnknownProvider  = errors.New("payment provider tidak dikenal")
+)
+
+type CheckoutRequest struct {
+	OrderID       string
+	Amount        float64
+	Currency      string
+	Description   string
+	CustomerName  string
+	CustomerEmail string
+	ExpiresAt     *time.Time // batas waktu pembayaran, nil = default provider
+}
+
+type Checkout struct {
+	ProviderReference string // token / id sesi checkout dari provider
+	CheckoutURL       string
+	ExpiresAt         *time.Time
+}
+
+// Event adalah notifikasi pembayaran yang sudah diverifikasi. ID unik per provider
+// dan dipakai untuk memproses setiap notifikasi tepat satu kali.
+type Event struct {
+	ID                string
+	OrderID           string
+	ProviderReference string // id transaksi di provider
+	Status            string // StatusPending, StatusPaid, StatusFailed, StatusExpired, StatusRefunded
+	Amount            float64
+	Method            string // metode bayar yang dipilih user di halaman checkout
+	Payload           []byte
+}
+
+type RefundRequest struct {
+	OrderID           string
+	ProviderReference string
+	Amount            float64
+	Reason            string
+}
+
+type Refund struct {
+	ProviderReference string
+	Status            string // StatusRefunded bila langsung selesai, StatusPending bila menunggu notifikasi
+}
+
+// Gateway adalah adapter satu payment provider.
+type Gateway interface {
+	Name() string
+	CreateCheckout(ctx context.Context, req CheckoutRequest) (Checkout, error)
+	// ParseWebhook memverifikasi signature notifikasi lalu menerjemahkannya ke Event.
+	ParseWebhook(header http.Header, body []byte) (Event, error)
+	Refund(ctx context.Context, req RefundRequest) (Refund, error)
+	// Cancel menutup checkout yang belum dibayar agar tidak bisa dibayar lagi.
+	Cancel(ctx context.Context, orderID string) error
+}
+
+var (
+	mu             sync.RWMutex
+	gateways       = map[string]Gateway{}
+	defaultGateway string
+)
+
+// Register mendaftarkan gateway. Gateway pertama yang didaftarkan menjadi default.
+func Register(gateway Gateway) {
+	mu.Lock()
+	defer mu.Unlock()
+	gateways[gateway.Name()] = gateway
+	if defaultGateway == "" {
+		defaultGateway = gateway.Name()
+	}
+}
+
+// Get mengambil gateway berdasarkan nama, dipakai untuk webhook dan refund.
+func Get(name string) (Gateway, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	gateway, ok := gateways[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, name)
+	}
+	return gateway, nil
+}
+
+// Default adalah gateway untuk checkout baru (PAYMENT_PROVIDER).
+func Default() (Gateway, error) {
+	mu.RLock()
+	name := defaultGateway
+	mu.RUnlock()
+	if name == "" {
+		return nil, ErrUnknownProvider
+	}
+	return Get(name)
+}
+
+// Providers adalah nama gateway yang terdaftar.
+func Providers() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(gateways))
+	for name := range gateways {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Setup mendaftarkan gateway dari env. Midtrans aktif bila MIDTRANS_SERVER_KEY diisi,
+// provider fake aktif bila FAKE_PAYMENT_SECRET diisi (lokal / testing).
+func Setup() {
+	if serverKey := os.Getenv("MIDTRANS_SERVER_KEY"); serverKey != "" {
+		Register(NewMidtrans(serverKey, os.Getenv("MIDTRANS_IS_PRODUCTION") == "true"))
+	}
+	if secret := os.Getenv("FAKE_PAYMENT_SECRET"); secret != "" {
+		Register(NewFake(secret, os.Getenv("FAKE_PAYMENT_CHECKOUT_URL")))
+	}
+
+	mu.Lock()
+	if name := os.Getenv("PAYMENT_PROVIDER"); name != "" {
+		if _, ok := gateways[name]; ok {
+			defaultGateway = name
+		} else {
+			log.Printf("PAYMENT_PROVIDER %s tidak terdaftar, memakai %s", name, defaultGateway)
+		}
+	}
+	mu.Unlock()
+
+	if len(Providers()) == 0 {
+		log.Println("Tidak ada payment provider, plan berbayar tidak bisa di-checkout")
+	}
+}
@@ -0,0 +1,79 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/Zackly23/queue-app/config"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// channelPrefix memisahkan channel realtime dari key Redis lain (cache, rate limit).
+const channelPrefix = "pica:realtime:"
+
+// Jenis pesan realtime yang dikirim ke channel album dan user.
+const (
+	MessageCommentCreated      = "comment.created"
+	MessageCommentUpdated      = "comment.updated"
+	MessageCommentDeleted      = "comment.deleted"
+	MessageCommentModerated    = "comment.moderated"
+	MessageReactionUpdated     = "reaction.updated"
+	MessageMediaAdded          = "media.added"
+	MessageAlbumUpdated        = "album.updated"
+	MessageNotificationCreated = "notification.created"
+)
+
+// Message adalah payload yang dikirim ke client lewat stream SSE.
+type Message struct {
+	Channel string      `json:"channel"`
+	Type    string      `json:"type"`
+	Data    interface{} `json:"data"`
+	SentAt  time.Time   `json:"sent_at"`
+}
+
+func AlbumChannel(albumID uuid.UUID) string {
+	return "album:" + albumID.String()
+}
+
+func UserChannel(userID uuid.UUID) string {
+	return "user:" + userID.String()
+}
+
+// Broadcast mem-publish pesan ke Redis sehingga semua replica album-service yang punya
+// client di channel tersebut ikut mengirimkannya. Gagal publish hanya di-log, karena
+// update realtime tidak boleh menggagalkan request utama.
+func Broadcast(channel string, messageType string, data interface{}) {
+	payload, err := json.Marshal(Message{
+		Channel: channel,
+		Type:    messageType,
+		Data:    data,
+		SentAt:  time.Now(),
+	})
+	if err != nil {
+		log.Printf("Gagal encode pesan realtime %s: %v", messageType, err)
+		return
+	}
+
+	if err := config.Redis.Publish(config.Ctx, channelPrefix+channel, payload).Err(); err != nil {
+		log.Printf("Gagal publish pesan realtime ke %s: %v", channel, err)
+	}
+}
+
+// Subscribe berlangganan ke channel realtime dan menunggu konfirmasi dari Redis,
+// sehingga pesan yang di-publish setelah fungsi ini kembali tidak terlewat.
+func Subscribe(ctx context.Context, channels []string) (*redis.PubSub, error) {
+	keys := make([]string, len(channels))
+	for i, channel := range channels {
+		keys[i] = channelPrefix + channel
+	}
+
+	pubsub := config.Redis.Subscribe(ctx, keys...)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+	return pubsub, nil
+}
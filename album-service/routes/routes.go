@@ -32,26 +32,14 @@ func DynamicStorageCapacityMiddleware(db *gorm.DB) fiber.Handler {
 			})
 		}
 
-		// Ambil semua album beserta isinya
-		var albums []models.Album
-		if err := db.Preload("AlbumImages").Preload("AlbumVideos").Where("user_id = ?", user.ID).Find(&albums).Error; err != nil {
+		// Hitung total penggunaan penyimpanan (dalam MB), termasuk trash yang masih dalam masa grace
+		storageUsed, err := utils.CalculateStorageUsed(db, user.ID)
+		if err != nil {
 			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 				"error": "Gagal mengambil data album user",
 			})	
 		}
 
-		// Hitung total penggunaan penyimpanan
-		// Hitung total penggunaan penyimpanan
-		var storageUsed float64 = 0.0
-		for _, album := range albums {
-			for _, image := range album.AlbumImages {
-				storageUsed += float64(image.Size) // dalam MB
-			}
-			for _, video := range album.AlbumVideos {
-				storageUsed += float64(video.Size) // dalam MB
-			}
-		}
-
 		storageCapacity := float64(user.Subscription.StorageCapacity) * 1024 // dari GB ke MB
 
 		fmt.Println("Storage Capacity:", storageCapacity, "MB")
@@ -151,6 +139,59 @@ func JWTMiddleware(db *gorm.DB) fiber.Handler {
 }
 
 
+// QueryTokenMiddleware memindahkan ?access_token= ke header Authorization, untuk client
+// seperti EventSource di browser yang tidak bisa mengirim header sendiri.
+func QueryTokenMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Get("Authorization") == "" {
+			if token := c.Query("access_token"); token != "" {
+				c.Request().Header.Set("Authorization", "Bearer "+token)
+			}
+		}
+		return c.Next()
+	}
+}
+
+// RoleMiddleware membatasi route hanya untuk user dengan salah satu role yang diberikan.
+// Dipasang setelah JWTMiddleware.
+func RoleMiddleware(db *gorm.DB, roles ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, err := utils.GetUserID(c)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Unauthorized",
+			})
+		}
+
+		var user models.User
+		if err := db.Select("id", "role").First(&user, "id = ?", userID).Error; err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Unauthorized",
+			})
+		}
+
+		for _, role := range roles {
+			if user.Role == role {
+				return c.Next()
+			}
+		}
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Tidak memiliki akses ke halaman ini",
+		})
+	}
+}
+
+// ModeratorMiddleware membatasi route untuk moderator dan admin.
+func ModeratorMiddleware(db *gorm.DB) fiber.Handler {
+	return RoleMiddleware(db, models.UserRoleModerator, models.UserRoleAdmin)
+}
+
+// AdminMiddleware membatasi route hanya untuk admin.
+func AdminMiddleware(db *gorm.DB) fiber.Handler {
+	return RoleMiddleware(db, models.UserRoleAdmin)
+}
+
+
 func SetupRoutes(app *fiber.App, db *gorm.DB, client notif.NotificationServiceClient) {
 	fmt.Println("Setting up routes...")
 
@@ -197,6 +238,30 @@ func SetupRoutes(app *fiber.App, db *gorm.DB, client notif.NotificationServiceCl
 		return handlers.VerifyTFA(c, db, client)
 	})
 
+	// Preview undangan album (tanpa JWT, untuk user yang belum punya akun)
+	v1.Get("/invitations/:token", func(c *fiber.Ctx) error {
+		return handlers.GetInvitationPreview(c, db)
+	})
+
+	// Katalog plan subscription (tanpa JWT)
+	v1.Get("/plans", func(c *fiber.Ctx) error {
+		return handlers.GetPlans(c, db)
+	})
+
+	v1.Get("/plans/:code", func(c *fiber.Ctx) error {
+		return handlers.GetPlan(c, db)
+	})
+
+	// Notifikasi payment provider (tanpa JWT, diverifikasi lewat signature)
+	v1.Post("/payments/webhook/:provider", func(c *fiber.Ctx) error {
+		return handlers.HandlePaymentWebhook(c, db, client)
+	})
+
+	// Stream realtime (SSE), token boleh lewat query karena EventSource tidak mendukung header
+	v1.Get("/realtime/stream", QueryTokenMiddleware(), JWTMiddleware(db), func(c *fiber.Ctx) error {
+		return handlers.StreamRealtime(c, db)
+	})
+
 	// Protected routes (dengan JWT middleware)
 	authRoutes := v1.Group("/", JWTMiddleware(db))
 
@@ -204,16 +269,78 @@ func SetupRoutes(app *fiber.App, db *gorm.DB, client notif.NotificationServiceCl
 		return handlers.Logout(c, db)
 	})
 
+	invitationRoutes := authRoutes.Group("/invitations")
+
+	invitationRoutes.Post("/accept", func(c *fiber.Ctx) error {
+		return handlers.AcceptInvitation(c, db)
+	})
+
+	invitationRoutes.Post("/decline", func(c *fiber.Ctx) error {
+		return handlers.DeclineInvitation(c, db)
+	})
+
 	userRoutes := authRoutes.Group("/users")
 
 	userRoutes.Post("/follow", func(c *fiber.Ctx) error {
 		return handlers.FollowUser(c, db)
 	})
 
+	userRoutes.Get("/follow-requests", func(c *fiber.Ctx) error {
+		return handlers.GetFollowRequests(c, db)
+	})
+
+	userRoutes.Put("/follow-requests/:userId/approve", func(c *fiber.Ctx) error {
+		return handlers.ApproveFollowRequest(c, db)
+	})
+
+	userRoutes.Put("/follow-requests/:userId/decline", func(c *fiber.Ctx) error {
+		return handlers.DeclineFollowRequest(c, db)
+	})
+
+	userRoutes.Put("/privacy", func(c *fiber.Ctx) error {
+		return handlers.UpdateAccountPrivacy(c, db)
+	})
+
+	userRoutes.Get("/blocks", func(c *fiber.Ctx) error {
+		return handlers.GetBlockedUsers(c, db)
+	})
+
 	userRoutes.Get("/subscription", func(c *fiber.Ctx) error {
 		return handlers.GetSubscriptionHistory(c, db)
 	})
 
+	userRoutes.Get("/subscription/preview", func(c *fiber.Ctx) error {
+		return handlers.PreviewSubscriptionChange(c, db)
+	})
+
+	userRoutes.Put("/subscription", func(c *fiber.Ctx) error {
+		return handlers.ChangeSubscription(c, db)
+	})
+
+	userRoutes.Delete("/subscription/scheduled", func(c *fiber.Ctx) error {
+		return handlers.CancelScheduledSubscription(c, db)
+	})
+
+	userRoutes.Get("/payments", func(c *fiber.Ctx) error {
+		return handlers.GetPayments(c, db)
+	})
+
+	userRoutes.Get("/invoices", func(c *fiber.Ctx) error {
+		return handlers.GetInvoices(c, db)
+	})
+
+	userRoutes.Get("/invoices/:invoiceId", func(c *fiber.Ctx) error {
+		return handlers.GetInvoice(c, db)
+	})
+
+	userRoutes.Get("/invoices/:invoiceId/download", func(c *fiber.Ctx) error {
+		return handlers.DownloadInvoice(c, db)
+	})
+
+	userRoutes.Post("/invoices/:invoiceId/receipt", func(c *fiber.Ctx) error {
+		return handlers.ResendInvoiceReceipt(c, db, client)
+	})
+
 	userRoutes.Delete("/deactivate", func(c *fiber.Ctx) error {
 		return handlers.DeactivateAccount(c, db, client)
 	})
@@ -230,6 +357,26 @@ func SetupRoutes(app *fiber.App, db *gorm.DB, client notif.NotificationServiceCl
 		return handlers.UpdateUserData(c, db)
 	})
 
+	userRoutes.Get("/:userId/tags", func(c *fiber.Ctx) error {
+		return handlers.GetUserTagStats(c, db)
+	})
+
+	userRoutes.Get("/:userId/followers", func(c *fiber.Ctx) error {
+		return handlers.GetFollowers(c, db)
+	})
+
+	userRoutes.Get("/:userId/following", func(c *fiber.Ctx) error {
+		return handlers.GetFollowing(c, db)
+	})
+
+	userRoutes.Post("/:userId/block", func(c *fiber.Ctx) error {
+		return handlers.BlockUser(c, db)
+	})
+
+	userRoutes.Delete("/:userId/block", func(c *fiber.Ctx) error {
+		return handlers.UnblockUser(c, db)
+	})
+
 	userRoutes.Get("/:userId/configuration", func(c *fiber.Ctx) error {
 		return handlers.GetUserConfiguration(c, db)
 	})
@@ -266,6 +413,22 @@ func SetupRoutes(app *fiber.App, db *gorm.DB, client notif.NotificationServiceCl
 		return handlers.PostAlbumComment(c, db)
 	})
 
+	albumRoutes.Put("/comments/:commentId", func(c *fiber.Ctx) error {
+		return handlers.UpdateAlbumComment(c, db)
+	})
+
+	albumRoutes.Delete("/comments/:commentId", func(c *fiber.Ctx) error {
+		return handlers.DeleteAlbumComment(c, db)
+	})
+
+	albumRoutes.Get("/comments/:commentId/history", func(c *fiber.Ctx) error {
+		return handlers.GetAlbumCommentHistory(c, db)
+	})
+
+	albumRoutes.Put("/comments/:commentId/moderation", func(c *fiber.Ctx) error {
+		return handlers.ModerateAlbumComment(c, db)
+	})
+
 	albumRoutes.Post("/likes", func(c *fiber.Ctx) error {
 		return handlers.ClickLikeAlbum(c, db)
 	})
@@ -273,6 +436,38 @@ func SetupRoutes(app *fiber.App, db *gorm.DB, client notif.NotificationServiceCl
 	albumRoutes.Post("/media/likes", func(c *fiber.Ctx) error {
 		return handlers.ClickLikeMedia(c, db)
 	})
+
+	albumRoutes.Get("/media/timeline", func(c *fiber.Ctx) error {
+		return handlers.GetMediaTimeline(c, db)
+	})
+
+	albumRoutes.Get("/media/timeline/:period", func(c *fiber.Ctx) error {
+		return handlers.GetMediaTimelinePeriod(c, db)
+	})
+
+	albumRoutes.Get("/media/map", func(c *fiber.Ctx) error {
+		return handlers.GetMediaMap(c, db)
+	})
+
+	albumRoutes.Get("/media/duplicates", func(c *fiber.Ctx) error {
+		return handlers.GetDuplicateMediaReport(c, db)
+	})
+
+	albumRoutes.Get("/media/:mediaId/similar", func(c *fiber.Ctx) error {
+		return handlers.FindSimilarMedia(c, db)
+	})
+
+	albumRoutes.Get("/media/:mediaId/reactions", func(c *fiber.Ctx) error {
+		return handlers.GetReactions(c, db)
+	})
+
+	albumRoutes.Put("/media/:mediaId/reactions", func(c *fiber.Ctx) error {
+		return handlers.SetReaction(c, db)
+	})
+
+	albumRoutes.Delete("/media/:mediaId/reactions", func(c *fiber.Ctx) error {
+		return handlers.DeleteReaction(c, db)
+	})
 	
 	albumRoutes.Get("/images/latest", func(c *fiber.Ctx) error {
 		return handlers.GetLatestImage(c, db)
@@ -282,17 +477,237 @@ func SetupRoutes(app *fiber.App, db *gorm.DB, client notif.NotificationServiceCl
 		return handlers.UpdateTargetEmail(c, db, client)
 	})
 
+	albumRoutes.Post("/import", DynamicStorageCapacityMiddleware(db), func(c *fiber.Ctx) error {
+		return handlers.ImportAlbum(c, db)
+	})
+
+	albumRoutes.Get("/imports/:importId", func(c *fiber.Ctx) error {
+		return handlers.GetAlbumImport(c, db)
+	})
+
+	albumRoutes.Get("/exports/:exportId", func(c *fiber.Ctx) error {
+		return handlers.GetAlbumExport(c, db)
+	})
+
+	albumRoutes.Get("/trash", func(c *fiber.Ctx) error {
+		return handlers.GetTrash(c, db)
+	})
+
+	albumRoutes.Post("/trash/media/:mediaId/restore", func(c *fiber.Ctx) error {
+		return handlers.RestoreMedia(c, db)
+	})
+
+	albumRoutes.Delete("/trash/media/:mediaId", func(c *fiber.Ctx) error {
+		return handlers.PurgeTrashedMedia(c, db)
+	})
+
+	albumRoutes.Post("/trash/:albumId/restore", func(c *fiber.Ctx) error {
+		return handlers.RestoreAlbum(c, db)
+	})
+
+	albumRoutes.Delete("/trash/:albumId", func(c *fiber.Ctx) error {
+		return handlers.PurgeTrashedAlbum(c, db)
+	})
+
+	albumRoutes.Get("/:albumId/invitations", func(c *fiber.Ctx) error {
+		return handlers.GetAlbumInvitations(c, db)
+	})
+
+	albumRoutes.Post("/:albumId/invitations/:invitationId/resend", func(c *fiber.Ctx) error {
+		return handlers.ResendAlbumInvitation(c, db, client)
+	})
+
+	albumRoutes.Put("/:albumId/invitations/:invitationId/download", func(c *fiber.Ctx) error {
+		return handlers.UpdateInvitationDownload(c, db)
+	})
+
+	albumRoutes.Get("/:albumId/download", func(c *fiber.Ctx) error {
+		return handlers.DownloadAlbum(c, db, client)
+	})
+
+	albumRoutes.Delete("/:albumId/invitations/:invitationId", func(c *fiber.Ctx) error {
+		return handlers.CancelAlbumInvitation(c, db)
+	})
+
+	albumRoutes.Put("/:albumId/media/order", func(c *fiber.Ctx) error {
+		return handlers.ReorderAlbumMedia(c, db)
+	})
+
+	albumRoutes.Put("/:albumId/cover", func(c *fiber.Ctx) error {
+		return handlers.SetAlbumCover(c, db)
+	})
+
+	albumRoutes.Get("/:albumId/children", func(c *fiber.Ctx) error {
+		return handlers.GetAlbumChildren(c, db)
+	})
+
+	albumRoutes.Get("/:albumId/breadcrumb", func(c *fiber.Ctx) error {
+		return handlers.GetAlbumBreadcrumb(c, db)
+	})
+
+	albumRoutes.Put("/:albumId/move", func(c *fiber.Ctx) error {
+		return handlers.MoveAlbum(c, db)
+	})
+
+	albumRoutes.Post("/:albumId/duplicate", func(c *fiber.Ctx) error {
+		return handlers.DuplicateAlbum(c, db)
+	})
+
+	albumRoutes.Get("/:albumId/reactions", func(c *fiber.Ctx) error {
+		return handlers.GetReactions(c, db)
+	})
+
+	albumRoutes.Put("/:albumId/reactions", func(c *fiber.Ctx) error {
+		return handlers.SetReaction(c, db)
+	})
+
+	albumRoutes.Delete("/:albumId/reactions", func(c *fiber.Ctx) error {
+		return handlers.DeleteReaction(c, db)
+	})
+
 	albumRoutes.Get("/:albumId", func(c *fiber.Ctx) error {
 		return handlers.GetAlbum(c, db)
 	})
 
 	albumRoutes.Put("/:albumID", DynamicStorageCapacityMiddleware(db), func(c *fiber.Ctx) error {
-		return handlers.UpdateAlbum(c, db)
+		return handlers.UpdateAlbum(c, db, client)
 	})
 
 	albumRoutes.Delete("/:albumID", func(c *fiber.Ctx) error {
 		return handlers.DeleteAlbum(c, db)
 	})
+
+	authRoutes.Get("/feed", func(c *fiber.Ctx) error {
+		return handlers.GetFeed(c, db)
+	})
+
+	authRoutes.Get("/search", func(c *fiber.Ctx) error {
+		return handlers.Search(c, db)
+	})
+
+	tagRoutes := authRoutes.Group("/tags")
+
+	tagRoutes.Get("/autocomplete", func(c *fiber.Ctx) error {
+		return handlers.AutocompleteTags(c, db)
+	})
+
+	tagRoutes.Get("/:tagName/albums", func(c *fiber.Ctx) error {
+		return handlers.GetAlbumsByTag(c, db)
+	})
+
+	reportRoutes := authRoutes.Group("/reports")
+
+	reportRoutes.Post("/", func(c *fiber.Ctx) error {
+		return handlers.CreateReport(c, db)
+	})
+
+	reportRoutes.Get("/", func(c *fiber.Ctx) error {
+		return handlers.GetMyReports(c, db)
+	})
+
+	moderationRoutes := authRoutes.Group("/moderation", ModeratorMiddleware(db))
+
+	moderationRoutes.Get("/reports", func(c *fiber.Ctx) error {
+		return handlers.GetModerationQueue(c, db)
+	})
+
+	moderationRoutes.Get("/reports/:reportId", func(c *fiber.Ctx) error {
+		return handlers.GetModerationReport(c, db)
+	})
+
+	moderationRoutes.Put("/reports/:reportId/status", func(c *fiber.Ctx) error {
+		return handlers.UpdateReportStatus(c, db)
+	})
+
+	moderationRoutes.Post("/reports/:reportId/actions", func(c *fiber.Ctx) error {
+		return handlers.TakeModerationAction(c, db)
+	})
+
+	moderationRoutes.Get("/actions", func(c *fiber.Ctx) error {
+		return handlers.GetModerationActions(c, db)
+	})
+
+	adminRoutes := authRoutes.Group("/admin", AdminMiddleware(db))
+
+	adminRoutes.Post("/tags/:tagId/merge", func(c *fiber.Ctx) error {
+		return handlers.MergeTag(c, db)
+	})
+
+	adminRoutes.Post("/tags/:tagId/aliases", func(c *fiber.Ctx) error {
+		return handlers.AddTagAlias(c, db)
+	})
+
+	adminRoutes.Delete("/tags/aliases/:alias", func(c *fiber.Ctx) error {
+		return handlers.DeleteTagAlias(c, db)
+	})
+
+	adminRoutes.Get("/plans", func(c *fiber.Ctx) error {
+		return handlers.GetAdminPlans(c, db)
+	})
+
+	adminRoutes.Post("/plans", func(c *fiber.Ctx) error {
+		return handlers.CreatePlan(c, db)
+	})
+
+	adminRoutes.Put("/plans/:code", func(c *fiber.Ctx) error {
+		return handlers.UpdatePlan(c, db)
+	})
+
+	adminRoutes.Delete("/plans/:code", func(c *fiber.Ctx) error {
+		return handlers.RetirePlan(c, db)
+	})
+
+	adminRoutes.Post("/payments/:paymentId/refund", func(c *fiber.Ctx) error {
+		return handlers.RefundPayment(c, db)
+	})
+
+	notificationRoutes := authRoutes.Group("/notifications")
+
+	notificationRoutes.Get("/", func(c *fiber.Ctx) error {
+		return handlers.GetNotifications(c, db)
+	})
+
+	notificationRoutes.Put("/read-all", func(c *fiber.Ctx) error {
+		return handlers.MarkAllNotificationsRead(c, db)
+	})
+
+	notificationRoutes.Get("/preferences", func(c *fiber.Ctx) error {
+		return handlers.GetNotificationPreferences(c, db)
+	})
+
+	notificationRoutes.Put("/preferences", func(c *fiber.Ctx) error {
+		return handlers.UpdateNotificationPreferences(c, db)
+	})
+
+	notificationRoutes.Put("/:notificationId/read", func(c *fiber.Ctx) error {
+		return handlers.MarkNotificationRead(c, db)
+	})
+
+	collectionRoutes := authRoutes.Group("/collections")
+
+	collectionRoutes.Get("/", func(c *fiber.Ctx) error {
+		return handlers.GetCollections(c, db)
+	})
+
+	collectionRoutes.Post("/", func(c *fiber.Ctx) error {
+		return handlers.StoreCollection(c, db)
+	})
+
+	collectionRoutes.Put("/:collectionId", func(c *fiber.Ctx) error {
+		return handlers.UpdateCollection(c, db)
+	})
+
+	collectionRoutes.Delete("/:collectionId", func(c *fiber.Ctx) error {
+		return handlers.DeleteCollection(c, db)
+	})
+
+	collectionRoutes.Post("/:collectionId/albums", func(c *fiber.Ctx) error {
+		return handlers.AddAlbumsToCollection(c, db)
+	})
+
+	collectionRoutes.Delete("/:collectionId/albums/:albumId", func(c *fiber.Ctx) error {
+		return handlers.RemoveAlbumFromCollection(c, db)
+	})
 	// Tambahkan route lain yang butuh proteksi di sini
 }
 
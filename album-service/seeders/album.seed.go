@@ -0,0 +1,57 @@
+package seeders
+
+import (
+	"github.com/Zackly23/queue-app/models"
+	"gorm.io/gorm"
+)
+
+// SeedAlbumPaths mengisi materialized path untuk album lama yang dibuat sebelum
+// ada sub-album, sehingga semuanya menjadi album root.
+func SeedAlbumPaths(db *gorm.DB) error {
+	return db.Unscoped().Model(&models.Album{}).
+		Where("(path IS NULL OR path = '') AND parent_id IS NULL").
+		Update("path", gorm.Expr("'/' || id::text || '/'")).Error
+}
+
+// SeedMediaPositions memberi posisi awal (berdasarkan waktu upload) untuk media di album
+// yang belum pernah diurutkan.
+func SeedMediaPositions(db *gorm.DB) error {
+	return db.Exec(`WITH ordered AS (
+		SELECT id, ROW_NUMBER() OVER (PARTITION BY album_id ORDER BY created_at, id) AS pos
+		FROM media
+		WHERE album_id NOT IN (SELECT album_id FROM media WHERE position > 0)
+	)
+	UPDATE media SET position = ordered.pos
+	FROM ordered WHERE ordered.id = media.id`).Error
+}
+
+// SeedReactionCounts mengisi jumlah reaksi per jenis untuk album dan media yang dibuat
+// sebelum ada reaksi (reaction_counts masih kosong), sekaligus menyamakan likes_count.
+func SeedReactionCounts(db *gorm.DB) error {
+	targets := []struct {
+		Table         string
+		ReactionTable string
+		Column        string
+	}{
+		{Table: "albums", ReactionTable: "album_reactions", Column: "album_id"},
+		{Table: "media", ReactionTable: "media_reactions", Column: "media_id"},
+	}
+
+	for _, target := range targets {
+		if err := db.Exec(`UPDATE ` + target.Table + ` t
+			SET reaction_counts = c.counts, likes_count = c.total
+			FROM (
+				SELECT ` + target.Column + ` AS target_id, jsonb_object_agg(type, total) AS counts, SUM(total) AS total
+				FROM (
+					SELECT ` + target.Column + `, type, COUNT(*) AS total FROM ` + target.ReactionTable + `
+					GROUP BY ` + target.Column + `, type
+				) per_type
+				GROUP BY ` + target.Column + `
+			) c
+			WHERE t.id = c.target_id AND t.reaction_counts = '{}'::jsonb`).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
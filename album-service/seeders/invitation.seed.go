@@ -0,0 +1,75 @@
+package seeders
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Zackly23/queue-app/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SeedAlbumInvitations membuat record undangan untuk target email lama. Email yang sudah punya akun
+// langsung dianggap menerima undangan sehingga aksesnya tidak hilang. Email tanpa akun dicatat
+// sebagai undangan expired yang belum pernah terkirim, supaya pemilik album bisa mengirim ulang
+// undangan bertoken dari daftar undangan.
+func SeedAlbumInvitations(db *gorm.DB) error {
+	var albums []models.Album
+	if err := db.Where("album_privacy = ? AND target_email IS NOT NULL", "restricted").Find(&albums).Error; err != nil {
+		return err
+	}
+
+	for _, album := range albums {
+		var emails []string
+		if err := json.Unmarshal(album.TargetEmail, &emails); err != nil {
+			continue
+		}
+
+		for _, email := range emails {
+			email = strings.ToLower(strings.TrimSpace(email))
+			if email == "" {
+				continue
+			}
+
+			var count int64
+			if err := db.Model(&models.AlbumInvitation{}).Where("album_id = ? AND email = ?", album.ID, email).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				continue
+			}
+
+			now := time.Now()
+			invitation := models.AlbumInvitation{
+				ID:         uuid.New(),
+				AlbumID:    album.ID,
+				InviterID:  album.UserID,
+				Email:      email,
+				Status:     models.InvitationStatusExpired,
+				TokenNonce: uuid.NewString(),
+				SentCount:  0,
+				LastSentAt: album.CreatedAt,
+				ExpiresAt:  now,
+			}
+
+			var user models.User
+			err := db.Where("LOWER(email) = ?", email).First(&user).Error
+			switch {
+			case err == nil:
+				invitation.Status = models.InvitationStatusAccepted
+				invitation.UserID = &user.ID
+				invitation.RespondedAt = &now
+			case !errors.Is(err, gorm.ErrRecordNotFound):
+				return err
+			}
+
+			if err := db.Create(&invitation).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
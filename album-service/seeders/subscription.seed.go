@@ -9,47 +9,97 @@ import (
 	"gorm.io/gorm"
 )
 
+// SeedSubscriptions membuat plan default bila belum ada. Batas storage dan ukuran media tidak lagi
+// ditulis di Features (katalog membentuknya dari StorageCapacity/MaximumMediaSize), jadi plan lama
+// yang Features-nya masih berisi teks storage diperbarui sekali. Dijalankan setelah AutoMigrate.
 func SeedSubscriptions(db *gorm.DB) error {
 	subscriptions := []models.Subscription{
 		{
 			ID:               uuid.New(),
+			Code:             models.DefaultPlanCode,
+			Version:          1,
 			SubscriptionType: "Basic",
+			Description:      "Untuk mulai menyimpan dan berbagi album",
+			Price:            0,
+			Currency:         "IDR",
+			BillingInterval:  models.BillingIntervalMonth,
 			StorageCapacity:  10,        // dalam GB
 			MaximumMediaSize: 0.1,      // 100 MB = 0.1 GB
-			Features:         []byte(`["Basic Support", "3 GB Storage", "Max 100MB media upload"]`),
+			Features:         []byte(`["Basic Support"]`),
+			IsActive:         true,
+			IsPublic:         true,
+			SortOrder:        1,
 			CreatedAt:        time.Now(),
 			UpdatedAt:        time.Now(),
 		},
 		{
 			ID:               uuid.New(),
+			Code:             "advanced",
+			Version:          1,
 			SubscriptionType: "Advanced",
+			Description:      "Untuk fotografer dengan koleksi yang terus bertambah",
+			Price:            49000,
+			Currency:         "IDR",
+			BillingInterval:  models.BillingIntervalMonth,
 			StorageCapacity:  50,
 			MaximumMediaSize: 1,        // 1 GB
-			Features:         []byte(`["Priority Support", "15 GB Storage", "Max 1GB media upload"]`),
+			Features:         []byte(`["Priority Support"]`),
+			IsActive:         true,
+			IsPublic:         true,
+			SortOrder:        2,
 			CreatedAt:        time.Now(),
 			UpdatedAt:        time.Now(),
 		},
 		{
 			ID:               uuid.New(),
+			Code:             "pro",
+			Version:          1,
 			SubscriptionType: "Pro",
+			Description:      "Untuk studio dan tim dengan arsip besar",
+			Price:            149000,
+			Currency:         "IDR",
+			BillingInterval:  models.BillingIntervalMonth,
 			StorageCapacity:  1000,
 			MaximumMediaSize: 5,
-			Features:         []byte(`["Premium Support", "50 GB Storage", "Max 5GB media upload"]`),
+			Features:         []byte(`["Premium Support"]`),
+			IsActive:         true,
+			IsPublic:         true,
+			SortOrder:        3,
 			CreatedAt:        time.Now(),
 			UpdatedAt:        time.Now(),
 		},
 	}
 
 	for _, sub := range subscriptions {
-		fmt.Println("seed record : ", sub)
+		fmt.Println("seed record : ", sub.Code)
 		var existing models.Subscription
-		err := db.Where("subscription_type = ?", sub.SubscriptionType).First(&existing).Error
+		err := db.Unscoped().Where("code = ?", sub.Code).First(&existing).Error
 		if err == gorm.ErrRecordNotFound {
 			if err := db.Create(&sub).Error; err != nil {
 				return err
 			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		// Plan dari seeder lama: Features masih berisi teks storage dan harga belum diisi
+		if err := db.Model(&models.Subscription{}).
+			Where("code = ? AND version = 1 AND features::text LIKE ?", sub.Code, `%Storage"%`).
+			Updates(map[string]interface{}{
+				"description": sub.Description,
+				"price":       sub.Price,
+				"features":    sub.Features,
+				"sort_order":  sub.SortOrder,
+			}).Error; err != nil {
+			return err
 		}
 	}
 
-	return nil
+	// Hanya versi terbaru dari setiap plan yang ditawarkan
+	return db.Exec(`UPDATE subscriptions s SET is_active = false
+		WHERE s.is_active AND EXISTS (
+			SELECT 1 FROM subscriptions n WHERE n.code = s.code AND n.version > s.version AND n.is_active
+		)`).Error
 }
@@ -0,0 +1,14 @@
+package utils
+
+import (
+	"github.com/Zackly23/queue-app/models"
+	"github.com/google/uuid"
+)
+
+// AlbumPath membentuk materialized path album ("/root_id/.../album_id/") berdasarkan path parent-nya.
+func AlbumPath(parent *models.Album, albumID uuid.UUID) string {
+	if parent == nil || parent.Path == "" {
+		return "/" + albumID.String() + "/"
+	}
+	return parent.Path + albumID.String() + "/"
+}
@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/Zackly23/queue-app/models"
+)
+
+// ArchiveStreamLimitMB adalah batas total ukuran media yang masih di-stream langsung sebagai ZIP.
+// Album yang lebih besar diproses di background dan link-nya dikirim lewat notifikasi.
+func ArchiveStreamLimitMB() float32 {
+	limit, err := strconv.ParseFloat(os.Getenv("ALBUM_ZIP_STREAM_LIMIT_MB"), 32)
+	if err != nil || limit <= 0 {
+		limit = 500
+	}
+	return float32(limit)
+}
+
+// ArchiveLinkTTL adalah masa berlaku link arsip album hasil export.
+func ArchiveLinkTTL() time.Duration {
+	hours, err := strconv.Atoi(os.Getenv("ALBUM_EXPORT_LINK_HOURS"))
+	if err != nil || hours <= 0 {
+		hours = 24
+	}
+	// presigned URL S3 maksimal berlaku 7 hari
+	if hours > 24*7 {
+		hours = 24 * 7
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// ZipEntryName membuat nama file unik di dalam ZIP dengan prefix urutan media.
+func ZipEntryName(index int, media models.Media) string {
+	name := path.Base(S3KeyFromURL(media.URL))
+	if name == "." || name == "/" {
+		name = media.ID.String()
+	}
+	return fmt.Sprintf("%03d_%s", index+1, name)
+}
+
+// WriteMediaZip menulis media ke ZIP langsung dari S3 satu per satu tanpa menampung file di memori.
+// flush dipanggil setelah setiap file agar data segera terkirim ke client.
+func WriteMediaZip(ctx context.Context, w io.Writer, medias []models.Media, flush func() error) error {
+	zw := zip.NewWriter(w)
+
+	for i, media := range medias {
+		body, err := OpenS3Object(ctx, S3KeyFromURL(media.URL))
+		if err != nil {
+			return err
+		}
+
+		// Foto dan video sudah terkompresi, cukup disimpan tanpa deflate
+		entry, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     ZipEntryName(i, media),
+			Method:   zip.Store,
+			Modified: media.CreatedAt,
+		})
+		if err != nil {
+			body.Close()
+			return err
+		}
+
+		_, err = io.Copy(entry, body)
+		body.Close()
+		if err != nil {
+			return fmt.Errorf("gagal menulis %s ke zip: %w", media.ID, err)
+		}
+
+		if flush != nil {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return zw.Close()
+}
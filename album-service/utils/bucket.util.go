@@ -3,7 +3,9 @@ package utils
 import (
 	"context"
 	"fmt"
+	"io"
 	"mime/multipart"
+	neturl "net/url"
 	"strings"
 	"time"
 
@@ -57,6 +59,12 @@ func DeleteFromS3(fileURL string, bucketName string) error {
 }
 
 func GeneratePresignedURL(bucketName, key string) (string, error) {
+	return GeneratePresignedURLWithExpiry(bucketName, key, 15*time.Minute)
+}
+
+// GeneratePresignedURLWithExpiry sama seperti GeneratePresignedURL dengan masa berlaku custom,
+// dipakai untuk link yang dikirim lewat email (mis. arsip album).
+func GeneratePresignedURLWithExpiry(bucketName, key string, expires time.Duration) (string, error) {
     // Buat presigner langsung dari s3
 	
 	client :=  config.S3Bucket.S3client
@@ -67,7 +75,7 @@ func GeneratePresignedURL(bucketName, key string) (string, error) {
     resp, err := presigner.PresignGetObject(context.TODO(), &s3.GetObjectInput{
         Bucket: aws.String(bucketName),
         Key:    aws.String(key),
-    }, s3.WithPresignExpires(15*time.Minute))
+    }, s3.WithPresignExpires(expires))
 
     if err != nil {
         return "", fmt.Errorf("failed to generate presigned url: %w", err)
@@ -76,3 +84,59 @@ func GeneratePresignedURL(bucketName, key string) (string, error) {
     return resp.URL, nil
 }
 
+// S3KeyFromURL mengambil object key dari URL S3 bucket aplikasi.
+func S3KeyFromURL(fileURL string) string {
+	prefix := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/", config.S3Bucket.BucketName, config.S3Bucket.Region)
+	return strings.TrimPrefix(fileURL, prefix)
+}
+
+// OpenS3Object membuka object S3 sebagai stream, pemanggil wajib menutup body-nya.
+func OpenS3Object(ctx context.Context, key string) (io.ReadCloser, error) {
+	out, err := config.S3Bucket.S3client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(config.S3Bucket.BucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object %s: %w", key, err)
+	}
+	return out.Body, nil
+}
+
+// UploadStreamToS3 mengupload data dari reader tanpa perlu tahu ukurannya (multipart upload).
+func UploadStreamToS3(ctx context.Context, key string, body io.Reader, contentType string) (string, error) {
+	uploader := manager.NewUploader(config.S3Bucket.S3client)
+
+	_, err := uploader.Upload(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(config.S3Bucket.BucketName),
+		Key:         aws.String(key),
+		Body:        body,
+		ContentType: aws.String(contentType),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload to S3: %w", err)
+	}
+
+	url := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", config.S3Bucket.BucketName, config.S3Bucket.Region, key)
+	return url, nil
+}
+
+// CopyS3Object menyalin object di dalam bucket aplikasi secara server-side (tanpa download).
+func CopyS3Object(ctx context.Context, srcKey, dstKey string) (string, error) {
+	// CopySource harus URL-encoded, tapi pemisah folder tetap "/"
+	segments := strings.Split(srcKey, "/")
+	for i, segment := range segments {
+		segments[i] = neturl.PathEscape(segment)
+	}
+
+	_, err := config.S3Bucket.S3client.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:     aws.String(config.S3Bucket.BucketName),
+		CopySource: aws.String(config.S3Bucket.BucketName + "/" + strings.Join(segments, "/")),
+		Key:        aws.String(dstKey),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to copy object %s: %w", srcKey, err)
+	}
+
+	url := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", config.S3Bucket.BucketName, config.S3Bucket.Region, dstKey)
+	return url, nil
+}
@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EncodeCursor membuat cursor pagination dari created_at dan id record terakhir di halaman.
+func EncodeCursor(createdAt time.Time, id uuid.UUID) string {
+	raw := createdAt.UTC().Format(time.RFC3339Nano) + "|" + id.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor membaca cursor dari EncodeCursor.
+func DecodeCursor(cursor string) (time.Time, uuid.UUID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, uuid.Nil, errors.New("cursor tidak valid")
+	}
+
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 {
+		return time.Time{}, uuid.Nil, errors.New("cursor tidak valid")
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return time.Time{}, uuid.Nil, errors.New("cursor tidak valid")
+	}
+
+	id, err := uuid.Parse(parts[1])
+	if err != nil {
+		return time.Time{}, uuid.Nil, errors.New("cursor tidak valid")
+	}
+
+	return createdAt, id, nil
+}
+
+// ParseLimit membaca query limit dengan nilai default dan batas maksimum.
+func ParseLimit(value string, fallback, max int) int {
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 1 {
+		return fallback
+	}
+	if limit > max {
+		return max
+	}
+	return limit
+}
+
+// EncodeScoreCursor membuat cursor untuk daftar yang diurutkan berdasarkan skor (mis. feed).
+// asOf ikut disimpan agar skor di halaman berikutnya dihitung dari waktu acuan yang sama.
+func EncodeScoreCursor(asOf time.Time, score float64, id uuid.UUID) string {
+	raw := asOf.UTC().Format(time.RFC3339Nano) + "|" + strconv.FormatFloat(score, 'g', -1, 64) + "|" + id.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeScoreCursor membaca cursor dari EncodeScoreCursor.
+func DecodeScoreCursor(cursor string) (time.Time, float64, uuid.UUID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, 0, uuid.Nil, errors.New("cursor tidak valid")
+	}
+
+	parts := strings.SplitN(string(raw), "|", 3)
+	if len(parts) != 3 {
+		return time.Time{}, 0, uuid.Nil, errors.New("cursor tidak valid")
+	}
+
+	asOf, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return time.Time{}, 0, uuid.Nil, errors.New("cursor tidak valid")
+	}
+
+	score, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil {
+		return time.Time{}, 0, uuid.Nil, errors.New("cursor tidak valid")
+	}
+
+	id, err := uuid.Parse(parts[2])
+	if err != nil {
+		return time.Time{}, 0, uuid.Nil, errors.New("cursor tidak valid")
+	}
+
+	return asOf, score, id, nil
+}
@@ -0,0 +1,147 @@
+package utils
+
+import (
+	"encoding/base64"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	id := uuid.MustParse("6f1c2a4e-9a7b-4c1d-8e2f-3a4b5c6d7e8f")
+	jakarta := time.FixedZone("WIB", 7*60*60)
+
+	tests := []struct {
+		name      string
+		createdAt time.Time
+	}{
+		{name: "utc", createdAt: time.Date(2026, 3, 14, 9, 26, 53, 0, time.UTC)},
+		{name: "nanosecond precision", createdAt: time.Date(2026, 3, 14, 9, 26, 53, 589793238, time.UTC)},
+		{name: "non utc zone", createdAt: time.Date(2026, 3, 14, 16, 26, 53, 0, jakarta)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			createdAt, gotID, err := DecodeCursor(EncodeCursor(tt.createdAt, id))
+			if err != nil {
+				t.Fatalf("DecodeCursor: %v", err)
+			}
+			if !createdAt.Equal(tt.createdAt) {
+				t.Errorf("created_at = %v, want %v", createdAt, tt.createdAt)
+			}
+			if gotID != id {
+				t.Errorf("id = %v, want %v", gotID, id)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	encode := func(raw string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(raw))
+	}
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{name: "not base64", cursor: "!!!"},
+		{name: "missing separator", cursor: encode("2026-03-14T09:26:53Z")},
+		{name: "bad time", cursor: encode("yesterday|6f1c2a4e-9a7b-4c1d-8e2f-3a4b5c6d7e8f")},
+		{name: "bad id", cursor: encode("2026-03-14T09:26:53Z|not-a-uuid")},
+		{name: "score cursor", cursor: EncodeScoreCursor(time.Now(), 1.5, uuid.New())},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := DecodeCursor(tt.cursor); err == nil {
+				t.Errorf("DecodeCursor(%q) berhasil, seharusnya error", tt.cursor)
+			}
+		})
+	}
+}
+
+// Skor feed bisa sangat kecil untuk album lama (recency meluruh eksponensial), cursor harus
+// mengembalikan nilai yang persis sama agar perbandingan (score, id) < cursor tidak melompati album.
+func TestScoreCursorRoundTrip(t *testing.T) {
+	id := uuid.MustParse("0a1b2c3d-4e5f-4a6b-8c7d-9e0f1a2b3c4d")
+	asOf := time.Date(2026, 10, 1, 12, 0, 0, 123456789, time.UTC)
+
+	tests := []struct {
+		name  string
+		score float64
+	}{
+		{name: "zero", score: 0},
+		{name: "typical", score: 3.718281828459045},
+		{name: "decayed", score: math.Pow(0.5, 500)},
+		{name: "smallest positive", score: math.SmallestNonzeroFloat64},
+		{name: "large", score: 1e12},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotAsOf, score, gotID, err := DecodeScoreCursor(EncodeScoreCursor(asOf, tt.score, id))
+			if err != nil {
+				t.Fatalf("DecodeScoreCursor: %v", err)
+			}
+			if !gotAsOf.Equal(asOf) {
+				t.Errorf("as_of = %v, want %v", gotAsOf, asOf)
+			}
+			if score != tt.score {
+				t.Errorf("score = %v, want %v", score, tt.score)
+			}
+			if gotID != id {
+				t.Errorf("id = %v, want %v", gotID, id)
+			}
+		})
+	}
+}
+
+func TestDecodeScoreCursorInvalid(t *testing.T) {
+	encode := func(raw string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(raw))
+	}
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{name: "not base64", cursor: "%%%"},
+		{name: "created_at cursor", cursor: EncodeCursor(time.Now(), uuid.New())},
+		{name: "bad score", cursor: encode("2026-10-01T12:00:00Z|high|0a1b2c3d-4e5f-4a6b-8c7d-9e0f1a2b3c4d")},
+		{name: "bad time", cursor: encode("now|1.5|0a1b2c3d-4e5f-4a6b-8c7d-9e0f1a2b3c4d")},
+		{name: "bad id", cursor: encode("2026-10-01T12:00:00Z|1.5|42")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, _, err := DecodeScoreCursor(tt.cursor); err == nil {
+				t.Errorf("DecodeScoreCursor(%q) berhasil, seharusnya error", tt.cursor)
+			}
+		})
+	}
+}
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int
+	}{
+		{value: "", want: 20},
+		{value: "abc", want: 20},
+		{value: "0", want: 20},
+		{value: "-5", want: 20},
+		{value: "1", want: 1},
+		{value: "50", want: 50},
+		{value: "51", want: 50},
+		{value: "1000", want: 50},
+	}
+
+	for _, tt := range tests {
+		if got := ParseLimit(tt.value, 20, 50); got != tt.want {
+			t.Errorf("ParseLimit(%q, 20, 50) = %d, want %d", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+// Tag EXIF yang dibaca, lihat spesifikasi EXIF 2.3 / TIFF 6.0
+const (
+	exifTagDateTime         = 0x0132
+	exifTagExifIFD          = 0x8769
+	exifTagGPSIFD           = 0x8825
+	exifTagDateTimeOriginal = 0x9003
+	gpsTagLatitudeRef       = 0x0001
+	gpsTagLatitude          = 0x0002
+	gpsTagLongitudeRef      = 0x0003
+	gpsTagLongitude         = 0x0004
+
+	exifTypeASCII    = 2
+	exifTypeRational = 5
+
+	maxExifSegment = 64 * 1024
+)
+
+var errNoExif = errors.New("exif tidak ditemukan")
+
+// exifImageTypes adalah mime type gambar yang dibaca EXIF-nya (saat ini hanya JPEG).
+var exifImageTypes = []string{"image/jpeg", "image/jpg"}
+
+// ExifImageTypes mengembalikan daftar mime type yang bisa dibaca EXIF-nya.
+func ExifImageTypes() []string {
+	return exifImageTypes
+}
+
+// HasExif mengecek apakah mime type bisa dibaca EXIF-nya.
+func HasExif(mimeType string) bool {
+	for _, exifType := range exifImageTypes {
+		if mimeType == exifType {
+			return true
+		}
+	}
+	return false
+}
+
+// CaptureInfo adalah waktu pengambilan dan lokasi GPS dari metadata EXIF foto.
+type CaptureInfo struct {
+	CapturedAt *time.Time
+	Latitude   *float64
+	Longitude  *float64
+}
+
+// ReadCaptureInfo membaca DateTimeOriginal dan koordinat GPS dari segment EXIF (APP1) file JPEG.
+// Waktu disimpan sebagai UTC dengan jam sesuai kamera (offset zona waktu diabaikan), sehingga
+// pengelompokan per hari mengikuti tanggal lokal saat foto diambil.
+func ReadCaptureInfo(r io.Reader) (CaptureInfo, error) {
+	var info CaptureInfo
+
+	segment, err := jpegExifSegment(bufio.NewReader(r))
+	if err != nil {
+		return info, err
+	}
+
+	tiff := segment[len("Exif\x00\x00"):]
+	if len(tiff) < 8 {
+		return info, errNoExif
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return info, errNoExif
+	}
+
+	ifd0 := readIFD(tiff, order, order.Uint32(tiff[4:8]))
+
+	dateTime := ifd0.ascii(exifTagDateTime)
+	if exifOffset, ok := ifd0.uint32(exifTagExifIFD); ok {
+		if original := readIFD(tiff, order, exifOffset).ascii(exifTagDateTimeOriginal); original != "" {
+			dateTime = original
+		}
+	}
+	if capturedAt, ok := parseExifTime(dateTime); ok {
+		info.CapturedAt = &capturedAt
+	}
+
+	if gpsOffset, ok := ifd0.uint32(exifTagGPSIFD); ok {
+		gps := readIFD(tiff, order, gpsOffset)
+		latitude, okLat := gps.coordinate(gpsTagLatitude, gps.ascii(gpsTagLatitudeRef), "S")
+		longitude, okLng := gps.coordinate(gpsTagLongitude, gps.ascii(gpsTagLongitudeRef), "W")
+		if okLat && okLng && latitude >= -90 && latitude <= 90 && longitude >= -180 && longitude <= 180 &&
+			(latitude != 0 || longitude != 0) {
+			info.Latitude = &latitude
+			info.Longitude = &longitude
+		}
+	}
+
+	return info, nil
+}
+
+// jpegExifSegment mencari segment APP1 berisi EXIF sebelum data gambar (SOS).
+func jpegExifSegment(r *bufio.Reader) ([]byte, error) {
+	var marker [2]byte
+	if _, err := io.ReadFull(r, marker[:]); err != nil || marker != [2]byte{0xFF, 0xD8} {
+		return nil, errNoExif
+	}
+
+	for {
+		if _, err := io.ReadFull(r, marker[:]); err != nil {
+			return nil, errNoExif
+		}
+		if marker[0] != 0xFF || marker[1] == 0xDA || marker[1] == 0xD9 {
+			return nil, errNoExif
+		}
+
+		var length [2]byte
+		if _, err := io.ReadFull(r, length[:]); err != nil {
+			return nil, errNoExif
+		}
+		size := int(binary.BigEndian.Uint16(length[:])) - 2
+		if size < 0 {
+			return nil, errNoExif
+		}
+
+		if marker[1] == 0xE1 && size <= maxExifSegment {
+			data := make([]byte, size)
+			if _, err := io.ReadFull(r, data); err != nil {
+				return nil, errNoExif
+			}
+			if bytes.HasPrefix(data, []byte("Exif\x00\x00")) {
+				return data, nil
+			}
+			continue
+		}
+
+		if _, err := r.Discard(size); err != nil {
+			return nil, errNoExif
+		}
+	}
+}
+
+type exifEntry struct {
+	Type  uint16
+	Count uint32
+	Value []byte // 4 byte value/offset
+}
+
+type exifIFD struct {
+	tiff    []byte
+	order   binary.ByteOrder
+	entries map[uint16]exifEntry
+}
+
+func readIFD(tiff []byte, order binary.ByteOrder, offset uint32) exifIFD {
+	ifd := exifIFD{tiff: tiff, order: order, entries: map[uint16]exifEntry{}}
+	if int(offset)+2 > len(tiff) {
+		return ifd
+	}
+
+	count := int(order.Uint16(tiff[offset:]))
+	for i := 0; i < count; i++ {
+		start := int(offset) + 2 + i*12
+		if start+12 > len(tiff) {
+			break
+		}
+		entry := tiff[start : start+12]
+		ifd.entries[order.Uint16(entry[0:2])] = exifEntry{
+			Type:  order.Uint16(entry[2:4]),
+			Count: order.Uint32(entry[4:8]),
+			Value: entry[8:12],
+		}
+	}
+	return ifd
+}
+
+// data mengembalikan isi entry, dari field value sendiri bila muat 4 byte atau dari offset.
+func (ifd exifIFD) data(entry exifEntry, size int) []byte {
+	if size <= 4 {
+		return entry.Value[:size]
+	}
+	offset := int(ifd.order.Uint32(entry.Value))
+	if offset < 0 || offset+size > len(ifd.tiff) {
+		return nil
+	}
+	return ifd.tiff[offset : offset+size]
+}
+
+func (ifd exifIFD) uint32(tag uint16) (uint32, bool) {
+	entry, ok := ifd.entries[tag]
+	if !ok {
+		return 0, false
+	}
+	return ifd.order.Uint32(entry.Value), true
+}
+
+func (ifd exifIFD) ascii(tag uint16) string {
+	entry, ok := ifd.entries[tag]
+	if !ok || entry.Type != exifTypeASCII || entry.Count > 256 {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimRight(string(ifd.data(entry, int(entry.Count))), "\x00"))
+}
+
+// coordinate membaca derajat, menit, detik (3 rational) menjadi derajat desimal.
+func (ifd exifIFD) coordinate(tag uint16, ref string, negativeRef string) (float64, bool) {
+	entry, ok := ifd.entries[tag]
+	if !ok || entry.Type != exifTypeRational || entry.Count != 3 {
+		return 0, false
+	}
+	data := ifd.data(entry, 24)
+	if data == nil {
+		return 0, false
+	}
+
+	var parts [3]float64
+	for i := range parts {
+		numerator := ifd.order.Uint32(data[i*8:])
+		denominator := ifd.order.Uint32(data[i*8+4:])
+		if denominator == 0 {
+			return 0, false
+		}
+		parts[i] = float64(numerator) / float64(denominator)
+	}
+
+	value := parts[0] + parts[1]/60 + parts[2]/3600
+	if strings.EqualFold(ref, negativeRef) {
+		value = -value
+	}
+	return value, true
+}
+
+// parseExifTime membaca format waktu EXIF "2006:01:02 15:04:05".
+func parseExifTime(value string) (time.Time, bool) {
+	if value == "" || strings.HasPrefix(value, "0000") {
+		return time.Time{}, false
+	}
+
+	t, err := time.Parse("2006:01:02 15:04:05", value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
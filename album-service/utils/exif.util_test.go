@@ -0,0 +1,274 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+)
+
+const exifTypeLong = 4
+
+type tiffEntry struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	data  []byte
+}
+
+func asciiEntry(tag uint16, value string) tiffEntry {
+	return tiffEntry{tag: tag, typ: exifTypeASCII, count: uint32(len(value) + 1), data: append([]byte(value), 0)}
+}
+
+func rationalEntry(order binary.AppendByteOrder, tag uint16, values ...[2]uint32) tiffEntry {
+	data := make([]byte, 0, len(values)*8)
+	for _, v := range values {
+		data = order.AppendUint32(data, v[0])
+		data = order.AppendUint32(data, v[1])
+	}
+	return tiffEntry{tag: tag, typ: exifTypeRational, count: uint32(len(values)), data: data}
+}
+
+func longEntry(order binary.AppendByteOrder, tag uint16, value uint32) tiffEntry {
+	return tiffEntry{tag: tag, typ: exifTypeLong, count: 1, data: order.AppendUint32(nil, value)}
+}
+
+func ifdSize(entries []tiffEntry) int {
+	size := 2 + 12*len(entries) + 4
+	for _, e := range entries {
+		if len(e.data) > 4 {
+			size += len(e.data)
+		}
+	}
+	return size
+}
+
+// writeIFD menulis IFD di offset base (relatif terhadap awal TIFF), nilai lebih dari 4 byte
+// diletakkan tepat setelah tabel entry.
+func writeIFD(order binary.AppendByteOrder, base int, entries []tiffEntry) []byte {
+	table := order.AppendUint16(nil, uint16(len(entries)))
+	var extra []byte
+	extraOffset := base + 2 + 12*len(entries) + 4
+
+	for _, e := range entries {
+		table = order.AppendUint16(table, e.tag)
+		table = order.AppendUint16(table, e.typ)
+		table = order.AppendUint32(table, e.count)
+		if len(e.data) <= 4 {
+			value := make([]byte, 4)
+			copy(value, e.data)
+			table = append(table, value...)
+			continue
+		}
+		table = order.AppendUint32(table, uint32(extraOffset+len(extra)))
+		extra = append(extra, e.data...)
+	}
+
+	table = order.AppendUint32(table, 0) // tidak ada IFD berikutnya
+	return append(table, extra...)
+}
+
+// buildTIFF menyusun header TIFF, IFD0, lalu Exif IFD dan GPS IFD bila diisi.
+func buildTIFF(order binary.AppendByteOrder, ifd0, exifIFD, gpsIFD []tiffEntry) []byte {
+	// Pointer ke Exif/GPS IFD muat di 4 byte, jadi ukuran IFD0 sudah bisa dihitung sebelum offset-nya diketahui
+	pointers := 0
+	if len(exifIFD) > 0 {
+		pointers++
+	}
+	if len(gpsIFD) > 0 {
+		pointers++
+	}
+	exifOffset := 8 + ifdSize(ifd0) + 12*pointers
+	gpsOffset := exifOffset + ifdSize(exifIFD)
+	if len(exifIFD) == 0 {
+		gpsOffset = exifOffset
+	}
+
+	if len(exifIFD) > 0 {
+		ifd0 = append(ifd0, longEntry(order, exifTagExifIFD, uint32(exifOffset)))
+	}
+	if len(gpsIFD) > 0 {
+		ifd0 = append(ifd0, longEntry(order, exifTagGPSIFD, uint32(gpsOffset)))
+	}
+
+	tiff := []byte("II*\x00")
+	if order == binary.BigEndian {
+		tiff = []byte("MM\x00*")
+	}
+	tiff = order.AppendUint32(tiff, 8)
+	tiff = append(tiff, writeIFD(order, 8, ifd0)...)
+	if len(exifIFD) > 0 {
+		tiff = append(tiff, writeIFD(order, exifOffset, exifIFD)...)
+	}
+	if len(gpsIFD) > 0 {
+		tiff = append(tiff, writeIFD(order, gpsOffset, gpsIFD)...)
+	}
+	return tiff
+}
+
+// jpegWithExif membungkus TIFF ke segment APP1 sebuah JPEG, didahului segment APP0 JFIF.
+func jpegWithExif(tiff []byte) []byte {
+	var buf bytes.Buffer
+	buf.Write([]byte{0xFF, 0xD8})
+
+	jfif := []byte("JFIF\x00\x01\x01\x00\x00\x01\x00\x01\x00\x00")
+	buf.Write([]byte{0xFF, 0xE0})
+	binary.Write(&buf, binary.BigEndian, uint16(len(jfif)+2))
+	buf.Write(jfif)
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	buf.Write([]byte{0xFF, 0xE1})
+	binary.Write(&buf, binary.BigEndian, uint16(len(payload)+2))
+	buf.Write(payload)
+
+	buf.Write([]byte{0xFF, 0xDA, 0x00, 0x02, 0xFF, 0xD9})
+	return buf.Bytes()
+}
+
+func TestReadCaptureInfo(t *testing.T) {
+	// Monas, Jakarta: 6°10'31.68" S, 106°49'37.20" E
+	gps := func(order binary.AppendByteOrder, latRef, lngRef string) []tiffEntry {
+		return []tiffEntry{
+			asciiEntry(gpsTagLatitudeRef, latRef),
+			rationalEntry(order, gpsTagLatitude, [2]uint32{6, 1}, [2]uint32{10, 1}, [2]uint32{3168, 100}),
+			asciiEntry(gpsTagLongitudeRef, lngRef),
+			rationalEntry(order, gpsTagLongitude, [2]uint32{106, 1}, [2]uint32{49, 1}, [2]uint32{3720, 100}),
+		}
+	}
+	lat := 6 + 10.0/60 + 31.68/3600
+	lng := 106 + 49.0/60 + 37.20/3600
+
+	tests := []struct {
+		name       string
+		order      binary.AppendByteOrder
+		ifd0       []tiffEntry
+		exif       []tiffEntry
+		gps        []tiffEntry
+		capturedAt *time.Time
+		lat, lng   *float64
+	}{
+		{
+			name:       "little endian with original time and gps",
+			order:      binary.LittleEndian,
+			ifd0:       []tiffEntry{asciiEntry(exifTagDateTime, "2026:08:17 10:00:00")},
+			exif:       []tiffEntry{asciiEntry(exifTagDateTimeOriginal, "2026:08:17 07:45:12")},
+			gps:        gps(binary.LittleEndian, "S", "E"),
+			capturedAt: ptr(time.Date(2026, 8, 17, 7, 45, 12, 0, time.UTC)),
+			lat:        ptr(-lat),
+			lng:        ptr(lng),
+		},
+		{
+			name:       "big endian with original time and gps",
+			order:      binary.BigEndian,
+			exif:       []tiffEntry{asciiEntry(exifTagDateTimeOriginal, "2025:12:31 23:59:59")},
+			gps:        gps(binary.BigEndian, "N", "W"),
+			capturedAt: ptr(time.Date(2025, 12, 31, 23, 59, 59, 0, time.UTC)),
+			lat:        ptr(lat),
+			lng:        ptr(-lng),
+		},
+		{
+			name:       "falls back to ifd0 time",
+			order:      binary.BigEndian,
+			ifd0:       []tiffEntry{asciiEntry(exifTagDateTime, "2024:02:29 12:30:00")},
+			capturedAt: ptr(time.Date(2024, 2, 29, 12, 30, 0, 0, time.UTC)),
+		},
+		{
+			name:  "zero time ignored",
+			order: binary.LittleEndian,
+			exif:  []tiffEntry{asciiEntry(exifTagDateTimeOriginal, "0000:00:00 00:00:00")},
+		},
+		{
+			name:  "malformed time ignored",
+			order: binary.LittleEndian,
+			exif:  []tiffEntry{asciiEntry(exifTagDateTimeOriginal, "17/08/2026 07:45")},
+		},
+		{
+			name:  "null island ignored",
+			order: binary.LittleEndian,
+			gps: []tiffEntry{
+				asciiEntry(gpsTagLatitudeRef, "N"),
+				rationalEntry(binary.LittleEndian, gpsTagLatitude, [2]uint32{0, 1}, [2]uint32{0, 1}, [2]uint32{0, 1}),
+				asciiEntry(gpsTagLongitudeRef, "E"),
+				rationalEntry(binary.LittleEndian, gpsTagLongitude, [2]uint32{0, 1}, [2]uint32{0, 1}, [2]uint32{0, 1}),
+			},
+		},
+		{
+			name:  "zero denominator ignored",
+			order: binary.LittleEndian,
+			gps: []tiffEntry{
+				asciiEntry(gpsTagLatitudeRef, "N"),
+				rationalEntry(binary.LittleEndian, gpsTagLatitude, [2]uint32{6, 0}, [2]uint32{10, 1}, [2]uint32{0, 1}),
+				asciiEntry(gpsTagLongitudeRef, "E"),
+				rationalEntry(binary.LittleEndian, gpsTagLongitude, [2]uint32{106, 1}, [2]uint32{49, 1}, [2]uint32{0, 1}),
+			},
+		},
+		{
+			name:  "latitude out of range ignored",
+			order: binary.LittleEndian,
+			gps: []tiffEntry{
+				asciiEntry(gpsTagLatitudeRef, "N"),
+				rationalEntry(binary.LittleEndian, gpsTagLatitude, [2]uint32{91, 1}, [2]uint32{0, 1}, [2]uint32{0, 1}),
+				asciiEntry(gpsTagLongitudeRef, "E"),
+				rationalEntry(binary.LittleEndian, gpsTagLongitude, [2]uint32{106, 1}, [2]uint32{0, 1}, [2]uint32{0, 1}),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tiff := buildTIFF(tt.order, tt.ifd0, tt.exif, tt.gps)
+			info, err := ReadCaptureInfo(bytes.NewReader(jpegWithExif(tiff)))
+			if err != nil {
+				t.Fatalf("ReadCaptureInfo: %v", err)
+			}
+
+			switch {
+			case tt.capturedAt == nil && info.CapturedAt != nil:
+				t.Errorf("captured_at = %v, want nil", *info.CapturedAt)
+			case tt.capturedAt != nil && (info.CapturedAt == nil || !info.CapturedAt.Equal(*tt.capturedAt)):
+				t.Errorf("captured_at = %v, want %v", info.CapturedAt, *tt.capturedAt)
+			}
+			checkCoordinate(t, "latitude", info.Latitude, tt.lat)
+			checkCoordinate(t, "longitude", info.Longitude, tt.lng)
+		})
+	}
+}
+
+func TestReadCaptureInfoWithoutExif(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "empty", data: nil},
+		{name: "png", data: []byte("\x89PNG\r\n\x1a\n")},
+		{name: "jpeg without app1", data: []byte{0xFF, 0xD8, 0xFF, 0xDA, 0x00, 0x02, 0xFF, 0xD9}},
+		{name: "truncated segment", data: []byte{0xFF, 0xD8, 0xFF, 0xE1, 0x01, 0x00, 'E', 'x'}},
+		{name: "app1 without exif header", data: []byte{0xFF, 0xD8, 0xFF, 0xE1, 0x00, 0x06, 'h', 't', 't', 'p', 0xFF, 0xD9}},
+		{name: "invalid byte order", data: jpegWithExif([]byte("XX*\x00\x08\x00\x00\x00\x00\x00"))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ReadCaptureInfo(bytes.NewReader(tt.data)); err == nil {
+				t.Error("ReadCaptureInfo berhasil, seharusnya error")
+			}
+		})
+	}
+}
+
+func checkCoordinate(t *testing.T, name string, got, want *float64) {
+	t.Helper()
+	switch {
+	case want == nil && got != nil:
+		t.Errorf("%s = %v, want nil", name, *got)
+	case want != nil && got == nil:
+		t.Errorf("%s = nil, want %v", name, *want)
+	case want != nil && math.Abs(*got-*want) > 1e-9:
+		t.Errorf("%s = %v, want %v", name, *got, *want)
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// ImportManifest adalah isi manifest.json / manifest.csv pada import album.
+type ImportManifest struct {
+	Title        string               `json:"title"`
+	Description  string               `json:"description"`
+	AlbumPrivacy string               `json:"album_privacy"`
+	Tags         []string             `json:"tags"`
+	Files        []ImportManifestFile `json:"files"`
+}
+
+type ImportManifestFile struct {
+	Name        string   `json:"name"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+}
+
+// IsImportManifest mengecek apakah nama file adalah manifest import.
+func IsImportManifest(name string) bool {
+	base := strings.ToLower(path.Base(name))
+	return base == "manifest.json" || base == "manifest.csv"
+}
+
+// FindFile mencari data file di manifest berdasarkan nama (tanpa folder, case-insensitive).
+func (m ImportManifest) FindFile(name string) (ImportManifestFile, bool) {
+	base := strings.ToLower(path.Base(name))
+	for _, file := range m.Files {
+		if strings.ToLower(path.Base(file.Name)) == base {
+			return file, true
+		}
+	}
+	return ImportManifestFile{}, false
+}
+
+// ParseImportManifest membaca manifest JSON atau CSV.
+// Format CSV: header "filename,title,description,tags" dengan tags dipisah ";".
+func ParseImportManifest(name string, r io.Reader) (ImportManifest, error) {
+	var manifest ImportManifest
+
+	if strings.HasSuffix(strings.ToLower(name), ".json") {
+		if err := json.NewDecoder(r).Decode(&manifest); err != nil {
+			return manifest, fmt.Errorf("manifest json tidak valid: %w", err)
+		}
+		return manifest, nil
+	}
+
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return manifest, fmt.Errorf("manifest csv tidak valid: %w", err)
+	}
+	if len(rows) == 0 {
+		return manifest, nil
+	}
+
+	columns := map[string]int{}
+	for i, column := range rows[0] {
+		columns[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+
+	nameColumn, ok := columns["filename"]
+	if !ok {
+		return manifest, fmt.Errorf("manifest csv wajib memiliki kolom filename")
+	}
+
+	value := func(row []string, column string) string {
+		if i, ok := columns[column]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	for _, row := range rows[1:] {
+		if nameColumn >= len(row) || strings.TrimSpace(row[nameColumn]) == "" {
+			continue
+		}
+
+		file := ImportManifestFile{
+			Name:        strings.TrimSpace(row[nameColumn]),
+			Title:       value(row, "title"),
+			Description: value(row, "description"),
+		}
+		for _, tag := range strings.Split(value(row, "tags"), ";") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				file.Tags = append(file.Tags, tag)
+			}
+		}
+		manifest.Files = append(manifest.Files, file)
+	}
+
+	return manifest, nil
+}
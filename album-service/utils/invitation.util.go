@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const invitationTokenPurpose = "album-invitation"
+
+// FrontendURL menggabungkan FRONTEND_URL dengan path yang diberikan.
+func FrontendURL(path string) string {
+	base := os.Getenv("FRONTEND_URL")
+	if base == "" {
+		base = "http://localhost:5173"
+	}
+	return strings.TrimRight(base, "/") + "/" + strings.TrimLeft(path, "/")
+}
+
+// NewInvitationNonce membuat nonce acak yang disimpan di undangan dan ikut ditandatangani di token.
+func NewInvitationNonce() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// GenerateInvitationToken membuat token undangan yang ditandatangani dengan JWT_SECRET_KEY.
+func GenerateInvitationToken(invitationID uuid.UUID, nonce string, expiresAt time.Time) (string, error) {
+	claims := jwt.MapClaims{
+		"purpose":       invitationTokenPurpose,
+		"invitation_id": invitationID.String(),
+		"nonce":         nonce,
+		"exp":           expiresAt.Unix(),
+		"iat":           time.Now().Unix(),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(os.Getenv("JWT_SECRET_KEY")))
+}
+
+// ParseInvitationToken memverifikasi token undangan dan mengembalikan ID undangan beserta nonce-nya.
+func ParseInvitationToken(tokenStr string) (uuid.UUID, string, error) {
+	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("metode signing tidak valid: %v", token.Header["alg"])
+		}
+		return []byte(os.Getenv("JWT_SECRET_KEY")), nil
+	})
+	if err != nil || !token.Valid {
+		return uuid.UUID{}, "", fmt.Errorf("token undangan tidak valid atau expired")
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["purpose"] != invitationTokenPurpose {
+		return uuid.UUID{}, "", fmt.Errorf("token undangan tidak valid")
+	}
+
+	idStr, _ := claims["invitation_id"].(string)
+	invitationID, err := uuid.Parse(idStr)
+	if err != nil {
+		return uuid.UUID{}, "", fmt.Errorf("token undangan tidak valid")
+	}
+
+	nonce, _ := claims["nonce"].(string)
+	return invitationID, nonce, nil
+}
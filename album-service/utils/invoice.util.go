@@ -0,0 +1,325 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Zackly23/queue-app/models"
+	"gorm.io/gorm"
+)
+
+const invoiceDateLayout = "02 January 2006"
+
+// InvoiceTax adalah pajak yang sudah termasuk di harga plan (INVOICE_TAX_NAME, INVOICE_TAX_RATE
+// dalam persen), default PPN 11%.
+func InvoiceTax() (string, float64) {
+	name := os.Getenv("INVOICE_TAX_NAME")
+	if name == "" {
+		name = "PPN"
+	}
+	rate := 11.0
+	if value := os.Getenv("INVOICE_TAX_RATE"); value != "" {
+		if parsed, err := strconv.ParseFloat(value, 64); err == nil && parsed >= 0 {
+			rate = parsed
+		}
+	}
+	return name, rate
+}
+
+func roundInvoiceAmount(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// FormatMoney menampilkan nominal, IDR tanpa desimal dengan pemisah ribuan titik.
+func FormatMoney(amount float64, currency string) string {
+	negative := amount < 0
+	amount = math.Abs(amount)
+
+	var text string
+	if currency == "" || currency == "IDR" {
+		whole := strconv.FormatInt(int64(math.Round(amount)), 10)
+		text = "Rp " + groupThousands(whole, ".")
+	} else {
+		parts := strings.SplitN(strconv.FormatFloat(amount, 'f', 2, 64), ".", 2)
+		text = currency + " " + groupThousands(parts[0], ",") + "." + parts[1]
+	}
+
+	if negative {
+		return "-" + text
+	}
+	return text
+}
+
+func groupThousands(digits, separator string) string {
+	var out strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			out.WriteString(separator)
+		}
+		out.WriteRune(digit)
+	}
+	return out.String()
+}
+
+// nextInvoiceNumber mengambil nomor urut berikutnya untuk tahun terbit, dikunci oleh upsert
+// sehingga dua invoice yang terbit bersamaan tidak mendapat nomor yang sama.
+func nextInvoiceNumber(tx *gorm.DB, issuedAt time.Time) (string, error) {
+	year := issuedAt.Year()
+
+	var sequence int64
+	err := tx.Raw(`INSERT INTO invoice_sequences (year, last_number) VALUES (?, 1)
+		ON CONFLICT (year) DO UPDATE SET last_number = invoice_sequences.last_number + 1
+		RETURNING last_number`, year).Scan(&sequence).Error
+	if err != nil {
+		return "", err
+	}
+
+	return formatInvoiceNumber(year, sequence), nil
+}
+
+// formatInvoiceNumber membentuk nomor invoice INV-<tahun>-<urutan 6 digit>.
+func formatInvoiceNumber(year int, sequence int64) string {
+	return fmt.Sprintf("INV-%d-%06d", year, sequence)
+}
+
+// invoiceAmounts menyusun baris invoice dari harga plan dan credit yang sudah termasuk pajak.
+// Baris plan dan credit ditampilkan sebelum pajak, selisih pembulatan masuk ke baris pajak agar
+// total tetap sama dengan nominal yang dibayar.
+func invoiceAmounts(description string, price, credit, paid float64, taxName string, taxRate float64) (lines []models.InvoiceLine, subtotal, taxAmount, total float64) {
+	exclusive := func(amount float64) float64 {
+		return roundInvoiceAmount(amount / (1 + taxRate/100))
+	}
+
+	planAmount := exclusive(price)
+	lines = []models.InvoiceLine{{
+		Kind:        models.InvoiceLinePlan,
+		Description: description,
+		Quantity:    1,
+		UnitPrice:   planAmount,
+		Amount:      planAmount,
+	}}
+
+	if credit := math.Min(credit, price); credit > 0 {
+		creditAmount := -exclusive(credit)
+		lines = append(lines, models.InvoiceLine{
+			Kind:        models.InvoiceLineCredit,
+			Description: "Kredit sisa periode plan sebelumnya",
+			Quantity:    1,
+			UnitPrice:   creditAmount,
+			Amount:      creditAmount,
+		})
+	}
+
+	for _, line := range lines {
+		subtotal += line.Amount
+	}
+	total = roundInvoiceAmount(paid)
+	subtotal = roundInvoiceAmount(math.Min(subtotal, total))
+	taxAmount = roundInvoiceAmount(total - subtotal)
+
+	lines = append(lines, models.InvoiceLine{
+		Kind:        models.InvoiceLineTax,
+		Description: fmt.Sprintf("%s %s%%", taxName, strconv.FormatFloat(taxRate, 'f', -1, 64)),
+		Quantity:    1,
+		UnitPrice:   taxAmount,
+		Amount:      taxAmount,
+	})
+	for i := range lines {
+		lines[i].SortOrder = i
+	}
+	return lines, subtotal, taxAmount, total
+}
+
+// CreateInvoice menerbitkan invoice untuk pembayaran subscription. Harga plan sudah termasuk
+// pajak, sehingga baris item ditampilkan sebelum pajak dan total sama dengan nominal yang dibayar.
+// Invoice yang sudah ada untuk subscription yang sama dikembalikan apa adanya.
+func CreateInvoice(tx *gorm.DB, payment models.Payment, paidAt time.Time) (models.Invoice, error) {
+	var invoice models.Invoice
+	err := tx.Preload("Lines").First(&invoice, "user_subscription_id = ?", payment.UserSubscriptionID).Error
+	if err == nil {
+		return invoice, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return invoice, err
+	}
+
+	var record models.UserSubscription
+	if err := tx.Preload("User").Preload("Subscription", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}).First(&record, "id = ?", payment.UserSubscriptionID).Error; err != nil {
+		return invoice, err
+	}
+
+	taxName, taxRate := InvoiceTax()
+	plan := record.Subscription
+	description := fmt.Sprintf("Plan %s v%d (%s - %s)", plan.SubscriptionType, plan.Version,
+		record.StartDate.Format(invoiceDateLayout), record.EndDate.Format(invoiceDateLayout))
+	lines, subtotal, taxAmount, total := invoiceAmounts(description, plan.Price, float64(record.Credit), payment.Amount, taxName, taxRate)
+
+	number, err := nextInvoiceNumber(tx, paidAt)
+	if err != nil {
+		return invoice, err
+	}
+
+	user := record.User
+	invoice = models.Invoice{
+		Number:             number,
+		UserID:             record.UserID,
+		UserSubscriptionID: record.ID,
+		PaymentID:          &payment.ID,
+		Status:             models.InvoiceStatusPaid,
+		Currency:           payment.Currency,
+		Subtotal:           subtotal,
+		TaxName:            taxName,
+		TaxRate:            taxRate,
+		TaxAmount:          taxAmount,
+		Total:              total,
+		PaymentMethod:      record.PaymentMethod,
+		BillingName:        strings.TrimSpace(user.FirstName + " " + user.LastName),
+		BillingEmail:       user.Email,
+		BillingCompany:     user.CompanyName,
+		BillingAddress:     user.Address,
+		BillingCity:        user.City,
+		BillingState:       user.State,
+		BillingZipCode:     user.ZipCode,
+		BillingCountry:     user.Country,
+		Lines:              lines,
+		IssuedAt:           paidAt,
+		PaidAt:             paidAt,
+	}
+	if payment.Method != "" {
+		invoice.PaymentMethod = payment.Method
+	}
+
+	err = tx.Create(&invoice).Error
+	return invoice, err
+}
+
+// invoiceSeller adalah identitas penerbit invoice (INVOICE_SELLER_NAME, INVOICE_SELLER_ADDRESS,
+// INVOICE_SELLER_TAX_ID).
+func invoiceSeller() (string, []string) {
+	name := os.Getenv("INVOICE_SELLER_NAME")
+	if name == "" {
+		name = "PixoVaulty"
+	}
+
+	details := []string{}
+	for _, line := range strings.Split(os.Getenv("INVOICE_SELLER_ADDRESS"), "|") {
+		if line = strings.TrimSpace(line); line != "" {
+			details = append(details, line)
+		}
+	}
+	if taxID := os.Getenv("INVOICE_SELLER_TAX_ID"); taxID != "" {
+		details = append(details, "NPWP: "+taxID)
+	}
+	return name, details
+}
+
+// RenderInvoicePDF membuat dokumen PDF invoice satu halaman.
+func RenderInvoicePDF(invoice models.Invoice) []byte {
+	const (
+		left  = 50.0
+		right = PDFPageWidth - 50
+	)
+	doc := NewPDFDocument()
+
+	sellerName, sellerDetails := invoiceSeller()
+	doc.Text(left, 70, 20, true, sellerName)
+	y := 90.0
+	for _, line := range sellerDetails {
+		doc.Text(left, y, 9, false, line)
+		y += 13
+	}
+
+	status := "LUNAS"
+	if invoice.Status == models.InvoiceStatusRefunded {
+		status = "REFUNDED"
+	}
+	doc.TextRight(right, 70, 20, true, "INVOICE")
+	doc.TextRight(right, 90, 10, false, invoice.Number)
+	doc.TextRight(right, 104, 9, false, "Tanggal: "+invoice.IssuedAt.Format(invoiceDateLayout))
+	doc.TextRight(right, 118, 9, true, status)
+
+	// Data penagihan
+	y = math.Max(y, 130) + 20
+	doc.Text(left, y, 9, true, "DITAGIHKAN KEPADA")
+	y += 15
+	billTo := []string{invoice.BillingName, invoice.BillingCompany, invoice.BillingEmail, invoice.BillingAddress}
+	cityLine := []string{}
+	for _, part := range []string{invoice.BillingCity, invoice.BillingState, invoice.BillingZipCode} {
+		if part != "" {
+			cityLine = append(cityLine, part)
+		}
+	}
+	billTo = append(billTo, strings.Join(cityLine, ", "), invoice.BillingCountry)
+	for _, line := range billTo {
+		if line == "" {
+			continue
+		}
+		doc.Text(left, y, 10, false, PDFFitText(line, 10, false, right-left))
+		y += 14
+	}
+
+	// Tabel item
+	const (
+		qtyRight   = 370.0
+		priceRight = 460.0
+	)
+	y += 20
+	doc.FillRect(left, y-13, right-left, 20, 0.92)
+	doc.Text(left+6, y, 9, true, "DESKRIPSI")
+	doc.TextRight(qtyRight, y, 9, true, "QTY")
+	doc.TextRight(priceRight, y, 9, true, "HARGA")
+	doc.TextRight(right-6, y, 9, true, "JUMLAH")
+	y += 24
+
+	var taxLines []models.InvoiceLine
+	for _, line := range invoice.Lines {
+		if line.Kind == models.InvoiceLineTax {
+			taxLines = append(taxLines, line)
+			continue
+		}
+		doc.Text(left+6, y, 10, false, PDFFitText(line.Description, 10, false, qtyRight-left-50))
+		doc.TextRight(qtyRight, y, 10, false, strconv.Itoa(line.Quantity))
+		doc.TextRight(priceRight, y, 10, false, FormatMoney(line.UnitPrice, invoice.Currency))
+		doc.TextRight(right-6, y, 10, false, FormatMoney(line.Amount, invoice.Currency))
+		y += 20
+	}
+	doc.Line(left, y-8, right, y-8, 0.5)
+
+	// Ringkasan
+	y += 10
+	summary := func(label, value string, bold bool) {
+		doc.TextRight(priceRight, y, 10, bold, label)
+		doc.TextRight(right-6, y, 10, bold, value)
+		y += 18
+	}
+	summary("Subtotal", FormatMoney(invoice.Subtotal, invoice.Currency), false)
+	for _, line := range taxLines {
+		summary(line.Description, FormatMoney(line.Amount, invoice.Currency), false)
+	}
+	doc.Line(priceRight-100, y-12, right, y-12, 0.5)
+	y += 2
+	summary("Total", FormatMoney(invoice.Total, invoice.Currency), true)
+
+	y += 20
+	paidNote := "Dibayar pada " + invoice.PaidAt.Format(invoiceDateLayout)
+	if invoice.PaymentMethod != "" {
+		paidNote += " melalui " + invoice.PaymentMethod
+	}
+	doc.Text(left, y, 9, false, paidNote)
+	if invoice.RefundedAt != nil {
+		y += 14
+		doc.Text(left, y, 9, false, "Dana dikembalikan pada "+invoice.RefundedAt.Format(invoiceDateLayout))
+	}
+	doc.Text(left, y+14, 9, false, "Harga sudah termasuk pajak.")
+
+	doc.Text(left, PDFPageHeight-50, 8, false, "Terima kasih telah berlangganan "+sellerName+". Invoice ini dibuat secara otomatis dan sah tanpa tanda tangan.")
+
+	return doc.Bytes()
+}
@@ -0,0 +1,158 @@
+package utils
+
+import (
+	"math"
+	"testing"
+
+	"github.com/Zackly23/queue-app/models"
+)
+
+func TestFormatInvoiceNumber(t *testing.T) {
+	tests := []struct {
+		year     int
+		sequence int64
+		want     string
+	}{
+		{year: 2026, sequence: 1, want: "INV-2026-000001"},
+		{year: 2026, sequence: 42, want: "INV-2026-000042"},
+		{year: 2027, sequence: 999999, want: "INV-2027-999999"},
+		{year: 2027, sequence: 1000000, want: "INV-2027-1000000"},
+	}
+
+	for _, tt := range tests {
+		if got := formatInvoiceNumber(tt.year, tt.sequence); got != tt.want {
+			t.Errorf("formatInvoiceNumber(%d, %d) = %s, want %s", tt.year, tt.sequence, got, tt.want)
+		}
+	}
+}
+
+func TestInvoiceAmounts(t *testing.T) {
+	tests := []struct {
+		name      string
+		price     float64
+		credit    float64
+		paid      float64
+		taxRate   float64
+		lines     []float64 // amount per baris, baris terakhir pajak
+		subtotal  float64
+		taxAmount float64
+		taxLabel  string
+	}{
+		{
+			name: "tax inclusive price", price: 111000, paid: 111000, taxRate: 11,
+			lines: []float64{100000, 11000}, subtotal: 100000, taxAmount: 11000, taxLabel: "PPN 11%",
+		},
+		{
+			name: "rounding goes to tax", price: 100000, paid: 100000, taxRate: 11,
+			lines: []float64{90090.09, 9909.91}, subtotal: 90090.09, taxAmount: 9909.91, taxLabel: "PPN 11%",
+		},
+		{
+			name: "upgrade credit", price: 111000, credit: 55500, paid: 55500, taxRate: 11,
+			lines: []float64{100000, -50000, 5500}, subtotal: 50000, taxAmount: 5500, taxLabel: "PPN 11%",
+		},
+		{
+			name: "credit capped at plan price", price: 111000, credit: 250000, paid: 0, taxRate: 11,
+			lines: []float64{100000, -100000, 0}, subtotal: 0, taxAmount: 0, taxLabel: "PPN 11%",
+		},
+		{
+			name: "fractional rate", price: 112500, paid: 112500, taxRate: 12.5,
+			lines: []float64{100000, 12500}, subtotal: 100000, taxAmount: 12500, taxLabel: "PPN 12.5%",
+		},
+		{
+			name: "no tax", price: 99999.99, paid: 99999.99, taxRate: 0,
+			lines: []float64{99999.99, 0}, subtotal: 99999.99, taxAmount: 0, taxLabel: "PPN 0%",
+		},
+		{
+			name: "paid less than subtotal", price: 111000, paid: 50000, taxRate: 11,
+			lines: []float64{100000, 0}, subtotal: 50000, taxAmount: 0, taxLabel: "PPN 11%",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines, subtotal, taxAmount, total := invoiceAmounts("Plan Pro v1", tt.price, tt.credit, tt.paid, "PPN", tt.taxRate)
+
+			if len(lines) != len(tt.lines) {
+				t.Fatalf("jumlah baris = %d, want %d", len(lines), len(tt.lines))
+			}
+			for i, line := range lines {
+				if line.Amount != tt.lines[i] || line.UnitPrice != tt.lines[i] {
+					t.Errorf("baris %d amount = %v, want %v", i, line.Amount, tt.lines[i])
+				}
+				if line.SortOrder != i {
+					t.Errorf("baris %d sort_order = %d", i, line.SortOrder)
+				}
+			}
+
+			if first := lines[0]; first.Kind != models.InvoiceLinePlan || first.Description != "Plan Pro v1" {
+				t.Errorf("baris pertama = %s %q, want plan", first.Kind, first.Description)
+			}
+			tax := lines[len(lines)-1]
+			if tax.Kind != models.InvoiceLineTax || tax.Description != tt.taxLabel {
+				t.Errorf("baris pajak = %s %q, want %s %q", tax.Kind, tax.Description, models.InvoiceLineTax, tt.taxLabel)
+			}
+
+			if subtotal != tt.subtotal || taxAmount != tt.taxAmount {
+				t.Errorf("subtotal, pajak = %v, %v, want %v, %v", subtotal, taxAmount, tt.subtotal, tt.taxAmount)
+			}
+			if total != tt.paid {
+				t.Errorf("total = %v, want nominal dibayar %v", total, tt.paid)
+			}
+			if math.Abs(subtotal+taxAmount-total) > 0.001 {
+				t.Errorf("subtotal + pajak = %v, want total %v", subtotal+taxAmount, total)
+			}
+		})
+	}
+}
+
+func TestInvoiceTax(t *testing.T) {
+	tests := []struct {
+		name     string
+		taxName  string
+		taxRate  string
+		wantName string
+		wantRate float64
+	}{
+		{name: "default", wantName: "PPN", wantRate: 11},
+		{name: "custom", taxName: "VAT", taxRate: "20", wantName: "VAT", wantRate: 20},
+		{name: "zero rate", taxRate: "0", wantName: "PPN", wantRate: 0},
+		{name: "invalid rate", taxRate: "sebelas", wantName: "PPN", wantRate: 11},
+		{name: "negative rate", taxRate: "-5", wantName: "PPN", wantRate: 11},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("INVOICE_TAX_NAME", tt.taxName)
+			t.Setenv("INVOICE_TAX_RATE", tt.taxRate)
+
+			name, rate := InvoiceTax()
+			if name != tt.wantName || rate != tt.wantRate {
+				t.Errorf("InvoiceTax() = %s %v, want %s %v", name, rate, tt.wantName, tt.wantRate)
+			}
+		})
+	}
+}
+
+func TestFormatMoney(t *testing.T) {
+	tests := []struct {
+		amount   float64
+		currency string
+		want     string
+	}{
+		{amount: 0, currency: "IDR", want: "Rp 0"},
+		{amount: 999, currency: "IDR", want: "Rp 999"},
+		{amount: 1000, currency: "IDR", want: "Rp 1.000"},
+		{amount: 1234567.4, currency: "IDR", want: "Rp 1.234.567"},
+		{amount: 90090.5, currency: "", want: "Rp 90.091"},
+		{amount: -50000, currency: "IDR", want: "-Rp 50.000"},
+		{amount: 1234.5, currency: "USD", want: "USD 1,234.50"},
+		{amount: 0.07, currency: "USD", want: "USD 0.07"},
+		{amount: -1000000, currency: "SGD", want: "-SGD 1,000,000.00"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatMoney(tt.amount, tt.currency); got != tt.want {
+			t.Errorf("FormatMoney(%v, %q) = %q, want %q", tt.amount, tt.currency, got, tt.want)
+		}
+	}
+}
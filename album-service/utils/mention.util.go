@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// @username tidak boleh diawali huruf/angka agar alamat email tidak ikut terbaca
+	mentionPattern = regexp.MustCompile(`(?:^|[^\w@])@([A-Za-z0-9_][A-Za-z0-9_.]{0,49})`)
+	hashtagPattern = regexp.MustCompile(`(?:^|[^\w&#])#([\p{L}\p{N}_]{1,50})`)
+)
+
+// ParseMentions mengambil daftar username unik (lowercase) dari teks.
+func ParseMentions(text string) []string {
+	return uniqueMatches(mentionPattern, text, func(match string) string {
+		return strings.ToLower(strings.TrimRight(match, "."))
+	})
+}
+
+// ParseHashtags mengambil daftar hashtag unik (dinormalisasi dengan NormalizeTag, tanpa #) dari teks.
+func ParseHashtags(text string) []string {
+	return uniqueMatches(hashtagPattern, text, NormalizeTag)
+}
+
+func uniqueMatches(pattern *regexp.Regexp, text string, normalize func(string) string) []string {
+	seen := map[string]bool{}
+	result := []string{}
+	for _, match := range pattern.FindAllStringSubmatch(text, -1) {
+		value := normalize(match[1])
+		if value == "" || seen[value] {
+			continue
+		}
+		seen[value] = true
+		result = append(result, value)
+	}
+	return result
+}
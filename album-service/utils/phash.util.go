@@ -0,0 +1,117 @@
+package utils
+
+import (
+	"errors"
+	"image"
+	"io"
+	"math/bits"
+
+	// decoder format yang bisa di-hash
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+)
+
+// Ukuran grid dHash: 9x8 piksel grayscale menghasilkan 64 bit perbandingan horizontal.
+const (
+	dHashWidth  = 9
+	dHashHeight = 8
+
+	// Gambar sangat besar tidak di-decode agar memori server aman
+	maxHashPixels = 80_000_000
+)
+
+var hashableImageTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/jpg":  true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+// HashableImageTypes mengembalikan daftar mime type gambar yang bisa di-hash.
+func HashableImageTypes() []string {
+	types := make([]string, 0, len(hashableImageTypes))
+	for mimeType := range hashableImageTypes {
+		types = append(types, mimeType)
+	}
+	return types
+}
+
+// IsHashableImage mengecek apakah mime type bisa di-decode untuk perceptual hash.
+func IsHashableImage(mimeType string) bool {
+	return hashableImageTypes[mimeType]
+}
+
+// DifferenceHash menghitung dHash 64-bit dari gambar. Gambar diperkecil menjadi 9x8 grayscale,
+// lalu setiap bit menandakan apakah piksel lebih terang dari piksel di kanannya. Gambar yang
+// mirip (resize, kompresi ulang, sedikit edit warna) menghasilkan hash dengan jarak Hamming kecil.
+func DifferenceHash(r io.ReadSeeker) (int64, error) {
+	config, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return 0, err
+	}
+	if config.Width*config.Height > maxHashPixels {
+		return 0, errors.New("gambar terlalu besar untuk di-hash")
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return 0, err
+	}
+
+	grid := grayscaleGrid(img)
+
+	var hash uint64
+	for y := 0; y < dHashHeight; y++ {
+		for x := 0; x < dHashWidth-1; x++ {
+			hash <<= 1
+			if grid[y][x] > grid[y][x+1] {
+				hash |= 1
+			}
+		}
+	}
+
+	// Disimpan sebagai bigint di Postgres
+	return int64(hash), nil
+}
+
+// grayscaleGrid memperkecil gambar menjadi 9x8 dengan rata-rata luminance per sel.
+// Piksel diambil dengan langkah tertentu agar gambar besar tetap cepat diproses.
+func grayscaleGrid(img image.Image) [dHashHeight][dHashWidth]float64 {
+	var sums [dHashHeight][dHashWidth]float64
+	var counts [dHashHeight][dHashWidth]int
+
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	stepX := max(1, width/(dHashWidth*32))
+	stepY := max(1, height/(dHashHeight*32))
+
+	for y := 0; y < height; y += stepY {
+		cellY := y * dHashHeight / height
+		for x := 0; x < width; x += stepX {
+			cellX := x * dHashWidth / width
+
+			r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			sums[cellY][cellX] += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+			counts[cellY][cellX]++
+		}
+	}
+
+	var grid [dHashHeight][dHashWidth]float64
+	for y := range grid {
+		for x := range grid[y] {
+			if counts[y][x] > 0 {
+				grid[y][x] = sums[y][x] / float64(counts[y][x])
+			}
+		}
+	}
+	return grid
+}
+
+// HammingDistance menghitung jumlah bit yang berbeda antara dua hash.
+func HammingDistance(a, b int64) int {
+	return bits.OnesCount64(uint64(a ^ b))
+}
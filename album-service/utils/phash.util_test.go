@@ -0,0 +1,127 @@
+package utils
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"testing"
+)
+
+func TestHammingDistance(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b int64
+		want int
+	}{
+		{name: "identical", a: 0x5a5a5a5a5a5a5a5a, b: 0x5a5a5a5a5a5a5a5a, want: 0},
+		{name: "one bit", a: 0, b: 1, want: 1},
+		{name: "nibble", a: 0x0f, b: 0x00, want: 4},
+		{name: "sign bit", a: math.MinInt64, b: 0, want: 1},
+		{name: "all bits", a: -1, b: 0, want: 64},
+		{name: "complement", a: 0x0123456789abcdef, b: ^int64(0x0123456789abcdef), want: 64},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HammingDistance(tt.a, tt.b); got != tt.want {
+				t.Errorf("HammingDistance(%#x, %#x) = %d, want %d", tt.a, tt.b, got, tt.want)
+			}
+			if got := HammingDistance(tt.b, tt.a); got != tt.want {
+				t.Errorf("HammingDistance tidak simetris: %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// scene menggambar pola gelombang halus; posisi dinormalisasi sehingga ukuran berbeda
+// menghasilkan gambar yang sama seperti hasil resize.
+func scene(width, height int, shade func(v float64) float64) image.Image {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			u, v := float64(x)/float64(width), float64(y)/float64(height)
+			value := 128 + 60*math.Sin(7*u+2*v) + 50*math.Cos(5*v-3*u*u)
+			img.SetGray(x, y, color.Gray{Y: uint8(math.Max(0, math.Min(255, shade(value))))})
+		}
+	}
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("png.Encode: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func encodeJPEG(t *testing.T, img image.Image, quality int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		t.Fatalf("jpeg.Encode: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func hashOf(t *testing.T, data []byte) int64 {
+	t.Helper()
+	hash, err := DifferenceHash(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("DifferenceHash: %v", err)
+	}
+	return hash
+}
+
+func TestDifferenceHashDistance(t *testing.T) {
+	identity := func(v float64) float64 { return v }
+	original := hashOf(t, encodePNG(t, scene(640, 480, identity)))
+
+	tests := []struct {
+		name    string
+		data    []byte
+		minDist int
+		maxDist int
+	}{
+		{name: "same image", data: encodePNG(t, scene(640, 480, identity)), minDist: 0, maxDist: 0},
+		{name: "resized", data: encodePNG(t, scene(200, 150, identity)), minDist: 0, maxDist: 4},
+		{name: "recompressed jpeg", data: encodeJPEG(t, scene(640, 480, identity), 40), minDist: 0, maxDist: 4},
+		{name: "brightened", data: encodePNG(t, scene(640, 480, func(v float64) float64 { return v*0.8 + 40 })), minDist: 0, maxDist: 10},
+		{name: "inverted", data: encodePNG(t, scene(640, 480, func(v float64) float64 { return 255 - v })), minDist: 40, maxDist: 64},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dist := HammingDistance(original, hashOf(t, tt.data))
+			if dist < tt.minDist || dist > tt.maxDist {
+				t.Errorf("jarak = %d, want %d..%d", dist, tt.minDist, tt.maxDist)
+			}
+		})
+	}
+}
+
+func TestDifferenceHashRejects(t *testing.T) {
+	// Header GIF 65535x65535 tanpa data gambar, harus ditolak sebelum di-decode
+	oversized := []byte("GIF89a\xff\xff\xff\xff\x00\x00\x00")
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "empty", data: nil},
+		{name: "not an image", data: []byte("bukan gambar")},
+		{name: "oversized", data: oversized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DifferenceHash(bytes.NewReader(tt.data)); err == nil {
+				t.Error("DifferenceHash berhasil, seharusnya error")
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package utils
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CalculateStorageUsed menghitung total penyimpanan user (MB). Media di trash tetap dihitung
+// selama masa TrashQuotaGrace, setelah itu tidak lagi membebani kuota. Media referensi hasil
+// duplikasi album tidak dihitung karena file-nya milik media lain.
+func CalculateStorageUsed(db *gorm.DB, userID interface{}) (float64, error) {
+	graceCutoff := time.Now().Add(-TrashQuotaGrace())
+
+	var storageUsed float64
+	err := db.Raw(`
+		SELECT COALESCE(SUM(media.size), 0) FROM media
+		JOIN albums a ON a.id = media.album_id
+		WHERE a.user_id = ? AND NOT media.is_reference AND (media.deleted_at IS NULL OR media.deleted_at > ?)`,
+		userID, graceCutoff).Scan(&storageUsed).Error
+
+	return storageUsed, err
+}
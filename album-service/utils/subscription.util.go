@@ -0,0 +1,135 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Zackly23/queue-app/models"
+	"github.com/Zackly23/queue-app/payments"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ActivatePaidSubscription memberlakukan subscription yang menunggu pembayaran. Periode
+// dihitung ulang dari waktu pembayaran dengan durasi yang sama seperti saat checkout.
+func ActivatePaidSubscription(tx *gorm.DB, record models.UserSubscription, now time.Time) error {
+	if err := tx.Model(&models.UserSubscription{}).
+		Where("user_id = ? AND status IN ? AND id <> ?", record.UserID,
+			[]string{models.UserSubscriptionActive, models.UserSubscriptionFreeTier}, record.ID).
+		Updates(map[string]interface{}{
+			"status":   models.UserSubscriptionExpired,
+			"end_date": now,
+		}).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Model(&models.UserSubscription{}).Where("id = ?", record.ID).Updates(map[string]interface{}{
+		"status":     models.UserSubscriptionActive,
+		"start_date": now,
+		"end_date":   now.Add(record.EndDate.Sub(record.StartDate)),
+	}).Error; err != nil {
+		return err
+	}
+
+	return tx.Model(&models.User{}).Where("id = ?", record.UserID).Update("subscription_id", record.SubscriptionID).Error
+}
+
+// RevertToDefaultPlan memindahkan user ke plan default, mis. setelah subscription berbayarnya
+// di-refund atau selama perpanjangan belum dibayar. Perubahan plan yang masih dijadwalkan ikut dibatalkan.
+func RevertToDefaultPlan(tx *gorm.DB, userID uuid.UUID, now time.Time) error {
+	if err := tx.Model(&models.UserSubscription{}).
+		Where("user_id = ? AND status = ?", userID, models.UserSubscriptionScheduled).
+		Update("status", models.UserSubscriptionCancelled).Error; err != nil {
+		return err
+	}
+
+	var plan models.Subscription
+	if err := tx.Where("code = ? AND is_active = ?", models.DefaultPlanCode, true).Order("version DESC").First(&plan).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Create(&models.UserSubscription{
+		UserID:         userID,
+		SubscriptionID: plan.ID,
+		PaymentMethod:  "Free Method",
+		StartDate:      now,
+		EndDate:        plan.PeriodEnd(now),
+		Currency:       plan.Currency,
+		ChangeType:     models.SubscriptionChangeDowngrade,
+		Status:         models.UserSubscriptionFreeTier,
+	}).Error; err != nil {
+		return err
+	}
+
+	return tx.Model(&models.User{}).Where("id = ?", userID).Update("subscription_id", plan.ID).Error
+}
+
+// UpgradeCheckoutTTL membatasi waktu bayar upgrade yang dipotong credit. Credit dihitung saat
+// checkout dibuat dan terus berkurang selama periode berjalan masih dipakai, jadi checkout upgrade
+// ditutup lebih cepat daripada default provider agar credit yang dibayar tidak terlalu basi.
+const UpgradeCheckoutTTL = time.Hour
+
+// NewPaymentOrderID membuat order_id tagihan subscription yang dikirim ke provider.
+func NewPaymentOrderID() string {
+	return "SUB-" + uuid.NewString()
+}
+
+// OpenCheckout membuat sesi checkout di provider untuk payment yang sudah disimpan lalu
+// menyimpan link-nya. Bila provider menolak, payment ditandai failed.
+func OpenCheckout(ctx context.Context, db *gorm.DB, gateway payments.Gateway, payment *models.Payment, plan models.Subscription) error {
+	var user models.User
+	if err := db.Select("id", "first_name", "last_name", "user_name", "email").First(&user, "id = ?", payment.UserID).Error; err != nil {
+		return err
+	}
+
+	name := user.UserName
+	if name == "" {
+		name = strings.TrimSpace(user.FirstName + " " + user.LastName)
+	}
+
+	checkout, err := gateway.CreateCheckout(ctx, payments.CheckoutRequest{
+		OrderID:       payment.OrderID,
+		Amount:        payment.Amount,
+		Currency:      payment.Currency,
+		Description:   fmt.Sprintf("%s (%s)", plan.SubscriptionType, plan.BillingInterval),
+		CustomerName:  name,
+		CustomerEmail: user.Email,
+		ExpiresAt:     payment.ExpiresAt,
+	})
+	if err != nil {
+		db.Model(payment).Update("status", models.PaymentStatusFailed)
+		return err
+	}
+
+	payment.ProviderReference = checkout.ProviderReference
+	payment.CheckoutURL = checkout.CheckoutURL
+	if checkout.ExpiresAt != nil {
+		payment.ExpiresAt = checkout.ExpiresAt
+	}
+	return db.Model(payment).Updates(map[string]interface{}{
+		"provider_reference": checkout.ProviderReference,
+		"checkout_url":       checkout.CheckoutURL,
+		"expires_at":         payment.ExpiresAt,
+	}).Error
+}
+
+// CancelCheckouts menutup checkout di provider untuk pembayaran yang sudah dibatalkan secara lokal.
+// Kegagalan hanya dicatat, pembayaran yang tetap masuk akan di-refund saat notifikasinya diterima.
+func CancelCheckouts(pending []models.Payment) {
+	for _, payment := range pending {
+		gateway, err := payments.Get(payment.Provider)
+		if err != nil {
+			log.Printf("Provider %s untuk checkout %s tidak tersedia", payment.Provider, payment.OrderID)
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		if err := gateway.Cancel(ctx, payment.OrderID); err != nil {
+			log.Printf("Gagal menutup checkout %s: %v", payment.OrderID, err)
+		}
+		cancel()
+	}
+}
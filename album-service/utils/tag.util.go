@@ -0,0 +1,163 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Zackly23/queue-app/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MaxTagLength adalah panjang maksimal nama tag setelah dinormalisasi.
+const MaxTagLength = 50
+
+var (
+	tagSeparatorPattern = regexp.MustCompile(`[\s\-]+`)
+	tagInvalidPattern   = regexp.MustCompile(`[^\p{L}\p{N}_\-]`)
+)
+
+// NormalizeTag mengubah input tag menjadi slug: lowercase, tanpa #, spasi menjadi "-",
+// dan hanya huruf, angka, "_" dan "-". Contoh: " #Street  Photography! " menjadi "street-photography".
+func NormalizeTag(raw string) string {
+	tag := strings.ToLower(strings.TrimSpace(raw))
+	tag = strings.TrimLeft(tag, "#")
+	tag = tagSeparatorPattern.ReplaceAllString(tag, "-")
+	tag = tagInvalidPattern.ReplaceAllString(tag, "")
+
+	if runes := []rune(tag); len(runes) > MaxTagLength {
+		tag = string(runes[:MaxTagLength])
+	}
+	return strings.Trim(tag, "-")
+}
+
+// NormalizeTags menormalisasi daftar tag dan membuang yang kosong atau duplikat.
+func NormalizeTags(raw []string) []string {
+	seen := map[string]bool{}
+	result := []string{}
+	for _, value := range raw {
+		tag := NormalizeTag(value)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+	return result
+}
+
+// FindTag mencari tag berdasarkan nama (setelah dinormalisasi), termasuk lewat alias.
+func FindTag(db *gorm.DB, name string) (models.AlbumTag, error) {
+	var tag models.AlbumTag
+	name = NormalizeTag(name)
+	if name == "" {
+		return tag, gorm.ErrRecordNotFound
+	}
+
+	err := db.Where("tag_name = ?", name).
+		Or("id IN (SELECT tag_id FROM tag_aliases WHERE alias = ?)", name).
+		First(&tag).Error
+	return tag, err
+}
+
+// FindOrCreateTags mengambil AlbumTag berdasarkan nama yang sudah dinormalisasi, alias diarahkan
+// ke tag utamanya dan tag yang belum ada akan dibuat.
+func FindOrCreateTags(db *gorm.DB, tags []string) ([]models.AlbumTag, error) {
+	names := NormalizeTags(tags)
+	tagModels := make([]models.AlbumTag, 0, len(names))
+	seen := map[string]bool{}
+
+	for _, name := range names {
+		tagModel, err := FindTag(db, name)
+		if err != nil {
+			if err != gorm.ErrRecordNotFound {
+				return nil, fmt.Errorf("failed to query tag: %w", err)
+			}
+			tagModel = models.AlbumTag{TagName: name}
+			if errCreate := db.Create(&tagModel).Error; errCreate != nil {
+				return nil, fmt.Errorf("failed to create tag: %w", errCreate)
+			}
+		}
+
+		// Dua input berbeda bisa mengarah ke tag yang sama lewat alias
+		if seen[tagModel.TagName] {
+			continue
+		}
+		seen[tagModel.TagName] = true
+		tagModels = append(tagModels, tagModel)
+	}
+
+	return tagModels, nil
+}
+
+// AttachAlbumTags menghubungkan tag ke album, tag yang belum ada akan dibuat.
+func AttachAlbumTags(db *gorm.DB, album models.Album, tags []string) error {
+	tagModels, err := FindOrCreateTags(db, tags)
+	if err != nil {
+		return err
+	}
+
+	for i := range tagModels {
+		//if tag has associated do nothing
+		if err := db.Model(&album).Association("Tags").Append(&tagModels[i]); err != nil {
+			return fmt.Errorf("failed to associate tag: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// ReplaceAlbumTags mengganti seluruh tag album dengan daftar baru, tag yang tidak ada
+// di daftar dilepas dari album.
+func ReplaceAlbumTags(db *gorm.DB, album models.Album, tags []string) error {
+	tagModels, err := FindOrCreateTags(db, tags)
+	if err != nil {
+		return err
+	}
+
+	if err := db.Model(&album).Association("Tags").Replace(tagModels); err != nil {
+		return fmt.Errorf("failed to replace tags: %w", err)
+	}
+	return nil
+}
+
+// MergeTags memindahkan semua album dan komentar dari tag source ke target, lalu menghapus source.
+// Nama source (bila berbeda dari target setelah dinormalisasi) disimpan sebagai alias target
+// agar input dengan nama lama tetap masuk ke tag target. Jalankan di dalam transaksi.
+func MergeTags(tx *gorm.DB, source models.AlbumTag, target models.AlbumTag) error {
+	joinTables := []struct {
+		Table  string
+		Column string
+	}{
+		{Table: "album_album_tags", Column: "album_id"},
+		{Table: "album_comment_tags", Column: "album_comment_id"},
+	}
+
+	for _, join := range joinTables {
+		if err := tx.Exec(`INSERT INTO `+join.Table+` (`+join.Column+`, album_tag_id)
+			SELECT `+join.Column+`, ? FROM `+join.Table+` WHERE album_tag_id = ?
+			ON CONFLICT DO NOTHING`, target.ID, source.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(`DELETE FROM `+join.Table+` WHERE album_tag_id = ?`, source.ID).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Model(&models.TagAlias{}).Where("tag_id = ?", source.ID).Update("tag_id", target.ID).Error; err != nil {
+		return err
+	}
+
+	if alias := NormalizeTag(source.TagName); alias != "" && alias != target.TagName {
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "alias"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{"tag_id": target.ID}),
+		}).Create(&models.TagAlias{Alias: alias, TagID: target.ID}).Error; err != nil {
+			return err
+		}
+	}
+
+	// Hapus permanen agar nama source tidak bentrok dengan unique index bila dibuat lagi
+	return tx.Unscoped().Delete(&models.AlbumTag{}, "id = ?", source.ID).Error
+}
@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeTag(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{raw: "travel", want: "travel"},
+		{raw: "Travel", want: "travel"},
+		{raw: "#travel", want: "travel"},
+		{raw: "##Travel", want: "travel"},
+		{raw: " #Street  Photography! ", want: "street-photography"},
+		{raw: "street-photography", want: "street-photography"},
+		{raw: "street - photography", want: "street-photography"},
+		{raw: "black_and_white", want: "black_and_white"},
+		{raw: "-sunset-", want: "sunset"},
+		{raw: "Kuliner Đà Nẵng", want: "kuliner-đà-nẵng"},
+		{raw: "2026", want: "2026"},
+		{raw: "!!!", want: ""},
+		{raw: "#", want: ""},
+		{raw: "   ", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeTag(tt.raw); got != tt.want {
+			t.Errorf("NormalizeTag(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeTagMaxLength(t *testing.T) {
+	long := strings.Repeat("é", MaxTagLength+10)
+	got := NormalizeTag(long)
+	if n := len([]rune(got)); n != MaxTagLength {
+		t.Errorf("panjang tag = %d rune, want %d", n, MaxTagLength)
+	}
+
+	// Pemotongan tidak boleh menyisakan "-" di akhir
+	dashed := strings.Repeat("a", MaxTagLength-1) + " b"
+	if got := NormalizeTag(dashed); strings.HasSuffix(got, "-") {
+		t.Errorf("NormalizeTag(%q) = %q, diakhiri -", dashed, got)
+	}
+}
+
+// Variasi penulisan dari user harus menghasilkan slug yang sama, karena slug itu yang
+// dicocokkan ke tag_name maupun tag_aliases.alias (mis. saat merge dan preferensi feed).
+func TestNormalizeTags(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  []string
+		want []string
+	}{
+		{name: "empty", raw: nil, want: []string{}},
+		{name: "keeps order", raw: []string{"sunset", "beach"}, want: []string{"sunset", "beach"}},
+		{
+			name: "spelling variants collapse",
+			raw:  []string{"Street Photography", "#street-photography", "STREET  PHOTOGRAPHY"},
+			want: []string{"street-photography"},
+		},
+		{name: "drops blanks", raw: []string{"", "#", "  ", "food"}, want: []string{"food"}},
+		{name: "first occurrence wins", raw: []string{"B", "a", "b", "A"}, want: []string{"b", "a"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeTags(tt.raw); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NormalizeTags(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,244 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Zackly23/queue-app/config"
+	"github.com/Zackly23/queue-app/models"
+	"gorm.io/gorm"
+)
+
+func envDays(key string, fallback int) time.Duration {
+	days, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || days < 0 {
+		days = fallback
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// TrashRetention adalah lama item berada di trash sebelum dihapus permanen oleh cron.
+func TrashRetention() time.Duration {
+	return envDays("TRASH_RETENTION_DAYS", 30)
+}
+
+// TrashQuotaGrace adalah lama item di trash masih dihitung ke kuota penyimpanan.
+func TrashQuotaGrace() time.Duration {
+	return envDays("TRASH_QUOTA_GRACE_DAYS", 7)
+}
+
+// mediaHandoff menentukan nasib file milik media yang akan dihapus permanen. heir adalah media lain
+// (termasuk yang di trash) dengan URL yang sama, nil bila tidak ada. Bila media yang dihapus adalah
+// pemilik file dan heir hanya referensi, heir mengambil alih kepemilikan sehingga ukurannya kembali
+// dihitung ke kuota pemilik album tersebut. File S3 hanya dihapus bila tidak ada heir.
+func mediaHandoff(media models.Media, heir *models.Media) (promoteHeir bool, deleteObject bool) {
+	if media.URL == "" {
+		return false, false
+	}
+	if heir == nil {
+		return false, true
+	}
+	return !media.IsReference && heir.IsReference, false
+}
+
+// releaseMediaRecord menjalankan bagian database dari mediaHandoff dan mengembalikan URL file
+// yang harus dihapus dari S3 setelah transaksi commit (kosong bila file masih dipakai).
+func releaseMediaRecord(db *gorm.DB, media models.Media) (string, error) {
+	if media.URL == "" {
+		return "", nil
+	}
+
+	var heir *models.Media
+	var found models.Media
+	err := db.Unscoped().Where("url = ? AND id <> ?", media.URL, media.ID).
+		Order("is_reference ASC, created_at ASC").First(&found).Error
+	switch {
+	case err == nil:
+		heir = &found
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return "", err
+	}
+
+	promoteHeir, deleteObject := mediaHandoff(media, heir)
+	if promoteHeir {
+		if err := db.Unscoped().Model(heir).Update("is_reference", false).Error; err != nil {
+			return "", err
+		}
+	}
+	if deleteObject {
+		return media.URL, nil
+	}
+	return "", nil
+}
+
+// deleteObjects menghapus file S3 yang sudah tidak direferensikan database. Dipanggil setelah
+// commit, sehingga kegagalan di sini hanya meninggalkan file yatim, bukan record tanpa file.
+func deleteObjects(urls []string) error {
+	var failed error
+	for _, url := range urls {
+		if url == "" {
+			continue
+		}
+		if err := DeleteFromS3(url, config.S3Bucket.BucketName); err != nil {
+			log.Printf("Gagal hapus file %s: %v", url, err)
+			failed = fmt.Errorf("gagal hapus file %s: %w", url, err)
+		}
+	}
+	return failed
+}
+
+// ReleaseMediaObject dipanggil sebelum record media dihapus permanen di luar transaksi purge:
+// kepemilikan file diserahkan ke referensi yang tersisa, atau file S3 dihapus bila tidak ada.
+func ReleaseMediaObject(db *gorm.DB, media models.Media) error {
+	url, err := releaseMediaRecord(db, media)
+	if err != nil {
+		return err
+	}
+	return deleteObjects([]string{url})
+}
+
+// PurgeMedia menghapus record media secara permanen lalu file-nya dari S3 setelah commit.
+func PurgeMedia(db *gorm.DB, media models.Media) error {
+	var orphan string
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		url, err := purgeMediaRecords(tx, media)
+		orphan = url
+		return err
+	}); err != nil {
+		return err
+	}
+
+	// Record sudah terhapus, file yang gagal dihapus cukup dicatat di log
+	_ = deleteObjects([]string{orphan})
+	return nil
+}
+
+// purgeMediaRecords menghapus media beserta reaksi, komentar dan laporannya, mengembalikan URL
+// file yang harus dihapus dari S3 setelah commit.
+func purgeMediaRecords(tx *gorm.DB, media models.Media) (string, error) {
+	orphan, err := releaseMediaRecord(tx, media)
+	if err != nil {
+		return "", err
+	}
+
+	if err := tx.Unscoped().Where("media_id = ?", media.ID).Delete(&models.MediaReaction{}).Error; err != nil {
+		return "", err
+	}
+	if err := purgeComments(tx, "media_id = ?", media.ID); err != nil {
+		return "", err
+	}
+	if err := closeReports(tx, models.ReportTargetMedia, media.ID); err != nil {
+		return "", err
+	}
+	// Cover pilihan pemilik dilepas hanya saat medianya benar-benar hilang
+	if err := tx.Unscoped().Model(&models.Album{}).Where("cover_media_id = ?", media.ID).
+		UpdateColumn("cover_media_id", nil).Error; err != nil {
+		return "", err
+	}
+
+	return orphan, tx.Unscoped().Delete(&media).Error
+}
+
+// closeReports menutup laporan yang masih aktif untuk konten yang dihapus permanen, karena
+// kontennya tidak bisa lagi ditinjau moderator. target bisa berupa ID atau subquery ID.
+func closeReports(tx *gorm.DB, targetType string, target interface{}) error {
+	return tx.Model(&models.Report{}).
+		Where("target_type = ? AND target_id IN (?) AND status IN ?", targetType, target,
+			[]string{models.ReportStatusOpen, models.ReportStatusInReview}).
+		Updates(map[string]interface{}{"status": models.ReportStatusDismissed, "resolved_at": time.Now()}).Error
+}
+
+// purgeComments menghapus permanen komentar (beserta riwayat edit, hashtag, mention dan laporannya) yang cocok dengan kondisi.
+func purgeComments(db *gorm.DB, condition string, args ...interface{}) error {
+	commentIDs := db.Unscoped().Model(&models.AlbumComment{}).Select("id").Where(condition, args...)
+	if err := db.Where("comment_id IN (?)", commentIDs).Delete(&models.AlbumCommentEdit{}).Error; err != nil {
+		return err
+	}
+	if err := db.Exec("DELETE FROM album_comment_tags WHERE album_comment_id IN (?)", commentIDs).Error; err != nil {
+		return err
+	}
+	if err := db.Where("source_type = ? AND source_id IN (?)", models.MentionSourceComment, commentIDs).Delete(&models.Mention{}).Error; err != nil {
+		return err
+	}
+	if err := closeReports(db, models.ReportTargetComment, commentIDs); err != nil {
+		return err
+	}
+	return db.Unscoped().Where(condition, args...).Delete(&models.AlbumComment{}).Error
+}
+
+// PurgeAlbum menghapus album beserta seluruh media, komentar, like dan relasinya secara permanen
+// dalam satu transaksi. File media dan arsip export dihapus dari S3 setelah commit.
+func PurgeAlbum(db *gorm.DB, album models.Album) error {
+	var orphans []string
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var medias []models.Media
+		if err := tx.Unscoped().Where("album_id = ?", album.ID).Find(&medias).Error; err != nil {
+			return err
+		}
+		for _, media := range medias {
+			url, err := purgeMediaRecords(tx, media)
+			if err != nil {
+				return err
+			}
+			orphans = append(orphans, url)
+		}
+
+		if err := purgeComments(tx, "album_id = ?", album.ID); err != nil {
+			return err
+		}
+		if err := tx.Where("album_id = ?", album.ID).Delete(&models.Mention{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("album_id = ?", album.ID).Delete(&models.AlbumReaction{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("album_id = ?", album.ID).Delete(&models.AlbumInvitation{}).Error; err != nil {
+			return err
+		}
+
+		// Arsip export ikut dihapus, file ZIP yang masih ada dihapus setelah commit
+		var exports []models.AlbumExport
+		if err := tx.Unscoped().Where("album_id = ?", album.ID).Find(&exports).Error; err != nil {
+			return err
+		}
+		for _, export := range exports {
+			if export.ObjectKey != "" && export.Status == models.ExportStatusCompleted {
+				orphans = append(orphans, fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", config.S3Bucket.BucketName, config.S3Bucket.Region, export.ObjectKey))
+			}
+		}
+		if err := tx.Unscoped().Where("album_id = ?", album.ID).Delete(&models.AlbumExport{}).Error; err != nil {
+			return err
+		}
+
+		// Riwayat import yang membuat album ini
+		importIDs := tx.Unscoped().Model(&models.AlbumImport{}).Select("id").Where("album_id = ?", album.ID)
+		if err := tx.Unscoped().Where("import_id IN (?)", importIDs).Delete(&models.AlbumImportItem{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("album_id = ?", album.ID).Delete(&models.AlbumImport{}).Error; err != nil {
+			return err
+		}
+
+		if err := closeReports(tx, models.ReportTargetAlbum, album.ID); err != nil {
+			return err
+		}
+		if err := tx.Model(&album).Association("Tags").Clear(); err != nil {
+			return err
+		}
+		if err := tx.Model(&album).Association("Collections").Clear(); err != nil {
+			return err
+		}
+
+		return tx.Unscoped().Delete(&album).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	_ = deleteObjects(orphans)
+	return nil
+}
@@ -0,0 +1,129 @@
+package utils
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/Zackly23/queue-app/models"
+	"github.com/google/uuid"
+)
+
+// storage mensimulasikan media yang berbagi file S3 lewat URL yang sama, termasuk media di trash.
+type storage struct {
+	media   []models.Media
+	deleted []string // URL yang dihapus dari S3
+}
+
+// purge menjalankan releaseMediaRecord secara in-memory: heir dipilih dengan urutan yang sama
+// seperti query (is_reference ASC, created_at ASC), lalu record media dihapus.
+func (s *storage) purge(t *testing.T, id uuid.UUID) {
+	t.Helper()
+
+	url := s.findURL(id)
+	index := -1
+	var candidates []int
+	for i, m := range s.media {
+		switch {
+		case m.ID == id:
+			index = i
+		case url != "" && m.URL == url:
+			candidates = append(candidates, i)
+		}
+	}
+	if index < 0 {
+		t.Fatalf("media %s tidak ada", id)
+	}
+	sort.Slice(candidates, func(a, b int) bool {
+		ma, mb := s.media[candidates[a]], s.media[candidates[b]]
+		if ma.IsReference != mb.IsReference {
+			return !ma.IsReference
+		}
+		return ma.CreatedAt.Before(mb.CreatedAt)
+	})
+
+	var heir *models.Media
+	if len(candidates) > 0 {
+		heir = &s.media[candidates[0]]
+	}
+	promote, deleteObject := mediaHandoff(s.media[index], heir)
+	if promote {
+		heir.IsReference = false
+	}
+	if deleteObject {
+		s.deleted = append(s.deleted, s.media[index].URL)
+	}
+	s.media = append(s.media[:index], s.media[index+1:]...)
+}
+
+func (s *storage) findURL(id uuid.UUID) string {
+	for _, m := range s.media {
+		if m.ID == id {
+			return m.URL
+		}
+	}
+	return ""
+}
+
+// owners mengembalikan media yang ukurannya dihitung ke kuota (bukan referensi) untuk URL tertentu.
+func (s *storage) owners(url string) []uuid.UUID {
+	var ids []uuid.UUID
+	for _, m := range s.media {
+		if m.URL == url && !m.IsReference {
+			ids = append(ids, m.ID)
+		}
+	}
+	return ids
+}
+
+func TestMediaOwnershipHandoff(t *testing.T) {
+	const shared = "https://bucket.s3.ap-southeast-1.amazonaws.com/images/albums/album_a/foto.jpg"
+	created := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	// Foto asli di album A, lalu diduplikasi ke album B dan C sebagai referensi
+	original := models.Media{ID: uuid.New(), URL: shared, CreatedAt: created}
+	copyB := models.Media{ID: uuid.New(), URL: shared, IsReference: true, CreatedAt: created.Add(time.Hour)}
+	copyC := models.Media{ID: uuid.New(), URL: shared, IsReference: true, CreatedAt: created.Add(2 * time.Hour)}
+	other := models.Media{ID: uuid.New(), URL: "https://bucket.s3.ap-southeast-1.amazonaws.com/images/lain.jpg", CreatedAt: created}
+
+	s := &storage{media: []models.Media{copyC, original, other, copyB}}
+
+	// Pemilik dihapus: salinan tertua mengambil alih, file tetap ada
+	s.purge(t, original.ID)
+	if owners := s.owners(shared); len(owners) != 1 || owners[0] != copyB.ID {
+		t.Fatalf("pemilik setelah purge asli = %v, want [%s]", owners, copyB.ID)
+	}
+	if len(s.deleted) != 0 {
+		t.Fatalf("file dihapus padahal masih dipakai: %v", s.deleted)
+	}
+
+	// Referensi dihapus: kepemilikan tidak berubah
+	s.purge(t, copyC.ID)
+	if owners := s.owners(shared); len(owners) != 1 || owners[0] != copyB.ID {
+		t.Errorf("pemilik setelah purge referensi = %v, want [%s]", owners, copyB.ID)
+	}
+
+	// Media terakhir yang memakai URL: file S3 dihapus, file lain tidak tersentuh
+	s.purge(t, copyB.ID)
+	if len(s.deleted) != 1 || s.deleted[0] != shared {
+		t.Errorf("file dihapus = %v, want [%s]", s.deleted, shared)
+	}
+	if owners := s.owners(other.URL); len(owners) != 1 {
+		t.Errorf("media lain ikut berubah: %v", owners)
+	}
+}
+
+func TestMediaHandoffWithoutFile(t *testing.T) {
+	// Media tanpa URL (mis. upload gagal) tidak pernah menghapus apa pun
+	promote, deleteObject := mediaHandoff(models.Media{ID: uuid.New()}, nil)
+	if promote || deleteObject {
+		t.Errorf("media tanpa URL: promote = %v, delete = %v", promote, deleteObject)
+	}
+
+	// Dua pemilik untuk URL yang sama (data lama): heir yang sudah pemilik tidak diubah
+	owner := models.Media{ID: uuid.New(), URL: "https://bucket/x.jpg"}
+	heir := models.Media{ID: uuid.New(), URL: "https://bucket/x.jpg"}
+	if promote, deleteObject := mediaHandoff(owner, &heir); promote || deleteObject {
+		t.Errorf("heir pemilik: promote = %v, delete = %v", promote, deleteObject)
+	}
+}
@@ -22,14 +22,15 @@ const notificationProto = protoDescriptor.notification;
 async function sendNotification(call, callback) {
   const { to, subject, type, body, name, metadata } = call.request;
 
-  const templateHTML = getTemplateHTML(type, {
-    ...metadata,
-    name: name,
-  });
-
   let user
 
   try {
+    // Tipe template yang tidak dikenal ikut dibalas sebagai error gRPC
+    const templateHTML = getTemplateHTML(type, {
+      ...metadata,
+      name: name,
+    });
+
     user = await prisma.user.findUnique({
       where: { email: to },
     });
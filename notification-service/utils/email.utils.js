@@ -32,6 +32,18 @@ function getTemplateHTML(type, variables = {}) {
     case "album-invitation":
       templateFile = "album.invitation.html";
       break;
+    case "album-export":
+      templateFile = "album.export.html";
+      break;
+    case "mention":
+      templateFile = "mention.html";
+      break;
+    case "notification":
+      templateFile = "notification.html";
+      break;
+    case "invoice-receipt":
+      templateFile = "invoice.receipt.html";
+      break;
     default:
       throw new Error("Unknown template type");
   }